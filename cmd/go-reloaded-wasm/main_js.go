@@ -0,0 +1,35 @@
+//go:build js && wasm
+
+package main
+
+import (
+	"github.com/GiannisPettas/go-reloaded/pkg/reloaded"
+	"syscall/js"
+)
+
+func main() {
+	p, err := reloaded.NewProcessor(reloaded.DefaultOptions())
+	if err != nil {
+		panic(err)
+	}
+	js.Global().Set(FUNCTION_NAME, js.FuncOf(func(_ js.Value, args []js.Value) any {
+		if len(args) == 0 || args[0].Type() != js.TypeString {
+			return map[string]any{"error": FUNCTION_NAME + " expects the text to transform as a string"}
+		}
+		var opts callOptions
+		if len(args) > 1 && args[1].Type() == js.TypeObject {
+			opts = callOptions{Language: option(args[1], "language"), Enable: option(args[1], "enable"), Disable: option(args[1], "disable")}
+		}
+		return transform(p, args[0].String(), opts)
+	}))
+	// The function is called from JavaScript for as long as the page lives
+	select {}
+}
+
+// option reads a string property, "" when it is not set
+func option(options js.Value, name string) string {
+	if value := options.Get(name); value.Type() == js.TypeString {
+		return value.String()
+	}
+	return ""
+}
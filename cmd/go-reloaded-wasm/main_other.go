@@ -0,0 +1,14 @@
+//go:build !(js && wasm)
+
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// main explains the build, transformText only exists in a browser or another JavaScript host
+func main() {
+	fmt.Fprintln(os.Stderr, "go-reloaded-wasm must be built with GOOS=js GOARCH=wasm")
+	os.Exit(2)
+}
@@ -0,0 +1,48 @@
+// Command go-reloaded-wasm exposes the transformer to JavaScript when built for the browser:
+//
+//	GOOS=js GOARCH=wasm go build -o go-reloaded.wasm ./cmd/go-reloaded-wasm
+//
+// It registers transformText(input, options) on the global object. options is optional,
+// {language: "fr", enable: "sentences", disable: "articles"} as in POST /transform, and the
+// result is {output, warnings: [{line, column, offset, command, reason}]} or {error}.
+package main
+
+import (
+	"context"
+	"github.com/GiannisPettas/go-reloaded/pkg/reloaded"
+)
+
+// Name of the function registered on the JavaScript global object
+const FUNCTION_NAME = "transformText"
+
+// callOptions are the per-call options a JavaScript caller can pass
+type callOptions struct {
+	Language, Enable, Disable string
+}
+
+// transform runs one call and returns the JavaScript result, a value js.ValueOf accepts
+func transform(p *reloaded.Processor, input string, opts callOptions) map[string]any {
+	call := reloaded.PerCallOptions{Language: opts.Language}
+	var err error
+	if call.Enable, err = reloaded.ParseStages(opts.Enable); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	if call.Disable, err = reloaded.ParseStages(opts.Disable); err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	report, err := p.ProcessReport(context.Background(), input, call)
+	if err != nil {
+		return map[string]any{"error": err.Error()}
+	}
+	warnings := make([]any, len(report.Warnings))
+	for i, warning := range report.Warnings {
+		warnings[i] = map[string]any{
+			"line":    warning.Line,
+			"column":  warning.Column,
+			"offset":  warning.Offset,
+			"command": warning.Command,
+			"reason":  warning.Reason,
+		}
+	}
+	return map[string]any{"output": report.Output, "warnings": warnings}
+}
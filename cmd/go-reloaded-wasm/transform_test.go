@@ -0,0 +1,37 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/pkg/reloaded"
+	"testing"
+)
+
+func TestTransform(t *testing.T) {
+	p, err := reloaded.NewProcessor(reloaded.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		input    string
+		opts     callOptions
+		expected string
+	}{
+		{"a apple (up) zz (hex)", callOptions{}, "map[output:an APPLE zz warnings:[map[column:17 command:(hex) line:1 offset:16 reason:\"zz\" is not a hexadecimal number]]]"},
+		{"Quoi?oui", callOptions{Language: "fr"}, "map[output:Quoi ? oui warnings:[]]"},
+		{"one. two", callOptions{Enable: "sentences", Disable: "articles"}, "map[output:One. Two warnings:[]]"},
+		{"text", callOptions{Enable: "spelling"}, "map[error:unknown stage \"spelling\", expected articles, quotes, ordinals or sentences]"},
+		{"text", callOptions{Language: "xx"}, ""},
+	}
+	for _, test := range tests {
+		result := transform(p, test.input, test.opts)
+		if test.expected == "" {
+			if _, ok := result["error"]; !ok {
+				t.Errorf("transform(%q, %+v) = %v, expected an error", test.input, test.opts, result)
+			}
+			continue
+		}
+		if fmt.Sprint(result) != test.expected {
+			t.Errorf("transform(%q, %+v) = %v, expected %s", test.input, test.opts, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/marker"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileTarget is one input and the output it is written to
+type fileTarget struct {
+	input  string
+	output string
+}
+
+// processTargets maps the positional arguments to the files to process. Two arguments are an
+// input and an output file, unless the second is an existing directory. Otherwise the last
+// argument is the directory every input is written to under its own name.
+func processTargets(args []string) ([]fileTarget, error) {
+	last := args[len(args)-1]
+	info, err := os.Stat(last)
+	isDir := err == nil && info.IsDir()
+	if len(args) == 2 && !isDir {
+		return []fileTarget{{input: args[0], output: last}}, nil
+	}
+	if err == nil && !isDir {
+		return nil, fmt.Errorf("the last argument must be a directory when processing several files, %s is a file", last)
+	}
+
+	targets := make([]fileTarget, 0, len(args)-1)
+	inputs := map[string]string{}
+	for _, input := range args[:len(args)-1] {
+		name := inputName(input)
+		if other, ok := inputs[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be written to %s", other, input, filepath.Join(last, name))
+		}
+		inputs[name] = input
+		targets = append(targets, fileTarget{input: input, output: filepath.Join(last, name)})
+	}
+	return targets, nil
+}
+
+// processConcat transforms the targets in order into their one output and reports every
+// input like processOne
+func processConcat(targets []fileTarget, opts config.Options, run *runFlags, logger *slog.Logger) int {
+	inputs := make([]string, len(targets))
+	for i, target := range targets {
+		inputs[i] = target.input
+	}
+	output := targets[0].output
+	start := time.Now()
+	_, err := controller.ConcatFiles(inputs, output, run.separator, opts)
+	if run.metrics != "" {
+		exportRunMetrics(logger, run.metrics, inputs[0], output, time.Since(start), err != nil)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error processing file: %v\n", err)
+		return exitCode(err)
+	}
+	if !run.quiet {
+		for _, input := range inputs {
+			fmt.Printf("Successfully processed %s -> %s\n", input, output)
+		}
+	}
+	if run.markProcessed {
+		if err := marker.Mark(output, opts); err != nil {
+			warn(logger, "could not mark the output", err)
+		}
+	}
+	if !run.quiet {
+		fmt.Printf("Joined %d files into %s in %v\n", len(inputs), output, time.Since(start).Round(time.Millisecond))
+	}
+	return 0
+}
+
+// inputName is the name an input is written to in an output directory: the base name of a
+// file, or the last element of the path of a URL
+func inputName(input string) string {
+	if !parser.IsURL(input) {
+		return filepath.Base(input)
+	}
+	if u, err := url.Parse(input); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+		return path.Base(u.Path)
+	}
+	return "index.txt"
+}
+
+// processBatch processes the targets on up to jobs workers. Every file is written to its own
+// output only and a failed file does not stop the others. The exit code is the one of the first
+// failed file in argument order, a summary of all files is printed at the end.
+func processBatch(targets []fileTarget, jobs int, opts config.Options, run *runFlags, logger *slog.Logger) int {
+	start := time.Now()
+	codes := make([]int, len(targets))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for worker := 0; worker < min(jobs, len(targets)); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				codes[i] = processOne(targets[i], opts, run, logger)
+			}
+		}()
+	}
+	for i := range targets {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	code, failed := 0, 0
+	var read, written int64
+	for i, target := range targets {
+		if codes[i] != 0 {
+			failed++
+			if code == 0 {
+				code = codes[i]
+			}
+			continue
+		}
+		if info, err := os.Stat(target.input); err == nil {
+			read += info.Size()
+		}
+		if info, err := os.Stat(target.output); err == nil {
+			written += info.Size()
+		}
+	}
+	if !run.quiet {
+		fmt.Printf("Processed %d files in %v: %d bytes in, %d bytes out, %d failed\n", len(targets), time.Since(start).Round(time.Millisecond), read, written, failed)
+	}
+	return code
+}
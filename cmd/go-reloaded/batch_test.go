@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessTargets(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "file.txt")
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []fileTarget
+		fails    bool
+	}{
+		{"two files", []string{"a.txt", "b.txt"}, []fileTarget{{"a.txt", "b.txt"}}, false},
+		{"into a directory", []string{"in/a.txt", dir}, []fileTarget{{"in/a.txt", filepath.Join(dir, "a.txt")}}, false},
+		{"several files", []string{"a.txt", "in/b.txt", out}, []fileTarget{{"a.txt", filepath.Join(out, "a.txt")}, {"in/b.txt", filepath.Join(out, "b.txt")}}, false},
+		{"several files into a file", []string{"a.txt", "b.txt", file}, nil, true},
+		{"same name twice", []string{"a/x.txt", "b/x.txt", out}, nil, true},
+		{"urls", []string{"https://example.com/docs/a.txt?v=2", "http://example.com", out}, []fileTarget{{"https://example.com/docs/a.txt?v=2", filepath.Join(out, "a.txt")}, {"http://example.com", filepath.Join(out, "index.txt")}}, false},
+	}
+	for _, test := range tests {
+		targets, err := processTargets(test.args)
+		if (err != nil) != test.fails || !reflect.DeepEqual(targets, test.expected) {
+			t.Errorf("%s: processTargets(%q) = %v, %v", test.name, test.args, targets, err)
+		}
+	}
+}
+
+func TestRunProcessJobs(t *testing.T) {
+	dir := t.TempDir()
+	var inputs []string
+	for i := 0; i < 20; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("in%02d.txt", i))
+		if err := os.WriteFile(path, []byte(fmt.Sprintf("file (up) %d a apple\n", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		inputs = append(inputs, path)
+	}
+	out := filepath.Join(dir, "out")
+
+	args := append([]string{"--quiet", "--jobs", "4"}, inputs...)
+	if code := runProcess(append(args, out)); code != 0 {
+		t.Fatalf("runProcess = %d, expected 0", code)
+	}
+	for i, input := range inputs {
+		content, err := os.ReadFile(filepath.Join(out, filepath.Base(input)))
+		if expected := fmt.Sprintf("FILE %d an apple\n", i); err != nil || string(content) != expected {
+			t.Errorf("Output of %s: %q, %v, expected %q", input, content, err, expected)
+		}
+	}
+
+	// A missing input fails the run but not the other files
+	missing := filepath.Join(dir, "missing.txt")
+	failOut := filepath.Join(dir, "fail")
+	if code := runProcess([]string{"--quiet", "--jobs", "2", missing, inputs[0], failOut}); code != EXIT_INPUT {
+		t.Errorf("Expected exit code %d for a missing input, got %d", EXIT_INPUT, code)
+	}
+	if _, err := os.Stat(filepath.Join(failOut, filepath.Base(inputs[0]))); err != nil {
+		t.Errorf("The other file should still be written: %v", err)
+	}
+
+	for _, args := range [][]string{
+		{"--jobs", "0", inputs[0], out},
+		{"--debug-chunks", filepath.Join(dir, "trace.jsonl"), inputs[0], inputs[1], out},
+		{"--trace", filepath.Join(dir, "trace.json"), inputs[0], inputs[1], out},
+	} {
+		if code := runProcess(args); code != EXIT_USAGE {
+			t.Errorf("runProcess(%q) = %d, expected %d", args, code, EXIT_USAGE)
+		}
+	}
+}
+
+func TestRunProcessOutput(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt")
+	if err := os.WriteFile(first, []byte("one (up)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("a apple\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The flags may follow the files
+	combined := filepath.Join(dir, "combined.txt")
+	if code := runProcess([]string{"--quiet", first, second, "--separator", `\n`, "--output", combined}); code != 0 {
+		t.Fatalf("runProcess = %d, expected 0", code)
+	}
+	if data, err := os.ReadFile(combined); err != nil || string(data) != "ONE\n\nan apple\n" {
+		t.Errorf("Expected the joined outputs, got %q, %v", data, err)
+	}
+
+	if code := runProcess([]string{"--quiet", "--output", combined, first, filepath.Join(dir, "missing.txt")}); code != EXIT_INPUT {
+		t.Errorf("Expected exit code %d for a missing input, got %d", EXIT_INPUT, code)
+	}
+	for _, args := range [][]string{
+		{"--output", combined},
+		{"--output", combined, "--resume", first, second},
+		{"--skip-processed", first, second, "--output", combined},
+	} {
+		if code := runProcess(args); code != EXIT_USAGE {
+			t.Errorf("runProcess(%q) = %d, expected %d", args, code, EXIT_USAGE)
+		}
+	}
+}
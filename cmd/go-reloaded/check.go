@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// runCheck transforms every file into a scratch directory and reports the files whose output
+// differs from their content and the commands that would not be applied. Nothing is written
+// next to the files; the exit code is 1 when anything was reported.
+func runCheck(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("check", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	flags.StringVar(&opts.Encoding, "encoding", opts.Encoding, "`encoding` of the files: utf8, latin1, utf16le, utf16be or auto")
+	list := flags.Bool("l", false, "only list the files that would change, one path per line like gofmt -l")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s check [-l] [options] <file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	return checkFiles(os.Stdout, flags.Args(), opts, *list)
+}
+
+// checkFiles reports on out the paths that would change and, unless list is set, the
+// commands that would not be applied. With list only the paths are printed, one per line,
+// for hooks and scripts. The exit code is 1 when anything was reported.
+func checkFiles(out io.Writer, paths []string, opts config.Options, list bool) int {
+	dir, err := os.MkdirTemp("", "go-reloaded-check-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Check error: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	warnings := &lineCounter{w: out}
+	opts.Warnings = warnings
+	if list {
+		opts.Warnings = nil
+	}
+	code, changed := 0, 0
+	for _, path := range paths {
+		output := filepath.Join(dir, "output")
+		if err := controller.ProcessFileWithOptions(path, output, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking %s: %v\n", path, err)
+			if code == 0 {
+				code = exitCode(err)
+			}
+			continue
+		}
+		same, err := sameContent(path, output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error checking %s: %v\n", path, err)
+			if code == 0 {
+				code = EXIT_INPUT
+			}
+			continue
+		}
+		if !same && list {
+			fmt.Fprintln(out, path)
+		} else if !same {
+			fmt.Fprintf(out, "%s: would change\n", path)
+		}
+		if !same {
+			changed++
+		}
+	}
+	if code == 0 && (changed > 0 || warnings.lines > 0) {
+		code = 1
+	}
+	return code
+}
+
+// lineCounter counts the lines written through it
+type lineCounter struct {
+	w     io.Writer
+	lines int
+}
+
+func (c *lineCounter) Write(p []byte) (int, error) {
+	c.lines += bytes.Count(p, []byte("\n"))
+	return c.w.Write(p)
+}
+
+// sameContent reports whether two files hold the same bytes, without reading either whole
+func sameContent(a, b string) (bool, error) {
+	fileA, err := os.Open(a)
+	if err != nil {
+		return false, err
+	}
+	defer fileA.Close()
+	fileB, err := os.Open(b)
+	if err != nil {
+		return false, err
+	}
+	defer fileB.Close()
+
+	readerA, readerB := bufio.NewReader(fileA), bufio.NewReader(fileB)
+	bufA, bufB := make([]byte, config.CHUNK_BYTES), make([]byte, config.CHUNK_BYTES)
+	for {
+		nA, errA := io.ReadFull(readerA, bufA)
+		nB, errB := io.ReadFull(readerB, bufB)
+		if !bytes.Equal(bufA[:nA], bufB[:nB]) {
+			return false, nil
+		}
+		endA := errA == io.EOF || errA == io.ErrUnexpectedEOF
+		endB := errB == io.EOF || errB == io.ErrUnexpectedEOF
+		switch {
+		case errA != nil && !endA:
+			return false, errA
+		case errB != nil && !endB:
+			return false, errB
+		case endA || endB:
+			return endA && endB, nil
+		}
+	}
+}
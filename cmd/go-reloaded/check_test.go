@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunCheck(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clean.txt":   "It was an apple, fine.\n",
+		"changed.txt": "It was a apple (up) .\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	clean, changed := filepath.Join(dir, "clean.txt"), filepath.Join(dir, "changed.txt")
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"clean", []string{clean}, 0},
+		{"changed", []string{clean, changed}, 1},
+		{"missing file", []string{filepath.Join(dir, "missing.txt"), changed}, EXIT_INPUT},
+		{"no files", nil, EXIT_USAGE},
+		{"invalid options", []string{"--smart-quotes", "--ascii-quotes", clean}, EXIT_USAGE},
+	}
+	for _, test := range tests {
+		if code := runCheck(test.args); code != test.expected {
+			t.Errorf("%s: runCheck(%q) = %d, expected %d", test.name, test.args, code, test.expected)
+		}
+	}
+
+	// Checking never writes the files
+	if content, err := os.ReadFile(changed); err != nil || string(content) != files["changed.txt"] {
+		t.Errorf("check modified %s: %q, %v", changed, content, err)
+	}
+}
+
+func TestCheckFilesList(t *testing.T) {
+	dir := t.TempDir()
+	clean, changed, warned := filepath.Join(dir, "clean.txt"), filepath.Join(dir, "changed.txt"), filepath.Join(dir, "warned.txt")
+	for path, content := range map[string]string{clean: "Fine.\n", changed: "a apple\n", warned: "zz (hex)\n"} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out bytes.Buffer
+	if code := checkFiles(&out, []string{clean, changed, warned}, config.DefaultOptions(), true); code != 1 {
+		t.Errorf("Expected 1 for a listed file, got %d", code)
+	}
+	// The warning is not printed, the file is listed because the command would be dropped
+	if out.String() != changed+"\n"+warned+"\n" {
+		t.Errorf("Expected only the changed paths, got %q", out.String())
+	}
+	out.Reset()
+	if code := checkFiles(&out, []string{clean}, config.DefaultOptions(), true); code != 0 || out.Len() != 0 {
+		t.Errorf("Expected a silent 0 for a clean file, got %d %q", code, out.String())
+	}
+
+	// The process form takes the same files with --check or -l and never needs an output
+	for _, args := range [][]string{{"-l", clean}, {"--check", clean}, {"--check", "--strict", clean}} {
+		if code := runProcess(args); code != 0 {
+			t.Errorf("runProcess(%q) = %d, expected 0", args, code)
+		}
+	}
+	if code := runProcess([]string{"-l", clean, changed}); code != 1 {
+		t.Errorf("Expected 1 from -l with a changed file, got %d", code)
+	}
+	if code := runProcess([]string{"--check"}); code != EXIT_USAGE {
+		t.Errorf("Expected a usage error without files, got %d", code)
+	}
+}
+
+func TestSameContent(t *testing.T) {
+	dir := t.TempDir()
+	long := make([]byte, 3*4096+17)
+	for i := range long {
+		long[i] = byte('a' + i%26)
+	}
+	altered := append([]byte(nil), long...)
+	altered[len(altered)-1] = '!'
+	for name, content := range map[string][]byte{"long": long, "copy": long, "altered": altered, "short": long[:100]} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"long", "copy", true},
+		{"long", "altered", false},
+		{"long", "short", false},
+		{"short", "long", false},
+	}
+	for _, test := range tests {
+		same, err := sameContent(filepath.Join(dir, test.a), filepath.Join(dir, test.b))
+		if err != nil || same != test.expected {
+			t.Errorf("sameContent(%s, %s) = %v, %v, expected %v", test.a, test.b, same, err, test.expected)
+		}
+	}
+	if _, err := sameContent(filepath.Join(dir, "long"), filepath.Join(dir, "missing")); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
@@ -0,0 +1,19 @@
+package main
+
+import "os"
+
+// legacyInvocation reports whether args are the original "<input_file> <output_file>" form
+// even though the first argument names a subcommand: exactly two arguments, the first an
+// existing file. Scripts written before the subcommands keep working for files called
+// "review" or "serve"; "process" is the explicit form that is never ambiguous.
+// The two positional form stays supported for the whole v1 series.
+func legacyInvocation(args []string) bool {
+	if len(args) != 2 {
+		return false
+	}
+	if _, ok := lookupSubcommand(args[0]); !ok {
+		return false
+	}
+	info, err := os.Stat(args[0])
+	return err == nil && info.Mode().IsRegular()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestLegacyInvocation(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.WriteFile("serve", []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir("review", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args   []string
+		legacy bool
+	}{
+		{[]string{"serve", "out.txt"}, true},             // an input file that happens to be called serve
+		{[]string{"serve", "-addr", ":8080"}, false},     // three arguments are never the old form
+		{[]string{"review", "out.txt"}, false},           // a directory is what review expects
+		{[]string{"help", "commands"}, false},            // no file called help
+		{[]string{"in.txt", "out.txt"}, false},           // not a subcommand, processed anyway
+		{[]string{"process", "serve", "out.txt"}, false}, // the explicit form
+	}
+	for _, test := range tests {
+		if legacy := legacyInvocation(test.args); legacy != test.legacy {
+			t.Errorf("legacyInvocation(%q) = %v, expected %v", test.args, legacy, test.legacy)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+)
+
+// Exit codes, so scripts can tell the failures apart. Every other failure exits with 1.
+const (
+	EXIT_USAGE     = 2 // unknown flag, wrong number of arguments or invalid options
+	EXIT_INPUT     = 3 // the input file is missing, unreadable or cannot be decoded
+	EXIT_OUTPUT    = 4 // the output cannot be encoded or written
+	EXIT_STRICT    = 5 // --strict and a warning was reported, the output is written anyway
+	EXIT_VERIFY    = 6 // --verify and transforming the output again changes it, the output is written anyway
+	EXIT_MEMORY    = 7 // --max-memory and the input needs more to be transformed, the output is incomplete
+	EXIT_UNAPPLIED = 8 // --unapplied error and a command was not applied, the output is not written or is incomplete
+)
+
+// exitCode picks the exit code of a failed file
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, controller.ErrStrict):
+		return EXIT_STRICT
+	case errors.Is(err, controller.ErrVerify):
+		return EXIT_VERIFY
+	case errors.Is(err, controller.ErrUnapplied):
+		return EXIT_UNAPPLIED
+	case errors.Is(err, controller.ErrMemory):
+		return EXIT_MEMORY
+	case errors.Is(err, controller.ErrInput):
+		return EXIT_INPUT
+	case errors.Is(err, controller.ErrOutput):
+		return EXIT_OUTPUT
+	}
+	return 1
+}
+
+// usageExit is the exit code of a failed flag parse, asking for the usage with -h succeeds
+func usageExit(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return EXIT_USAGE
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Name of the input in the warnings of --format-stdin
+const STDIN_NAME = "<stdin>"
+
+// formatStdin transforms the fragment read from in, a region selected in an editor, and
+// writes it to out. Errors, and the warnings when report is set, go to errs; with opts.Strict
+// a warning fails the run after the fragment was written, as for a file.
+func formatStdin(in io.Reader, out, errs io.Writer, opts config.Options, report bool) int {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		fmt.Fprintf(errs, "Error reading %s: %v\n", STDIN_NAME, err)
+		return EXIT_INPUT
+	}
+	result, found := formatFragment(string(data), opts)
+	if _, err := io.WriteString(out, result); err != nil {
+		fmt.Fprintf(errs, "Error writing output: %v\n", err)
+		return EXIT_OUTPUT
+	}
+	if report {
+		for _, warning := range found {
+			fmt.Fprintf(errs, "%s:%s\n", STDIN_NAME, warning)
+		}
+	}
+	if opts.Strict && len(found) > 0 {
+		return EXIT_STRICT
+	}
+	return 0
+}
+
+// formatFragment transforms a fragment and keeps the whitespace around it byte for byte:
+// the indentation before its first word and the spaces and line breaks after its last, which
+// the transformer would otherwise drop. A fragment is transformed on its own, so the same
+// selection always gives the same result, whatever text surrounds it in the editor. Warning
+// positions are those in the fragment.
+func formatFragment(text string, opts config.Options) (string, []transformer.Warning) {
+	body := strings.TrimLeftFunc(text, unicode.IsSpace)
+	lead := text[:len(text)-len(body)]
+	body = strings.TrimRightFunc(body, unicode.IsSpace)
+	trail := text[len(lead)+len(body):]
+	if body == "" {
+		return text, nil
+	}
+
+	output, warnings := transformer.ProcessTextWarnings(body, opts)
+	lines := strings.Count(lead, "\n")
+	indent := utf8.RuneCountInString(lead[strings.LastIndexByte(lead, '\n')+1:])
+	for i := range warnings {
+		if warnings[i].Line == 1 {
+			warnings[i].Column += indent
+		}
+		warnings[i].Line += lines
+		warnings[i].Offset += len(lead)
+	}
+	return lead + output + trail, warnings
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestFormatFragment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a apple (up) ,here", "an APPLE, here"},
+		{"a apple (up) ,here\n", "an APPLE, here\n"},
+		{"    indented (cap) line  \n\n", "    Indented line  \n\n"},
+		{"\n\tsecond (up) line\r\n", "\n\tSECOND line\r\n"},
+		{"one\ntwo (up)\n", "one\nTWO\n"},
+		{"  \n ", "  \n "},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if result, _ := formatFragment(test.input, config.DefaultOptions()); result != test.expected {
+			t.Errorf("formatFragment(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// Warnings point into the fragment, past the whitespace before it
+	_, warnings := formatFragment("\n  zz (hex)\n", config.DefaultOptions())
+	if len(warnings) != 1 || warnings[0].Line != 2 || warnings[0].Column != 6 || warnings[0].Offset != 6 {
+		t.Errorf("Unexpected warnings %+v", warnings)
+	}
+}
+
+func TestFormatStdin(t *testing.T) {
+	var out, errs bytes.Buffer
+	opts := config.DefaultOptions()
+	if code := formatStdin(strings.NewReader("it (up) is"), &out, &errs, opts, false); code != 0 || out.String() != "IT is" || errs.Len() != 0 {
+		t.Errorf("Got %d %q %q", code, out.String(), errs.String())
+	}
+
+	out.Reset()
+	opts.Strict = true
+	if code := formatStdin(strings.NewReader("zz (hex) ok\n"), &out, &errs, opts, true); code != EXIT_STRICT || out.String() != "zz ok\n" {
+		t.Errorf("Expected the fragment written and a strict failure, got %d %q", code, out.String())
+	}
+	if !strings.HasPrefix(errs.String(), STDIN_NAME+":1:4: (hex)") {
+		t.Errorf("Unexpected warnings %q", errs.String())
+	}
+
+	if code := runProcess([]string{"--format-stdin", "file.txt"}); code != EXIT_USAGE {
+		t.Errorf("Expected a usage error for a file with --format-stdin, got %d", code)
+	}
+}
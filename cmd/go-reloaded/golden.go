@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/selftest"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"io"
+	"os"
+)
+
+// runGolden runs the golden tests at every path through the file pipeline and prints a
+// summary. A path is a golden_tests.md style file or a directory of case files; the exit
+// code is 1 when a case failed. With -update the outputs are written back as the expectations.
+func runGolden(args []string) int {
+	flags := flag.NewFlagSet("golden", flag.ContinueOnError)
+	verbose := flags.Bool("v", false, "also list the cases that pass")
+	update := flags.Bool("update", false, "write the current outputs to the golden files instead of comparing")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s golden [-v] [-update] <file_or_directory>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	var tests []testutils.GoldenTest
+	for _, path := range flags.Args() {
+		loaded, err := testutils.LoadGolden(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Golden error: %v\n", err)
+			return EXIT_INPUT
+		}
+		tests = append(tests, loaded...)
+	}
+
+	if *update {
+		changed, err := testutils.UpdateGoldenTests(tests, selftest.ProcessText)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Golden error: %v\n", err)
+			return 1
+		}
+		for _, test := range changed {
+			fmt.Printf("UPDATE %s (%s)\n", test.Name, test.Path)
+		}
+		fmt.Printf("%d updated, %d unchanged\n", len(changed), len(tests)-len(changed))
+		return 0
+	}
+
+	report, err := testutils.RunGolden(tests, selftest.ProcessText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Golden error: %v\n", err)
+		return 1
+	}
+	writeGoldenReport(os.Stdout, tests, report, *verbose)
+	if !report.Passed() {
+		return 1
+	}
+	return 0
+}
+
+// writeGoldenReport prints every failed case with its expected and actual output, the
+// passing ones when verbose is set, and a summary line
+func writeGoldenReport(w io.Writer, tests []testutils.GoldenTest, report testutils.GoldenReport, verbose bool) {
+	failed := make(map[string]bool, len(report.Failures))
+	for _, failure := range report.Failures {
+		failed[failure.Test.Name] = true
+		fmt.Fprintf(w, "FAIL %s\n  expected: %q\n  actual:   %q\n", failure.Test.Name, failure.Test.Expected, failure.Actual)
+	}
+	if verbose {
+		for _, test := range tests {
+			if !failed[test.Name] {
+				fmt.Fprintf(w, "PASS %s\n", test.Name)
+			}
+		}
+	}
+	fmt.Fprintf(w, "%d passed, %d failed\n", report.Cases-len(report.Failures), len(report.Failures))
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunGolden(t *testing.T) {
+	passing, failing := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(passing, "upper.input.txt"):    "it (up)\n",
+		filepath.Join(passing, "upper.expected.txt"): "IT\n",
+		filepath.Join(failing, "wrong.input.txt"):    "it (up)\n",
+		filepath.Join(failing, "wrong.expected.txt"): "it\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected int
+	}{
+		{"passing", []string{passing}, 0},
+		{"project cases", []string{"../../testdata", "../../docs/golden_tests.md"}, 0},
+		{"failing", []string{passing, failing}, 1},
+		{"missing", []string{filepath.Join(passing, "missing")}, EXIT_INPUT},
+		{"no paths", nil, EXIT_USAGE},
+	}
+	for _, test := range tests {
+		if code := runGolden(test.args); code != test.expected {
+			t.Errorf("%s: runGolden(%q) = %d, expected %d", test.name, test.args, code, test.expected)
+		}
+	}
+}
+
+func TestRunGoldenUpdate(t *testing.T) {
+	dir := t.TempDir()
+	expectedPath := filepath.Join(dir, "upper.expected.txt")
+	if err := os.WriteFile(filepath.Join(dir, "upper.input.txt"), []byte("it (up)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(expectedPath, []byte("outdated\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runGolden([]string{dir}); code != 1 {
+		t.Fatalf("Expected the outdated case to fail, got %d", code)
+	}
+	if code := runGolden([]string{"-update", dir}); code != 0 {
+		t.Fatalf("runGolden -update = %d, expected 0", code)
+	}
+	if content, err := os.ReadFile(expectedPath); err != nil || string(content) != "IT\n" {
+		t.Errorf("Expected the update to write IT, got %q, %v", content, err)
+	}
+	if code := runGolden([]string{dir}); code != 0 {
+		t.Errorf("Expected the updated case to pass, got %d", code)
+	}
+}
+
+func TestWriteGoldenReport(t *testing.T) {
+	tests := []testutils.GoldenTest{{Name: "a", Input: "x", Expected: "x"}, {Name: "b", Input: "y", Expected: "Y"}}
+	report := testutils.GoldenReport{Cases: 2, Failures: []testutils.GoldenFailure{{Test: tests[1], Actual: "y"}}}
+
+	var out bytes.Buffer
+	writeGoldenReport(&out, tests, report, true)
+	expected := "FAIL b\n  expected: \"Y\"\n  actual:   \"y\"\nPASS a\n1 passed, 1 failed\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
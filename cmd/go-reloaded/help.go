@@ -0,0 +1,106 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"os"
+)
+
+// helpTopic is one page of the help system
+type helpTopic struct {
+	name    string
+	summary string
+	write   func(w io.Writer)
+}
+
+// helpTopics lists the pages available through "help <topic>"
+var helpTopics = []helpTopic{
+	{"commands", "transformation commands usable inside the text", writeCommandsHelp},
+	{"formats", "input and output file formats", writeFormatsHelp},
+	{"config", "options of the processing mode", writeConfigHelp},
+}
+
+// runHelp prints the overview or a single help topic
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		writeOverviewHelp(os.Stdout)
+		return 0
+	}
+
+	for _, topic := range helpTopics {
+		if topic.name == args[0] {
+			topic.write(os.Stdout)
+			return 0
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Unknown help topic %q\n", args[0])
+	writeOverviewHelp(os.Stderr)
+	return EXIT_USAGE
+}
+
+func writeOverviewHelp(w io.Writer) {
+	fmt.Fprintf(w, "Usage:\n")
+	writeSubcommandsHelp(w)
+	fmt.Fprintf(w, "\nHelp topics:\n")
+	for _, topic := range helpTopics {
+		fmt.Fprintf(w, "  %-10s %s\n", topic.name, topic.summary)
+	}
+}
+
+// writeCommandsHelp is generated from the transformer command registry
+func writeCommandsHelp(w io.Writer) {
+	fmt.Fprintf(w, "Commands apply to the word(s) before them:\n\n")
+	for _, info := range transformer.Commands() {
+		syntax := "(" + info.Name + ")"
+		if info.MultiWord {
+			syntax += " (" + info.Name + ", n)"
+		}
+		fmt.Fprintf(w, "  %-24s %s\n", syntax, info.Summary)
+		fmt.Fprintf(w, "  %-24s %s\n", "", "example: "+info.Example)
+	}
+
+	fmt.Fprintf(w, "\nModifiers:\n")
+	fmt.Fprintf(w, "  %-24s %s\n", "(name, "+transformer.COUNT_ALL+")", "apply a multi-word command to every preceding word")
+	fmt.Fprintf(w, "  %-24s %s\n", "(name"+transformer.FORWARD_MARKER+") (name"+transformer.FORWARD_MARKER+", n)", "apply to the next word(s) instead of the previous ones")
+	fmt.Fprintf(w, "  %-24s %s\n", `\(name\)`, "escaped parentheses are kept as literal text")
+	fmt.Fprintf(w, "\nInvalid or unknown commands are left in the text unchanged.\n")
+}
+
+func writeFormatsHelp(w io.Writer) {
+	fmt.Fprintf(w, "Input:\n")
+	fmt.Fprintf(w, "  UTF-8 plain text of any size. Files up to %d bytes are processed in a single pass,\n", config.CHUNK_BYTES)
+	fmt.Fprintf(w, "  larger files in %d byte chunks with %d words of overlap between chunks.\n", config.CHUNK_BYTES, config.OVERLAP_WORDS)
+	fmt.Fprintf(w, "  Spaces and tabs are normalized to single spaces, line breaks are kept.\n")
+	fmt.Fprintf(w, "\nOutput:\n")
+	fmt.Fprintf(w, "  UTF-8 plain text written to the output path, parent directories are created.\n")
+	fmt.Fprintf(w, "  An existing output file is overwritten.\n")
+}
+
+// writeConfigHelp is generated from the processing flag definitions
+func writeConfigHelp(w io.Writer) {
+	opts := config.DefaultOptions()
+	flags, _ := newProcessFlags(&opts)
+
+	fmt.Fprintf(w, "Options (place them before the input and output files):\n\n")
+	flags.VisitAll(func(f *flag.Flag) {
+		name, usage := flag.UnquoteUsage(f)
+		// One-letter flags are written the short way: -l, -v
+		line := "  --" + f.Name
+		if len(f.Name) == 1 {
+			line = "  -" + f.Name
+		}
+		if name != "" {
+			line += " " + name
+		}
+		fmt.Fprintf(w, "%-28s %s", line, usage)
+		if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+			fmt.Fprintf(w, " (default %s)", f.DefValue)
+		}
+		fmt.Fprintln(w)
+	})
+	fmt.Fprintf(w, "\nSystem limits: CHUNK_BYTES=%d, OVERLAP_WORDS=%d\n", config.CHUNK_BYTES, config.OVERLAP_WORDS)
+}
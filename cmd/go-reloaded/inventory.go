@@ -0,0 +1,159 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"os"
+	"slices"
+)
+
+// runInventory lists every command of the files with its line and column, and how often
+// each command is used, without transforming anything
+func runInventory(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	flags.StringVar(&opts.Encoding, "encoding", opts.Encoding, "`encoding` of the files: utf8, latin1, utf16le, utf16be or auto")
+	flags.StringVar(&opts.Compress, "compress", opts.Compress, "`codec` of the files: gzip, zstd, none or auto to go by the .gz or .zst name")
+	asJSON := flags.Bool("json", false, "write the inventory as one JSON object")
+	summary := flags.Bool("summary", false, "only print the counts, not every command")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s inventory [-json] [-summary] [options] <file>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	code := 0
+	var files []inventoryFile
+	for _, path := range flags.Args() {
+		found, err := controller.InventoryFile(path, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+			if code == 0 {
+				code = exitCode(err)
+			}
+			continue
+		}
+		files = append(files, inventoryFile{Path: path, Commands: found})
+	}
+	report := newInventory(files)
+	if *summary {
+		report.Files = nil
+	}
+	if *asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing inventory: %v\n", err)
+			return 1
+		}
+		return code
+	}
+	writeInventory(os.Stdout, report)
+	return code
+}
+
+// inventory is the report of the inventory subcommand
+type inventory struct {
+	Files    []inventoryFile `json:"files,omitempty"`
+	Total    int             `json:"total"`
+	Counts   []commandUsage  `json:"counts"`
+	Searched int             `json:"searched"` // files read
+}
+
+// inventoryFile holds the commands of one file
+type inventoryFile struct {
+	Path     string                   `json:"path"`
+	Commands []transformer.Occurrence `json:"commands"`
+}
+
+// commandUsage is how often one command is written across the files
+type commandUsage struct {
+	Name    string `json:"name"`
+	Count   int    `json:"count"`
+	Invalid int    `json:"invalid,omitempty"` // of them with a problem, see transformer.Occurrence
+	Files   int    `json:"files"`             // files it is written in
+}
+
+// newInventory counts the commands of files per name, the most used first
+func newInventory(files []inventoryFile) inventory {
+	report := inventory{Files: files, Counts: []commandUsage{}, Searched: len(files)}
+	usage := map[string]*commandUsage{}
+	for _, file := range files {
+		seen := map[string]bool{}
+		for _, found := range file.Commands {
+			u, ok := usage[found.Name]
+			if !ok {
+				u = &commandUsage{Name: found.Name}
+				usage[found.Name] = u
+			}
+			u.Count++
+			if found.Problem != "" {
+				u.Invalid++
+			}
+			if !seen[found.Name] {
+				seen[found.Name] = true
+				u.Files++
+			}
+			report.Total++
+		}
+	}
+	for _, u := range usage {
+		report.Counts = append(report.Counts, *u)
+	}
+	slices.SortFunc(report.Counts, func(a, b commandUsage) int {
+		return cmp.Or(b.Count-a.Count, cmp.Compare(a.Name, b.Name))
+	})
+	return report
+}
+
+// writeInventory prints every command like a compiler message, then the counts:
+//
+//	notes.txt:3:7: (up, 2)
+//	notes.txt:4:1: (hex, 2): hex takes no count
+func writeInventory(w io.Writer, report inventory) {
+	for _, file := range report.Files {
+		for _, found := range file.Commands {
+			fmt.Fprintf(w, "%s:%d:%d: %s", file.Path, found.Line, found.Column, found.Command)
+			if found.Problem != "" {
+				fmt.Fprintf(w, ": %s", found.Problem)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(report.Files) > 0 && report.Total > 0 {
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%s in %s\n", countOf(report.Total, "command"), countOf(report.Searched, "file"))
+	for _, u := range report.Counts {
+		fmt.Fprintf(w, "  %-8s %6d in %s", u.Name, u.Count, countOf(u.Files, "file"))
+		if u.Invalid > 0 {
+			fmt.Fprintf(w, ", %d invalid", u.Invalid)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// countOf writes n with noun, made plural unless n is 1: "1 file", "3 files"
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInventory(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt.gz")
+	if err := os.WriteFile(first, []byte("\ufeffgo (up, 2) 1E (hex)\n(up) ff (hex, 2)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("x (cap) y (up>)\n"))
+	zw.Close()
+	if err := os.WriteFile(second, compressed.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []inventoryFile
+	for _, path := range []string{first, second} {
+		found, err := controller.InventoryFile(path, config.DefaultOptions())
+		if err != nil {
+			t.Fatalf("InventoryFile(%s) failed: %v", path, err)
+		}
+		files = append(files, inventoryFile{Path: filepath.Base(path), Commands: found})
+	}
+	var out bytes.Buffer
+	writeInventory(&out, newInventory(files))
+	expected := `a.txt:1:4: (up, 2)
+a.txt:1:15: (hex)
+a.txt:2:1: (up)
+a.txt:2:9: (hex, 2): hex takes no count
+b.txt.gz:1:3: (cap)
+b.txt.gz:1:11: (up>)
+
+6 commands in 2 files
+  up            3 in 2 files
+  hex           2 in 1 file, 1 invalid
+  cap           1 in 1 file
+`
+	if out.String() != expected {
+		t.Errorf("Expected inventory:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	for _, test := range []struct {
+		args     []string
+		expected int
+	}{
+		{[]string{"-summary", first}, 0},
+		{[]string{"-json", first, filepath.Join(dir, "missing.txt")}, EXIT_INPUT},
+		{nil, EXIT_USAGE},
+	} {
+		if code := runInventory(test.args); code != test.expected {
+			t.Errorf("runInventory(%q) = %d, expected %d", test.args, code, test.expected)
+		}
+	}
+}
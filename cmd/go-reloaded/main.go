@@ -1,10 +1,32 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"go-reloaded/internal/config"
-	"go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/archive"
+	"github.com/GiannisPettas/go-reloaded/internal/bench"
+	"github.com/GiannisPettas/go-reloaded/internal/chunktrace"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/marker"
+	"github.com/GiannisPettas/go-reloaded/internal/metrics"
+	"github.com/GiannisPettas/go-reloaded/internal/minimize"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/review"
+	"github.com/GiannisPettas/go-reloaded/internal/selftest"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"golang.org/x/term"
+	"io"
+	"log/slog"
+	"maps"
+	"math"
 	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 )
 
 func main() {
@@ -13,23 +35,925 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Configuration error: %v\n", err)
 		os.Exit(1)
 	}
-	
-	// Check command line arguments
-	if len(os.Args) != 3 {
-		fmt.Fprintf(os.Stderr, "Usage: %s <input_file> <output_file>\n", os.Args[0])
-		fmt.Fprintf(os.Stderr, "Example: %s input.txt output.txt\n", os.Args[0])
-		os.Exit(1)
+
+	// Subcommands, anything else is the original "[options] <input_file> <output_file>" form
+	if len(os.Args) > 1 && !legacyInvocation(os.Args[1:]) {
+		if command, ok := lookupSubcommand(os.Args[1]); ok {
+			os.Exit(command.run(os.Args[2:]))
+		}
+	}
+	os.Exit(runProcess(os.Args[1:]))
+}
+
+// runProcess transforms one file: [options] <input_file> <output_file>, or several files
+// into a directory: [options] <input_file>... <output_dir>
+func runProcess(args []string) int {
+	opts := config.DefaultOptions()
+	flags, run := newProcessFlags(&opts)
+	files, err := parseInterspersed(flags, args)
+	if err != nil {
+		return usageExit(err)
+	}
+
+	// --format-stdin is a filter for editors, it takes no files
+	if run.formatStdin {
+		if len(files) > 0 {
+			fmt.Fprintf(os.Stderr, "Invalid options: --format-stdin reads stdin and takes no files\n")
+			return EXIT_USAGE
+		}
+		if err := opts.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+			return EXIT_USAGE
+		}
+		return formatStdin(os.Stdin, os.Stdout, os.Stderr, opts, run.warnings || opts.Strict)
+	}
+
+	// --check and -l take files only, there is no output to write
+	if run.check || run.list {
+		if len(files) == 0 {
+			flags.Usage()
+			return EXIT_USAGE
+		}
+		if err := opts.Validate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+			return EXIT_USAGE
+		}
+		// The report lists the commands that would not apply, Strict would only fail the files
+		opts.Strict = false
+		return checkFiles(os.Stdout, files, opts, run.list)
+	}
+
+	// Check command line arguments, --output takes the inputs only
+	if len(files) < 2 && (run.output == "" || len(files) == 0) {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	var targets []fileTarget
+	if run.output != "" {
+		for _, input := range files {
+			targets = append(targets, fileTarget{input: input, output: run.output})
+		}
+	} else if targets, err = processTargets(files); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	concat := len(targets) > 1 && run.output != ""
+	if concat && (opts.Checkpoint || opts.Resume || run.skipProcessed) {
+		fmt.Fprintf(os.Stderr, "Invalid options: --output joins several inputs and cannot be combined with --checkpoint, --resume or --skip-processed\n")
+		return EXIT_USAGE
+	}
+	if run.jobs < 1 {
+		fmt.Fprintf(os.Stderr, "Invalid options: --jobs must be at least 1, got %d\n", run.jobs)
+		return EXIT_USAGE
+	}
+	if (len(targets) > 1 || archive.FormatOf(targets[0].input) != "") && run.debugChunks != "" {
+		fmt.Fprintf(os.Stderr, "Invalid options: --debug-chunks traces a single file\n")
+		return EXIT_USAGE
+	}
+	if run.trace != "" && (len(targets) > 1 || archive.FormatOf(targets[0].input) != "") {
+		fmt.Fprintf(os.Stderr, "Invalid options: --trace records a single file\n")
+		return EXIT_USAGE
+	}
+	if run.trace != "" && (opts.Checkpoint || opts.Resume) {
+		fmt.Fprintf(os.Stderr, "Invalid options: --trace covers the whole file and cannot be combined with --checkpoint or --resume\n")
+		return EXIT_USAGE
+	}
+	logger, err := newLogger(os.Stderr, run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	opts.Logger = logger
+
+	if opts.MaxMemory > 0 {
+		// The garbage collector works harder near the limit instead of growing the heap past it
+		debug.SetMemoryLimit(opts.MaxMemory)
+	}
+	if run.nice {
+		if err := lowerPriority(); err != nil {
+			warn(logger, "could not lower process priority", err)
+		}
+	}
+
+	if run.debugChunks != "" {
+		trace, err := openTrace(run.debugChunks)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening chunk trace: %v\n", err)
+			return EXIT_OUTPUT
+		}
+		defer trace.Close()
+		opts.DebugChunks = trace
+	}
+	if run.trace != "" {
+		trace, err := openTrace(run.trace)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error opening edit trace: %v\n", err)
+			return EXIT_OUTPUT
+		}
+		defer trace.Close()
+		opts.Trace = trace
+	}
+	if (run.warnings || opts.Strict) && logger == nil {
+		// With a logger the warnings are log records
+		opts.Warnings = os.Stderr
+	}
+
+	if len(targets) == 1 {
+		return processOne(targets[0], opts, run, logger)
+	}
+	if concat {
+		return processConcat(targets, opts, run, logger)
+	}
+	return processBatch(targets, run.jobs, opts, run, logger)
+}
+
+// processOne transforms one input into its output and reports the outcome
+func processOne(target fileTarget, opts config.Options, run *runFlags, logger *slog.Logger) int {
+	inputFile, outputFile := target.input, target.output
+
+	if run.skipProcessed && !parser.IsURL(inputFile) {
+		processed, err := marker.IsProcessed(inputFile, opts)
+		if err != nil {
+			warn(logger, "could not read processed marker", err)
+		}
+		if processed {
+			if err := copyProcessed(inputFile, outputFile, opts); err != nil {
+				fmt.Fprintf(os.Stderr, "Error copying file: %v\n", err)
+				return 1
+			}
+			if !run.quiet {
+				fmt.Printf("Skipped %s, already processed with these options\n", inputFile)
+			}
+			return 0
+		}
+	}
+
+	// Process the file, or every text file of an archive
+	start := time.Now()
+	var err error
+	var entries archive.Report
+	isArchive := archive.FormatOf(inputFile) != "" && !parser.IsURL(inputFile)
+	if isArchive {
+		entries, err = archive.Process(inputFile, outputFile, opts)
+	} else {
+		err = controller.ProcessFileWithOptions(inputFile, outputFile, opts)
+	}
+	if run.metrics != "" {
+		exportRunMetrics(logger, run.metrics, inputFile, outputFile, time.Since(start), err != nil)
 	}
-	
-	inputFile := os.Args[1]
-	outputFile := os.Args[2]
-	
-	// Process the file
-	err := controller.ProcessFile(inputFile, outputFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error processing file: %v\n", err)
-		os.Exit(1)
+		return exitCode(err)
+	}
+	if run.markProcessed || run.skipProcessed {
+		if err := marker.Mark(outputFile, opts); err != nil {
+			warn(logger, "could not mark the output", err)
+		}
+	}
+
+	if !run.quiet && isArchive {
+		fmt.Printf("Successfully processed %s -> %s, %d of %d entries transformed\n", inputFile, outputFile, entries.Transformed, entries.Entries)
+	} else if !run.quiet {
+		fmt.Printf("Successfully processed %s -> %s\n", inputFile, outputFile)
+	}
+	return 0
+}
+
+// parseInterspersed parses args with flags allowed between and after the files, as in
+// "a.txt b.txt --output all.txt", and returns the files. Everything after "--" is a file.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var files []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		// Parse stops at the first file, or right after a "--" it consumed
+		if len(rest) == 0 || (len(rest) < len(args) && args[len(args)-len(rest)-1] == "--") {
+			return append(files, rest...), nil
+		}
+		files, args = append(files, rest[0]), rest[1:]
+	}
+}
+
+// runFlags are the processing mode options that do not change the transformation
+type runFlags struct {
+	nice          bool
+	jobs          int
+	debugChunks   string
+	trace         string
+	markProcessed bool
+	skipProcessed bool
+	metrics       string
+	warnings      bool
+	verbose       bool
+	quiet         bool
+	logFormat     string
+	check         bool
+	list          bool
+	formatStdin   bool
+	output        string // one output all inputs are joined into
+	separator     string // written between two joined inputs
+}
+
+// newProcessFlags defines the options of the default processing mode, bound to opts
+func newProcessFlags(opts *config.Options) (*flag.FlagSet, *runFlags) {
+	flags := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	bindTransformFlags(flags, opts)
+	flags.StringVar(&opts.Encoding, "encoding", opts.Encoding, "`encoding` of the input and output files: utf8, latin1, utf16le, utf16be or auto")
+	flags.StringVar(&opts.Compress, "compress", opts.Compress, "`codec` of the input and output files: gzip, zstd, none or auto to go by the .gz or .zst name")
+	flags.Func("max-memory", "fail with 7 rather than use more than `size` bytes (512MB, 1GB), 0 is unlimited", func(value string) error {
+		size, err := parseSize(value)
+		opts.MaxMemory = size
+		return err
+	})
+	flags.DurationVar(&opts.FetchTimeout, "fetch-timeout", config.FETCH_TIMEOUT, "give up reading an http(s) input after `duration`, 0 is the 60s default, not unlimited")
+	flags.Func("max-fetch", "fail rather than download more than `size` bytes of an http(s) input (512MB, 1GB), 0 is unlimited", func(value string) error {
+		size, err := parseSize(value)
+		opts.MaxFetchBytes = size
+		return err
+	})
+	flags.Float64Var(&opts.IOThrottleMBps, "io-throttle", opts.IOThrottleMBps, "limit disk bandwidth to `MBps` megabytes per second (0 = unlimited)")
+	run := &runFlags{}
+	flags.BoolVar(&run.nice, "nice", false, "lower the process CPU priority for batch runs on shared servers")
+	flags.IntVar(&run.jobs, "jobs", runtime.NumCPU(), "process up to `N` of several input files at the same time")
+	flags.StringVar(&run.debugChunks, "debug-chunks", "", "write one JSON record per chunk to `file` (- for stderr), check it with verify-chunks")
+	flags.StringVar(&run.trace, "trace", "", "write every edit with its position and the rule or command behind it to `file` as JSON (- for stderr)")
+	flags.StringVar(&run.output, "output", "", "transform every input in order and join them into `file`")
+	flags.Func("separator", "`text` written between two inputs joined by --output, \\n and \\t escapes included", func(value string) error {
+		run.separator = value
+		if unquoted, err := strconv.Unquote(`"` + value + `"`); err == nil {
+			run.separator = unquoted
+		}
+		return nil
+	})
+	flags.BoolVar(&run.markProcessed, "mark-processed", false, "tag the output file as processed (extended attribute)")
+	flags.StringVar(&run.metrics, "metrics", "", "send run metrics to `url`: statsd://host:8125 or otlp://host:4318")
+	flags.BoolVar(&run.skipProcessed, "skip-processed", false, "copy an input tagged as processed with the same options unchanged, implies --mark-processed")
+	flags.BoolVar(&run.warnings, "warnings", false, "report commands that were dropped or left in the text on stderr")
+	flags.BoolVar(&opts.Strict, "strict", false, "exit with 5 when a warning was reported, implies --warnings")
+	flags.BoolVar(&opts.Verify, "verify", false, "exit with 6 when transforming the output again would change it")
+	flags.BoolVar(&opts.Checkpoint, "checkpoint", false, "save the progress on large files next to the output, see --resume")
+	flags.BoolVar(&opts.Resume, "resume", false, "continue an interrupted run from its checkpoint instead of starting over, implies --checkpoint")
+	flags.BoolVar(&run.verbose, "verbose", false, "log chunk progress and stage timings on stderr")
+	flags.BoolVar(&run.verbose, "v", false, "shorthand for --verbose")
+	flags.BoolVar(&run.quiet, "quiet", false, "print nothing but errors")
+	flags.StringVar(&run.logFormat, "log-format", LOG_FORMAT_TEXT, "`format` of the log records: text or json")
+	flags.BoolVar(&run.check, "check", false, "write nothing, report the files that would change like the check subcommand")
+	flags.BoolVar(&run.list, "l", false, "write nothing, list the files that would change one per line like gofmt -l")
+	flags.BoolVar(&run.formatStdin, "format-stdin", false, "transform a fragment from stdin to stdout keeping the whitespace around it, for editors")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <input_file> <output_file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] [--jobs N] <input_file>... <output_dir>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [options] --output <output_file> <input_file>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s --check|-l [options] <file>...\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s --format-stdin [options] < fragment\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Example: %s input.txt output.txt\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Run '%s help' for commands, options and subcommands.\n", os.Args[0])
+	}
+	return flags, run
+}
+
+// Formats of the log records
+const (
+	LOG_FORMAT_TEXT = "text"
+	LOG_FORMAT_JSON = "json"
+)
+
+// newLogger returns the logger asked for by --verbose, --quiet and --log-format, or nil
+// when none of them is given and the tool prints what it always printed
+func newLogger(w io.Writer, run *runFlags) (*slog.Logger, error) {
+	level := slog.LevelInfo
+	switch {
+	case run.quiet:
+		level = slog.LevelError
+	case run.verbose:
+		level = slog.LevelDebug
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	switch run.logFormat {
+	case LOG_FORMAT_JSON:
+		return slog.New(slog.NewJSONHandler(w, handlerOpts)), nil
+	case LOG_FORMAT_TEXT, "":
+		if !run.verbose && !run.quiet {
+			return nil, nil
+		}
+		return slog.New(slog.NewTextHandler(w, handlerOpts)), nil
+	}
+	return nil, fmt.Errorf("invalid log format %q, expected text or json", run.logFormat)
+}
+
+// warn reports a problem that does not stop processing, as a log record when there is a logger
+func warn(logger *slog.Logger, msg string, err error) {
+	if logger != nil {
+		logger.Warn(msg, "err", err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", msg, err)
+}
+
+// exportRunMetrics sends the metrics of one processed file, a failing backend only warns
+func exportRunMetrics(logger *slog.Logger, destination, inputPath, outputPath string, elapsed time.Duration, failed bool) {
+	exporter, err := metrics.New(destination)
+	if err != nil {
+		warn(logger, "could not export metrics", err)
+		return
+	}
+	defer exporter.Close()
+
+	var read, written int64
+	if info, err := os.Stat(inputPath); err == nil {
+		read = info.Size()
+	}
+	if info, err := os.Stat(outputPath); err == nil && !failed {
+		written = info.Size()
+	}
+	if err := exporter.Export(metrics.FileRun(read, written, elapsed, failed)); err != nil {
+		warn(logger, "could not export metrics", err)
+	}
+}
+
+// copyProcessed writes an already processed input to the output unchanged and marks the copy.
+// Nothing is written when both paths are the same file.
+func copyProcessed(inputPath, outputPath string, opts config.Options) error {
+	inputInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return err
+	}
+	if outputInfo, err := os.Stat(outputPath); err == nil && os.SameFile(inputInfo, outputInfo) {
+		return nil
+	}
+
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return err
+	}
+	output, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(output, input); err != nil {
+		output.Close()
+		return err
+	}
+	if err := output.Close(); err != nil {
+		return err
+	}
+	return marker.Mark(outputPath, opts)
+}
+
+// openTrace opens the destination of --debug-chunks or --trace, "-" is stderr.
+// The trace is written unbuffered, so records before a failure are kept even without Close.
+func openTrace(path string) (io.WriteCloser, error) {
+	if path == "-" {
+		return nopCloser{os.Stderr}, nil
+	}
+	return os.Create(path)
+}
+
+// size units of parseSize, by their suffix
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"GB", 1 << 30}, {"G", 1 << 30}, {"MB", 1 << 20}, {"M", 1 << 20}, {"KB", 1 << 10}, {"K", 1 << 10}, {"B", 1},
+}
+
+// parseSize reads a byte count with an optional unit: 1048576, 512KB, 64MB or 1G
+func parseSize(value string) (int64, error) {
+	number, unit := strings.ToUpper(strings.TrimSpace(value)), int64(1)
+	for _, candidate := range sizeUnits {
+		if strings.HasSuffix(number, candidate.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, candidate.suffix)), candidate.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/unit {
+		return 0, fmt.Errorf("invalid size %q, expected a byte count like 512MB", value)
+	}
+	return n * unit, nil
+}
+
+// nopCloser keeps stderr open when the trace is closed
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error { return nil }
+
+// bindTransformFlags defines the options that change the transformation itself
+func bindTransformFlags(flags *flag.FlagSet, opts *config.Options) {
+	flags.BoolVar(&opts.UpperHex, "upper-hex", opts.UpperHex, "emit uppercase digits for (tohex)")
+	flags.BoolVar(&opts.RadixPrefix, "radix-prefix", opts.RadixPrefix, "prefix (tohex)/(tobin) results with 0x/0b")
+	flags.StringVar(&opts.DigitGrouping, "digit-grouping", opts.DigitGrouping, "group the digits of (hex), (bin) and (unrom) results in the `style` of a locale: none, en, de, fr, ch or in")
+	flags.Func("lang", "use the punctuation and digit grouping of a `language`: en or fr", func(lang string) error {
+		rules, err := config.LanguagePunctuation(lang)
+		if err != nil {
+			return err
+		}
+		grouping, err := config.LanguageGrouping(lang)
+		opts.Punctuation, opts.DigitGrouping = rules, grouping
+		return err
+	})
+	flags.BoolVar(&opts.NativeDigits, "native-digits", opts.NativeDigits, "write conversion results in the digit script of the converted word")
+	flags.BoolVar(&opts.StripSoftHyphens, "strip-soft-hyphens", opts.StripSoftHyphens, "remove U+00AD soft hyphens from words")
+	flags.BoolVar(&opts.NormalizeOrdinals, "fix-ordinals", opts.NormalizeOrdinals, "join detached ordinal suffixes (1 st -> 1st)")
+	flags.BoolVar(&opts.PlainOrdinals, "plain-ordinals", opts.PlainOrdinals, "with --fix-ordinals, convert superscript suffixes to plain letters")
+	flags.BoolVar(&opts.TitleSmallWords, "title-small-words", opts.TitleSmallWords, "(title) keeps small words like of/the lowercase inside hyphenated words")
+	flags.BoolVar(&opts.CapitalizeSentences, "capitalize-sentences", opts.CapitalizeSentences, "uppercase the first letter of every sentence")
+	flags.StringVar(&opts.Normalize, "normalize", opts.Normalize, "Unicode normalization `form` of the output: nfc, nfd or none")
+	flags.StringVar(&opts.LineEnding, "line-ending", opts.LineEnding, "output line endings: `auto` keeps each line's ending, lf or crlf force one")
+	flags.StringVar(&opts.BOM, "bom", opts.BOM, "UTF-8 byte order mark of the output: `keep` it when the input had one, strip or add")
+	flags.BoolVar(&opts.PreserveWhitespace, "preserve-whitespace", opts.PreserveWhitespace, "keep runs of spaces, tabs and indentation instead of collapsing them")
+	flags.BoolVar(&opts.SmartQuotes, "smart-quotes", opts.SmartQuotes, "convert paired straight quotes to typographic quotes")
+	flags.BoolVar(&opts.ASCIIQuotes, "ascii-quotes", opts.ASCIIQuotes, "convert typographic quotes to straight ASCII quotes")
+	flags.StringVar(&opts.UnappliedCommandPolicy, "unapplied", opts.UnappliedCommandPolicy, "`policy` for commands that cannot be applied: drop them, keep them as written or error, exiting with 8")
+	flags.StringVar(&opts.ShortCountPolicy, "short-count", opts.ShortCountPolicy, "`policy` for counts larger than the words there are: clamp silently or warn")
+	flags.StringVar(&opts.CommandScope, "scope", opts.CommandScope, "keep commands from reaching past a `boundary`: sentence or paragraph")
+	flags.StringVar(&opts.Dialogue, "dialogue", opts.Dialogue, "normalize dialogue punctuation in the american or logical `style`")
+	flags.StringVar(&opts.RawStart, "raw-start", opts.RawStart, "`marker` opening a region kept exactly as written, \"\" with --raw-end \"\" disables raw regions")
+	flags.StringVar(&opts.RawEnd, "raw-end", opts.RawEnd, "`marker` closing a raw region")
+	flags.BoolVar(&opts.Markdown, "markdown", opts.Markdown, "leave code blocks, inline code and link URLs of markdown untouched")
+	flags.BoolFunc("french-spacing", "space ? ! ; : on both sides like French typography", func(string) error {
+		rules, err := config.ParsePunctuation("?=spaced !=spaced ;=spaced :=spaced", opts.PunctuationRules())
+		opts.Punctuation = rules
+		return err
+	})
+	flags.Var(articleFlag{opts, config.ARTICLE_A}, "article-a", "space separated `words` always preceded by \"a\" (university one euro)")
+	flags.Var(articleFlag{opts, config.ARTICLE_AN}, "article-an", "space separated `words` always preceded by \"an\" (hour honest FBI)")
+	flags.Var(articleFlag{opts, ""}, "article-default", "space separated `words` removed from the built-in exceptions, they follow the vowel/h rule again")
+	flags.Func("config", "read options from `file`, one \"flag = value\" per line", func(path string) error {
+		return applyConfigFile(flags, path)
+	})
+	flags.Func("substitutions", "run the find/replace rules of `file` before the commands or after the built-in rules", func(path string) error {
+		substitutions, err := config.LoadSubstitutions(path)
+		opts.Substitutions = append(opts.Substitutions, substitutions...)
+		return err
+	})
+	flags.Func("autocorrect", "fix the typos of the dictionary `file`, one \"typo = correction\" per line, before the commands", func(path string) error {
+		corrections, err := config.LoadCorrections(path)
+		if err != nil {
+			return err
+		}
+		opts.Autocorrect = maps.Clone(opts.Autocorrect)
+		if opts.Autocorrect == nil {
+			opts.Autocorrect = map[string]string{}
+		}
+		maps.Copy(opts.Autocorrect, corrections)
+		return nil
+	})
+	flags.Func("var", "expand ${`name`} and {{name}} placeholders, given as name=value, before the commands", func(definition string) error {
+		name, value, err := config.ParseVariable(definition)
+		if err != nil {
+			return err
+		}
+		opts.ExpandVariables = true
+		opts.Variables = maps.Clone(opts.Variables)
+		if opts.Variables == nil {
+			opts.Variables = map[string]string{}
+		}
+		opts.Variables[name] = value
+		return nil
+	})
+	flags.BoolFunc("expand-env", "expand placeholders from the environment too, --var wins", func(string) error {
+		opts.ExpandVariables = true
+		opts.Variables = environmentVariables(opts.Variables)
+		return nil
+	})
+	flags.Var(aliasFlag{opts}, "alias", "space separated alias=command `pairs` naming commands another way, e.g. \"uppercase=up caps=cap\"")
+	flags.Var(punctuationFlag{opts}, "punctuation", "space separated rune=mode rules (left, right, both, spaced, off), e.g. \"—=spaced --=spaced ¿=right\"")
+}
+
+// applyConfigFile sets every "key = value" of a config file as if it was passed as --key=value.
+// Options given on the command line after --config override the file.
+func applyConfigFile(flags *flag.FlagSet, path string) error {
+	settings, err := config.LoadSettings(path)
+	if err != nil {
+		return err
+	}
+	for _, setting := range settings {
+		if setting.Key == "config" || flags.Lookup(setting.Key) == nil {
+			return fmt.Errorf("%s line %d: unknown option %q", path, setting.Line, setting.Key)
+		}
+		if err := flags.Set(setting.Key, setting.Value); err != nil {
+			return fmt.Errorf("%s line %d: %w", path, setting.Line, err)
+		}
+	}
+	return nil
+}
+
+// articleFlag adds words to the article exceptions of the options, or removes them when article is ""
+type articleFlag struct {
+	opts    *config.Options
+	article string
+}
+
+func (f articleFlag) String() string {
+	return ""
+}
+
+func (f articleFlag) Set(words string) error {
+	exceptions, err := config.AddArticleExceptions(f.opts.ArticleRules(), f.article, words)
+	if err != nil {
+		return err
+	}
+	f.opts.ArticleExceptions = exceptions
+	return nil
+}
+
+// punctuationFlag applies --punctuation rules on top of the options' current punctuation set
+type punctuationFlag struct {
+	opts *config.Options
+}
+
+func (f punctuationFlag) String() string {
+	return ""
+}
+
+func (f punctuationFlag) Set(spec string) error {
+	rules, err := config.ParsePunctuation(spec, f.opts.PunctuationRules())
+	if err != nil {
+		return err
+	}
+	f.opts.Punctuation = rules
+	return nil
+}
+
+// environmentVariables returns variables with the environment added below them. Variables
+// spanning lines, like exported shell functions, cannot be placeholders and are left out.
+func environmentVariables(variables map[string]string) map[string]string {
+	expanded := map[string]string{}
+	for _, entry := range os.Environ() {
+		name, value, err := config.ParseVariable(entry)
+		if err == nil && !strings.ContainsAny(value, "\r\n") {
+			expanded[name] = value
+		}
+	}
+	maps.Copy(expanded, variables)
+	return expanded
+}
+
+// aliasFlag adds --alias pairs to the command aliases of the options
+type aliasFlag struct {
+	opts *config.Options
+}
+
+func (f aliasFlag) String() string {
+	return ""
+}
+
+func (f aliasFlag) Set(spec string) error {
+	aliases, err := config.ParseCommandAliases(spec, f.opts.CommandAliases)
+	if err != nil {
+		return err
+	}
+	if err := transformer.ValidateAliases(aliases); err != nil {
+		return err
+	}
+	f.opts.CommandAliases = aliases
+	return nil
+}
+
+// runSelftest runs the selected self-test suites: the embedded corpus, whose output hash is
+// compared with the recorded artifact, and the embedded golden cases
+func runSelftest(args []string) int {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	suites := flags.String("suite", "corpus,golden", "comma separated `suites` to run: corpus and golden")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s selftest [-suite corpus,golden]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	var runs []func() bool
+	for _, suite := range strings.Split(*suites, ",") {
+		switch strings.TrimSpace(suite) {
+		case "corpus":
+			runs = append(runs, selftestCorpus)
+		case "golden":
+			runs = append(runs, selftestGolden)
+		default:
+			fmt.Fprintf(os.Stderr, "Invalid options: unknown selftest suite %q\n", suite)
+			return EXIT_USAGE
+		}
+	}
+
+	status := 0
+	for _, run := range runs {
+		if !run() {
+			status = 1
+		}
+	}
+	return status
+}
+
+// selftestCorpus processes the embedded corpus and compares its output hash with the recorded artifact
+func selftestCorpus() bool {
+	result, err := selftest.Run()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Selftest error: %v\n", err)
+		return false
+	}
+
+	if !result.Passed() {
+		fmt.Fprintf(os.Stderr, "Selftest FAILED: output differs from the conformance artifact\n")
+		fmt.Fprintf(os.Stderr, "Expected: %s\nActual:   %s\n", result.Expected, result.Actual)
+		return false
+	}
+
+	fmt.Printf("Selftest passed: %d bytes in, %d bytes out, sha256 %s\n", result.InputBytes, result.OutputBytes, result.Actual)
+	return true
+}
+
+// selftestGolden runs the embedded golden cases and reports the ones whose output differs
+func selftestGolden() bool {
+	result, err := selftest.Golden()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Golden selftest error: %v\n", err)
+		return false
+	}
+
+	for _, failure := range result.Failures {
+		fmt.Fprintf(os.Stderr, "Golden case FAILED: %s\nExpected: %q\nActual:   %q\n", failure.Test.Name, failure.Test.Expected, failure.Actual)
+	}
+	if !result.Passed() {
+		fmt.Fprintf(os.Stderr, "Golden selftest FAILED: %d of %d cases differ\n", len(result.Failures), result.Cases)
+		return false
+	}
+
+	fmt.Printf("Golden selftest passed: %d cases\n", result.Cases)
+	return true
+}
+
+// runReview transforms every matching file below a directory and lets the user accept or reject
+// each hunk, in a terminal UI on a terminal and with a line prompt otherwise
+func runReview(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("review", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	exts := flags.String("ext", ".txt", "comma separated file extensions to review")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s review [-ext .txt,.md] [options] <directory>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	changes, err := review.Collect(flags.Arg(0), strings.Split(*exts, ","), opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Review error: %v\n", err)
+		return 1
+	}
+	if len(changes) == 0 {
+		fmt.Println("No changes, every file is already clean")
+		return 0
+	}
+
+	var summary review.Summary
+	if stdin, stdout := int(os.Stdin.Fd()), int(os.Stdout.Fd()); term.IsTerminal(stdin) && term.IsTerminal(stdout) {
+		summary, err = browseTerminal(changes, stdin, stdout)
+	} else {
+		// Answers piped in, or output going elsewhere, take the line prompt
+		summary, err = review.Run(changes, os.Stdin, os.Stdout)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Review error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("\nReviewed %d file(s): %d hunk(s) accepted, %d rejected, %d file(s) written\n",
+		summary.FilesReviewed, summary.Accepted, summary.Rejected, summary.FilesWritten)
+	return 0
+}
+
+// browseTerminal runs review.Browse on the terminal, in raw mode while it lasts
+func browseTerminal(changes []review.FileChange, stdin, stdout int) (review.Summary, error) {
+	state, err := term.MakeRaw(stdin)
+	if err != nil {
+		return review.Summary{}, fmt.Errorf("failed to set up the terminal: %w", err)
+	}
+	defer term.Restore(stdin, state)
+	size := func() (int, int) {
+		width, height, err := term.GetSize(stdout)
+		if err != nil {
+			return 80, 24
+		}
+		return width, height
+	}
+	return review.Browse(changes, os.Stdin, os.Stdout, size)
+}
+
+// runBench processes a synthetic corpus with the full pipeline and reports throughput,
+// allocations and stage timings
+func runBench(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("bench", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	sizeMB := flags.Float64("mb", 16, "corpus size in `megabytes`")
+	density := flags.Float64("density", 0.05, "share of words followed by a command, from 0 to 1")
+	seed := flags.Int64("seed", 1, "seed of the corpus generator, the same seed gives the same corpus")
+	runs := flags.Int("runs", 3, "runs over the corpus, the fastest is kept")
+	keep := flags.String("keep", "", "write the generated corpus to `file` and keep it")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s bench [-mb 16] [-density 0.05] [-seed 1] [-runs 3] [-keep file] [options]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	spec := bench.RunSpec{
+		Corpus:  bench.CorpusSpec{Size: int64(*sizeMB * 1024 * 1024), Density: *density, Seed: *seed},
+		Runs:    *runs,
+		Options: opts,
+		Keep:    *keep,
+	}
+	if err := spec.Corpus.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	if spec.Runs <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid options: -runs must be positive, got %d\n", spec.Runs)
+		return EXIT_USAGE
+	}
+
+	report, err := bench.Run(spec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Bench error: %v\n", err)
+		return 1
+	}
+	report.WriteReport(os.Stdout)
+	return 0
+}
+
+// runBenchcmp runs two go-reloaded binaries over a corpus and reports throughput and memory deltas
+func runBenchcmp(args []string) int {
+	flags := flag.NewFlagSet("benchcmp", flag.ContinueOnError)
+	runs := flags.Int("runs", 3, "runs per file and binary, the fastest is kept")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s benchcmp [-runs N] <old_binary> <new_binary> <corpus_dir>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 3 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	result, err := bench.Compare(flags.Arg(0), flags.Arg(1), flags.Arg(2), *runs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Benchcmp error: %v\n", err)
+		return 1
+	}
+
+	result.WriteReport(os.Stdout)
+	return 0
+}
+
+// runVerifyChunks checks a --debug-chunks trace against the files it was recorded for
+func runVerifyChunks(args []string) int {
+	flags := flag.NewFlagSet("verify-chunks", flag.ContinueOnError)
+	inputPath := flags.String("input", "", "input `file` of the traced run, checks its size and range checksums")
+	outputPath := flags.String("output", "", "output `file` of the traced run, checks its size and range checksums")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s verify-chunks [-input file] [-output file] <trace>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	records, err := readTrace(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verify error: %v\n", err)
+		return 1
+	}
+	input, err := openSection(*inputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verify error: %v\n", err)
+		return 1
+	}
+	output, err := openSection(*outputPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Verify error: %v\n", err)
+		return 1
+	}
+
+	if err := chunktrace.Verify(records, input, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Chunk trace FAILED:\n%v\n", err)
+		return 1
+	}
+	fmt.Printf("Chunk trace OK: %d chunk(s) tile %d input bytes and %d output bytes\n",
+		len(records), records[len(records)-1].InputEnd, records[len(records)-1].OutputEnd)
+	return 0
+}
+
+// runMinimize shrinks an input whose chunked output differs from a single pass to a small reproducer
+func runMinimize(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("minimize", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s minimize [options] <failing_input> <output_file>\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "Writes the smallest text found whose chunked output still differs from a single pass.\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	input, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Minimize error: %v\n", err)
+		return EXIT_INPUT
+	}
+	dir, err := os.MkdirTemp("", "go-reloaded-minimize-*")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Minimize error: %v\n", err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+
+	result, err := minimize.Minimize(string(input), minimize.ChunkedDivergence(opts, dir))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Minimize error: %v\n", err)
+		return 1
+	}
+	if err := os.WriteFile(flags.Arg(1), []byte(result.Text), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Minimize error: %v\n", err)
+		return EXIT_OUTPUT
+	}
+	fmt.Printf("Minimized %d bytes to %d bytes in %d runs -> %s\n", len(input), len(result.Text), result.Runs, flags.Arg(1))
+	return 0
+}
+
+func readTrace(path string) ([]chunktrace.Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return chunktrace.Read(file)
+}
+
+// openSection opens a file for the verifier, an empty path skips the file checks.
+// The file stays open until the process exits.
+func openSection(path string) (*io.SectionReader, error) {
+	if path == "" {
+		return nil, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
 	}
-	
-	fmt.Printf("Successfully processed %s -> %s\n", inputFile, outputFile)
-}
\ No newline at end of file
+	return io.NewSectionReader(file, 0, info.Size()), nil
+}
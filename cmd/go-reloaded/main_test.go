@@ -1,15 +1,18 @@
 package main
 
 import (
-	"go-reloaded/internal/testutils"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestMainWithValidArgs(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
 	// Create test input file
 	inputContent := "hello (up) world"
 	inputPath, err := testutils.CreateTestFile(inputContent)
@@ -24,7 +27,7 @@ func TestMainWithValidArgs(t *testing.T) {
 	defer os.Remove(outputPath)
 	
 	// Run main with arguments
-	cmd := exec.Command("go", "run", "main.go", inputPath, outputPath)
+	cmd := exec.Command("go", "run", ".", inputPath, outputPath)
 	cmd.Dir = "."
 	output, err := cmd.CombinedOutput()
 	
@@ -38,9 +41,53 @@ func TestMainWithValidArgs(t *testing.T) {
 	}
 }
 
+// The original two argument form, the process subcommand and an input named like a
+// subcommand all write the same output and print the same message
+func TestMainLegacyInvocation(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if output, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		t.Fatalf("Build failed: %v, output: %s", err, output)
+	}
+	input := "it was a apple (up) , ' really '\n"
+	for _, name := range []string{"in.txt", "serve"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(input), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := [][]string{
+		{"in.txt", "legacy.txt"},
+		{"process", "in.txt", "process.txt"},
+		{"--warnings", "in.txt", "flags.txt"},
+		{"serve", "named.txt"},
+	}
+	for _, args := range tests {
+		cmd := exec.Command(binary, args...)
+		cmd.Dir = dir
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%q failed: %v, output: %s", args, err, output)
+		}
+		in, out := args[len(args)-2], args[len(args)-1]
+		if expected := "Successfully processed " + in + " -> " + out + "\n"; string(output) != expected {
+			t.Errorf("%q printed %q, expected %q", args, output, expected)
+		}
+		result, err := os.ReadFile(filepath.Join(dir, out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != "it was an APPLE, 'really'\n" {
+			t.Errorf("%q wrote %q", args, result)
+		}
+	}
+}
+
 func TestMainWithInvalidArgs(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
 	// Test with no arguments
-	cmd := exec.Command("go", "run", "main.go")
+	cmd := exec.Command("go", "run", ".")
 	cmd.Dir = "."
 	output, err := cmd.CombinedOutput()
 	
@@ -54,7 +101,8 @@ func TestMainWithInvalidArgs(t *testing.T) {
 }
 
 func TestMainWithNonexistentFile(t *testing.T) {
-	cmd := exec.Command("go", "run", "main.go", "nonexistent.txt", "output.txt")
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	cmd := exec.Command("go", "run", ".", "nonexistent.txt", "output.txt")
 	cmd.Dir = "."
 	output, err := cmd.CombinedOutput()
 	
@@ -65,4 +113,423 @@ func TestMainWithNonexistentFile(t *testing.T) {
 	if !strings.Contains(string(output), "does not exist") {
 		t.Errorf("Expected file not found error, got: %s", string(output))
 	}
-}
\ No newline at end of file
+}
+func TestMainSelftest(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	cmd := exec.Command("go", "run", ".", "selftest")
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+
+	if err != nil {
+		t.Fatalf("Selftest failed: %v, output: %s", err, string(output))
+	}
+
+	if !strings.Contains(string(output), "Selftest passed") {
+		t.Errorf("Expected selftest success message, got: %s", string(output))
+	}
+}
+
+func TestMainWithNiceAndThrottle(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile("hello (up) world")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	outputPath := filepath.Join(t.TempDir(), "throttled.txt")
+
+	cmd := exec.Command("go", "run", ".", "--nice", "--io-throttle", "10", inputPath, outputPath)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if string(data) != "HELLO world" {
+		t.Errorf("Expected %q, got %q", "HELLO world", string(data))
+	}
+}
+
+func TestMainDebugChunks(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile(strings.Repeat("some words for the trace\n", 400))
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	dir := t.TempDir()
+	outputPath, tracePath := filepath.Join(dir, "out.txt"), filepath.Join(dir, "trace.jsonl")
+	cmd := exec.Command("go", "run", ".", "--debug-chunks", tracePath, inputPath, outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	}
+
+	cmd = exec.Command("go", "run", ".", "verify-chunks", "-input", inputPath, "-output", outputPath, tracePath)
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Chunk trace OK") {
+		t.Fatalf("verify-chunks failed: %v, output: %s", err, string(output))
+	}
+
+	// A modified output no longer matches the recorded checksums
+	if err := os.WriteFile(outputPath, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd = exec.Command("go", "run", ".", "verify-chunks", "-output", outputPath, tracePath)
+	if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "FAILED") {
+		t.Errorf("Expected verify-chunks to fail, got %v, output: %s", err, string(output))
+	}
+}
+
+func TestMainTrace(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile("it was a apple (up) .\n")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	dir := t.TempDir()
+	outputPath, tracePath := filepath.Join(dir, "out.txt"), filepath.Join(dir, "trace.json")
+	cmd := exec.Command("go", "run", ".", "--trace", tracePath, inputPath, outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	}
+	trace, err := os.ReadFile(tracePath)
+	if err != nil {
+		t.Fatalf("Failed to read trace: %v", err)
+	}
+	expected := `[
+{"offset":7,"line":1,"column":8,"original":"a","replacement":"an","rules":["articles"]},
+{"offset":9,"line":1,"column":10,"original":"apple (up) ","replacement":"APPLE","rules":["command","punctuation"],"commands":["(up)"]}
+]
+`
+	if string(trace) != expected {
+		t.Errorf("Expected trace\n%s\ngot\n%s", expected, trace)
+	}
+}
+
+func TestMainWarnings(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile("fine (up)\nzz (hex) and (up, 0)\n")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+	outputPath := filepath.Join(t.TempDir(), "out.txt")
+
+	cmd := exec.Command("go", "run", ".", "--warnings", inputPath, outputPath)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Main execution failed: %v, stderr: %s", err, stderr.String())
+	}
+	for _, expected := range []string{
+		inputPath + `:2:4: (hex): "zz" is not a hexadecimal number`,
+		inputPath + ":2:14: (up, 0): count must be positive",
+	} {
+		if !strings.Contains(stderr.String(), expected+"\n") {
+			t.Errorf("Expected %q on stderr, got:\n%s", expected, stderr.String())
+		}
+	}
+
+	// Without the flag nothing is reported
+	cmd = exec.Command("go", "run", ".", inputPath, outputPath)
+	stderr.Reset()
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil || strings.Contains(stderr.String(), "(hex)") {
+		t.Errorf("Expected no warnings, got %v, stderr: %s", err, stderr.String())
+	}
+}
+
+func TestMainLogging(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile("zz (hex) fine\n")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+	outputPath := filepath.Join(t.TempDir(), "out.txt")
+
+	run := func(args ...string) (string, string, error) {
+		cmd := exec.Command("go", append([]string{"run", "."}, append(args, inputPath, outputPath)...)...)
+		var stdout, stderr strings.Builder
+		cmd.Stdout, cmd.Stderr = &stdout, &stderr
+		err := cmd.Run()
+		return stdout.String(), stderr.String(), err
+	}
+
+	// JSON records on stderr, the warning among them
+	stdout, stderr, err := run("--log-format", "json", "-v")
+	if err != nil || !strings.Contains(stdout, "Successfully processed") {
+		t.Fatalf("JSON run failed: %v, stdout: %s, stderr: %s", err, stdout, stderr)
+	}
+	for _, expected := range []string{`"level":"WARN","msg":"command not applied"`, `"msg":"processed file"`, `"stages":{`} {
+		if !strings.Contains(stderr, expected) {
+			t.Errorf("Expected %s in the log, got:\n%s", expected, stderr)
+		}
+	}
+
+	// Quiet prints nothing at all for a successful run
+	if stdout, stderr, err := run("--quiet", "--warnings"); err != nil || stdout != "" || stderr != "" {
+		t.Errorf("Expected a silent run, got %v, stdout: %q, stderr: %q", err, stdout, stderr)
+	}
+
+	if _, stderr, err := run("--log-format", "xml"); err == nil || !strings.Contains(stderr, "invalid log format") {
+		t.Errorf("Expected an error for an unknown log format, got %v, stderr: %s", err, stderr)
+	}
+}
+
+func TestMainExitCodes(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	if output, err := exec.Command("go", "build", "-o", binary, ".").CombinedOutput(); err != nil {
+		t.Fatalf("Build failed: %v, output: %s", err, output)
+	}
+	clean, warned := filepath.Join(dir, "clean.txt"), filepath.Join(dir, "warned.txt")
+	if err := os.WriteFile(clean, []byte("fine (up)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(warned, []byte("zz (hex)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	escaped := filepath.Join(dir, "escaped.txt")
+	if err := os.WriteFile(escaped, []byte("it \\(up\\)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	longLine := filepath.Join(dir, "long-line.txt")
+	if err := os.WriteFile(longLine, []byte(strings.Repeat("word ", 40000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out.txt")
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+	}{
+		{"success", []string{clean, out}, 0},
+		{"help flag", []string{"-h"}, 0},
+		{"no arguments", nil, EXIT_USAGE},
+		{"unknown flag", []string{"--no-such-flag", clean, out}, EXIT_USAGE},
+		{"invalid options", []string{"--smart-quotes", "--ascii-quotes", clean, out}, EXIT_USAGE},
+		{"unknown help topic", []string{"help", "nothing"}, EXIT_USAGE},
+		{"bench without runs", []string{"bench", "-runs", "0"}, EXIT_USAGE},
+		{"bench density", []string{"bench", "-density", "2"}, EXIT_USAGE},
+		{"bench", []string{"bench", "-mb", "0.1", "-runs", "1"}, 0},
+		{"unknown selftest suite", []string{"selftest", "-suite", "corpus,nothing"}, EXIT_USAGE},
+		{"golden selftest", []string{"selftest", "-suite", "golden"}, 0},
+		{"missing input", []string{filepath.Join(dir, "missing.txt"), out}, EXIT_INPUT},
+		{"unreadable input", []string{dir, out}, EXIT_INPUT},
+		{"unwritable output", []string{clean, filepath.Join(clean, "out.txt")}, EXIT_OUTPUT},
+		{"invalid max memory", []string{"--max-memory", "lots", clean, out}, EXIT_USAGE},
+		{"max memory below the minimum", []string{"--max-memory", "1MB", clean, out}, EXIT_USAGE},
+		{"max memory", []string{"--max-memory", "8MB", longLine, out}, EXIT_MEMORY},
+		{"verify", []string{"--verify", clean, out}, 0},
+		{"verify not a fixed point", []string{"--verify", escaped, out}, EXIT_VERIFY},
+		{"invalid unapplied policy", []string{"--unapplied", "never", clean, out}, EXIT_USAGE},
+		{"unapplied error", []string{"--unapplied", "error", warned, out}, EXIT_UNAPPLIED},
+		{"strict without warnings", []string{"--strict", clean, out}, 0},
+		{"strict", []string{"--strict", warned, out}, EXIT_STRICT},
+		{"warnings only", []string{"--warnings", warned, out}, 0},
+	}
+	for _, test := range tests {
+		output, err := exec.Command(binary, test.args...).CombinedOutput()
+		code := 0
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			code = exitErr.ExitCode()
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if code != test.code {
+			t.Errorf("%s: exit code %d, expected %d, output: %s", test.name, code, test.code, output)
+		}
+	}
+
+	// A strict failure still writes the output
+	if result, err := os.ReadFile(out); err != nil || string(result) != "zz\n" {
+		t.Errorf("Expected the strict run to write its output, got %q, %v", result, err)
+	}
+}
+
+func TestMainSkipProcessed(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	dir := t.TempDir()
+	inputPath, cleanPath, copyPath := filepath.Join(dir, "in.txt"), filepath.Join(dir, "clean.txt"), filepath.Join(dir, "copy.txt")
+	if err := os.WriteFile(inputPath, []byte("hello (up) world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("go", "run", ".", "--mark-processed", inputPath, cleanPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	} else if strings.Contains(string(output), "Warning") {
+		t.Skipf("extended attributes not available: %s", string(output))
+	}
+
+	// The marked output is copied as is instead of being transformed again
+	cmd = exec.Command("go", "run", ".", "--skip-processed", cleanPath, copyPath)
+	output, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(output), "Skipped") {
+		t.Fatalf("Expected the clean file to be skipped: %v, output: %s", err, string(output))
+	}
+	if data, err := os.ReadFile(copyPath); err != nil || string(data) != "HELLO world" {
+		t.Errorf("Expected an unchanged copy, got %q, %v", string(data), err)
+	}
+
+	// An unmarked input is processed
+	cmd = exec.Command("go", "run", ".", "--skip-processed", inputPath, copyPath)
+	if output, err := cmd.CombinedOutput(); err != nil || strings.Contains(string(output), "Skipped") {
+		t.Errorf("Expected the input to be processed: %v, output: %s", err, string(output))
+	}
+}
+
+func TestMainMetrics(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer listener.Close()
+
+	inputPath, err := testutils.CreateTestFile("hello (up) world")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	cmd := exec.Command("go", "run", ".", "--metrics", "statsd://"+listener.LocalAddr().String(), inputPath, filepath.Join(t.TempDir(), "out.txt"))
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	}
+
+	buf := make([]byte, 2048)
+	listener.SetDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("No metrics received: %v", err)
+	}
+	packet := string(buf[:n])
+	if !strings.Contains(packet, "go_reloaded.files_processed:1|c") || !strings.Contains(packet, "go_reloaded.bytes_read:16|c") {
+		t.Errorf("Unexpected metrics packet %q", packet)
+	}
+}
+
+func TestMainWithConfigFile(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile("a hour at a university with a ukulele")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "go-reloaded.conf")
+	configContent := "# article exceptions\narticle-an = hour\narticle-a = university\narticle-default = ukulele\ncapitalize-sentences\n"
+	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	outputPath := filepath.Join(dir, "configured.txt")
+
+	cmd := exec.Command("go", "run", ".", "--config", configPath, inputPath, outputPath)
+	cmd.Dir = "."
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("Main execution failed: %v, output: %s", err, string(output))
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if expected := "An hour at a university with an ukulele"; string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+}
+
+func TestMainHelpTopics(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	tests := []struct {
+		topic    string
+		contains string
+	}{
+		{"", "Help topics:"},
+		{"commands", "(tohex)"},
+		{"formats", "UTF-8"},
+		{"config", "--capitalize-sentences"},
+	}
+
+	for _, test := range tests {
+		args := []string{"run", ".", "help"}
+		if test.topic != "" {
+			args = append(args, test.topic)
+		}
+		cmd := exec.Command("go", args...)
+		cmd.Dir = "."
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("help %s failed: %v, output: %s", test.topic, err, string(output))
+		}
+		if !strings.Contains(string(output), test.contains) {
+			t.Errorf("help %s: expected %q in output, got: %s", test.topic, test.contains, string(output))
+		}
+	}
+}
+
+func TestConfigHelpShortFlags(t *testing.T) {
+	var help strings.Builder
+	writeConfigHelp(&help)
+	for _, line := range strings.Split(help.String(), "\n") {
+		if strings.HasPrefix(line, "  --v ") || strings.HasPrefix(line, "  --l ") {
+			t.Errorf("Expected one-letter flags written with one dash, got %q", line)
+		}
+	}
+	if !strings.Contains(help.String(), "\n  -v ") {
+		t.Errorf("Expected -v in the options help, got: %s", help.String())
+	}
+}
+
+func TestMainHelpUnknownTopic(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	cmd := exec.Command("go", "run", ".", "help", "nonsense")
+	cmd.Dir = "."
+	if output, err := cmd.CombinedOutput(); err == nil {
+		t.Errorf("Expected error for unknown help topic, got output: %s", string(output))
+	}
+}
+
+func TestMainMinimize(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	inputPath, err := testutils.CreateTestFile(strings.Repeat("a apple (up, 2) ' quoted '\n", 300))
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	// Chunked and single-pass outputs agree, so there is nothing to minimize
+	cmd := exec.Command("go", "run", ".", "minimize", inputPath, filepath.Join(t.TempDir(), "min.txt"))
+	output, err := cmd.CombinedOutput()
+	if err == nil || !strings.Contains(string(output), "does not reproduce the failure") {
+		t.Errorf("Expected minimize to report no failure, got %v, output: %s", err, string(output))
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{"1048576": 1 << 20, "512KB": 512 << 10, "64MB": 64 << 20, "64m": 64 << 20, "1G": 1 << 30, "0": 0, "10 MB": 10 << 20}
+	for value, expected := range tests {
+		if size, err := parseSize(value); err != nil || size != expected {
+			t.Errorf("parseSize(%q) = %d, %v, expected %d", value, size, err, expected)
+		}
+	}
+	for _, value := range []string{"", "MB", "-1MB", "1.5GB", "9999999999G"} {
+		if _, err := parseSize(value); err == nil {
+			t.Errorf("Expected error for size %q", value)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package main
+
+import "errors"
+
+// lowerPriority is not supported on this platform
+func lowerPriority() error {
+	return errors.New("process priority is not supported on this platform")
+}
@@ -0,0 +1,13 @@
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package main
+
+import "syscall"
+
+// Niceness applied by --nice, low enough to yield to interactive work
+const NICE_LEVEL = 10
+
+// lowerPriority renices the current process
+func lowerPriority() error {
+	return syscall.Setpriority(syscall.PRIO_PROCESS, 0, NICE_LEVEL)
+}
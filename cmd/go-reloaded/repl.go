@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"os"
+	"strings"
+)
+
+// REPL meta commands, everything else is transformed
+const (
+	REPL_TOKENS = ":tokens" // toggle the token trace
+	REPL_QUIT   = ":quit"
+)
+
+// runRepl transforms every line read from in and echoes the result to out
+func runRepl(args []string, in io.Reader, out io.Writer) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("repl", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	showTokens := flags.Bool("tokens", false, "print the token stream after commands are applied")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s repl [-tokens] [options]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+
+	interactive := isTerminal(in)
+	if interactive {
+		fmt.Fprintf(out, "go-reloaded REPL - type text to transform, %s to toggle tokens, %s to exit\n", REPL_TOKENS, REPL_QUIT)
+	}
+
+	reader := bufio.NewReader(in)
+	for {
+		if interactive {
+			fmt.Fprint(out, "> ")
+		}
+
+		line, err := reader.ReadString('\n')
+		line = strings.TrimRight(line, "\r\n")
+		if line != "" || err == nil {
+			switch line {
+			case REPL_QUIT:
+				return 0
+			case REPL_TOKENS:
+				*showTokens = !*showTokens
+				fmt.Fprintf(out, "token trace %s\n", onOff(*showTokens))
+			default:
+				if *showTokens {
+					for _, token := range transformer.TokenizeWithOptions(line, opts) {
+						fmt.Fprintf(out, "  %s\n", token)
+					}
+				}
+				fmt.Fprintln(out, transformer.ProcessTextWithOptions(line, opts))
+			}
+		}
+
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error reading input: %v\n", err)
+			return 1
+		}
+	}
+}
+
+// isTerminal reports whether in is an interactive terminal, prompts are hidden for pipes
+func isTerminal(in io.Reader) bool {
+	file, ok := in.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
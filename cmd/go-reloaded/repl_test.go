@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestReplTransformsLines(t *testing.T) {
+	in := strings.NewReader("hello (up) world\nFF (hex) items ,ok\n")
+	var out bytes.Buffer
+
+	if code := runRepl(nil, in, &out); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	expected := "HELLO world\n255 items, ok\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestReplTokenTrace(t *testing.T) {
+	in := strings.NewReader(":tokens\nhi (cap)\n:quit\nignored\n")
+	var out bytes.Buffer
+
+	runRepl(nil, in, &out)
+
+	output := out.String()
+	if !strings.Contains(output, `WORD("Hi")`) {
+		t.Errorf("Expected token trace in output, got %q", output)
+	}
+	if strings.Contains(output, "ignored") {
+		t.Errorf("Lines after :quit should not be processed, got %q", output)
+	}
+}
+
+func TestReplOptions(t *testing.T) {
+	in := strings.NewReader("255 (tohex)")
+	var out bytes.Buffer
+
+	runRepl([]string{"-upper-hex"}, in, &out)
+
+	if out.String() != "FF\n" {
+		t.Errorf("Expected %q, got %q", "FF\n", out.String())
+	}
+}
+
+func TestReplVariables(t *testing.T) {
+	t.Setenv("GO_RELOADED_CITY", "athens")
+	t.Setenv("GO_RELOADED_NAME", "env")
+	in := strings.NewReader("${GO_RELOADED_NAME} in {{GO_RELOADED_CITY}} (cap) {{x}}")
+	var out bytes.Buffer
+
+	runRepl([]string{"-var", "GO_RELOADED_NAME=ada", "-expand-env"}, in, &out)
+
+	if out.String() != "ada in Athens {{x}}\n" {
+		t.Errorf("Expected %q, got %q", "ada in Athens {{x}}\n", out.String())
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/server"
+	"github.com/GiannisPettas/go-reloaded/internal/tracing"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// time given to running requests when the server is stopped
+const SHUTDOWN_TIMEOUT = 10 * time.Second
+
+// runServe serves POST /transform until interrupted
+func runServe(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	addr := flags.String("addr", ":8080", "listen `address`")
+	maxBody := flags.Int64("max-body", server.MAX_BODY_BYTES, "largest accepted request body in `bytes`")
+	traces := flags.String("traces", "", "export OpenTelemetry spans to `url`: otlp://host:4318 or otlp+https://host:4318")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s serve [-addr :8080] [-traces otlp://host:4318] [options]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+
+	processor, err := transformer.NewProcessor(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	var tracer *tracing.Tracer
+	if *traces != "" {
+		exporter, err := tracing.NewOTLP(*traces)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+			return EXIT_USAGE
+		}
+		tracer = tracing.NewTracer(exporter, func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		})
+		defer tracer.Close()
+	}
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           server.New(processor, tracer, *maxBody).Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+		defer cancel()
+		httpServer.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("Serving POST /transform, POST /transform/batch and GET /metrics on %s\n", *addr)
+	if err := httpServer.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,15 @@
+package main
+
+import "testing"
+
+func TestServeRejectsInvalidOptions(t *testing.T) {
+	for _, args := range [][]string{
+		{"-traces", "jaeger://localhost:14268"},
+		{"--smart-quotes", "--ascii-quotes"},
+		{"extra"},
+	} {
+		if code := runServe(args); code != EXIT_USAGE {
+			t.Errorf("runServe(%q) = %d, expected %d", args, code, EXIT_USAGE)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// subcommand is one mode of the tool, selected by the first argument. Every mode parses
+// its own flags from the arguments after its name.
+type subcommand struct {
+	name     string
+	synopsis string // arguments shown by help after the name
+	summary  string
+	run      func(args []string) int
+}
+
+// subcommands lists the modes in the order help shows them. They are set in init because
+// help itself is one of them.
+var subcommands []subcommand
+
+func init() {
+	subcommands = []subcommand{
+		{"process", "[options] <input> <output>", "transform a file, also for inputs named like a subcommand", runProcess},
+		{"check", "[-l] [options] <file>...", "report files that would change and commands that would not apply", runCheck},
+		{"inventory", "[-json] [-summary] <file>...", "list every command of the files and how often each is used", runInventory},
+		{"watch", "[-interval 1s] <input> <output>", "transform the input again whenever it changes", runWatch},
+		{"serve", "[-addr :8080] [options]", "serve POST /transform over HTTP", runServe},
+		{"repl", "[-tokens] [options]", "transform lines interactively", func(args []string) int { return runRepl(args, os.Stdin, os.Stdout) }},
+		{"review", "[-ext .txt] <directory>", "browse the changed files and accept or reject each hunk", runReview},
+		{"selftest", "[-suite corpus,golden]", "verify output against the embedded corpus and golden cases", runSelftest},
+		{"golden", "[-update] <file_or_dir>...", "run golden cases or update their expectations", runGolden},
+		{"bench", "[-mb 16] [-density 0.05]", "measure the pipeline on a synthetic corpus", runBench},
+		{"benchcmp", "<old> <new> <corpus_dir>", "compare two builds", runBenchcmp},
+		{"verify-chunks", "[flags] <trace>", "check a --debug-chunks trace", runVerifyChunks},
+		{"minimize", "[options] <input> <output>", "shrink an input whose chunked output differs", runMinimize},
+		{"help", "[topic]", "show help", runHelp},
+	}
+}
+
+// lookupSubcommand finds the mode called name
+func lookupSubcommand(name string) (subcommand, bool) {
+	for _, command := range subcommands {
+		if command.name == name {
+			return command, true
+		}
+	}
+	return subcommand{}, false
+}
+
+// writeSubcommandsHelp lists the original two file form and every subcommand
+func writeSubcommandsHelp(w io.Writer) {
+	lines := [][2]string{{"[options] <input_file> <output_file>", "transform a file"}}
+	for _, command := range subcommands {
+		usage := command.name
+		if command.synopsis != "" {
+			usage += " " + command.synopsis
+		}
+		lines = append(lines, [2]string{usage, command.summary})
+	}
+	for _, line := range lines {
+		fmt.Fprintf(w, "  %s %-38s %s\n", os.Args[0], line[0], line[1])
+	}
+}
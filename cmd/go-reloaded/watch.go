@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+)
+
+// runWatch transforms the input, then again whenever it changes, until interrupted
+func runWatch(args []string) int {
+	opts := config.DefaultOptions()
+	flags := flag.NewFlagSet("watch", flag.ContinueOnError)
+	bindTransformFlags(flags, &opts)
+	flags.StringVar(&opts.Encoding, "encoding", opts.Encoding, "`encoding` of the input and output files: utf8, latin1, utf16le, utf16be or auto")
+	flags.StringVar(&opts.Compress, "compress", opts.Compress, "`codec` of the input and output files: gzip, zstd, none or auto to go by the .gz or .zst name")
+	interval := flags.Duration("interval", time.Second, "how often the input is checked for changes")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s watch [-interval 1s] [options] <input_file> <output_file>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return usageExit(err)
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return EXIT_USAGE
+	}
+	if err := opts.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid options: %v\n", err)
+		return EXIT_USAGE
+	}
+	if *interval <= 0 {
+		fmt.Fprintf(os.Stderr, "Invalid options: -interval must be positive, got %v\n", *interval)
+		return EXIT_USAGE
+	}
+	if samePath(flags.Arg(0), flags.Arg(1)) {
+		// Every run would change the input and start the next one
+		fmt.Fprintf(os.Stderr, "Invalid options: watch needs an output file other than the input\n")
+		return EXIT_USAGE
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := watch(ctx, flags.Arg(0), flags.Arg(1), opts, *interval, os.Stdout, os.Stderr); err != nil {
+		fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+		return EXIT_INPUT
+	}
+	return 0
+}
+
+// watch processes input into output, then again every time the size or modification time of
+// the input changes, until ctx is done. A failed run is reported on errOut and the input is
+// watched on. Only an input missing from the start is an error.
+func watch(ctx context.Context, input, output string, opts config.Options, interval time.Duration, out, errOut io.Writer) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last os.FileInfo
+	for {
+		info, err := os.Stat(input)
+		if err != nil && last == nil {
+			return err
+		}
+		// A missing input is an editor replacing the file, the new one is picked up next time
+		if err == nil && (last == nil || info.Size() != last.Size() || !info.ModTime().Equal(last.ModTime())) {
+			last = info
+			if err := controller.ProcessFileWithOptions(input, output, opts); err != nil {
+				fmt.Fprintf(errOut, "Error processing file: %v\n", err)
+			} else {
+				fmt.Fprintf(out, "Processed %s -> %s\n", input, output)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// samePath reports whether two paths name the same file, also before the second one exists
+func samePath(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA == nil && errB == nil {
+		return os.SameFile(infoA, infoB)
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
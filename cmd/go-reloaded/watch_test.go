@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchReprocessesChanges(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.txt"), filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(input, []byte("first (up)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var out, errOut bytes.Buffer
+	done := make(chan error, 1)
+	go func() {
+		done <- watch(ctx, input, output, config.DefaultOptions(), 10*time.Millisecond, &out, &errOut)
+	}()
+
+	// waits until the output holds expected
+	awaitOutput := func(expected string) {
+		t.Helper()
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if content, err := os.ReadFile(output); err == nil && string(content) == expected {
+				return
+			}
+		}
+		t.Fatalf("output never became %q", expected)
+	}
+	awaitOutput("FIRST")
+	if err := os.WriteFile(input, []byte("the second one (up, 2)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	awaitOutput("the SECOND ONE")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("watch returned %v", err)
+	}
+	if errOut.Len() != 0 {
+		t.Errorf("Unexpected errors: %s", errOut.String())
+	}
+}
+
+func TestWatchMissingInput(t *testing.T) {
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := watch(context.Background(), filepath.Join(dir, "missing.txt"), filepath.Join(dir, "out.txt"), config.DefaultOptions(), time.Millisecond, &out, &out)
+	if err == nil {
+		t.Error("Expected error for an input missing from the start")
+	}
+}
+
+func TestRunWatchRejectsInvalidArguments(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	if err := os.WriteFile(input, []byte("text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{input},
+		{input, input},
+		{"-interval", "0s", input, filepath.Join(dir, "out.txt")},
+		{"-interval", "1s", filepath.Join(dir, ".", "input.txt"), filepath.Join(dir, "input.txt")},
+	} {
+		if code := runWatch(args); code != EXIT_USAGE {
+			t.Errorf("runWatch(%q) = %d, expected %d", args, code, EXIT_USAGE)
+		}
+	}
+}
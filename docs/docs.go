@@ -0,0 +1,9 @@
+// Package docs embeds the documentation files the tool checks itself against
+package docs
+
+import _ "embed"
+
+// GoldenTests is golden_tests.md, the cases every build must reproduce exactly
+//
+//go:embed golden_tests.md
+var GoldenTests string
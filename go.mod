@@ -1,3 +1,11 @@
-module go-reloaded
+module github.com/GiannisPettas/go-reloaded
 
 go 1.24.9
+
+require (
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/term v0.36.0
+	golang.org/x/text v0.30.0
+)
+
+require golang.org/x/sys v0.37.0 // indirect
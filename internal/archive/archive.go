@@ -0,0 +1,186 @@
+// Package archive transforms the text files inside zip and tar archives, writing a new
+// archive with the same entries in the same order
+package archive
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats, recognized by the file name
+const (
+	FORMAT_ZIP    = "zip"
+	FORMAT_TAR    = "tar"
+	FORMAT_TAR_GZ = "tar.gz"
+)
+
+// Bytes of an entry looked at to decide whether it is text
+const SNIFF_BYTES = 512
+
+// FormatOf returns the FORMAT_* of the archive named path, "" when it is not named like one
+func FormatOf(path string) string {
+	name := strings.ToLower(path)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return FORMAT_ZIP
+	case strings.HasSuffix(name, ".tar"):
+		return FORMAT_TAR
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return FORMAT_TAR_GZ
+	}
+	return ""
+}
+
+// Report counts the entries of a processed archive
+type Report struct {
+	Entries     int // entries copied to the output, directories included
+	Transformed int // text files among them
+}
+
+// Process writes the archive at inputPath to outputPath with every text file transformed as
+// controller.ProcessFileWithOptions would. Paths, modes, times and the entries that are not
+// text are kept as they are. The output is written to a temp file that replaces outputPath
+// at the end, so the input can be replaced in place and a failure leaves nothing behind.
+//
+// A zip is written as a zip, a tar with or without gzip as either. An entry failing
+// opts.Strict or opts.Verify does not stop the others, its error is returned at the end.
+func Process(inputPath, outputPath string, opts config.Options) (Report, error) {
+	format, outputFormat := FormatOf(inputPath), FormatOf(outputPath)
+	if format == "" {
+		return Report{}, fmt.Errorf("%s is not named like a zip or tar archive", inputPath)
+	}
+	if (format == FORMAT_ZIP) != (outputFormat == FORMAT_ZIP) || outputFormat == "" {
+		return Report{}, fmt.Errorf("a %s archive cannot be written to %s", format, outputPath)
+	}
+	if err := opts.Validate(); err != nil {
+		return Report{}, fmt.Errorf("invalid options: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "go-reloaded-archive-*")
+	if err != nil {
+		return Report{}, controller.OutputError(fmt.Errorf("failed to create temp dir: %w", err))
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return Report{}, controller.OutputError(fmt.Errorf("failed to create output directory: %w", err))
+	}
+	output, err := os.CreateTemp(filepath.Dir(outputPath), ".go-reloaded-archive-*")
+	if err != nil {
+		return Report{}, controller.OutputError(fmt.Errorf("failed to create output: %w", err))
+	}
+	defer os.Remove(output.Name())
+	// Temp files are private, the archive gets the mode of any other output
+	if err := output.Chmod(0644); err != nil {
+		output.Close()
+		return Report{}, controller.OutputError(fmt.Errorf("failed to create output: %w", err))
+	}
+
+	p := &processor{archive: inputPath, dir: dir, opts: entryOptions(opts, inputPath)}
+	if format == FORMAT_ZIP {
+		err = p.processZip(inputPath, output)
+	} else {
+		err = p.processTar(inputPath, format == FORMAT_TAR_GZ, output, outputFormat == FORMAT_TAR_GZ)
+	}
+	if closeErr := output.Close(); err == nil && closeErr != nil {
+		err = controller.OutputError(fmt.Errorf("failed to write output: %w", closeErr))
+	}
+	if err != nil {
+		return p.report, err
+	}
+	if err := os.Rename(output.Name(), outputPath); err != nil {
+		return p.report, controller.OutputError(fmt.Errorf("failed to write output: %w", err))
+	}
+	return p.report, p.failed
+}
+
+// entryOptions are the options every entry is processed with: entries are never compressed
+// on their own and a chunk trace or edit trace of several files could not be read back
+func entryOptions(opts config.Options, archive string) config.Options {
+	opts.Compress = config.COMPRESS_NONE
+	opts.DebugChunks, opts.Trace = nil, nil
+	if opts.Logger != nil {
+		opts.Logger = opts.Logger.With("archive", archive)
+	}
+	return opts
+}
+
+// processor transforms the entries of one archive through files in dir
+type processor struct {
+	archive string
+	dir     string
+	opts    config.Options
+	report  Report
+	failed  error // first Strict or Verify error of an entry
+}
+
+// isText reports whether an entry starting with head is a plain text file
+func isText(head []byte) bool {
+	return strings.HasPrefix(http.DetectContentType(head), "text/plain")
+}
+
+// transform processes the text entry called name with content into a file and returns it
+// opened. An entry failing Strict or Verify is still written.
+func (p *processor) transform(name string, content io.Reader) (*os.File, error) {
+	input, output := filepath.Join(p.dir, "entry"), filepath.Join(p.dir, "transformed")
+	file, err := os.Create(input)
+	if err != nil {
+		return nil, controller.InputError(fmt.Errorf("failed to extract %s: %w", name, err))
+	}
+	_, err = io.Copy(file, content)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, controller.InputError(fmt.Errorf("failed to extract %s: %w", name, err))
+	}
+
+	opts := p.opts
+	if opts.Warnings != nil {
+		opts.Warnings = &renamingWriter{w: opts.Warnings, from: input, to: p.archive + ":" + name}
+	}
+	if opts.Logger != nil {
+		opts.Logger = opts.Logger.With("entry", name)
+	}
+	if err := controller.ProcessFileWithOptions(input, output, opts); err != nil {
+		if !errors.Is(err, controller.ErrStrict) && !errors.Is(err, controller.ErrVerify) {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if p.failed == nil {
+			p.failed = fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	p.report.Transformed++
+	return os.Open(output)
+}
+
+// renamingWriter replaces the temp file name at the start of every warning line with the
+// archive and entry name
+type renamingWriter struct {
+	w        io.Writer
+	from, to string
+}
+
+func (r *renamingWriter) Write(line []byte) (int, error) {
+	if !strings.HasPrefix(string(line), r.from) {
+		return r.w.Write(line)
+	}
+	if _, err := io.WriteString(r.w, r.to+string(line[len(r.from):])); err != nil {
+		return 0, err
+	}
+	return len(line), nil
+}
+
+// sniff returns a reader over content and whether content is text
+func sniff(content io.Reader) (io.Reader, bool) {
+	reader := bufio.NewReaderSize(content, SNIFF_BYTES)
+	head, _ := reader.Peek(SNIFF_BYTES)
+	return reader, len(head) > 0 && isText(head)
+}
@@ -0,0 +1,222 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// an entry that is not text, it must come out byte for byte
+var binaryEntry = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR (up) a apple")
+
+func TestFormatOf(t *testing.T) {
+	tests := map[string]string{
+		"docs.zip":    FORMAT_ZIP,
+		"DOCS.ZIP":    FORMAT_ZIP,
+		"docs.tar":    FORMAT_TAR,
+		"docs.tar.gz": FORMAT_TAR_GZ,
+		"docs.tgz":    FORMAT_TAR_GZ,
+		"docs.gz":     "",
+		"docs.txt":    "",
+	}
+	for path, expected := range tests {
+		if format := FormatOf(path); format != expected {
+			t.Errorf("FormatOf(%q) = %q, expected %q", path, format, expected)
+		}
+	}
+}
+
+func TestProcessZip(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "docs.zip"), filepath.Join(dir, "out", "docs.zip")
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, entry := range []struct {
+		name    string
+		content []byte
+	}{
+		{"guide/", nil},
+		{"guide/intro.txt", []byte("it was a apple (up) .\n")},
+		{"guide/logo.png", binaryEntry},
+	} {
+		file, err := writer.CreateHeader(&zip.FileHeader{Name: entry.name, Method: zip.Deflate, Modified: modified})
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(entry.content)
+	}
+	writer.SetComment("bundle")
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Process(input, output, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if report != (Report{Entries: 3, Transformed: 1}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	reader, err := zip.OpenReader(output)
+	if err != nil {
+		t.Fatalf("Output is not a zip: %v", err)
+	}
+	defer reader.Close()
+	if reader.Comment != "bundle" {
+		t.Errorf("Expected the comment to be kept, got %q", reader.Comment)
+	}
+	expected := map[string]string{"guide/": "", "guide/intro.txt": "it was an APPLE.\n", "guide/logo.png": string(binaryEntry)}
+	for i, file := range reader.File {
+		if names := []string{"guide/", "guide/intro.txt", "guide/logo.png"}; file.Name != names[i] {
+			t.Errorf("Entry %d is %s, expected %s", i, file.Name, names[i])
+		}
+		if !file.Modified.Equal(modified) {
+			t.Errorf("%s: modification time %v not kept", file.Name, file.Modified)
+		}
+		content, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(content)
+		content.Close()
+		if err != nil || string(data) != expected[file.Name] {
+			t.Errorf("%s: expected %q, got %q, %v", file.Name, expected[file.Name], data, err)
+		}
+	}
+}
+
+func TestProcessTar(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "docs.tar.gz"), filepath.Join(dir, "docs.tar")
+
+	var buf bytes.Buffer
+	compressed := gzip.NewWriter(&buf)
+	writer := tar.NewWriter(compressed)
+	for _, entry := range []struct {
+		header  tar.Header
+		content []byte
+	}{
+		{tar.Header{Name: "notes/", Typeflag: tar.TypeDir, Mode: 0755}, nil},
+		{tar.Header{Name: "notes/todo.md", Typeflag: tar.TypeReg, Mode: 0600}, []byte("buy milk (cap) ,then bread\n")},
+		{tar.Header{Name: "notes/data.bin", Typeflag: tar.TypeReg, Mode: 0644}, binaryEntry},
+		{tar.Header{Name: "notes/latest", Typeflag: tar.TypeSymlink, Linkname: "todo.md"}, nil},
+	} {
+		entry.header.Size = int64(len(entry.content))
+		if err := writer.WriteHeader(&entry.header); err != nil {
+			t.Fatal(err)
+		}
+		writer.Write(entry.content)
+	}
+	writer.Close()
+	compressed.Close()
+	if err := os.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Process(input, output, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Process failed: %v", err)
+	}
+	if report != (Report{Entries: 4, Transformed: 1}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	reader := tar.NewReader(file)
+	var entries []string
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Output is not a tar: %v", err)
+		}
+		data, _ := io.ReadAll(reader)
+		entries = append(entries, header.Name+"="+string(data))
+		if header.Name == "notes/todo.md" && header.Mode != 0600 {
+			t.Errorf("Mode of %s not kept: %o", header.Name, header.Mode)
+		}
+		if header.Name == "notes/latest" && header.Linkname != "todo.md" {
+			t.Errorf("Link target not kept: %q", header.Linkname)
+		}
+	}
+	expected := []string{"notes/=", "notes/todo.md=buy Milk, then bread\n", "notes/data.bin=" + string(binaryEntry), "notes/latest="}
+	if strings.Join(entries, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected entries %q, got %q", expected, entries)
+	}
+}
+
+func TestProcessStrictEntries(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "docs.tar"), filepath.Join(dir, "out.tar")
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		content := []byte("zz (hex) and " + name + " (up)\n")
+		writer.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(content))})
+		writer.Write(content)
+	}
+	writer.Close()
+	if err := os.WriteFile(input, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var warnings bytes.Buffer
+	opts := config.DefaultOptions()
+	opts.Strict, opts.Warnings = true, &warnings
+	report, err := Process(input, output, opts)
+	if !errors.Is(err, controller.ErrStrict) || !strings.Contains(err.Error(), "a.txt") {
+		t.Errorf("Expected a strict error for the first entry, got %v", err)
+	}
+	if report.Transformed != 2 {
+		t.Errorf("Expected both entries transformed, got %+v", report)
+	}
+	if _, statErr := os.Stat(output); statErr != nil {
+		t.Errorf("The archive should be written anyway: %v", statErr)
+	}
+	if !strings.HasPrefix(warnings.String(), input+":a.txt:1:") || !strings.Contains(warnings.String(), input+":b.txt:1:") {
+		t.Errorf("Warnings should name the archive entries, got %q", warnings.String())
+	}
+}
+
+func TestProcessErrors(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "broken.zip")
+	if err := os.WriteFile(broken, []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	output := filepath.Join(dir, "out.zip")
+	if _, err := Process(broken, output, config.DefaultOptions()); !errors.Is(err, controller.ErrInput) {
+		t.Errorf("Expected an input error, got %v", err)
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("A failed run should leave no output, got %v", err)
+	}
+	if _, err := Process(broken, filepath.Join(dir, "out.tar"), config.DefaultOptions()); err == nil {
+		t.Error("Expected error for a zip written as a tar")
+	}
+	if _, err := Process(filepath.Join(dir, "docs.txt"), output, config.DefaultOptions()); err == nil {
+		t.Error("Expected error for an input that is no archive")
+	}
+}
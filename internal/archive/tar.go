@@ -0,0 +1,93 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"os"
+)
+
+// processTar copies the tar at inputPath to output, transforming its text files. Either
+// side can be compressed with gzip.
+func (p *processor) processTar(inputPath string, gzipped bool, output io.Writer, gzipOutput bool) error {
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return controller.InputError(fmt.Errorf("failed to read archive: %w", err))
+	}
+	defer file.Close()
+	var input io.Reader = file
+	if gzipped {
+		decompressed, err := gzip.NewReader(file)
+		if err != nil {
+			return controller.InputError(fmt.Errorf("failed to read archive: %w", err))
+		}
+		defer decompressed.Close()
+		input = decompressed
+	}
+
+	var compressed *gzip.Writer
+	if gzipOutput {
+		compressed = gzip.NewWriter(output)
+		output = compressed
+	}
+	reader, writer := tar.NewReader(input), tar.NewWriter(output)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return controller.InputError(fmt.Errorf("failed to read archive: %w", err))
+		}
+		if err := p.processTarEntry(writer, header, reader); err != nil {
+			return err
+		}
+		p.report.Entries++
+	}
+	if err := writer.Close(); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write archive: %w", err))
+	}
+	if compressed != nil {
+		if err := compressed.Close(); err != nil {
+			return controller.OutputError(fmt.Errorf("failed to write archive: %w", err))
+		}
+	}
+	return nil
+}
+
+// processTarEntry writes one entry, links, directories and files that are not text are copied
+func (p *processor) processTarEntry(writer *tar.Writer, header *tar.Header, content io.Reader) error {
+	text := false
+	if header.Typeflag == tar.TypeReg {
+		content, text = sniff(content)
+	}
+	if !text {
+		if err := writer.WriteHeader(header); err != nil {
+			return controller.OutputError(fmt.Errorf("failed to write %s: %w", header.Name, err))
+		}
+		if _, err := io.Copy(writer, content); err != nil {
+			return controller.InputError(fmt.Errorf("failed to copy %s: %w", header.Name, err))
+		}
+		return nil
+	}
+
+	transformed, err := p.transform(header.Name, content)
+	if err != nil {
+		return err
+	}
+	defer transformed.Close()
+	info, err := transformed.Stat()
+	if err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write %s: %w", header.Name, err))
+	}
+	header.Size = info.Size()
+	if err := writer.WriteHeader(header); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write %s: %w", header.Name, err))
+	}
+	if _, err := io.Copy(writer, transformed); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write %s: %w", header.Name, err))
+	}
+	return nil
+}
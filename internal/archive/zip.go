@@ -0,0 +1,75 @@
+package archive
+
+import (
+	"archive/zip"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+)
+
+// processZip copies the zip at inputPath to output, transforming its text files
+func (p *processor) processZip(inputPath string, output io.Writer) error {
+	reader, err := zip.OpenReader(inputPath)
+	if err != nil {
+		return controller.InputError(fmt.Errorf("failed to read archive: %w", err))
+	}
+	defer reader.Close()
+
+	writer := zip.NewWriter(output)
+	for _, file := range reader.File {
+		if err := p.processZipEntry(writer, file); err != nil {
+			return err
+		}
+		p.report.Entries++
+	}
+	if err := writer.SetComment(reader.Comment); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write archive: %w", err))
+	}
+	if err := writer.Close(); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write archive: %w", err))
+	}
+	return nil
+}
+
+// processZipEntry writes one entry, an entry that is not text is copied without recompressing
+func (p *processor) processZipEntry(writer *zip.Writer, file *zip.File) error {
+	text := false
+	if !file.FileInfo().IsDir() {
+		content, err := file.Open()
+		if err != nil {
+			return controller.InputError(fmt.Errorf("failed to read %s: %w", file.Name, err))
+		}
+		_, text = sniff(content)
+		content.Close()
+	}
+	if !text {
+		if err := writer.Copy(file); err != nil {
+			return controller.InputError(fmt.Errorf("failed to copy %s: %w", file.Name, err))
+		}
+		return nil
+	}
+
+	content, err := file.Open()
+	if err != nil {
+		return controller.InputError(fmt.Errorf("failed to read %s: %w", file.Name, err))
+	}
+	transformed, err := p.transform(file.Name, content)
+	content.Close()
+	if err != nil {
+		return err
+	}
+	defer transformed.Close()
+
+	// The sizes and checksum are those of the new content, set when the entry is written
+	header := file.FileHeader
+	header.CRC32, header.CompressedSize64, header.UncompressedSize64 = 0, 0, 0
+	header.CompressedSize, header.UncompressedSize = 0, 0
+	entry, err := writer.CreateHeader(&header)
+	if err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write %s: %w", file.Name, err))
+	}
+	if _, err := io.Copy(entry, transformed); err != nil {
+		return controller.OutputError(fmt.Errorf("failed to write %s: %w", file.Name, err))
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package bench
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BinaryStats holds the measurements of one binary over the whole corpus
+type BinaryStats struct {
+	Path     string
+	Duration time.Duration // sum over files of the fastest run per file
+	PeakRSS  int64         // highest resident set size seen in any run, 0 if unsupported
+}
+
+// Throughput returns the processed megabytes per second
+func (s BinaryStats) Throughput(bytes int64) float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(bytes) / (1024 * 1024) / s.Duration.Seconds()
+}
+
+// Comparison is the result of running two binaries over the same corpus
+type Comparison struct {
+	Files      int
+	Bytes      int64
+	Runs       int
+	Old        BinaryStats
+	New        BinaryStats
+	Mismatches []string // corpus files whose outputs differ between the binaries
+}
+
+// Compare runs the old and new binaries over every file in corpusDir and
+// measures wall time and peak memory. Each file is processed runs times per
+// binary and the fastest run is kept to reduce noise.
+func Compare(oldBinary, newBinary, corpusDir string, runs int) (Comparison, error) {
+	if runs <= 0 {
+		return Comparison{}, fmt.Errorf("runs must be positive, got %d", runs)
+	}
+
+	files, totalBytes, err := corpusFiles(corpusDir)
+	if err != nil {
+		return Comparison{}, err
+	}
+	if len(files) == 0 {
+		return Comparison{}, fmt.Errorf("no files found in corpus %s", corpusDir)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-reloaded-benchcmp-*")
+	if err != nil {
+		return Comparison{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	result := Comparison{
+		Files: len(files),
+		Bytes: totalBytes,
+		Runs:  runs,
+		Old:   BinaryStats{Path: oldBinary},
+		New:   BinaryStats{Path: newBinary},
+	}
+
+	for i, file := range files {
+		oldOut := filepath.Join(tmpDir, fmt.Sprintf("%d.old", i))
+		newOut := filepath.Join(tmpDir, fmt.Sprintf("%d.new", i))
+
+		if err := measure(&result.Old, file, oldOut, runs); err != nil {
+			return Comparison{}, err
+		}
+		if err := measure(&result.New, file, newOut, runs); err != nil {
+			return Comparison{}, err
+		}
+
+		same, err := sameContent(oldOut, newOut)
+		if err != nil {
+			return Comparison{}, err
+		}
+		if !same {
+			result.Mismatches = append(result.Mismatches, file)
+		}
+	}
+
+	return result, nil
+}
+
+// measure runs the binary on one input runs times and folds the best time into stats
+func measure(stats *BinaryStats, inputPath, outputPath string, runs int) error {
+	var best time.Duration
+	for run := 0; run < runs; run++ {
+		cmd := exec.Command(stats.Path, inputPath, outputPath)
+		var stderr bytes.Buffer
+		cmd.Stdout = io.Discard
+		cmd.Stderr = &stderr
+
+		start := time.Now()
+		err := cmd.Run()
+		elapsed := time.Since(start)
+		if err != nil {
+			return fmt.Errorf("%s failed on %s: %w: %s", stats.Path, inputPath, err, stderr.String())
+		}
+
+		if run == 0 || elapsed < best {
+			best = elapsed
+		}
+		if rss := PeakRSS(cmd.ProcessState); rss > stats.PeakRSS {
+			stats.PeakRSS = rss
+		}
+	}
+
+	stats.Duration += best
+	return nil
+}
+
+// corpusFiles lists every regular file below dir in a stable order
+func corpusFiles(dir string) ([]string, int64, error) {
+	var files []string
+	var total int64
+
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, path)
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read corpus %s: %w", dir, err)
+	}
+
+	sort.Strings(files)
+	return files, total, nil
+}
+
+// sameContent reports whether two files have identical bytes
+func sameContent(a, b string) (bool, error) {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false, fmt.Errorf("failed to read output %s: %w", a, err)
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to read output %s: %w", b, err)
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// WriteReport prints a human readable comparison table
+func (c Comparison) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "Corpus: %d files, %d bytes, best of %d runs\n\n", c.Files, c.Bytes, c.Runs)
+	fmt.Fprintf(w, "%-12s %14s %14s %10s\n", "", "old", "new", "delta")
+
+	oldTput, newTput := c.Old.Throughput(c.Bytes), c.New.Throughput(c.Bytes)
+	fmt.Fprintf(w, "%-12s %14s %14s %10s\n", "time", c.Old.Duration.Round(time.Microsecond), c.New.Duration.Round(time.Microsecond),
+		delta(float64(c.Old.Duration), float64(c.New.Duration)))
+	fmt.Fprintf(w, "%-12s %14s %14s %10s\n", "throughput", fmt.Sprintf("%.2f MB/s", oldTput), fmt.Sprintf("%.2f MB/s", newTput),
+		delta(oldTput, newTput))
+	if c.Old.PeakRSS > 0 && c.New.PeakRSS > 0 {
+		fmt.Fprintf(w, "%-12s %14s %14s %10s\n", "peak RSS", formatBytes(c.Old.PeakRSS), formatBytes(c.New.PeakRSS),
+			delta(float64(c.Old.PeakRSS), float64(c.New.PeakRSS)))
+	}
+
+	if len(c.Mismatches) > 0 {
+		fmt.Fprintf(w, "\nWARNING: outputs differ for %d file(s):\n", len(c.Mismatches))
+		for _, file := range c.Mismatches {
+			fmt.Fprintf(w, "  %s\n", file)
+		}
+	}
+}
+
+// delta formats the relative change from old to new
+func delta(oldValue, newValue float64) string {
+	if oldValue == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%+.1f%%", (newValue-oldValue)/oldValue*100)
+}
+
+// formatBytes prints a byte count in MB with two decimals
+func formatBytes(n int64) string {
+	return fmt.Sprintf("%.2f MB", float64(n)/(1024*1024))
+}
@@ -0,0 +1,59 @@
+package bench
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// buildBinary compiles the CLI into a temp dir so it can be benchmarked against itself
+func buildBinary(t *testing.T) string {
+	t.Helper()
+	testutils.RequireSuite(t, testutils.SUITE_INTEGRATION)
+	binary := filepath.Join(t.TempDir(), "go-reloaded")
+	cmd := exec.Command("go", "build", "-o", binary, "github.com/GiannisPettas/go-reloaded/cmd/go-reloaded")
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build binary: %v, output: %s", err, string(output))
+	}
+	return binary
+}
+
+func TestCompareSameBinary(t *testing.T) {
+	binary := buildBinary(t)
+
+	corpusDir := t.TempDir()
+	os.WriteFile(filepath.Join(corpusDir, "a.txt"), []byte("hello (up) world !"), 0644)
+	os.WriteFile(filepath.Join(corpusDir, "b.txt"), []byte(strings.Repeat("FF (hex) a apple , ", 500)), 0644)
+
+	result, err := Compare(binary, binary, corpusDir, 2)
+	if err != nil {
+		t.Fatalf("Compare failed: %v", err)
+	}
+
+	if result.Files != 2 {
+		t.Errorf("Expected 2 files, got %d", result.Files)
+	}
+	if len(result.Mismatches) != 0 {
+		t.Errorf("Same binary should produce identical outputs, mismatches: %v", result.Mismatches)
+	}
+	if result.Old.Duration <= 0 || result.New.Duration <= 0 {
+		t.Errorf("Expected positive durations, got %v and %v", result.Old.Duration, result.New.Duration)
+	}
+
+	var report bytes.Buffer
+	result.WriteReport(&report)
+	if !strings.Contains(report.String(), "throughput") {
+		t.Errorf("Expected throughput in report, got: %s", report.String())
+	}
+}
+
+func TestCompareEmptyCorpus(t *testing.T) {
+	_, err := Compare("old", "new", t.TempDir(), 1)
+	if err == nil {
+		t.Errorf("Compare should fail on an empty corpus")
+	}
+}
@@ -0,0 +1,122 @@
+package bench
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// CorpusSpec describes a synthetic corpus. The same spec always generates the same text.
+type CorpusSpec struct {
+	Size    int64   // bytes to generate, the last line may run over
+	Density float64 // share of words followed by a command, from 0 to 1
+	Seed    int64
+}
+
+// words of the synthetic text, the vowel words give the article fix something to do
+var corpusWords = []string{
+	"the", "river", "ran", "past", "old", "mill", "and", "over", "stones", "while",
+	"she", "wrote", "letters", "to", "her", "brother", "about", "apple", "orchard", "hour",
+	"evening", "ink", "umbrella", "we", "never", "knew", "what", "came", "next", "honest",
+}
+
+// case commands with the largest count they are generated with
+var corpusCaseCommands = []struct {
+	name     string
+	maxCount int
+}{
+	{"up", 3}, {"low", 3}, {"cap", 3}, {"title", 2}, {"up>", 1},
+}
+
+// Validate checks that the spec describes a corpus that can be generated
+func (spec CorpusSpec) Validate() error {
+	if spec.Size <= 0 {
+		return fmt.Errorf("corpus size must be positive, got %d", spec.Size)
+	}
+	if spec.Density < 0 || spec.Density > 1 {
+		return fmt.Errorf("command density must be between 0 and 1, got %g", spec.Density)
+	}
+	return nil
+}
+
+// Generate writes the corpus described by spec to w and returns the number of commands in it.
+// Lines mix plain words with case and numeric commands, articles before vowels, detached
+// punctuation and quotes, so every stage of the pipeline has work to do.
+func Generate(w io.Writer, spec CorpusSpec) (int, error) {
+	if err := spec.Validate(); err != nil {
+		return 0, err
+	}
+	random := rand.New(rand.NewSource(spec.Seed))
+	writer := bufio.NewWriter(w)
+	commands := 0
+
+	var line strings.Builder
+	for written := int64(0); written < spec.Size; {
+		line.Reset()
+		words := 8 + random.Intn(9)
+		quoted := random.Intn(4) == 0
+		for i := 0; i < words; i++ {
+			if i > 0 {
+				line.WriteByte(' ')
+			}
+			if quoted && i == 2 {
+				line.WriteString("' ")
+			}
+			command := random.Float64() < spec.Density
+			line.WriteString(generateWord(random, command))
+			if command {
+				commands++
+			}
+			if quoted && i == 4 {
+				line.WriteString(" '")
+			}
+			if random.Intn(10) == 0 {
+				line.WriteString(" ,")
+			}
+		}
+		line.WriteString(" .\n")
+		n, err := writer.WriteString(line.String())
+		if err != nil {
+			return commands, fmt.Errorf("failed to write corpus: %w", err)
+		}
+		written += int64(n)
+	}
+	if err := writer.Flush(); err != nil {
+		return commands, fmt.Errorf("failed to write corpus: %w", err)
+	}
+	return commands, nil
+}
+
+// generateWord returns one word of the corpus, followed by a command when command is set
+func generateWord(random *rand.Rand, command bool) string {
+	word := corpusWords[random.Intn(len(corpusWords))]
+	if word == "apple" || word == "evening" || word == "umbrella" {
+		word = "a " + word
+	}
+	if !command {
+		return word
+	}
+
+	value := random.Int63n(1 << 20)
+	switch random.Intn(8) {
+	case 0:
+		return strconv.FormatInt(value, 16) + " (hex)"
+	case 1:
+		return strconv.FormatInt(value%256, 2) + " (bin)"
+	case 2:
+		return strconv.FormatInt(value, 10) + " (tohex)"
+	case 3:
+		return strconv.FormatInt(1+value%3999, 10) + " (rom)"
+	}
+	cmd := corpusCaseCommands[random.Intn(len(corpusCaseCommands))]
+	if count := 1 + random.Intn(cmd.maxCount); count > 1 {
+		return word + " (" + cmd.name + ", " + strconv.Itoa(count) + ")"
+	}
+	if strings.HasSuffix(cmd.name, ">") {
+		return "(" + cmd.name + ") " + word
+	}
+	return word + " (" + cmd.name + ")"
+}
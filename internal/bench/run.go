@@ -0,0 +1,174 @@
+package bench
+
+import (
+	"context"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// RunSpec configures a benchmark of the whole pipeline on a synthetic corpus
+type RunSpec struct {
+	Corpus  CorpusSpec
+	Runs    int            // runs over the corpus, the fastest is reported
+	Options config.Options // transformation options, the logger is replaced to collect stage timings
+	Keep    string         // path the generated corpus is written to and kept at, "" uses a temp file
+}
+
+// Stages are the time one run spent in each stage of the controller
+type Stages struct {
+	Read      time.Duration // reading and decoding
+	Transform time.Duration
+	Write     time.Duration // encoding and writing
+}
+
+// RunReport holds the measurements of the fastest run
+type RunReport struct {
+	Corpus     CorpusSpec
+	Bytes      int64 // size of the generated corpus
+	Commands   int   // commands in the corpus
+	Runs       int
+	Duration   time.Duration
+	Stages     Stages
+	Allocs     uint64 // heap allocations
+	AllocBytes uint64 // bytes allocated on the heap
+}
+
+// Throughput returns the processed megabytes per second
+func (r RunReport) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / (1024 * 1024) / r.Duration.Seconds()
+}
+
+// Run generates the corpus of spec, processes it spec.Runs times with controller.ProcessFileWithOptions
+// and reports the fastest run. Stage timings come from the "processed file" log record.
+func Run(spec RunSpec) (RunReport, error) {
+	if spec.Runs <= 0 {
+		return RunReport{}, fmt.Errorf("runs must be positive, got %d", spec.Runs)
+	}
+	if err := spec.Corpus.Validate(); err != nil {
+		return RunReport{}, err
+	}
+
+	tmpDir, err := os.MkdirTemp("", "go-reloaded-bench-*")
+	if err != nil {
+		return RunReport{}, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	inputPath := filepath.Join(tmpDir, "corpus.txt")
+	if spec.Keep != "" {
+		inputPath = spec.Keep
+	}
+	commands, err := writeCorpus(inputPath, spec.Corpus)
+	if err != nil {
+		return RunReport{}, err
+	}
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return RunReport{}, fmt.Errorf("failed to read corpus: %w", err)
+	}
+
+	report := RunReport{Corpus: spec.Corpus, Bytes: info.Size(), Commands: commands, Runs: spec.Runs}
+	recorder := &stageRecorder{}
+	opts := spec.Options
+	opts.Logger = slog.New(recorder)
+	outputPath := filepath.Join(tmpDir, "output.txt")
+	for run := 0; run < spec.Runs; run++ {
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		err := controller.ProcessFileWithOptions(inputPath, outputPath, opts)
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		if err != nil {
+			return RunReport{}, fmt.Errorf("run %d failed: %w", run+1, err)
+		}
+
+		if run == 0 || elapsed < report.Duration {
+			report.Duration = elapsed
+			report.Stages = recorder.stages
+			report.Allocs = after.Mallocs - before.Mallocs
+			report.AllocBytes = after.TotalAlloc - before.TotalAlloc
+		}
+	}
+	return report, nil
+}
+
+// writeCorpus generates the corpus of spec into a new file at path
+func writeCorpus(path string, spec CorpusSpec) (int, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create corpus: %w", err)
+	}
+	commands, err := Generate(file, spec)
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to write corpus: %w", closeErr)
+	}
+	return commands, err
+}
+
+// stageRecorder is a slog handler keeping the stage timings of the last processed file
+type stageRecorder struct {
+	stages Stages
+}
+
+func (r *stageRecorder) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= slog.LevelInfo
+}
+
+func (r *stageRecorder) Handle(_ context.Context, record slog.Record) error {
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Key != "stages" || attr.Value.Kind() != slog.KindGroup {
+			return true
+		}
+		for _, stage := range attr.Value.Group() {
+			switch stage.Key {
+			case "read":
+				r.stages.Read = stage.Value.Duration()
+			case "transform":
+				r.stages.Transform = stage.Value.Duration()
+			case "write":
+				r.stages.Write = stage.Value.Duration()
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+func (r *stageRecorder) WithAttrs([]slog.Attr) slog.Handler { return r }
+func (r *stageRecorder) WithGroup(string) slog.Handler      { return r }
+
+// WriteReport prints the measurements of the fastest run
+func (r RunReport) WriteReport(w io.Writer) {
+	fmt.Fprintf(w, "Corpus: %s, %d commands (density %g, seed %d), best of %d runs\n\n", formatBytes(r.Bytes), r.Commands, r.Corpus.Density, r.Corpus.Seed, r.Runs)
+	fmt.Fprintf(w, "%-12s %14s\n", "time", r.Duration.Round(time.Microsecond))
+	fmt.Fprintf(w, "%-12s %14s\n", "throughput", fmt.Sprintf("%.2f MB/s", r.Throughput()))
+	fmt.Fprintf(w, "%-12s %14d\n", "allocs", r.Allocs)
+	fmt.Fprintf(w, "%-12s %14s\n", "allocated", formatBytes(int64(r.AllocBytes)))
+	fmt.Fprintf(w, "\nStages:\n")
+	for _, stage := range []struct {
+		name     string
+		duration time.Duration
+	}{
+		{"read", r.Stages.Read},
+		{"transform", r.Stages.Transform},
+		{"write", r.Stages.Write},
+	} {
+		share := 0.0
+		if r.Duration > 0 {
+			share = float64(stage.duration) / float64(r.Duration) * 100
+		}
+		fmt.Fprintf(w, "  %-10s %14s %6.1f%%\n", stage.name, stage.duration.Round(time.Microsecond), share)
+	}
+}
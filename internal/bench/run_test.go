@@ -0,0 +1,75 @@
+package bench
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGenerateReproducible(t *testing.T) {
+	spec := CorpusSpec{Size: 20000, Density: 0.2, Seed: 7}
+	var first, second bytes.Buffer
+	commandsFirst, err := Generate(&first, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commandsSecond, err := Generate(&second, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() || commandsFirst != commandsSecond {
+		t.Error("The same spec generated different corpora")
+	}
+	if int64(first.Len()) < spec.Size || commandsFirst == 0 {
+		t.Errorf("Generated %d bytes with %d commands, expected at least %d bytes with commands", first.Len(), commandsFirst, spec.Size)
+	}
+	if strings.Count(first.String(), "(") < commandsFirst {
+		t.Errorf("Corpus has fewer commands than the %d reported", commandsFirst)
+	}
+
+	var plain bytes.Buffer
+	if commands, err := Generate(&plain, CorpusSpec{Size: 5000, Seed: 7}); err != nil || commands != 0 || strings.Contains(plain.String(), "(") {
+		t.Errorf("Expected no commands at density 0, got %d, %v", commands, err)
+	}
+}
+
+func TestGenerateInvalidSpec(t *testing.T) {
+	for _, spec := range []CorpusSpec{{Size: 0}, {Size: 10, Density: -0.1}, {Size: 10, Density: 1.5}} {
+		if _, err := Generate(&bytes.Buffer{}, spec); err == nil {
+			t.Errorf("Expected error for %+v", spec)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	keep := filepath.Join(t.TempDir(), "corpus.txt")
+	spec := RunSpec{Corpus: CorpusSpec{Size: 64 * 1024, Density: 0.1, Seed: 1}, Runs: 2, Options: config.DefaultOptions(), Keep: keep}
+	report, err := Run(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Bytes < spec.Corpus.Size || report.Commands == 0 || report.Duration <= 0 || report.Allocs == 0 {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	if report.Stages.Read <= 0 || report.Stages.Transform <= 0 || report.Stages.Write <= 0 {
+		t.Errorf("Expected every stage to be timed, got %+v", report.Stages)
+	}
+	if info, err := os.Stat(keep); err != nil || info.Size() != report.Bytes {
+		t.Errorf("Expected the corpus kept at %s, got %v", keep, err)
+	}
+
+	var out bytes.Buffer
+	report.WriteReport(&out)
+	for _, expected := range []string{"throughput", "allocs", "transform"} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Report misses %q:\n%s", expected, out.String())
+		}
+	}
+
+	if _, err := Run(RunSpec{Corpus: spec.Corpus, Options: config.DefaultOptions()}); err == nil {
+		t.Error("Expected error for zero runs")
+	}
+}
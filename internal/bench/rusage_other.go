@@ -0,0 +1,10 @@
+//go:build !unix
+
+package bench
+
+import "os"
+
+// PeakRSS is not available on this platform, memory columns are omitted
+func PeakRSS(state *os.ProcessState) int64 {
+	return 0
+}
@@ -0,0 +1,175 @@
+package chunktrace
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// Record describes what one chunk read, carried and wrote. Byte ranges are half-open [start, end).
+// The final record is the last chunk, it writes everything still carried.
+type Record struct {
+	Chunk         int    `json:"chunk"`
+	InputStart    int64  `json:"input_start"`
+	InputEnd      int64  `json:"input_end"`      // bytes consumed, the rest of the read is read again by the next chunk
+	ReadBytes     int    `json:"read_bytes"`     // bytes read from the input file
+	AdjustedBytes int    `json:"adjusted_bytes"` // UTF-8 bytes left after decoding and rune boundary adjustment
+	InputCRC      uint32 `json:"input_crc32"`    // CRC-32 (IEEE) of the consumed input range
+	OverlapIn     int    `json:"overlap_in"`     // untransformed words carried over from the previous chunk
+	Processed     int    `json:"processed"`      // words handled by the chunk: written or carried over
+	Written       int    `json:"written"`        // words written to the output
+	OverlapOut    int    `json:"overlap_out"`    // untransformed words carried over to the next chunk
+	OutputStart   int64  `json:"output_start"`
+	OutputEnd     int64  `json:"output_end"`
+	OutputCRC     uint32 `json:"output_crc32"` // CRC-32 (IEEE) of the written output range
+	Final         bool   `json:"final,omitempty"`
+	Restart       bool   `json:"restart,omitempty"` // the chunked output was dropped, the file is read again from chunk 0
+}
+
+// Writer emits one JSON line per record.
+// A nil *Writer does not trace anything, so callers can use it unconditionally.
+type Writer struct {
+	enc *json.Encoder
+}
+
+// NewWriter returns a writer tracing to w, or nil when w is nil (tracing disabled)
+func NewWriter(w io.Writer) *Writer {
+	if w == nil {
+		return nil
+	}
+	return &Writer{enc: json.NewEncoder(w)}
+}
+
+// Write appends one record to the trace
+func (w *Writer) Write(record Record) error {
+	if w == nil {
+		return nil
+	}
+	if err := w.enc.Encode(record); err != nil {
+		return fmt.Errorf("failed to write chunk trace: %w", err)
+	}
+	return nil
+}
+
+// Checksum returns the CRC-32 used by the trace for a byte range
+func Checksum(data []byte) uint32 {
+	return crc32.ChecksumIEEE(data)
+}
+
+// Read parses a trace written by Writer
+func Read(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("trace line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read chunk trace: %w", err)
+	}
+	return records, nil
+}
+
+// Verify checks that the input ranges of the records tile the input exactly, that the output
+// ranges tile the output, and that every processed word is either written or carried over to
+// the next chunk exactly once. input and output may be nil; when given, their sizes and the
+// checksums of every range are checked too. All problems found are returned joined.
+// A restart record discards the records before it, their output was overwritten.
+func Verify(records []Record, input, output *io.SectionReader) error {
+	if len(records) == 0 {
+		return errors.New("empty chunk trace")
+	}
+
+	var problems []error
+	report := func(record Record, format string, args ...any) {
+		problems = append(problems, fmt.Errorf("chunk %d: "+format, append([]any{record.Chunk}, args...)...))
+	}
+
+	var inputEnd, outputEnd int64
+	carried, next := 0, 0
+	for _, record := range records {
+		if record.Restart {
+			problems, inputEnd, outputEnd, carried, next = nil, 0, 0, 0, 0
+		}
+		if record.Chunk != next {
+			report(record, "out of order, expected chunk %d", next)
+		}
+		next++
+		if record.InputStart != inputEnd {
+			report(record, "input starts at %d, previous chunk ended at %d (%s)", record.InputStart, inputEnd, gapOrOverlap(record.InputStart-inputEnd))
+		}
+		if record.InputEnd < record.InputStart || record.InputEnd-record.InputStart > int64(record.ReadBytes) {
+			report(record, "consumed input [%d, %d) does not fit the %d bytes read", record.InputStart, record.InputEnd, record.ReadBytes)
+		}
+		if record.OutputStart != outputEnd {
+			report(record, "output starts at %d, previous chunk ended at %d (%s)", record.OutputStart, outputEnd, gapOrOverlap(record.OutputStart-outputEnd))
+		}
+		if record.OutputEnd < record.OutputStart {
+			report(record, "output range [%d, %d) is negative", record.OutputStart, record.OutputEnd)
+		}
+		if record.OverlapIn != carried {
+			report(record, "carries in %d words, previous chunk carried out %d", record.OverlapIn, carried)
+		}
+		if lost := record.Processed - record.Written - record.OverlapOut; lost > 0 {
+			report(record, "drops %d of %d processed words", lost, record.Processed)
+		} else if lost < 0 {
+			report(record, "duplicates %d words, %d processed but %d written and %d carried", -lost, record.Processed, record.Written, record.OverlapOut)
+		}
+		if input != nil {
+			verifyChecksum(input, record.InputStart, record.InputEnd, record.InputCRC, func(format string, args ...any) {
+				report(record, "input "+format, args...)
+			})
+		}
+		if output != nil {
+			verifyChecksum(output, record.OutputStart, record.OutputEnd, record.OutputCRC, func(format string, args ...any) {
+				report(record, "output "+format, args...)
+			})
+		}
+		inputEnd, outputEnd, carried = record.InputEnd, record.OutputEnd, record.OverlapOut
+	}
+
+	last := records[len(records)-1]
+	if carried != 0 {
+		report(last, "%d carried words are never written", carried)
+	}
+	if input != nil && inputEnd != input.Size() {
+		report(last, "input ends at %d, the input file has %d bytes", inputEnd, input.Size())
+	}
+	if output != nil && outputEnd != output.Size() {
+		report(last, "output ends at %d, the output file has %d bytes", outputEnd, output.Size())
+	}
+	return errors.Join(problems...)
+}
+
+// gapOrOverlap describes the distance between two ranges that should touch
+func gapOrOverlap(delta int64) string {
+	if delta > 0 {
+		return fmt.Sprintf("%d bytes skipped", delta)
+	}
+	return fmt.Sprintf("%d bytes repeated", -delta)
+}
+
+// verifyChecksum compares the CRC-32 of file[start:end) with the recorded one
+func verifyChecksum(file *io.SectionReader, start, end int64, expected uint32, report func(string, ...any)) {
+	if start < 0 || end < start || end > file.Size() {
+		report("range [%d, %d) is outside the %d byte file", start, end, file.Size())
+		return
+	}
+	hash := crc32.NewIEEE()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, start, end-start)); err != nil {
+		report("range [%d, %d) cannot be read: %v", start, end, err)
+		return
+	}
+	if actual := hash.Sum32(); actual != expected {
+		report("checksum of [%d, %d) is %08x, trace says %08x", start, end, actual, expected)
+	}
+}
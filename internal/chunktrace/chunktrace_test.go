@@ -0,0 +1,95 @@
+package chunktrace
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// sample builds a clean two chunk trace over input and output, with a final flush of 2 words
+func sample(input, output []byte) []Record {
+	return []Record{
+		{Chunk: 0, InputStart: 0, InputEnd: 6, ReadBytes: 6, AdjustedBytes: 6, InputCRC: Checksum(input[:6]),
+			Processed: 3, Written: 1, OverlapOut: 2, OutputEnd: 4, OutputCRC: Checksum(output[:4])},
+		{Chunk: 1, InputStart: 6, InputEnd: 12, ReadBytes: 6, AdjustedBytes: 6, InputCRC: Checksum(input[6:12]),
+			OverlapIn: 2, Processed: 4, Written: 2, OverlapOut: 2, OutputStart: 4, OutputEnd: 8, OutputCRC: Checksum(output[4:8])},
+		{Chunk: 2, InputStart: 12, InputEnd: 12, OverlapIn: 2, Processed: 2, Written: 2,
+			OutputStart: 8, OutputEnd: 12, OutputCRC: Checksum(output[8:12]), Final: true},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	input, output := []byte("aa bb ccdd e"), []byte("AA BB CCDD E")
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, record := range sample(input, output) {
+		if err := w.Write(record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1] != sample(input, output)[1] || !records[2].Final {
+		t.Errorf("Round trip changed the records: %+v", records)
+	}
+
+	if _, err := Read(strings.NewReader("{\"chunk\":0}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("Expected an error for line 2, got %v", err)
+	}
+
+	// A nil writer traces nothing
+	if err := NewWriter(nil).Write(Record{}); err != nil {
+		t.Errorf("Nil writer returned %v", err)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	input, output := []byte("aa bb ccdd e"), []byte("AA BB CCDD E")
+	section := func(data []byte) *io.SectionReader {
+		return io.NewSectionReader(bytes.NewReader(data), 0, int64(len(data)))
+	}
+
+	if err := Verify(sample(input, output), section(input), section(output)); err != nil {
+		t.Fatalf("Clean trace failed: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func(records []Record)
+		expect string
+	}{
+		{"input gap", func(r []Record) { r[1].InputStart = 7 }, "1 bytes skipped"},
+		{"input repeated", func(r []Record) { r[0].InputEnd = 7; r[0].ReadBytes = 7 }, "1 bytes repeated"},
+		{"consumed more than read", func(r []Record) { r[0].ReadBytes = 5 }, "does not fit"},
+		{"output gap", func(r []Record) { r[1].OutputStart = 5 }, "output starts at 5"},
+		// the overlap held back by chunk 0 is skipped again by chunk 1
+		{"dropped overlap", func(r []Record) { r[1].Written = 0 }, "chunk 1: drops 2 of 4 processed words"},
+		{"duplicated words", func(r []Record) { r[0].Written = 3 }, "duplicates 2 words"},
+		{"carry mismatch", func(r []Record) { r[1].OverlapIn = 1; r[1].Processed = 3; r[1].Written = 1 }, "carries in 1 words, previous chunk carried out 2"},
+		{"never flushed", func(r []Record) { r[2].Written = 0; r[2].OverlapOut = 2 }, "2 carried words are never written"},
+		{"input checksum", func(r []Record) { r[1].InputCRC++ }, "input checksum"},
+		{"output checksum", func(r []Record) { r[0].OutputCRC++ }, "output checksum"},
+		{"out of order", func(r []Record) { r[1].Chunk = 5 }, "expected chunk 1"},
+	}
+
+	for _, test := range tests {
+		records := sample(input, output)
+		test.change(records)
+		err := Verify(records, section(input), section(output))
+		if err == nil || !strings.Contains(err.Error(), test.expect) {
+			t.Errorf("%s: expected %q, got %v", test.name, test.expect, err)
+		}
+	}
+
+	// File sizes are only checked when the files are given
+	if err := Verify(sample(input, output), section(append(input, 'x')), nil); err == nil || !strings.Contains(err.Error(), "13 bytes") {
+		t.Errorf("Expected an input size error, got %v", err)
+	}
+	if err := Verify(nil, nil, nil); err == nil {
+		t.Error("Expected an error for an empty trace")
+	}
+}
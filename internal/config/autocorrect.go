@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// validateCorrection reports a typo/correction pair of Options.Autocorrect that cannot apply
+func validateCorrection(typo, correction string) error {
+	if typo == "" || strings.IndexFunc(typo, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("invalid typo %q, expected one word", typo)
+	}
+	if strings.ToLower(typo) != typo {
+		return fmt.Errorf("typo %q must be lowercase", typo)
+	}
+	if strings.TrimSpace(correction) == "" {
+		return fmt.Errorf("typo %q has no correction", typo)
+	}
+	if strings.ContainsAny(correction, "\r\n") {
+		return fmt.Errorf("correction of %q must not contain line breaks", typo)
+	}
+	return nil
+}
+
+// ReadCorrections parses an autocorrect dictionary, one "typo = correction" pair per line:
+//
+//	teh = the
+//	alot = a lot
+//
+// Typos are single words matched whatever their case, a later line wins over an earlier
+// one of the same typo. Blank lines and lines starting with '#' are ignored.
+func ReadCorrections(r io.Reader) (map[string]string, error) {
+	corrections := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		typo, correction, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected typo = correction, got %q", lineNum, line)
+		}
+		typo, correction = strings.ToLower(strings.TrimSpace(typo)), strings.TrimSpace(correction)
+		if err := validateCorrection(typo, correction); err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		corrections[typo] = correction
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read autocorrect dictionary: %w", err)
+	}
+	return corrections, nil
+}
+
+// LoadCorrections reads the autocorrect dictionary of a file from disk
+func LoadCorrections(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open autocorrect dictionary: %w", err)
+	}
+	defer file.Close()
+
+	corrections, err := ReadCorrections(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return corrections, nil
+}
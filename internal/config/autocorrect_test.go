@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadCorrections(t *testing.T) {
+	input := "# typos\nteh = the\n\nAlot = a lot\ndont=don't\nteh = thee\n"
+	corrections, err := ReadCorrections(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]string{"teh": "thee", "alot": "a lot", "dont": "don't"}
+	if len(corrections) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, corrections)
+	}
+	for typo, correction := range expected {
+		if corrections[typo] != correction {
+			t.Errorf("Correction of %q: expected %q, got %q", typo, correction, corrections[typo])
+		}
+	}
+
+	for _, input := range []string{"teh the", "teh =", "a lot = alot", "= the"} {
+		if _, err := ReadCorrections(strings.NewReader(input)); err == nil {
+			t.Errorf("Expected an error reading %q", input)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Autocorrect = map[string]string{"Teh": "the"}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected an error for a typo that is not lowercase")
+	}
+}
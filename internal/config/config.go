@@ -1,14 +1,29 @@
 package config
 
-import "fmt"
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
 
 // System constants for chunk processing
 const (
 	CHUNK_BYTES   = 4096 // 4KB chunks for memory efficiency - can go from 1kb to 8kb
 	OVERLAP_WORDS = 20   // Number of words to preserve between chunks - can go from 10 to 20
-	// Also determines token buffer size (4x OVERLAP_WORDS = 80 tokens)
+	// Also sizes the initial token buffer (4x OVERLAP_WORDS = 80 tokens), which grows as needed
 )
 
+// Longest download of an http(s) input when Options.FetchTimeout is 0
+const FETCH_TIMEOUT = 60 * time.Second
+
+// Smallest Options.MaxMemory, the Go runtime alone needs a few megabytes
+const MIN_MAX_MEMORY = 8 << 20
+
 // ValidateConstants checks if all constants are within valid ranges
 func ValidateConstants() error {
 	if CHUNK_BYTES <= 0 {
@@ -31,3 +46,606 @@ func ValidateConstants() error {
 	}
 	return nil
 }
+
+// Options holds runtime settings that change how text is transformed.
+// The zero value is not meant to be used directly, start from DefaultOptions.
+type Options struct {
+	UpperHex    bool // (tohex) emits uppercase digits: 255 -> FF instead of ff
+	RadixPrefix bool // (tohex)/(tobin) prepend 0x/0b to the result
+
+	NativeDigits bool // conversions of words written in another digit script answer in that script: ١E (hex) -> ٣٠
+
+	DigitGrouping string // GROUPING_* style of the decimal results of (hex), (bin) and (unrom), "" behaves like GROUPING_NONE
+
+	StripSoftHyphens bool // remove U+00AD soft hyphens instead of keeping them inside words
+
+	NormalizeOrdinals bool // join detached ordinal suffixes: "1 st" -> "1st"
+	PlainOrdinals     bool // with NormalizeOrdinals, also turn "1ˢᵗ" into "1st"
+
+	TitleSmallWords bool // (title) keeps small words lowercase inside compounds: "State-of-the-Art"
+
+	CapitalizeSentences bool // uppercase the first letter of every sentence and line
+
+	SmartQuotes bool // emit paired quotes as typographic “ ” ‘ ’ and apostrophes as ’
+	ASCIIQuotes bool // convert typographic quotes in the input to straight ASCII quotes
+
+	PreserveWhitespace bool // keep runs of spaces, tabs and indentation instead of collapsing them
+
+	Normalize  string // NORMALIZE_* Unicode normalization form of the output, "" behaves like NORMALIZE_NONE
+	LineEnding string // LINE_ENDING_* for the output, "" behaves like LINE_ENDING_AUTO
+	BOM        string // BOM_* policy for the UTF-8 byte order mark of the output, "" behaves like BOM_KEEP
+
+	SkipArticles bool // leave a/an untouched before vowels and h
+	SkipQuotes   bool // leave quotes where they are instead of pairing them
+
+	// RawStart and RawEnd delimit regions kept exactly as written: no commands, spacing,
+	// quote or article fixes. DefaultOptions uses RAW_START and RAW_END, "" disables raw regions
+	RawStart string
+	RawEnd   string
+
+	Markdown bool // pass fenced code blocks, inline code, link destinations and autolinks through untouched
+
+	// UnappliedCommandPolicy is the UNAPPLIED_* fate of a command that cannot be applied. ""
+	// drops a well-formed command like (up, 0) and keeps a malformed one like (up, two) as text
+	UnappliedCommandPolicy string
+
+	// ShortCountPolicy is the SHORT_COUNT_* handling of a count reaching past the first or
+	// last word of the text, like (up, 100) after three words. "" behaves like SHORT_COUNT_CLAMP
+	ShortCountPolicy string
+
+	// CommandScope is the SCOPE_* boundary no command reaches across, "" lets a count reach
+	// every word of the text
+	CommandScope string
+
+	// CommandAliases maps lowercase names to the command they stand for: "uppercase" -> "up".
+	// Commands and aliases are recognized whatever their case, (UP) and (Uppercase) included
+	CommandAliases map[string]string
+
+	// Substitutions are find/replace rules run in order, before the commands or after every
+	// built-in rule. Raw regions are left alone.
+	Substitutions []Substitution
+
+	// Autocorrect maps lowercase typos to their correction, applied to every word before the
+	// commands: "teh" -> "the". A word written capitalized or in capitals keeps its case
+	Autocorrect map[string]string
+
+	// ExpandVariables replaces ${name} and {{name}} placeholders with their Variables before
+	// the commands, a placeholder of no variable stays as written. Values do not span lines.
+	ExpandVariables bool
+	Variables       map[string]string
+
+	// ArticleExceptions maps lowercase words to the article they take, overriding the vowel/h rule:
+	// "university" -> "a", "fbi" -> "an". nil means DefaultArticleExceptions
+	ArticleExceptions map[string]string
+
+	Dialogue string // DIALOGUE_* style for punctuation between quotations and their tags, "" leaves dialogue alone
+
+	// Punctuation maps each punctuation rune to its ATTACH_* spacing rule, nil means DefaultPunctuation
+	Punctuation map[rune]int
+
+	Encoding string // ENCODING_* of the input and output files, ENCODING_AUTO detects it per file
+
+	Compress string // COMPRESS_* codec of the input and output files, "" behaves like COMPRESS_AUTO
+
+	IOThrottleMBps float64 // limit combined read/write bandwidth in MB/s, 0 means unlimited
+
+	// MaxMemory bounds the memory of a run in bytes, 0 means unlimited. A chunked run fails
+	// with controller.ErrMemory rather than carry more text between chunks than fits.
+	MaxMemory int64
+
+	// An input naming an http or https URL is streamed as it is transformed, its body read
+	// within FetchTimeout (0 behaves like FETCH_TIMEOUT, there is no unlimited) and failing
+	// past MaxFetchBytes (0 means unlimited)
+	FetchTimeout  time.Duration
+	MaxFetchBytes int64
+
+	DebugChunks io.Writer // receives one JSON record per chunk, see internal/chunktrace; nil disables the trace
+
+	// Warnings receives one line per command that was dropped or left in the text, prefixed
+	// with the input path: "in.txt:3:7: (up, 0): count must be positive". nil discards them
+	Warnings io.Writer
+
+	// Trace receives every edit of the file as a JSON array, see transformer.TraceText; nil
+	// disables it. A traced file is transformed in one pass whatever its size.
+	Trace io.Writer
+
+	Strict bool // processing a file fails once it is written when a warning was reported
+
+	Verify bool // processing a file fails once it is written when transforming the output again changes it
+
+	// Checkpoint saves the state of a chunked run next to the output every
+	// controller.CHECKPOINT_BYTES of input, Resume continues an interrupted run from there
+	// instead of starting over and implies Checkpoint. Neither works with a compressed output.
+	Checkpoint bool
+	Resume     bool
+
+	// Logger receives chunk progress (debug), stage timings per file (info) and the commands
+	// that were not applied (warn). nil logs nothing
+	Logger *slog.Logger
+}
+
+// DefaultOptions returns the options matching the classic go-reloaded behavior
+func DefaultOptions() Options {
+	return Options{Normalize: NORMALIZE_NONE, LineEnding: LINE_ENDING_AUTO, BOM: BOM_KEEP, Encoding: ENCODING_UTF8,
+		Compress: COMPRESS_AUTO, RawStart: RAW_START, RawEnd: RAW_END}
+}
+
+// ArticleRules returns the configured article exceptions, or the built-in ones when none are set.
+// The returned map must not be modified.
+func (o Options) ArticleRules() map[string]string {
+	if o.ArticleExceptions == nil {
+		return defaultArticleExceptions
+	}
+	return o.ArticleExceptions
+}
+
+// PunctuationRules returns the configured punctuation set, or the classic one when none is set.
+// The returned map must not be modified.
+func (o Options) PunctuationRules() map[rune]int {
+	if o.Punctuation == nil {
+		return defaultPunctuation
+	}
+	return o.Punctuation
+}
+
+// Validate reports option combinations that cannot be honored together
+func (o Options) Validate() error {
+	if o.SmartQuotes && o.ASCIIQuotes {
+		return fmt.Errorf("smart quotes and ASCII quotes are mutually exclusive")
+	}
+	for r, attach := range o.Punctuation {
+		if err := validatePunctuationRune(r); err != nil {
+			return err
+		}
+		if attach < ATTACH_LEFT || attach > ATTACH_SPACED {
+			return fmt.Errorf("invalid attach mode %d for punctuation %q", attach, r)
+		}
+	}
+	for word, article := range o.ArticleExceptions {
+		if article != ARTICLE_A && article != ARTICLE_AN {
+			return fmt.Errorf("invalid article %q for exception %q, expected a or an", article, word)
+		}
+		if word == "" || word != strings.ToLower(word) {
+			return fmt.Errorf("article exception %q must be a non-empty lowercase word", word)
+		}
+	}
+	for alias, command := range o.CommandAliases {
+		if err := validateAliasName(alias); err != nil {
+			return err
+		}
+		if command == "" {
+			return fmt.Errorf("alias %q stands for no command", alias)
+		}
+	}
+	for _, substitution := range o.Substitutions {
+		if err := validateSubstitution(substitution); err != nil {
+			return err
+		}
+	}
+	for typo, correction := range o.Autocorrect {
+		if err := validateCorrection(typo, correction); err != nil {
+			return err
+		}
+	}
+	for name, value := range o.Variables {
+		if !validVariableName(name) {
+			return fmt.Errorf("invalid variable name %q, expected letters, digits, _, . or - after a letter or _", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("variable %q must not contain line breaks", name)
+		}
+	}
+	if (o.RawStart == "") != (o.RawEnd == "") {
+		return fmt.Errorf("raw regions need both a start and an end marker, got %q and %q", o.RawStart, o.RawEnd)
+	}
+	if o.RawStart != "" && o.RawStart == o.RawEnd {
+		return fmt.Errorf("raw start and end markers must differ, both are %q", o.RawStart)
+	}
+	if strings.ContainsAny(o.RawStart+o.RawEnd, "\r\n") {
+		return fmt.Errorf("raw markers must not contain line breaks")
+	}
+	switch o.Normalize {
+	case "", NORMALIZE_NONE, NORMALIZE_NFC, NORMALIZE_NFD:
+	default:
+		return fmt.Errorf("invalid normalization %q, expected nfc, nfd or none", o.Normalize)
+	}
+	switch o.LineEnding {
+	case "", LINE_ENDING_AUTO, LINE_ENDING_LF, LINE_ENDING_CRLF:
+	default:
+		return fmt.Errorf("invalid line ending %q, expected auto, lf or crlf", o.LineEnding)
+	}
+	switch o.BOM {
+	case "", BOM_KEEP, BOM_STRIP, BOM_ADD:
+	default:
+		return fmt.Errorf("invalid BOM policy %q, expected keep, strip or add", o.BOM)
+	}
+	switch o.UnappliedCommandPolicy {
+	case "", UNAPPLIED_DROP, UNAPPLIED_KEEP, UNAPPLIED_ERROR:
+	default:
+		return fmt.Errorf("invalid unapplied command policy %q, expected drop, keep or error", o.UnappliedCommandPolicy)
+	}
+	switch o.ShortCountPolicy {
+	case "", SHORT_COUNT_CLAMP, SHORT_COUNT_WARN:
+	default:
+		return fmt.Errorf("invalid short count policy %q, expected clamp or warn", o.ShortCountPolicy)
+	}
+	switch o.CommandScope {
+	case "", SCOPE_SENTENCE, SCOPE_PARAGRAPH:
+	default:
+		return fmt.Errorf("invalid command scope %q, expected sentence or paragraph", o.CommandScope)
+	}
+	switch o.Dialogue {
+	case "", DIALOGUE_AMERICAN, DIALOGUE_LOGICAL:
+	default:
+		return fmt.Errorf("invalid dialogue style %q, expected american or logical", o.Dialogue)
+	}
+	switch o.DigitGrouping {
+	case "", GROUPING_NONE, GROUPING_EN, GROUPING_DE, GROUPING_FR, GROUPING_CH, GROUPING_IN:
+	default:
+		return fmt.Errorf("invalid digit grouping %q, expected none, en, de, fr, ch or in", o.DigitGrouping)
+	}
+	switch o.Encoding {
+	case "", ENCODING_UTF8, ENCODING_LATIN1, ENCODING_UTF16LE, ENCODING_UTF16BE, ENCODING_AUTO:
+	default:
+		return fmt.Errorf("invalid encoding %q, expected utf8, latin1, utf16le, utf16be or auto", o.Encoding)
+	}
+	switch o.Compress {
+	case "", COMPRESS_AUTO, COMPRESS_NONE, COMPRESS_GZIP, COMPRESS_ZSTD:
+	default:
+		return fmt.Errorf("invalid compression %q, expected gzip, zstd, none or auto", o.Compress)
+	}
+	if o.IOThrottleMBps < 0 {
+		return fmt.Errorf("IO throttle must not be negative, got %g", o.IOThrottleMBps)
+	}
+	if o.FetchTimeout < 0 {
+		return fmt.Errorf("fetch timeout must not be negative, got %v", o.FetchTimeout)
+	}
+	if o.MaxFetchBytes < 0 {
+		return fmt.Errorf("fetch size limit must not be negative, got %d", o.MaxFetchBytes)
+	}
+	if o.MaxMemory < 0 || (o.MaxMemory > 0 && o.MaxMemory < MIN_MAX_MEMORY) {
+		return fmt.Errorf("memory limit must be 0 or at least %d bytes, got %d", MIN_MAX_MEMORY, o.MaxMemory)
+	}
+	if o.Trace != nil && (o.Checkpoint || o.Resume) {
+		return fmt.Errorf("a trace covers the whole file and cannot be combined with checkpoints")
+	}
+	return nil
+}
+
+// Default markers of a raw region: "run (raw)(up) stays(endraw) now" -> "run (up) stays now"
+const (
+	RAW_START = "(raw)"
+	RAW_END   = "(endraw)"
+)
+
+// Unicode normalization forms of the output. Commands always run on composed (NFC) text when one is set.
+const (
+	NORMALIZE_NONE = "none" // keep composed and decomposed characters as written
+	NORMALIZE_NFC  = "nfc"  // composed: "é" is one code point
+	NORMALIZE_NFD  = "nfd"  // decomposed: "é" is "e" and a combining acute accent
+)
+
+// Output line endings
+const (
+	LINE_ENDING_AUTO = "auto" // keep every line ending as it was in the input
+	LINE_ENDING_LF   = "lf"   // "\n" everywhere
+	LINE_ENDING_CRLF = "crlf" // "\r\n" everywhere
+)
+
+// File encodings, text is transcoded to UTF-8 on read and back on write
+const (
+	ENCODING_UTF8    = "utf8"
+	ENCODING_LATIN1  = "latin1" // ISO-8859-1
+	ENCODING_UTF16LE = "utf16le"
+	ENCODING_UTF16BE = "utf16be"
+	ENCODING_AUTO    = "auto" // UTF-16 by byte order mark, else UTF-8 when valid, else Latin-1
+)
+
+// Compression codecs of the files. Compressed input is decompressed as it is read, the
+// output is compressed as it is written.
+const (
+	COMPRESS_AUTO = "auto" // by file name: .gz is gzip, .zst is zstd, anything else is plain
+	COMPRESS_NONE = "none" // plain files whatever their name
+	COMPRESS_GZIP = "gzip" // gzip input and output whatever their name
+	COMPRESS_ZSTD = "zstd" // zstd input and output whatever their name
+)
+
+// CompressionOf returns the COMPRESS_* codec of the file at path, never COMPRESS_AUTO
+func (o Options) CompressionOf(path string) string {
+	switch o.Compress {
+	case "", COMPRESS_AUTO:
+	default:
+		return o.Compress
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz":
+		return COMPRESS_GZIP
+	case ".zst":
+		return COMPRESS_ZSTD
+	}
+	return COMPRESS_NONE
+}
+
+// Byte order mark policies. The input BOM is always removed before transforming.
+const (
+	BOM_KEEP  = "keep"  // write a BOM when the input had one
+	BOM_STRIP = "strip" // never write a BOM
+	BOM_ADD   = "add"   // always write a BOM
+)
+
+// EmitBOM reports whether the output should start with a BOM
+func (o Options) EmitBOM(inputHadBOM bool) bool {
+	switch o.BOM {
+	case BOM_STRIP:
+		return false
+	case BOM_ADD:
+		return true
+	}
+	return inputHadBOM
+}
+
+// Policies for commands that cannot be applied: no preceding word, a count that is not
+// positive, a word a conversion cannot read, or a malformed command like (up, two)
+const (
+	UNAPPLIED_DROP  = "drop"  // remove the command from the text
+	UNAPPLIED_KEEP  = "keep"  // leave the command in the text as written
+	UNAPPLIED_ERROR = "error" // leave it as written and fail, see transformer.ErrUnapplied
+)
+
+// Policies for a count larger than the words there are, the command changes the words there
+// are either way. Strict and UNAPPLIED_ERROR fail on the warning like on any other.
+const (
+	SHORT_COUNT_CLAMP = "clamp" // say nothing
+	SHORT_COUNT_WARN  = "warn"  // report a warning
+)
+
+// Scopes of a command, the words it can reach. A count reaching past the boundary is short,
+// see ShortCountPolicy.
+const (
+	SCOPE_SENTENCE  = "sentence"  // up to . ! or ? ending a sentence, or a blank line
+	SCOPE_PARAGRAPH = "paragraph" // up to a blank line
+)
+
+// Dialogue punctuation styles
+const (
+	DIALOGUE_AMERICAN = "american" // comma inside the closing quote: "Wait," she said
+	DIALOGUE_LOGICAL  = "logical"  // comma outside the closing quote: "Wait", she said
+)
+
+// Digit grouping styles of decimal conversion results, named after the locales using them
+const (
+	GROUPING_NONE = "none" // 1048576
+	GROUPING_EN   = "en"   // 1,048,576
+	GROUPING_DE   = "de"   // 1.048.576
+	GROUPING_FR   = "fr"   // 1 048 576 with narrow no-break spaces
+	GROUPING_CH   = "ch"   // 1'048'576
+	GROUPING_IN   = "in"   // 10,48,576: thousands, then groups of two
+)
+
+// Articles an exception can require
+const (
+	ARTICLE_A  = "a"
+	ARTICLE_AN = "an"
+)
+
+// words the vowel/h rule gets wrong because of how they are pronounced
+var defaultArticleExceptions = map[string]string{
+	// vowel letter, consonant sound
+	"one": ARTICLE_A, "once": ARTICLE_A, "one-time": ARTICLE_A, "one-way": ARTICLE_A,
+	"euro": ARTICLE_A, "euros": ARTICLE_A, "european": ARTICLE_A, "eulogy": ARTICLE_A, "eucalyptus": ARTICLE_A, "ewe": ARTICLE_A,
+	"unicorn": ARTICLE_A, "uniform": ARTICLE_A, "union": ARTICLE_A, "unique": ARTICLE_A, "unit": ARTICLE_A, "united": ARTICLE_A,
+	"universal": ARTICLE_A, "universe": ARTICLE_A, "university": ARTICLE_A, "unanimous": ARTICLE_A,
+	"use": ARTICLE_A, "used": ARTICLE_A, "useful": ARTICLE_A, "user": ARTICLE_A, "usual": ARTICLE_A,
+	"utensil": ARTICLE_A, "utility": ARTICLE_A, "utopia": ARTICLE_A, "ukulele": ARTICLE_A, "urinal": ARTICLE_A,
+	// silent h
+	"heir": ARTICLE_AN, "heiress": ARTICLE_AN, "heirloom": ARTICLE_AN,
+	"honest": ARTICLE_AN, "honesty": ARTICLE_AN, "honor": ARTICLE_AN, "honour": ARTICLE_AN,
+	"honorable": ARTICLE_AN, "honourable": ARTICLE_AN, "honorary": ARTICLE_AN,
+	"hour": ARTICLE_AN, "hours": ARTICLE_AN, "hourly": ARTICLE_AN,
+	// initialisms spelled letter by letter
+	"fbi": ARTICLE_AN, "mba": ARTICLE_AN, "mri": ARTICLE_AN, "sos": ARTICLE_AN, "x-ray": ARTICLE_AN,
+}
+
+// DefaultArticleExceptions returns a copy of the built-in article exceptions:
+// "a university", "a one", "an hour", "an FBI agent"
+func DefaultArticleExceptions() map[string]string {
+	exceptions := make(map[string]string, len(defaultArticleExceptions))
+	for word, article := range defaultArticleExceptions {
+		exceptions[word] = article
+	}
+	return exceptions
+}
+
+// AddArticleExceptions returns a copy of base where every space separated word takes the
+// given article. An article of "" removes the words from the exceptions instead.
+func AddArticleExceptions(base map[string]string, article string, words string) (map[string]string, error) {
+	if article != "" && article != ARTICLE_A && article != ARTICLE_AN {
+		return nil, fmt.Errorf("invalid article %q, expected a or an", article)
+	}
+
+	exceptions := make(map[string]string, len(base))
+	for word, a := range base {
+		exceptions[word] = a
+	}
+	for _, word := range strings.Fields(words) {
+		word = strings.ToLower(word)
+		if article == "" {
+			delete(exceptions, word)
+		} else {
+			exceptions[word] = article
+		}
+	}
+	return exceptions, nil
+}
+
+// Punctuation spacing rules
+const (
+	ATTACH_LEFT   = iota // glued to the previous word, space after: "word, next" (classic)
+	ATTACH_RIGHT         // space before, glued to the next word: "word ¿next"
+	ATTACH_BOTH          // glued on both sides: "word—next"
+	ATTACH_SPACED        // spaced on both sides: "word ; next" (French high punctuation)
+)
+
+// DOUBLE_HYPHEN is the punctuation rune whose rule spaces runs of two or more hyphens, the
+// typewriter dash "--". A single hyphen always belongs to its word: "well-known".
+const DOUBLE_HYPHEN = '-'
+
+// names used by ParsePunctuation and the help system
+var attachNames = map[string]int{
+	"left":   ATTACH_LEFT,
+	"right":  ATTACH_RIGHT,
+	"both":   ATTACH_BOTH,
+	"spaced": ATTACH_SPACED,
+}
+
+// the classic go-reloaded punctuation set and the dashes
+var defaultPunctuation = map[rune]int{
+	',': ATTACH_LEFT, '.': ATTACH_LEFT, '!': ATTACH_LEFT,
+	'?': ATTACH_LEFT, ';': ATTACH_LEFT, ':': ATTACH_LEFT,
+	'—': ATTACH_BOTH, '–': ATTACH_SPACED, DOUBLE_HYPHEN: ATTACH_BOTH,
+}
+
+// DefaultPunctuation returns a copy of the classic punctuation set: , . ! ? ; : attached left,
+// em dashes and "--" glued on both sides and en dashes spaced on both sides
+func DefaultPunctuation() map[rune]int {
+	rules := make(map[rune]int, len(defaultPunctuation))
+	for r, attach := range defaultPunctuation {
+		rules[r] = attach
+	}
+	return rules
+}
+
+// FrenchPunctuation returns the default set with spaces before and after ? ! ; :
+func FrenchPunctuation() map[rune]int {
+	rules := DefaultPunctuation()
+	for _, r := range "?!;:" {
+		rules[r] = ATTACH_SPACED
+	}
+	return rules
+}
+
+// LanguagePunctuation returns the punctuation preset of a language: "en" (classic) or "fr"
+func LanguagePunctuation(lang string) (map[rune]int, error) {
+	switch lang {
+	case "en":
+		return DefaultPunctuation(), nil
+	case "fr":
+		return FrenchPunctuation(), nil
+	}
+	return nil, fmt.Errorf("unsupported language %q, expected en or fr", lang)
+}
+
+// LanguageGrouping returns the digit grouping style of a language: "en" or "fr"
+func LanguageGrouping(lang string) (string, error) {
+	switch lang {
+	case "en":
+		return GROUPING_EN, nil
+	case "fr":
+		return GROUPING_FR, nil
+	}
+	return "", fmt.Errorf("unsupported language %q, expected en or fr", lang)
+}
+
+// ParsePunctuation applies a space separated list of rune=mode rules on top of base,
+// e.g. "?=spaced —=both ¿=right". Mode "off" removes the rune from the set. "--" stands for
+// DOUBLE_HYPHEN: "--=spaced".
+func ParsePunctuation(spec string, base map[rune]int) (map[rune]int, error) {
+	rules := make(map[rune]int, len(base))
+	for r, attach := range base {
+		rules[r] = attach
+	}
+
+	for _, item := range strings.Fields(spec) {
+		runeStr, mode, found := strings.Cut(item, "=")
+		if runeStr == "--" {
+			runeStr = string(DOUBLE_HYPHEN)
+		}
+		if !found || utf8.RuneCountInString(runeStr) != 1 {
+			return nil, fmt.Errorf("invalid punctuation rule %q, expected <rune>=<mode>", item)
+		}
+		r, _ := utf8.DecodeRuneInString(runeStr)
+		if err := validatePunctuationRune(r); err != nil {
+			return nil, err
+		}
+
+		if mode == "off" {
+			delete(rules, r)
+			continue
+		}
+		attach, ok := attachNames[mode]
+		if !ok {
+			return nil, fmt.Errorf("invalid punctuation mode %q in %q, expected left, right, both, spaced or off", mode, item)
+		}
+		rules[r] = attach
+	}
+	return rules, nil
+}
+
+// ParseCommandAliases adds a space separated list of alias=command pairs to base,
+// e.g. "uppercase=up caps=cap". Command "off" removes the alias.
+func ParseCommandAliases(spec string, base map[string]string) (map[string]string, error) {
+	aliases := make(map[string]string, len(base))
+	for alias, command := range base {
+		aliases[alias] = command
+	}
+
+	for _, item := range strings.Fields(spec) {
+		alias, command, found := strings.Cut(item, "=")
+		if !found || command == "" {
+			return nil, fmt.Errorf("invalid command alias %q, expected <alias>=<command>", item)
+		}
+		alias, command = strings.ToLower(alias), strings.ToLower(command)
+		if err := validateAliasName(alias); err != nil {
+			return nil, err
+		}
+		if command == "off" {
+			delete(aliases, alias)
+			continue
+		}
+		aliases[alias] = command
+	}
+	return aliases, nil
+}
+
+// ParseVariable reads a name=value definition of the Variables
+func ParseVariable(definition string) (name, value string, err error) {
+	name, value, found := strings.Cut(definition, "=")
+	if !found || !validVariableName(name) {
+		return "", "", fmt.Errorf("invalid variable %q, expected <name>=<value>", definition)
+	}
+	return name, value, nil
+}
+
+// names of variables start with a letter or _ and go on with letters, digits, _, . or -
+func validVariableName(name string) bool {
+	for i, r := range name {
+		switch {
+		case r == '_' || unicode.IsLetter(r):
+		case i > 0 && (unicode.IsDigit(r) || r == '.' || r == '-'):
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// rejects alias names that would not read as a command name, they are lowercase letters
+func validateAliasName(alias string) error {
+	if alias == "" {
+		return fmt.Errorf("command aliases need a name")
+	}
+	for _, r := range alias {
+		if !unicode.IsLetter(r) || unicode.ToLower(r) != r {
+			return fmt.Errorf("command alias %q must be a lowercase word", alias)
+		}
+	}
+	return nil
+}
+
+// rejects runes the tokenizer already gives another meaning
+func validatePunctuationRune(r rune) error {
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || strings.ContainsRune("()'\"\\", r) {
+		return fmt.Errorf("%q cannot be used as punctuation", r)
+	}
+	return nil
+}
@@ -1,6 +1,10 @@
 package config
 
-import "testing"
+import (
+	"io"
+	"testing"
+	"time"
+)
 
 // Purpose: Tests constants during development/CI
 
@@ -27,3 +31,304 @@ func TestValidateConstants(t *testing.T) {
 		t.Errorf("ValidateConstants should not return error with current constants: %v", err)
 	}
 }
+
+func TestDefaultOptionsValid(t *testing.T) {
+	if err := DefaultOptions().Validate(); err != nil {
+		t.Errorf("DefaultOptions should be valid: %v", err)
+	}
+}
+
+func TestValidateConflictingQuotes(t *testing.T) {
+	opts := DefaultOptions()
+	opts.SmartQuotes = true
+	opts.ASCIIQuotes = true
+	if err := opts.Validate(); err == nil {
+		t.Errorf("Validate should reject smart and ASCII quotes together")
+	}
+}
+
+func TestParsePunctuation(t *testing.T) {
+	rules, err := ParsePunctuation("?=spaced —=both ,=off", DefaultPunctuation())
+	if err != nil {
+		t.Fatalf("ParsePunctuation failed: %v", err)
+	}
+
+	if rules['?'] != ATTACH_SPACED {
+		t.Errorf("Expected ? to be spaced, got %d", rules['?'])
+	}
+	if rules['—'] != ATTACH_BOTH {
+		t.Errorf("Expected — to attach both sides, got %d", rules['—'])
+	}
+	if _, ok := rules[',']; ok {
+		t.Errorf("Expected , to be removed")
+	}
+	if rules['.'] != ATTACH_LEFT {
+		t.Errorf("Expected . to keep the default rule")
+	}
+
+	rules, err = ParsePunctuation("--=spaced –=off", DefaultPunctuation())
+	if err != nil {
+		t.Fatalf("ParsePunctuation failed: %v", err)
+	}
+	if rules[DOUBLE_HYPHEN] != ATTACH_SPACED {
+		t.Errorf("Expected -- to be spaced, got %d", rules[DOUBLE_HYPHEN])
+	}
+	if _, ok := rules['–']; ok {
+		t.Errorf("Expected – to be removed")
+	}
+}
+
+func TestParsePunctuationInvalid(t *testing.T) {
+	for _, spec := range []string{"?", "?=sideways", "a=left", "(=left", "ab=left"} {
+		if _, err := ParsePunctuation(spec, nil); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+}
+
+func TestAddArticleExceptions(t *testing.T) {
+	base := map[string]string{"hour": ARTICLE_AN}
+	exceptions, err := AddArticleExceptions(base, ARTICLE_A, "University ONE")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exceptions["university"] != ARTICLE_A || exceptions["one"] != ARTICLE_A || exceptions["hour"] != ARTICLE_AN {
+		t.Errorf("Unexpected exceptions: %v", exceptions)
+	}
+	if len(base) != 1 {
+		t.Errorf("Base map was modified: %v", base)
+	}
+
+	exceptions, _ = AddArticleExceptions(exceptions, "", "hour")
+	if _, ok := exceptions["hour"]; ok {
+		t.Error("Expected hour to be removed")
+	}
+
+	if _, err := AddArticleExceptions(nil, "the", "x"); err == nil {
+		t.Error("Expected error for invalid article")
+	}
+
+	opts := DefaultOptions()
+	opts.ArticleExceptions = map[string]string{"Hour": ARTICLE_AN}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for non-lowercase exception")
+	}
+}
+
+func TestParseCommandAliases(t *testing.T) {
+	base := map[string]string{"caps": "cap"}
+	aliases, err := ParseCommandAliases("Uppercase=UP shout=up", base)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if aliases["uppercase"] != "up" || aliases["shout"] != "up" || aliases["caps"] != "cap" {
+		t.Errorf("Unexpected aliases: %v", aliases)
+	}
+	if len(base) != 1 {
+		t.Errorf("Base map was modified: %v", base)
+	}
+
+	aliases, _ = ParseCommandAliases("shout=off", aliases)
+	if _, ok := aliases["shout"]; ok {
+		t.Error("Expected shout to be removed")
+	}
+
+	for _, spec := range []string{"uppercase", "uppercase=", "=up", "upper-case=up", "up2=up"} {
+		if _, err := ParseCommandAliases(spec, nil); err == nil {
+			t.Errorf("Expected error for %q", spec)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.CommandAliases = map[string]string{"Shout": "up"}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for non-lowercase alias")
+	}
+	opts.CommandAliases = map[string]string{"shout": ""}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for an alias of no command")
+	}
+}
+
+func TestParseVariable(t *testing.T) {
+	if name, value, err := ParseVariable("team.lead=Grace = Hopper"); err != nil || name != "team.lead" || value != "Grace = Hopper" {
+		t.Errorf("Unexpected variable %q = %q, %v", name, value, err)
+	}
+	for _, definition := range []string{"name", "=value", "1st=x", "a b=c"} {
+		if _, _, err := ParseVariable(definition); err == nil {
+			t.Errorf("Expected error for %q", definition)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Variables = map[string]string{"address": "1 Main St\nSpringfield"}
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for a value spanning lines")
+	}
+}
+
+func TestArticleRules(t *testing.T) {
+	opts := DefaultOptions()
+	rules := opts.ArticleRules()
+	if rules["university"] != ARTICLE_A || rules["hour"] != ARTICLE_AN || rules["fbi"] != ARTICLE_AN {
+		t.Errorf("Unexpected built-in exceptions: %v", rules)
+	}
+
+	// Every built-in entry passes validation
+	opts.ArticleExceptions = DefaultArticleExceptions()
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Built-in exceptions do not validate: %v", err)
+	}
+
+	// A copy can be changed without touching the built-in list
+	delete(opts.ArticleExceptions, "one")
+	if DefaultOptions().ArticleRules()["one"] != ARTICLE_A {
+		t.Error("Built-in exceptions were modified through a copy")
+	}
+	if _, ok := opts.ArticleRules()["one"]; ok {
+		t.Error("Configured exceptions should replace the built-in ones")
+	}
+}
+
+func TestValidateLineEnding(t *testing.T) {
+	opts := DefaultOptions()
+	opts.LineEnding = "cr"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for unknown line ending")
+	}
+}
+
+func TestValidateUnappliedCommandPolicy(t *testing.T) {
+	opts := DefaultOptions()
+	opts.UnappliedCommandPolicy = "ignore"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for unknown unapplied command policy")
+	}
+}
+
+func TestValidateShortCountPolicy(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ShortCountPolicy = "error"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for unknown short count policy")
+	}
+}
+
+func TestValidateCommandScope(t *testing.T) {
+	opts := DefaultOptions()
+	for _, scope := range []string{"", SCOPE_SENTENCE, SCOPE_PARAGRAPH} {
+		opts.CommandScope = scope
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Unexpected error for scope %q: %v", scope, err)
+		}
+	}
+	opts.CommandScope = "line"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for unknown command scope")
+	}
+}
+
+func TestValidateDigitGrouping(t *testing.T) {
+	opts := DefaultOptions()
+	opts.DigitGrouping = "us"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for unknown digit grouping")
+	}
+	for _, lang := range []string{"en", "fr"} {
+		opts.DigitGrouping, _ = LanguageGrouping(lang)
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Grouping of %s rejected: %v", lang, err)
+		}
+	}
+	if _, err := LanguageGrouping("xx"); err == nil {
+		t.Error("Expected error for unknown language")
+	}
+}
+
+func TestValidateRawMarkers(t *testing.T) {
+	tests := []struct {
+		start, end string
+		valid      bool
+	}{
+		{"", "", true},
+		{"<<", ">>", true},
+		{"(raw)", "", false},
+		{"||", "||", false},
+		{"(raw\n)", "(endraw)", false},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.RawStart, opts.RawEnd = test.start, test.end
+		if err := opts.Validate(); (err == nil) != test.valid {
+			t.Errorf("Validate(%q, %q) = %v, expected valid %v", test.start, test.end, err, test.valid)
+		}
+	}
+}
+
+func TestCompressionOf(t *testing.T) {
+	tests := []struct {
+		compress, path, expected string
+	}{
+		{COMPRESS_AUTO, "notes.txt.gz", COMPRESS_GZIP},
+		{"", "NOTES.GZ", COMPRESS_GZIP},
+		{COMPRESS_AUTO, "notes.zst", COMPRESS_ZSTD},
+		{COMPRESS_AUTO, "notes.txt", COMPRESS_NONE},
+		{COMPRESS_GZIP, "notes.txt", COMPRESS_GZIP},
+		{COMPRESS_NONE, "notes.gz", COMPRESS_NONE},
+	}
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.Compress = test.compress
+		if codec := opts.CompressionOf(test.path); codec != test.expected {
+			t.Errorf("CompressionOf(%q) with %q = %q, expected %q", test.path, test.compress, codec, test.expected)
+		}
+	}
+
+	opts := DefaultOptions()
+	opts.Compress = COMPRESS_ZSTD
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Unexpected error for zstd: %v", err)
+	}
+	opts.Compress = "lz4"
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for compression \"lz4\"")
+	}
+}
+
+func TestValidateMaxMemory(t *testing.T) {
+	opts := DefaultOptions()
+	for _, limit := range []int64{-1, MIN_MAX_MEMORY - 1} {
+		opts.MaxMemory = limit
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Expected error for memory limit %d", limit)
+		}
+	}
+	opts.MaxMemory = MIN_MAX_MEMORY
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Minimum memory limit rejected: %v", err)
+	}
+}
+
+func TestValidateFetchLimits(t *testing.T) {
+	opts := DefaultOptions()
+	opts.FetchTimeout = -time.Second
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for a negative fetch timeout")
+	}
+	opts.FetchTimeout, opts.MaxFetchBytes = 0, -1
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for a negative fetch size limit")
+	}
+}
+
+func TestValidateTrace(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Trace = io.Discard
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Trace rejected: %v", err)
+	}
+	opts.Resume = true
+	if err := opts.Validate(); err == nil {
+		t.Error("Expected error for a trace of a resumed run")
+	}
+}
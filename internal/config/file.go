@@ -0,0 +1,58 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Setting is one "key = value" line of a config file; keys are the CLI flag names without dashes
+type Setting struct {
+	Key   string
+	Value string
+	Line  int
+}
+
+// ReadSettings parses a config file: one "key = value" per line, blank lines and
+// lines starting with '#' are ignored. A bare key is shorthand for "key = true".
+func ReadSettings(r io.Reader) ([]Setting, error) {
+	var settings []Setting
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			value = "true"
+		}
+		key = strings.TrimSpace(key)
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected key = value, got %q", lineNum, line)
+		}
+		settings = append(settings, Setting{Key: key, Value: strings.TrimSpace(value), Line: lineNum})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return settings, nil
+}
+
+// LoadSettings reads the settings of a config file from disk
+func LoadSettings(path string) ([]Setting, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer file.Close()
+
+	settings, err := ReadSettings(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return settings, nil
+}
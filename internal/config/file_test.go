@@ -0,0 +1,42 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadSettings(t *testing.T) {
+	input := "# comment\n\nupper-hex\narticle-an = hour  honest \n punctuation = —=both\n"
+	settings, err := ReadSettings(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Setting{
+		{"upper-hex", "true", 3},
+		{"article-an", "hour  honest", 4},
+		{"punctuation", "—=both", 5},
+	}
+	if len(settings) != len(expected) {
+		t.Fatalf("Expected %d settings, got %v", len(expected), settings)
+	}
+	for i, setting := range settings {
+		if setting != expected[i] {
+			t.Errorf("Setting %d: expected %+v, got %+v", i, expected[i], setting)
+		}
+	}
+}
+
+func TestReadSettingsInvalid(t *testing.T) {
+	for _, input := range []string{"= value", "two words = x"} {
+		if _, err := ReadSettings(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadSettings(%q): expected error", input)
+		}
+	}
+}
+
+func TestLoadSettingsMissingFile(t *testing.T) {
+	if _, err := LoadSettings("/nonexistent/go-reloaded.conf"); err == nil {
+		t.Error("Expected error for missing config file")
+	}
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Stages a substitution runs in
+const (
+	SUBSTITUTE_BEFORE = "before" // on the input, before the commands
+	SUBSTITUTE_AFTER  = "after"  // on the output, after every built-in rule
+)
+
+// Substitution is a find/replace rule of Options.Substitutions. It runs on one line at a
+// time, so a pattern never matches a line break.
+type Substitution struct {
+	Name        string // names the rule in errors
+	Pattern     string // regular expression in the syntax of package regexp
+	Replacement string // replaces every match, $1 and ${name} expand to submatches
+	Stage       string // SUBSTITUTE_* stage, "" behaves like SUBSTITUTE_AFTER
+	Disabled    bool   // keep the rule without running it
+}
+
+// compiled patterns by their source, Options are copied for every call
+var patterns sync.Map
+
+// Regexp returns the compiled pattern of the substitution
+func (s Substitution) Regexp() (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(s.Pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(s.Pattern)
+	if err != nil {
+		return nil, err
+	}
+	patterns.Store(s.Pattern, re)
+	return re, nil
+}
+
+// validateSubstitution reports a substitution that cannot run
+func validateSubstitution(s Substitution) error {
+	if s.Pattern == "" {
+		return fmt.Errorf("substitution %q has no pattern", s.Name)
+	}
+	if _, err := s.Regexp(); err != nil {
+		return fmt.Errorf("substitution %q: %w", s.Name, err)
+	}
+	switch s.Stage {
+	case "", SUBSTITUTE_BEFORE, SUBSTITUTE_AFTER:
+	default:
+		return fmt.Errorf("invalid stage %q of substitution %q, expected before or after", s.Stage, s.Name)
+	}
+	return nil
+}
+
+// ReadSubstitutions parses a substitutions file. Every rule starts with its name in
+// brackets and sets find, and optionally replace, stage and enabled:
+//
+//	[product names]
+//	find = \bgolang\b
+//	replace = Go
+//	stage = after
+//
+// Blank lines and lines starting with '#' are ignored, a value in double quotes is read
+// like a Go string so it can keep spaces at its ends. Rules run in the order of the file.
+func ReadSubstitutions(r io.Reader) ([]Substitution, error) {
+	var substitutions []Substitution
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			substitutions = append(substitutions, Substitution{Name: strings.TrimSpace(line[1 : len(line)-1])})
+			continue
+		}
+
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("line %d: expected [name] or key = value, got %q", lineNum, line)
+		}
+		if len(substitutions) == 0 {
+			return nil, fmt.Errorf("line %d: %q comes before the first [name]", lineNum, line)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", lineNum, value)
+			}
+			value = unquoted
+		}
+
+		s := &substitutions[len(substitutions)-1]
+		switch key {
+		case "find":
+			s.Pattern = value
+		case "replace":
+			s.Replacement = value
+		case "stage":
+			s.Stage = value
+		case "enabled":
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: enabled must be true or false, got %q", lineNum, value)
+			}
+			s.Disabled = !enabled
+		default:
+			return nil, fmt.Errorf("line %d: unknown key %q, expected find, replace, stage or enabled", lineNum, key)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read substitutions: %w", err)
+	}
+	for _, s := range substitutions {
+		if err := validateSubstitution(s); err != nil {
+			return nil, err
+		}
+	}
+	return substitutions, nil
+}
+
+// LoadSubstitutions reads the substitutions of a file from disk
+func LoadSubstitutions(path string) ([]Substitution, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open substitutions file: %w", err)
+	}
+	defer file.Close()
+
+	substitutions, err := ReadSubstitutions(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return substitutions, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadSubstitutions(t *testing.T) {
+	input := "# names\n[product names]\nfind = \\bgolang\\b\nreplace = Go\n\n[trademark]\nfind = Acme\nreplace = \" Acme™ \"\nstage = before\nenabled = false\n"
+	substitutions, err := ReadSubstitutions(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []Substitution{
+		{Name: "product names", Pattern: `\bgolang\b`, Replacement: "Go"},
+		{Name: "trademark", Pattern: "Acme", Replacement: " Acme™ ", Stage: SUBSTITUTE_BEFORE, Disabled: true},
+	}
+	if len(substitutions) != len(expected) {
+		t.Fatalf("Expected %d substitutions, got %v", len(expected), substitutions)
+	}
+	for i, substitution := range substitutions {
+		if substitution != expected[i] {
+			t.Errorf("Substitution %d: expected %+v, got %+v", i, expected[i], substitution)
+		}
+	}
+}
+
+func TestReadSubstitutionsInvalid(t *testing.T) {
+	for _, input := range []string{
+		"find = x",
+		"[a]\nfind",
+		"[a]\nreplace = y",
+		"[a]\nfind = (",
+		"[a]\nfind = x\nstage = during",
+		"[a]\nfind = x\nenabled = maybe",
+		"[a]\nfind = \"x",
+		"[a]\nfind = x\nflags = i",
+	} {
+		if _, err := ReadSubstitutions(strings.NewReader(input)); err == nil {
+			t.Errorf("ReadSubstitutions(%q): expected error", input)
+		}
+	}
+}
+
+func TestValidateSubstitutions(t *testing.T) {
+	opts := DefaultOptions()
+	opts.Substitutions = []Substitution{{Name: "ok", Pattern: "a+"}}
+	if err := opts.Validate(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	opts.Substitutions = append(opts.Substitutions, Substitution{Name: "broken", Pattern: "[a"})
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), `"broken"`) {
+		t.Errorf("Expected an error naming the broken substitution, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/exporter"
+	"github.com/GiannisPettas/go-reloaded/internal/marker"
+	"os"
+	"time"
+)
+
+const (
+	CHECKPOINT_BYTES   = 16 << 20      // input read between two checkpoints of a chunked run
+	CHECKPOINT_SUFFIX  = ".checkpoint" // added to the output path to name its checkpoint
+	CHECKPOINT_VERSION = 2             // bumped when chunkState changes
+)
+
+// How much input is read between checkpoints, tests lower it
+var checkpointEvery int64 = CHECKPOINT_BYTES
+
+// How a resumed run opens its output, tests swap it like createSink
+var resumeSink = exporter.ResumeSink
+
+// CheckpointPath names the checkpoint of a run writing outputPath
+func CheckpointPath(outputPath string) string {
+	return outputPath + CHECKPOINT_SUFFIX
+}
+
+// chunkState is everything a chunked run carries from one chunk to the next. A run started
+// from a saved state writes the same output as the run that saved it.
+type chunkState struct {
+	Offset       int64          `json:"offset"`        // input bytes read
+	OutputOffset int64          `json:"output_offset"` // output bytes written
+	Chunk        int            `json:"chunk"`
+	Carry        []byte         `json:"carry"`         // text read but not transformed yet, it starts at the beginning of a line
+	CarriedWords int            `json:"carried_words"` // words in Carry
+	RetryAt      int            `json:"retry_at"`      // carry length at which a cut is tried again after a failed one
+	LineBase     int            `json:"line_base"`     // lines of the file before Carry
+	Words        int64          `json:"words"`         // words of the file before Carry
+	Scope        int64          `json:"scope"`         // of them, the words before the last boundary of the command scope
+	WarnedLines  int            `json:"warned_lines"`  // lines whose warnings were reported, they are not reported again after a restart
+	Warnings     int            `json:"warnings"`      // warnings reported, set when the state is saved
+	Clamped      int            `json:"clamped"`       // of them, short counts, set when the state is saved
+	Applied      map[string]int `json:"applied"`       // words changed per command name, set when the state is saved
+	Started      bool           `json:"started"`       // output was written, the BOM is not written again
+	BOM          string         `json:"bom"`           // written before the first piece
+	Encoding     string         `json:"encoding"`      // resolved from the first chunk
+}
+
+// checkpoint is the file a chunked run saves its state to. It names the input and options
+// it was saved for, so a run is never resumed for another input.
+type checkpoint struct {
+	path          string
+	Version       int        `json:"version"`
+	InputSize     int64      `json:"input_size"`
+	InputModified time.Time  `json:"input_modified"`
+	Options       string     `json:"options"` // marker.Fingerprint of the options
+	State         chunkState `json:"state"`
+}
+
+// newCheckpoint describes the checkpoint of a run from inputPath to outputPath with opts
+func newCheckpoint(inputPath, outputPath string, opts config.Options) (*checkpoint, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, inputError(fmt.Errorf("failed to get file info: %w", err))
+	}
+	return &checkpoint{
+		path:          CheckpointPath(outputPath),
+		Version:       CHECKPOINT_VERSION,
+		InputSize:     info.Size(),
+		InputModified: info.ModTime(),
+		Options:       marker.Fingerprint(opts),
+	}, nil
+}
+
+// load returns the saved state, false when there is no checkpoint. A checkpoint saved for
+// another input, other options or another version is an error rather than a fresh start,
+// which would truncate the output.
+func (c *checkpoint) load() (chunkState, bool, error) {
+	data, err := os.ReadFile(c.path)
+	if os.IsNotExist(err) {
+		return chunkState{}, false, nil
+	}
+	if err != nil {
+		return chunkState{}, false, inputError(fmt.Errorf("failed to read checkpoint: %w", err))
+	}
+	var saved checkpoint
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return chunkState{}, false, inputError(fmt.Errorf("failed to read checkpoint %s: %w", c.path, err))
+	}
+	if saved.Version != c.Version || saved.InputSize != c.InputSize || !saved.InputModified.Equal(c.InputModified) || saved.Options != c.Options {
+		return chunkState{}, false, inputError(fmt.Errorf("checkpoint %s was saved for another input or other options, remove it to start over", c.path))
+	}
+	return saved.State, true, nil
+}
+
+// save replaces the checkpoint with state once the output written so far is on disk
+func (c *checkpoint) save(sink exporter.Sink, state chunkState) error {
+	if syncer, ok := sink.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			return outputError(fmt.Errorf("failed to save checkpoint: %w", err))
+		}
+	}
+	c.State = state
+	data, err := json.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+	// The old checkpoint stays valid until the new one replaces it
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return outputError(fmt.Errorf("failed to save checkpoint: %w", err))
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		os.Remove(tmp)
+		return outputError(fmt.Errorf("failed to save checkpoint: %w", err))
+	}
+	return nil
+}
+
+// remove deletes the checkpoint of a finished or rewritten run
+func (c *checkpoint) remove() error {
+	if err := os.Remove(c.path); err != nil && !os.IsNotExist(err) {
+		return outputError(fmt.Errorf("failed to remove checkpoint: %w", err))
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProcessFileResume(t *testing.T) {
+	checkpointEvery = 16 * 1024
+	t.Cleanup(func() { checkpointEvery = CHECKPOINT_BYTES })
+
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	var text strings.Builder
+	for i := 0; i < 3000; i++ {
+		text.WriteString("it was a apple (up) here , ' she said ' \n")
+		if i%100 == 0 {
+			text.WriteString("zz (hex) left in\n")
+		}
+	}
+	if err := os.WriteFile(input, []byte(text.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	expected := filepath.Join(dir, "expected.txt")
+	strict := config.DefaultOptions()
+	strict.Strict = true
+	plain, plainErr := ProcessFileResult(input, expected, strict)
+	if !errors.Is(plainErr, ErrStrict) {
+		t.Fatalf("Expected the plain run to fail strict, got %v", plainErr)
+	}
+
+	// The first run is interrupted by a failing read after a few checkpoints
+	output := filepath.Join(dir, "output.txt")
+	opts := strict
+	opts.Checkpoint = true
+	injectFaults(t, 30, -1, false)
+	if err := ProcessFileWithOptions(input, output, opts); !errors.Is(err, testutils.ErrInjected) {
+		t.Fatalf("Expected the injected read failure, got %v", err)
+	}
+	if _, err := os.Stat(CheckpointPath(output)); err != nil {
+		t.Fatalf("Expected a checkpoint after the interrupted run: %v", err)
+	}
+
+	injectFaults(t, -1, -1, false)
+	opts.Resume = true
+	var warnings bytes.Buffer
+	opts.Warnings = &warnings
+	resumed, err := ProcessFileResult(input, output, opts)
+	if err == nil || err.Error() != plainErr.Error() {
+		t.Errorf("Expected the resumed run to count all warnings as %q, got %v", plainErr, err)
+	}
+	if resumed.Applied["up"] != 3000 || !maps.Equal(resumed.Applied, plain.Applied) {
+		t.Errorf("Expected the resumed run to count the words changed before the checkpoint, got %v", resumed.Applied)
+	}
+	if first := strings.SplitN(warnings.String(), ":", 3); len(first) < 2 || first[1] == "2" {
+		t.Errorf("The resumed run should not report the first warnings again, got %q", warnings.String())
+	}
+	got, _ := os.ReadFile(output)
+	want, _ := os.ReadFile(expected)
+	if !bytes.Equal(got, want) {
+		t.Errorf("Resumed output differs from the plain run, %d bytes instead of %d", len(got), len(want))
+	}
+	if _, err := os.Stat(CheckpointPath(output)); !os.IsNotExist(err) {
+		t.Errorf("The checkpoint should be removed after the run, got %v", err)
+	}
+
+	// Without a checkpoint a resumed run starts over
+	if err := ProcessFileWithOptions(input, output, opts); !errors.Is(err, ErrStrict) {
+		t.Errorf("Expected a fresh run, got %v", err)
+	}
+	if got, _ := os.ReadFile(output); !bytes.Equal(got, want) {
+		t.Error("Fresh resumed run differs from the plain run")
+	}
+}
+
+func TestProcessFileResumeMismatch(t *testing.T) {
+	checkpointEvery = 8 * 1024
+	t.Cleanup(func() { checkpointEvery = CHECKPOINT_BYTES })
+
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "input.txt"), filepath.Join(dir, "output.txt")
+	if err := os.WriteFile(input, []byte(strings.Repeat("a apple (up) here\n", 3000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := config.DefaultOptions()
+	opts.Checkpoint = true
+	injectFaults(t, 10, -1, false)
+	if err := ProcessFileWithOptions(input, output, opts); !errors.Is(err, testutils.ErrInjected) {
+		t.Fatalf("Expected the injected read failure, got %v", err)
+	}
+	injectFaults(t, -1, -1, false)
+
+	opts.Resume = true
+	other := opts
+	other.SkipArticles = true
+	if err := ProcessFileWithOptions(input, output, other); !errors.Is(err, ErrInput) || !strings.Contains(err.Error(), "checkpoint") {
+		t.Errorf("Expected a checkpoint error for other options, got %v", err)
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(input, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessFileWithOptions(input, output, opts); !errors.Is(err, ErrInput) {
+		t.Errorf("Expected a checkpoint error for a changed input, got %v", err)
+	}
+
+	if err := ProcessFileWithOptions(input, filepath.Join(dir, "output.gz"), opts); err == nil {
+		t.Error("Expected error for a checkpoint of a compressed output")
+	}
+}
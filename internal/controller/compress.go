@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/exporter"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"time"
+)
+
+// openInput opens the content of inputPath, decompressed as it is read when codec is not
+// COMPRESS_NONE. A URL is streamed, its body is read as the chunks are.
+func openInput(inputPath, codec string, opts config.Options) (parser.Source, error) {
+	switch {
+	case parser.IsURL(inputPath):
+		return parser.OpenURLStream(inputPath, codec, fetchTimeout(opts), opts.MaxFetchBytes)
+	case codec == config.COMPRESS_NONE:
+		return openSource(inputPath)
+	}
+	return parser.OpenStream(inputPath, codec)
+}
+
+// fetchTimeout is the time a URL input may take, config.FETCH_TIMEOUT for 0
+func fetchTimeout(opts config.Options) time.Duration {
+	if opts.FetchTimeout == 0 {
+		return config.FETCH_TIMEOUT
+	}
+	return opts.FetchTimeout
+}
+
+// createOutput creates the output file at path, compressed with codec
+func createOutput(path, codec string) (exporter.Sink, error) {
+	sink, err := createSink(path)
+	if err != nil {
+		return nil, err
+	}
+	return exporter.CompressSink(sink, codec)
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessFileGzip(t *testing.T) {
+	dir := t.TempDir()
+	small := "it was a apple (up) .\n"
+	large := strings.Repeat("He said : ' this is incredible (cap, 2) ! ' \n", 2*config.CHUNK_BYTES/40)
+	explicit := config.DefaultOptions()
+	explicit.Compress = config.COMPRESS_GZIP
+	explicitZstd := config.DefaultOptions()
+	explicitZstd.Compress = config.COMPRESS_ZSTD
+	verify := config.DefaultOptions()
+	verify.Verify = true
+
+	tests := []struct {
+		name          string
+		input, output string
+		opts          config.Options
+		text          string
+	}{
+		{"gz to gz", "in.txt.gz", "out.txt.gz", config.DefaultOptions(), small},
+		{"chunked gz to gz", "in.txt.gz", "out.txt.gz", config.DefaultOptions(), large},
+		{"gz to plain", "in.gz", "out.txt", config.DefaultOptions(), large},
+		{"explicit gzip", "in.txt", "out.txt", explicit, small},
+		{"verified gz", "in.txt.gz", "out.txt.gz", verify, small},
+		{"zst to zst", "in.txt.zst", "out.txt.zst", config.DefaultOptions(), small},
+		{"chunked zst to gz", "in.txt.zst", "out.txt.gz", config.DefaultOptions(), large},
+		{"explicit zstd", "in.txt", "out.txt", explicitZstd, large},
+		{"verified zst", "in.txt.zst", "out.txt.zst", verify, large},
+	}
+	for _, test := range tests {
+		input, output := filepath.Join(dir, test.input), filepath.Join(dir, test.output)
+		compressed := gzipped(t, test.text)
+		if test.opts.CompressionOf(input) == config.COMPRESS_ZSTD {
+			compressed = zstded(t, test.text)
+		}
+		if err := os.WriteFile(input, compressed, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ProcessFileWithOptions(input, output, test.opts); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+
+		plain := filepath.Join(dir, "plain.txt")
+		if err := os.WriteFile(plain, []byte(test.text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		expected := filepath.Join(dir, "expected.txt")
+		if err := ProcessFile(plain, expected); err != nil {
+			t.Fatal(err)
+		}
+		want, _ := os.ReadFile(expected)
+		got, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch test.opts.CompressionOf(output) {
+		case config.COMPRESS_GZIP:
+			got = gunzipped(t, got)
+		case config.COMPRESS_ZSTD:
+			got = unzstded(t, got)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: output differs from the plain run, %d bytes instead of %d", test.name, len(got), len(want))
+		}
+	}
+}
+
+func TestProcessFileCompressionErrors(t *testing.T) {
+	dir := t.TempDir()
+	notGzip := filepath.Join(dir, "in.txt.gz")
+	if err := os.WriteFile(notGzip, []byte("plain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessFile(notGzip, filepath.Join(dir, "out.txt")); !errors.Is(err, ErrInput) {
+		t.Errorf("Expected an input error for a broken gzip file, got %v", err)
+	}
+
+	notZstd := filepath.Join(dir, "in.zst")
+	if err := os.WriteFile(notZstd, []byte("plain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessFile(notZstd, filepath.Join(dir, "out.txt")); !errors.Is(err, ErrInput) || !strings.Contains(err.Error(), "zstd") {
+		t.Errorf("Expected an input error naming zstd, got %v", err)
+	}
+}
+
+// gzipped compresses text
+func gzipped(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	if _, err := io.WriteString(writer, text); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// gunzipped decompresses data
+func gunzipped(t *testing.T, data []byte) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
+
+// zstded compresses text with zstd
+func zstded(t *testing.T, text string) []byte {
+	var buf bytes.Buffer
+	writer, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(writer, text); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// unzstded decompresses zstd data
+func unzstded(t *testing.T, data []byte) []byte {
+	reader, err := zstd.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	plain, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return plain
+}
@@ -0,0 +1,110 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/exporter"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// ConcatFiles transforms inputs in order into the one file at outputPath, with separator
+// written between two of them. Every input is transformed on its own, nothing carries over
+// from one to the next, and the output has at most the BOM of the first. It returns the
+// Result of every input transformed. A Strict or Verify failure fails the run once all
+// inputs are written, any other error stops it before the output is created.
+func ConcatFiles(inputs []string, outputPath, separator string, opts config.Options) ([]Result, error) {
+	if opts.Checkpoint || opts.Resume {
+		return nil, fmt.Errorf("invalid options: checkpoints need one input per output")
+	}
+	dir, err := os.MkdirTemp("", "go-reloaded-concat-*")
+	if err != nil {
+		return nil, outputError(fmt.Errorf("failed to create a directory for the pieces: %w", err))
+	}
+	defer os.RemoveAll(dir)
+
+	var results []Result
+	var failed error // the first Strict or Verify failure
+	pieces := make([]string, len(inputs))
+	for i, input := range inputs {
+		pieces[i] = filepath.Join(dir, strconv.Itoa(i)+".txt")
+		result, err := ProcessFileResult(input, pieces[i], opts)
+		if err != nil && !errors.Is(err, ErrStrict) && !errors.Is(err, ErrVerify) {
+			return results, err
+		}
+		if err != nil && failed == nil {
+			failed = fmt.Errorf("%s: %w", input, err)
+		}
+		results = append(results, result)
+	}
+	if err := joinPieces(pieces, outputPath, separator, opts); err != nil {
+		return results, outputError(err)
+	}
+	return results, failed
+}
+
+// joinPieces writes the transformed pieces to outputPath in order, separated by separator
+// in the output encoding
+func joinPieces(pieces []string, outputPath, separator string, opts config.Options) error {
+	var bom, encoded string
+	if opts.Encoding != config.ENCODING_AUTO {
+		codec, err := parser.Codec(opts.Encoding)
+		if err != nil {
+			return err
+		}
+		if bom, err = exporter.Encode(parser.UTF8_BOM, codec); err != nil {
+			return err
+		}
+		if encoded, err = exporter.Encode(separator, codec); err != nil {
+			return fmt.Errorf("failed to encode the separator: %w", err)
+		}
+	} else {
+		bom, encoded = parser.UTF8_BOM, separator
+	}
+
+	sink, err := createOutput(outputPath, opts.CompressionOf(outputPath))
+	if err != nil {
+		return fmt.Errorf("failed to create output: %w", err)
+	}
+	for i, piece := range pieces {
+		if i > 0 {
+			if _, err := io.WriteString(sink, encoded); err != nil {
+				sink.Close()
+				return fmt.Errorf("failed to write output: %w", err)
+			}
+		}
+		if err := appendPiece(sink, piece, opts.CompressionOf(piece), i > 0, bom); err != nil {
+			sink.Close()
+			return err
+		}
+	}
+	if err := sink.Close(); err != nil {
+		return fmt.Errorf("failed to close output: %w", err)
+	}
+	return nil
+}
+
+// appendPiece copies one transformed piece to w, without its leading BOM when skipBOM is set
+func appendPiece(w io.Writer, piece, codec string, skipBOM bool, bom string) error {
+	source, err := parser.OpenDecompressed(piece, codec)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	reader := bufio.NewReader(source)
+	if skipBOM {
+		if start, _ := reader.Peek(len(bom)); bytes.Equal(start, []byte(bom)) {
+			reader.Discard(len(bom))
+		}
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConcatFiles(t *testing.T) {
+	dir := t.TempDir()
+	inputs := []string{filepath.Join(dir, "a.txt"), filepath.Join(dir, "b.txt"), filepath.Join(dir, "c.txt")}
+	// A quote left open in one file does not pair with one of the next
+	contents := []string{"\ufeffhello (up) ' world\n", "\ufeffit was a apple '\n", "1E (hex) (low)\n"}
+	for i, input := range inputs {
+		if err := os.WriteFile(input, []byte(contents[i]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	outputPath := filepath.Join(dir, "all.txt")
+	results, err := ConcatFiles(inputs, outputPath, "\n", config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("ConcatFiles failed: %v", err)
+	}
+	expected := "\ufeffHELLO 'world\n\nit was an apple '\n\n30\n"
+	if data, err := os.ReadFile(outputPath); err != nil || string(data) != expected {
+		t.Errorf("Expected %q, got %q, %v", expected, string(data), err)
+	}
+	if len(results) != len(inputs) || results[0].Applied["up"] != 1 || results[2].Applied["hex"] != 1 {
+		t.Errorf("Expected the counts of every input, got %+v", results)
+	}
+
+	// A Strict failure still writes the whole output, a missing input writes nothing
+	strict := config.DefaultOptions()
+	strict.Strict = true
+	if err := os.WriteFile(inputs[1], []byte("(up) zz (hex)\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	strictPath := filepath.Join(dir, "strict.txt")
+	if _, err := ConcatFiles(inputs, strictPath, "", strict); !errors.Is(err, ErrStrict) {
+		t.Errorf("Expected ErrStrict, got %v", err)
+	}
+	if _, err := os.Stat(strictPath); err != nil {
+		t.Errorf("Expected the output of a Strict failure: %v", err)
+	}
+	missingPath := filepath.Join(dir, "missing.txt")
+	if _, err := ConcatFiles([]string{inputs[0], filepath.Join(dir, "none.txt")}, missingPath, "", config.DefaultOptions()); !errors.Is(err, ErrInput) {
+		t.Errorf("Expected ErrInput, got %v", err)
+	}
+	if _, err := os.Stat(missingPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no output for a missing input, got %v", err)
+	}
+
+	resume := config.DefaultOptions()
+	resume.Resume = true
+	if _, err := ConcatFiles(inputs, outputPath, "", resume); err == nil {
+		t.Error("Expected checkpoints to be rejected")
+	}
+}
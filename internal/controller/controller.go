@@ -1,150 +1,634 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
-	"go-reloaded/internal/config"
-	"go-reloaded/internal/exporter"
-	"go-reloaded/internal/parser"
-	"go-reloaded/internal/transformer"
+	"github.com/GiannisPettas/go-reloaded/internal/chunktrace"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/exporter"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/throttle"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
 	"os"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	textencoding "golang.org/x/text/encoding"
 )
 
 // ProcessFile orchestrates the complete workflow: Parser → Transformer → Exporter
 func ProcessFile(inputPath, outputPath string) error {
-	// Check if input file exists
-	if _, err := os.Stat(inputPath); os.IsNotExist(err) {
-		return fmt.Errorf("input file does not exist: %s", inputPath)
+	return ProcessFileWithOptions(inputPath, outputPath, config.DefaultOptions())
+}
+
+// ProcessFileWithOptions runs the same workflow as ProcessFile with custom transformation options
+func ProcessFileWithOptions(inputPath, outputPath string, opts config.Options) error {
+	_, err := ProcessFileResult(inputPath, outputPath, opts)
+	return err
+}
+
+// Result is what ProcessFileResult did with one file
+type Result struct {
+	BytesIn   int64                 // input bytes transformed, decompressed
+	BytesOut  int64                 // output bytes written, before compression
+	Chunks    int                   // chunks read, 1 for a file transformed at once
+	Restarted bool                  // a command reached back into written output, the file was read again to rewrite it
+	Applied   map[string]int        // words changed per command name, nil when none
+	Warnings  []transformer.Warning // warnings reported, with their lines in the file. A resumed run has only its own
+	Duration  time.Duration
+}
+
+// ProcessFileResult runs the same workflow as ProcessFileWithOptions and also reports what
+// it did. A file that was written has its Result even when Strict or Verify fail it.
+func ProcessFileResult(inputPath, outputPath string, opts config.Options) (Result, error) {
+	if err := opts.Validate(); err != nil {
+		return Result{}, fmt.Errorf("invalid options: %w", err)
+	}
+	if err := transformer.ValidateAliases(opts.CommandAliases); err != nil {
+		return Result{}, fmt.Errorf("invalid options: %w", err)
 	}
+	if (opts.Checkpoint || opts.Resume) && opts.CompressionOf(outputPath) != config.COMPRESS_NONE {
+		return Result{}, fmt.Errorf("invalid options: checkpoints need an uncompressed output, %s is compressed", outputPath)
+	}
+	if (opts.Checkpoint || opts.Resume) && parser.IsURL(inputPath) {
+		return Result{}, fmt.Errorf("invalid options: checkpoints need a local input, %s is a URL", inputPath)
+	}
+
+	start := time.Now()
+	codec := opts.CompressionOf(inputPath)
 	// Get file size to determine if we need chunked processing
-	fileInfo, err := os.Stat(inputPath)
+	size, err := inputSize(inputPath, codec)
+	if err != nil {
+		return Result{}, err
+	}
+
+	var stats runStats
+	if size >= 0 && size <= int64(config.CHUNK_BYTES) {
+		// For small files, process in one chunk
+		err = processSingleChunk(inputPath, outputPath, opts, &stats)
+	} else {
+		// For larger files, use chunked processing with overlap. The size of a URL or
+		// compressed input is only known at its end, a small one is a single chunk there too.
+		err = processChunkedFile(inputPath, codec, size, outputPath, opts, &stats)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
+		return Result{}, err
+	}
+	elapsed := time.Since(start)
+	logFile(loggerOf(opts), inputPath, outputPath, stats, elapsed)
+	result := Result{
+		BytesIn:   stats.bytesIn,
+		BytesOut:  stats.bytesOut,
+		Chunks:    stats.chunks,
+		Restarted: stats.restarted,
+		Applied:   stats.applied,
+		Warnings:  stats.reported,
+		Duration:  elapsed,
+	}
+	if opts.Strict && stats.warnings > 0 {
+		return result, strictError(stats)
+	}
+	if opts.Verify {
+		return result, verifyOutput(outputPath, opts)
 	}
+	return result, nil
+}
+
+// strictError is the ErrStrict of a file with warnings, telling the commands that were not
+// applied from the short counts of those that were: "3 warnings: 1 command not applied, 2 short counts"
+func strictError(stats runStats) error {
+	unapplied := stats.warnings - stats.clamped
+	return fmt.Errorf("%w: %s: %s not applied, %s", ErrStrict, plural(stats.warnings, "warning"),
+		plural(unapplied, "command"), plural(stats.clamped, "short count"))
+}
 
-	// For small files, process in one chunk
-	if fileInfo.Size() <= int64(config.CHUNK_BYTES) {
-		return processSingleChunk(inputPath, outputPath)
+// plural writes n with noun, in the plural unless n is 1: "2 warnings"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
 	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
 
-	// For larger files, use chunked processing with overlap
-	return processChunkedFile(inputPath, outputPath)
+// inputSize returns the size of the content of inputPath, -1 for a URL or a compressed
+// input, whose content is streamed and ends where its stream ends, see openInput
+func inputSize(inputPath, codec string) (int64, error) {
+	if parser.IsURL(inputPath) {
+		return -1, nil
+	}
+	fileInfo, err := os.Stat(inputPath)
+	if os.IsNotExist(err) {
+		return 0, inputError(fmt.Errorf("input file does not exist: %s", inputPath))
+	}
+	if err != nil {
+		return 0, inputError(fmt.Errorf("failed to get file info: %w", err))
+	}
+	if codec != config.COMPRESS_NONE {
+		return -1, nil
+	}
+	return fileInfo.Size(), nil
 }
 
-// processSingleChunk handles files that fit in a single chunk
-func processSingleChunk(inputPath, outputPath string) error {
-	// Read entire file
-	data, err := parser.ReadChunk(inputPath, 0)
+// How a run opens its input and creates its output, tests swap them to inject faults
+var (
+	openSource = parser.OpenSource
+	createSink = exporter.CreateSink
+)
+
+// processSingleChunk handles local uncompressed files that fit in a single chunk
+func processSingleChunk(inputPath, outputPath string, opts config.Options, stats *runStats) error {
+	limiter := throttle.NewLimiter(opts.IOThrottleMBps)
+	trace := chunktrace.NewWriter(opts.DebugChunks)
+
+	// Read entire file, the input is closed before the output is created so both can be the same file
+	start := time.Now()
+	source, err := openSource(inputPath)
+	if err != nil {
+		return inputError(fmt.Errorf("failed to read file: %w", err))
+	}
+	raw, err := parser.ReadRawChunkAt(source, 0)
+	source.Close()
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return inputError(fmt.Errorf("failed to read file: %w", err))
 	}
+	limiter.Wait(len(raw))
+
+	encoding := resolveEncoding(opts.Encoding, raw)
+	codec, err := parser.Codec(encoding)
+	if err != nil {
+		return err
+	}
+	data, used, err := parser.DecodeChunk(raw, encoding)
+	if err != nil {
+		return inputError(fmt.Errorf("failed to read file: %w", err))
+	}
+
+	// A BOM would stick to the first word, so it is removed before transforming
+	data, hadBOM := parser.StripBOM(data)
 
 	// Convert to text
 	text := string(data)
+	since(&stats.read, start)
 
 	// Apply transformations in single pass
-	result := transformer.ProcessText(text)
+	start = time.Now()
+	var result string
+	var warnings []transformer.Warning
+	if opts.Trace != nil {
+		report := transformer.TraceText(text, opts)
+		result, warnings = report.Output, report.Warnings
+		stats.countApplied(report.Applied)
+		if err := writeEdits(opts.Trace, report.Edits); err != nil {
+			return err
+		}
+	} else {
+		segment := transformer.ProcessSegment(text, opts)
+		result, warnings = segment.Output, segment.Warnings
+		stats.countSegment(segment.Applied, 0, true)
+	}
+	since(&stats.transform, start)
+	if err := reportWarnings(opts, inputPath, warnings, 0, 0, stats); err != nil {
+		return err
+	}
+	if opts.EmitBOM(hadBOM) {
+		result = parser.UTF8_BOM + result
+	}
 
 	// Write to output
-	err = exporter.WriteChunk(outputPath, result)
+	start = time.Now()
+	result, err = exporter.Encode(result, codec)
 	if err != nil {
-		return fmt.Errorf("failed to write output: %w", err)
+		return outputError(err)
+	}
+	limiter.Wait(len(result))
+	if err := writeFile(outputPath, opts.CompressionOf(outputPath), result); err != nil {
+		return outputError(fmt.Errorf("failed to write output: %w", err))
 	}
+	since(&stats.write, start)
+	stats.chunks, stats.bytesIn, stats.bytesOut = 1, int64(used), int64(len(result))
 
-	return nil
+	words := len(strings.Fields(result))
+	return trace.Write(chunktrace.Record{
+		InputEnd:      int64(used),
+		ReadBytes:     len(raw),
+		AdjustedBytes: len(data),
+		InputCRC:      chunktrace.Checksum(raw[:used]),
+		Processed:     words,
+		Written:       words,
+		OutputEnd:     int64(len(result)),
+		OutputCRC:     chunktrace.Checksum([]byte(result)),
+	})
 }
 
-// processChunkedFile handles large files with proper chunked processing
-func processChunkedFile(inputPath, outputPath string) error {
-	fileInfo, err := os.Stat(inputPath)
+// processChunkedFile handles large files chunk by chunk. The carried text and the decoded
+// chunk are transformed together, the output is written up to the last line break that
+// nothing continues across (see transformer.Cut), a tied one too once the carry passes
+// TIED_CUT_BYTES, and leaves at least OVERLAP_WORDS words, and the untransformed rest is
+// carried to the next chunk. A backward command reaching past the carried words changes
+// words already written: the rest of the file is then read to plan the words every such
+// command changes, and the output is written again chunk by chunk from the start with them
+// changed, so the chunked output is always identical to single-pass processing and memory
+// stays bounded by the carry. The content is read as openInput reads it, decompressed with
+// compression; size is -1 for a URL or compressed input, which ends where its stream ends.
+func processChunkedFile(inputPath, compression string, size int64, outputPath string, opts config.Options, stats *runStats) error {
+
+	limiter := throttle.NewLimiter(opts.IOThrottleMBps)
+	trace := chunktrace.NewWriter(opts.DebugChunks)
+	logger := loggerOf(opts)
+	state := chunkState{Encoding: opts.Encoding}
+	// A trace diffs the whole text, it is transformed at once
+	singlePass := opts.Trace != nil
+	// Commands changing words written before their segment, see planReaches. Once there is
+	// one, the rest of the file is read without writing, then the output is rewritten.
+	var plan []reach
+	planning := false
+	var rewriting *rewrite
+	restart := false
+	var codec textencoding.Encoding
+	var err error
+
+	checkpoints := opts.Checkpoint || opts.Resume
+	var saved *checkpoint
+	if checkpoints {
+		if saved, err = newCheckpoint(inputPath, outputPath, opts); err != nil {
+			return err
+		}
+	}
+
+	// Both files stay open for the whole run, the output is created by the first write
+	source, err := openInput(inputPath, compression, opts)
 	if err != nil {
-		return fmt.Errorf("failed to get file info: %w", err)
+		return inputError(fmt.Errorf("failed to read chunk at offset 0: %w", err))
+	}
+	defer source.Close()
+	var sink exporter.Sink
+	closeSink := func() error {
+		if sink == nil {
+			return nil
+		}
+		err := sink.Close()
+		sink = nil
+		if err != nil {
+			return outputError(fmt.Errorf("failed to close output: %w", err))
+		}
+		return nil
+	}
+	defer closeSink()
+
+	if opts.Resume {
+		resumed, ok, err := saved.load()
+		if err != nil {
+			return err
+		}
+		if ok {
+			if sink, err = resumeSink(outputPath, resumed.OutputOffset); err != nil {
+				return outputError(fmt.Errorf("failed to resume output: %w", err))
+			}
+			if codec, err = parser.Codec(resumed.Encoding); err != nil {
+				return err
+			}
+			state, stats.warnings, stats.clamped, stats.applied = resumed, resumed.Warnings, resumed.Clamped, resumed.Applied
+			logger.Debug("resuming from checkpoint", "input", inputPath, "chunk", state.Chunk, "input_start", state.Offset)
+		}
 	}
+	checkpointed := state.Offset
 
-	var offset int64 = 0
-	var overlapContext string
-	isFirstChunk := true
+	// writes one piece of output in the file encoding, the first piece creates the file.
+	// The output range of the piece is added to record.
+	write := func(content string, record *chunktrace.Record) error {
+		defer since(&stats.write, time.Now())
+		if !state.Started {
+			content = state.BOM + content
+		}
+		encoded, err := exporter.Encode(content, codec)
+		if err != nil {
+			return err
+		}
+		limiter.Wait(len(encoded))
+		if sink == nil {
+			if sink, err = createOutput(outputPath, opts.CompressionOf(outputPath)); err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(sink, encoded); err != nil {
+			return err
+		}
+		state.Started = true
+		record.Written = len(strings.Fields(content))
+		record.OutputEnd = record.OutputStart + int64(len(encoded))
+		record.OutputCRC = chunktrace.Checksum([]byte(encoded))
+		state.OutputOffset = record.OutputEnd
+		return nil
+	}
 
 	for {
 		// Read chunk
-		data, err := parser.ReadChunk(inputPath, offset)
+		start := time.Now()
+		offset := state.Offset
+		raw, err := parser.ReadRawChunkAt(source, offset)
 		if err != nil {
-			return fmt.Errorf("failed to read chunk at offset %d: %w", offset, err)
+			return inputError(fmt.Errorf("failed to read chunk at offset %d: %w", offset, err))
 		}
+		limiter.Wait(len(raw))
 
-		// If no data, we're done
-		if len(data) == 0 {
-			break
+		// Only the first chunk can start with a BOM or tell the encoding
+		if offset == 0 {
+			state.Encoding = resolveEncoding(opts.Encoding, raw)
+			if codec, err = parser.Codec(state.Encoding); err != nil {
+				return err
+			}
+		}
+		data, used, err := parser.DecodeChunk(raw, state.Encoding)
+		if err != nil {
+			return inputError(fmt.Errorf("failed to decode chunk at offset %d: %w", offset, err))
+		}
+		if offset == 0 {
+			var hadBOM bool
+			data, hadBOM = parser.StripBOM(data)
+			if opts.EmitBOM(hadBOM) {
+				state.BOM = parser.UTF8_BOM
+			}
 		}
 
-		// Convert to text
-		chunkText := string(data)
+		since(&stats.read, start)
 
-		// Merge with overlap context
-		var textToProcess string
-		if overlapContext != "" {
-			textToProcess = overlapContext + chunkText
-		} else {
-			textToProcess = chunkText
+		record := chunktrace.Record{
+			Chunk:         state.Chunk,
+			InputStart:    offset,
+			InputEnd:      offset + int64(used),
+			ReadBytes:     len(raw),
+			AdjustedBytes: len(data),
+			InputCRC:      chunktrace.Checksum(raw[:used]),
+			OverlapIn:     state.CarriedWords,
+			OutputStart:   state.OutputOffset,
+			OutputEnd:     state.OutputOffset,
+			Restart:       restart,
 		}
 
-		// Apply single-pass FSM transformation to this chunk
-		processedChunk := transformer.ProcessText(textToProcess)
+		// Bytes of a character cut by the chunk end are read again. A chunk smaller than
+		// expected, or one reaching the file size, is the last one.
+		state.Offset += int64(used)
+		last := len(raw) < config.CHUNK_BYTES || (size >= 0 && state.Offset >= size)
+		state.CarriedWords = joinedWords(state.Carry, data, state.CarriedWords)
+		state.Carry = append(state.Carry, data...)
+		if limit := carryLimit(opts); limit > 0 && int64(len(state.Carry)) > limit {
+			// Text without a cut must be transformed at once
+			return fmt.Errorf("%w: more than %d bytes from line %d on must be transformed at once", ErrMemory, limit, state.LineBase+1)
+		}
 
-		// If we had overlap context, remove it from the processed result to avoid duplication
-		if overlapContext != "" {
-			// Skip the overlap words from the processed result
-			overlapWordCount := len(strings.Fields(overlapContext))
-			processedWords := strings.Fields(processedChunk)
-			if len(processedWords) > overlapWordCount {
-				processedChunk = strings.Join(processedWords[overlapWordCount:], " ")
+		// The last chunk writes everything carried, the others up to their last cut. Without
+		// a cut the carry must double before the next try, so a long line or an open quote
+		// costs linear time, not one transformation per chunk.
+		if last || (!singlePass && len(state.Carry) >= state.RetryAt) {
+			text := string(state.Carry)
+			start = time.Now()
+			var segment transformer.Segment
+			if opts.Trace != nil {
+				report := transformer.TraceText(text, opts)
+				segment = transformer.Segment{Output: report.Output, Warnings: report.Warnings}
+				// A traced text is written at once, its counts need no lines
+				for command, words := range report.Applied {
+					segment.Applied = append(segment.Applied, transformer.CommandCount{Command: command, Words: words})
+				}
+				if err := writeEdits(opts.Trace, report.Edits); err != nil {
+					return err
+				}
+			} else {
+				segment = transformer.ProcessSegment(text, opts)
+			}
+			since(&stats.transform, start)
+			cut := transformer.Cut{Input: len(text), Output: len(segment.Output)}
+			if !last {
+				cut = lastCut(segment.Cuts, transformer.SegmentEnd(text, config.OVERLAP_WORDS), len(text) >= TIED_CUT_BYTES)
+			}
+			if cut.Input < 0 {
+				state.RetryAt = 2 * len(text)
 			} else {
-				processedChunk = "" // All words were overlap
+				if rewriting == nil {
+					plan = planReaches(plan, segment.Reaches, state)
+					if plan != nil && !planning {
+						logger.Debug("planning a rewrite", "input", inputPath, "chunk", state.Chunk)
+						planning = true
+					}
+				} else {
+					segment = rewriting.piece(text, cut, segment, state, opts)
+					cut.Output = len(segment.Output)
+				}
+				lines := strings.Count(text[:cut.Input], "\n")
+				// While planning nothing is written, the rewrite writes it all
+				if !planning {
+					// Warnings after the cut come again with the carried text
+					var warnings []transformer.Warning
+					for _, warning := range segment.Warnings {
+						if last || warning.Line <= lines {
+							warnings = append(warnings, warning)
+						}
+					}
+					if err := reportWarnings(opts, inputPath, warnings, state.LineBase, state.WarnedLines, stats); err != nil {
+						return err
+					}
+					stats.countSegment(segment.Applied, lines, last)
+					state.WarnedLines = max(state.WarnedLines, state.LineBase+lines)
+					// The first piece creates the output file, even an empty one
+					if cut.Output > 0 || !state.Started {
+						if err := write(segment.Output[:cut.Output], &record); err != nil {
+							return outputError(fmt.Errorf("failed to write chunk: %w", err))
+						}
+					}
+				}
+				state.LineBase += lines
+				if cut.Scope >= 0 {
+					state.Scope = state.Words + int64(cut.Scope)
+				}
+				state.Words += int64(cut.Words)
+				state.Carry = append(state.Carry[:0], text[cut.Input:]...)
+				state.CarriedWords = len(strings.Fields(text[cut.Input:]))
+				state.RetryAt = 0
 			}
 		}
+		if last && planning {
+			// Every command is planned: the output is written again from the start
+			logger.Debug("rewriting", "input", inputPath, "commands", len(plan))
+			stats.restarted = true
+			if err := closeSink(); err != nil {
+				return err
+			}
+			// An older checkpoint is void, and the rewrite has none of its own
+			if checkpoints {
+				if err := saved.remove(); err != nil {
+					return err
+				}
+			}
+			// Warnings already reported are not reported again, the counts start over
+			state = chunkState{Carry: state.Carry[:0], WarnedLines: state.WarnedLines}
+			stats.applied = nil
+			rewriting, plan, planning, restart = &rewrite{plan: plan}, nil, false, true
+			continue
+		}
+		record.OverlapOut = state.CarriedWords
+		record.Processed = record.Written + record.OverlapOut
+		record.Final = last
+		// A planning chunk writes nothing, the rewrite has records of its own
+		if !planning {
+			if err := trace.Write(record); err != nil {
+				return err
+			}
+		}
+		logger.Debug("chunk",
+			"input", inputPath,
+			"chunk", state.Chunk,
+			"input_start", record.InputStart,
+			"input_end", record.InputEnd,
+			"output_end", record.OutputEnd,
+			"carried_words", record.OverlapOut,
+		)
+		state.Chunk++
+		restart = false
 
-		// Extract overlap for next chunk and get remaining text
-		newOverlap, remaining := parser.ExtractOverlapWords(processedChunk)
-
-		// Write remaining text to output
-		if remaining != "" {
-			if isFirstChunk {
-				err = exporter.WriteChunk(outputPath, remaining)
-				isFirstChunk = false
-			} else {
-				err = exporter.AppendChunk(outputPath, remaining)
+		if last {
+			stats.chunks, stats.bytesIn, stats.bytesOut = state.Chunk, record.InputEnd, record.OutputEnd
+			if err := closeSink(); err != nil {
+				return err
 			}
-			if err != nil {
-				return fmt.Errorf("failed to write chunk: %w", err)
+			if checkpoints {
+				return saved.remove()
 			}
+			return nil
 		}
+		// A single pass writes nothing before the end and a rewrite follows a plan held in
+		// memory, so neither has anything to resume
+		if checkpoints && !singlePass && !planning && rewriting == nil && state.Started && state.Offset-checkpointed >= checkpointEvery {
+			state.Warnings, state.Clamped, state.Applied = stats.warnings, stats.clamped, stats.applied
+			if err := saved.save(sink, state); err != nil {
+				return err
+			}
+			checkpointed = state.Offset
+		}
+	}
+}
 
-		// Update context and offset
-		overlapContext = newOverlap
-		offset += int64(len(data))
+// Carried text from which a cut may be tied, see transformer.Cut: the backward commands
+// reaching over it then rewrite the output instead of growing the carry. It stays under
+// the carry of the smallest --max-memory.
+const TIED_CUT_BYTES = 8 * config.CHUNK_BYTES
 
-		// Safety check to prevent infinite loops
-		if offset >= fileInfo.Size() {
-			break
-		}
+// Bytes the transformer allocates per byte of text, measured at about 120 on mixed text
+const MEMORY_PER_TEXT_BYTE = 128
 
-		// If chunk was smaller than expected, we're at end of file
-		if len(data) < config.CHUNK_BYTES {
-			break
-		}
+// carryLimit is the most text a chunked run may carry under opts.MaxMemory, 0 when unlimited
+func carryLimit(opts config.Options) int64 {
+	return opts.MaxMemory / MEMORY_PER_TEXT_BYTE
+}
+
+// writeFile creates the file at path with content compressed with codec, a failing close
+// is an error
+func writeFile(path, codec, content string) error {
+	sink, err := createOutput(path, codec)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(sink, content)
+	if closeErr := sink.Close(); err == nil {
+		err = closeErr
 	}
+	return err
+}
 
-	// Write any remaining overlap context at the end
-	if overlapContext != "" {
-		if isFirstChunk {
-			err = exporter.WriteChunk(outputPath, overlapContext)
-		} else {
-			err = exporter.AppendChunk(outputPath, overlapContext)
+// reportWarnings writes the warnings of a segment starting after line firstLine of the file
+// to opts.Warnings and opts.Logger, skipping those on the first skip lines, which were
+// reported before. Under config.UNAPPLIED_ERROR it stops at the first one with ErrUnapplied.
+func reportWarnings(opts config.Options, path string, warnings []transformer.Warning, firstLine, skip int, stats *runStats) error {
+	for _, warning := range warnings {
+		warning.Line += firstLine
+		if warning.Line <= skip {
+			continue
 		}
-		if err != nil {
-			return fmt.Errorf("failed to write final overlap: %w", err)
+		stats.warnings++
+		if warning.Applied {
+			stats.clamped++
+		}
+		stats.reported = append(stats.reported, warning)
+		if opts.Logger != nil {
+			opts.Logger.Warn("command not applied",
+				"input", path,
+				"line", warning.Line,
+				"column", warning.Column,
+				"command", warning.Command,
+				"reason", warning.Reason,
+			)
+		}
+		if opts.Warnings != nil {
+			if _, err := fmt.Fprintf(opts.Warnings, "%s:%s\n", path, warning); err != nil {
+				return fmt.Errorf("failed to write warnings: %w", err)
+			}
+		}
+		// The first one fails the file, before the text holding it is written
+		if opts.UnappliedCommandPolicy == config.UNAPPLIED_ERROR {
+			return fmt.Errorf("%w: %s:%s", ErrUnapplied, path, warning)
 		}
 	}
+	return nil
+}
 
+// writeEdits writes the edits of a traced file as a JSON array, one edit per line
+func writeEdits(w io.Writer, edits []transformer.Edit) error {
+	var b strings.Builder
+	b.WriteString("[")
+	for i, edit := range edits {
+		line, err := json.Marshal(edit)
+		if err != nil {
+			return fmt.Errorf("failed to write trace: %w", err)
+		}
+		if i > 0 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+		b.Write(line)
+	}
+	if len(edits) > 0 {
+		b.WriteString("\n")
+	}
+	b.WriteString("]\n")
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("failed to write trace: %w", err)
+	}
 	return nil
 }
+
+// resolveEncoding picks the file encoding, detecting it from the first chunk for ENCODING_AUTO
+func resolveEncoding(name string, firstChunk []byte) string {
+	switch name {
+	case config.ENCODING_AUTO:
+		return parser.DetectEncoding(firstChunk)
+	case "":
+		return config.ENCODING_UTF8
+	}
+	return name
+}
+
+// lastCut returns the last of cuts at or before limit, tied ones only with tied, or a cut at
+// -1 when there is none
+func lastCut(cuts []transformer.Cut, limit int, tied bool) transformer.Cut {
+	for i := len(cuts) - 1; i >= 0; i-- {
+		if cuts[i].Input <= limit && (tied || !cuts[i].Tied) {
+			return cuts[i]
+		}
+	}
+	return transformer.Cut{Input: -1, Output: -1}
+}
+
+// joinedWords returns the number of words in carry+data, given the words in carry:
+// a word cut by the chunk end continues at the start of data
+func joinedWords(carry, data []byte, carriedWords int) int {
+	words := carriedWords + len(bytes.Fields(data))
+	last, _ := utf8.DecodeLastRune(carry)
+	first, _ := utf8.DecodeRune(data)
+	if len(carry) > 0 && len(data) > 0 && !unicode.IsSpace(last) && !unicode.IsSpace(first) {
+		words--
+	}
+	return words
+}
@@ -1,10 +1,25 @@
 package controller
 
 import (
-	"go-reloaded/internal/testutils"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/chunktrace"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"maps"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 // Purpose: Tests constants during development/CI
@@ -107,3 +122,571 @@ func TestProcessFileNotFound(t *testing.T) {
 		t.Errorf("ProcessFile should return error for nonexistent input file")
 	}
 }
+
+func TestProcessFileErrorCategories(t *testing.T) {
+	dir := t.TempDir()
+	clean := filepath.Join(dir, "clean.txt")
+	if err := os.WriteFile(clean, []byte("fine"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	strict := config.DefaultOptions()
+	strict.Strict = true
+	warned := filepath.Join(dir, "warned.txt")
+	if err := os.WriteFile(warned, []byte("(up) zz (hex)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		output   string
+		opts     config.Options
+		category error
+	}{
+		{"missing input", filepath.Join(dir, "missing.txt"), filepath.Join(dir, "out.txt"), config.DefaultOptions(), ErrInput},
+		{"directory input", dir, filepath.Join(dir, "out.txt"), config.DefaultOptions(), ErrInput},
+		{"output below a file", clean, filepath.Join(clean, "out.txt"), config.DefaultOptions(), ErrOutput},
+		{"strict", warned, filepath.Join(dir, "out.txt"), strict, ErrStrict},
+	}
+	for _, test := range tests {
+		err := ProcessFileWithOptions(test.input, test.output, test.opts)
+		if !errors.Is(err, test.category) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.category, err)
+		}
+	}
+	if err := ProcessFileWithOptions(warned, filepath.Join(dir, "out.txt"), strict); err == nil || !strings.Contains(err.Error(), "2 warnings: 2 commands not applied, 0 short counts") {
+		t.Errorf("Expected the strict error to count 2 commands, got %v", err)
+	}
+	// A clamped short count was applied, it is a warning but no command left unapplied
+	short := filepath.Join(dir, "short.txt")
+	if err := os.WriteFile(short, []byte("one two (up, 5) zz (hex)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	shortCounts := strict
+	shortCounts.ShortCountPolicy = config.SHORT_COUNT_WARN
+	err := ProcessFileWithOptions(short, filepath.Join(dir, "out.txt"), shortCounts)
+	if !errors.Is(err, ErrStrict) || !strings.Contains(err.Error(), "2 warnings: 1 command not applied, 1 short count") {
+		t.Errorf("Expected the strict error to tell the short count apart, got %v", err)
+	}
+	if err := ProcessFileWithOptions(clean, filepath.Join(dir, "out.txt"), strict); err != nil {
+		t.Errorf("Strict processing of a clean file failed: %v", err)
+	}
+
+	// An unapplied command fails the file before anything is written
+	unapplied := config.DefaultOptions()
+	unapplied.UnappliedCommandPolicy = config.UNAPPLIED_ERROR
+	output := filepath.Join(dir, "unapplied.txt")
+	err = ProcessFileWithOptions(warned, output, unapplied)
+	if !errors.Is(err, ErrUnapplied) || !strings.Contains(err.Error(), warned+":1:1: (up): no preceding word") {
+		t.Errorf("Expected an unapplied error naming the first command, got %v", err)
+	}
+	if _, statErr := os.Stat(output); !os.IsNotExist(statErr) {
+		t.Errorf("Expected no output for an unapplied command, got %v", statErr)
+	}
+}
+
+func TestProcessFileResult(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		chunked bool
+		restart bool
+	}{
+		{"single chunk", "a apple (up) , 12 (rom) zz (hex) it (cap>, 2) is here", false, false},
+		{"chunked", differentialInput(21, 30000, "(up, 0)", "zz (hex)"), true, false},
+		{"restarted", differentialInput(22, 30000, "(up, 0)") + "\nend (up, 3000)", true, true},
+	}
+	for _, test := range tests {
+		inputPath := filepath.Join(t.TempDir(), "input.txt")
+		if err := os.WriteFile(inputPath, []byte(test.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputPath := filepath.Join(t.TempDir(), "output.txt")
+		result, err := ProcessFileResult(inputPath, outputPath, config.DefaultOptions())
+		if err != nil {
+			t.Fatalf("%s: ProcessFileResult failed: %v", test.name, err)
+		}
+
+		p, err := transformer.NewProcessor(config.DefaultOptions())
+		if err != nil {
+			t.Fatal(err)
+		}
+		report, _ := p.ProcessReport(t.Context(), test.input, transformer.PerCallOptions{})
+		if !maps.Equal(result.Applied, report.Applied) {
+			t.Errorf("%s: expected the counts of a single pass %v, got %v", test.name, report.Applied, result.Applied)
+		}
+		if fmt.Sprint(result.Warnings) != fmt.Sprint(report.Warnings) {
+			t.Errorf("%s: expected the warnings of a single pass %v, got %v", test.name, report.Warnings, result.Warnings)
+		}
+		if result.BytesIn != int64(len(test.input)) || result.BytesOut != int64(len(report.Output)) {
+			t.Errorf("%s: expected %d bytes in and %d out, got %d and %d", test.name, len(test.input), len(report.Output), result.BytesIn, result.BytesOut)
+		}
+		if (result.Chunks > 1) != test.chunked || result.Restarted != test.restart || result.Duration <= 0 {
+			t.Errorf("%s: unexpected chunks %d, restarted %v or duration %v", test.name, result.Chunks, result.Restarted, result.Duration)
+		}
+	}
+}
+
+// TestProcessFileURLStreams holds the rest of the body back until output was written, a
+// download read to its end before processing would never see it
+func TestProcessFileURLStreams(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output.txt")
+	head, tail := differentialInput(24, 30000), differentialInput(25, 30000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, head)
+		w.(http.Flusher).Flush()
+		for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if info, err := os.Stat(output); err == nil && info.Size() > 0 {
+				io.WriteString(w, "\n"+tail)
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	if err := ProcessFileWithOptions(server.URL+"/streamed.txt", output, config.DefaultOptions()); err != nil {
+		t.Fatalf("ProcessFileWithOptions failed: %v", err)
+	}
+	if got, _ := os.ReadFile(output); string(got) != transformer.ProcessText(head+"\n"+tail) {
+		t.Errorf("Expected the whole body transformed once output was written mid-download, got %d bytes", len(got))
+	}
+}
+
+func TestProcessFileURL(t *testing.T) {
+	large := differentialInput(23, 30000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.txt":
+			io.WriteString(w, "a apple (up) here")
+		case "/large.txt":
+			io.WriteString(w, large)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	output := filepath.Join(t.TempDir(), "output.txt")
+	if err := ProcessFileWithOptions(server.URL+"/small.txt", output, config.DefaultOptions()); err != nil {
+		t.Fatalf("ProcessFileWithOptions failed: %v", err)
+	}
+	if got, _ := os.ReadFile(output); string(got) != "an APPLE here" {
+		t.Errorf("Expected the transformed download, got %q", got)
+	}
+
+	result, err := ProcessFileResult(server.URL+"/large.txt", output, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("ProcessFileResult failed: %v", err)
+	}
+	if got, _ := os.ReadFile(output); string(got) != transformer.ProcessText(large) || result.Chunks < 2 {
+		t.Errorf("Expected a chunked download identical to a single pass, got %d chunks", result.Chunks)
+	}
+
+	if err := ProcessFileWithOptions(server.URL+"/missing.txt", output, config.DefaultOptions()); !errors.Is(err, ErrInput) {
+		t.Errorf("Expected an input error for a missing document, got %v", err)
+	}
+	limited := config.DefaultOptions()
+	limited.MaxFetchBytes = 1000
+	if err := ProcessFileWithOptions(server.URL+"/large.txt", output, limited); !errors.Is(err, ErrInput) || !errors.Is(err, parser.ErrTooLarge) {
+		t.Errorf("Expected an input error for a document past the limit, got %v", err)
+	}
+	checkpointed := config.DefaultOptions()
+	checkpointed.Checkpoint = true
+	if err := ProcessFileWithOptions(server.URL+"/small.txt", output, checkpointed); err == nil {
+		t.Error("Expected an error for a checkpoint of a URL input")
+	}
+}
+
+func TestProcessFileThrottled(t *testing.T) {
+	inputContent := strings.Repeat("word (up) ", 1000)
+	inputPath, err := testutils.CreateTestFile(inputContent)
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	outputPath := filepath.Join(t.TempDir(), "throttled.txt")
+
+	opts := config.DefaultOptions()
+	opts.IOThrottleMBps = 0.5
+	if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+		t.Fatalf("ProcessFileWithOptions failed: %v", err)
+	}
+
+	outputData, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.HasPrefix(string(outputData), "WORD WORD") {
+		t.Errorf("Unexpected output prefix: %q", string(outputData)[:20])
+	}
+}
+
+func TestProcessFileBOM(t *testing.T) {
+	bom := "\uFEFF"
+	tests := []struct {
+		name     string
+		input    string
+		policy   string
+		expected string
+	}{
+		{"keep", bom + "hello (up) world", config.BOM_KEEP, bom + "HELLO world"},
+		{"strip", bom + "hello (up) world", config.BOM_STRIP, "HELLO world"},
+		{"add", "hello (up) world", config.BOM_ADD, bom + "HELLO world"},
+		{"keep without BOM", "hello (up) world", config.BOM_KEEP, "HELLO world"},
+		{"chunked", bom + "ff (hex) " + strings.Repeat("word ", 2000), config.BOM_KEEP, bom + "255 word"},
+	}
+
+	for _, test := range tests {
+		inputPath, err := testutils.CreateTestFile(test.input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "bom.txt")
+
+		opts := config.DefaultOptions()
+		opts.BOM = test.policy
+		if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			t.Fatalf("%s: ProcessFileWithOptions failed: %v", test.name, err)
+		}
+
+		outputData, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if !strings.HasPrefix(string(outputData), test.expected) {
+			t.Errorf("%s: expected output starting with %q, got %q", test.name, test.expected, string(outputData))
+		}
+		if strings.Count(string(outputData), bom) > 1 {
+			t.Errorf("%s: BOM written more than once", test.name)
+		}
+	}
+}
+
+func TestProcessFileEncodings(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		input    string
+		expected string
+	}{
+		{"latin1", config.ENCODING_LATIN1, "caf\xe9 cr\xe8me (up) !", "caf\xe9 CR\xc8ME!"},
+		{"auto latin1", config.ENCODING_AUTO, "caf\xe9 cr\xe8me (up) !", "caf\xe9 CR\xc8ME!"},
+		{"auto utf16le", config.ENCODING_AUTO, "\xff\xfeh\x00\xe9\x00 \x00(\x00u\x00p\x00)\x00", "\xff\xfeH\x00\xc9\x00"},
+		{"utf16be", config.ENCODING_UTF16BE, "\x00h\x00\xe9\x00 \x00(\x00u\x00p\x00)", "\x00H\x00\xc9"},
+	}
+
+	for _, test := range tests {
+		inputPath, err := testutils.CreateTestFile(test.input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "encoded.txt")
+
+		opts := config.DefaultOptions()
+		opts.Encoding = test.encoding
+		if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			t.Fatalf("%s: ProcessFileWithOptions failed: %v", test.name, err)
+		}
+
+		outputData, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(outputData) != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.name, test.expected, string(outputData))
+		}
+	}
+}
+
+func TestProcessFileChunkedLatin1(t *testing.T) {
+	inputPath, err := testutils.CreateTestFile(strings.Repeat("\xe9t\xe9 ", 2000) + "fin (up)")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+	outputPath := filepath.Join(t.TempDir(), "latin1.txt")
+
+	opts := config.DefaultOptions()
+	opts.Encoding = config.ENCODING_LATIN1
+	if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+		t.Fatalf("ProcessFileWithOptions failed: %v", err)
+	}
+
+	outputData, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	output := string(outputData)
+	if !strings.HasPrefix(output, "\xe9t\xe9 \xe9t\xe9") || !strings.HasSuffix(output, "\xe9t\xe9 FIN") {
+		t.Errorf("Unexpected output boundaries: %q ... %q", output[:8], output[len(output)-8:])
+	}
+	for _, word := range strings.Fields(output) {
+		if word != "\xe9t\xe9" && word != "FIN" {
+			t.Fatalf("Output is not Latin-1 encoded, found word %q", word)
+		}
+	}
+}
+
+func TestProcessFileUnencodableOutput(t *testing.T) {
+	inputPath, err := testutils.CreateTestFile("il dit ' oui '")
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+
+	opts := config.DefaultOptions()
+	opts.Encoding = config.ENCODING_LATIN1
+	opts.SmartQuotes = true
+	if err := ProcessFileWithOptions(inputPath, filepath.Join(t.TempDir(), "out.txt"), opts); err == nil {
+		t.Error("Expected error for quotes Latin-1 cannot represent")
+	}
+}
+
+func TestProcessFileRuneAtChunkEnd(t *testing.T) {
+	// The chunk end cuts "é" in half, the rest of the file must still be processed
+	input := strings.Repeat("a", config.CHUNK_BYTES-1) + " é " + strings.Repeat("word ", 1000) + "end (up)"
+	inputPath, err := testutils.CreateTestFile(input)
+	if err != nil {
+		t.Fatalf("Failed to create input file: %v", err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+	outputPath := filepath.Join(t.TempDir(), "boundary.txt")
+
+	if err := ProcessFile(inputPath, outputPath); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	outputData, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if !strings.HasSuffix(string(outputData), "word END") {
+		t.Errorf("Output lost text after the chunk boundary: ...%q", string(outputData)[len(outputData)-20:])
+	}
+}
+
+func TestProcessFileChunkTrace(t *testing.T) {
+	for _, size := range []int{100, 25000} {
+		line := "it was a apple and the word went on\n"
+		input := strings.Repeat(line, size/len(line))
+		inputPath, err := testutils.CreateTestFile(input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "traced.txt")
+
+		var trace bytes.Buffer
+		opts := config.DefaultOptions()
+		opts.DebugChunks = &trace
+		if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			t.Fatalf("ProcessFileWithOptions failed: %v", err)
+		}
+
+		records, err := chunktrace.Read(&trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		inputFile, outputFile := openSection(t, inputPath), openSection(t, outputPath)
+		if err := chunktrace.Verify(records, inputFile, outputFile); err != nil {
+			t.Errorf("%d bytes: trace does not verify: %v", size, err)
+		}
+		if size > config.CHUNK_BYTES && len(records) < 3 {
+			t.Errorf("%d bytes: expected several chunks and a final flush, got %d records", size, len(records))
+		}
+
+		// No word is lost or repeated at the chunk boundaries, line breaks survive
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if expected := strings.ReplaceAll(input, "a apple", "an apple"); strings.TrimSpace(string(output)) != strings.TrimSpace(expected) {
+			t.Errorf("%d bytes: chunked output differs from the input with articles fixed", size)
+		}
+	}
+}
+
+func TestProcessFileEditTrace(t *testing.T) {
+	for _, size := range []int{100, 3 * config.CHUNK_BYTES} {
+		input := differentialInput(int64(size), size)
+		inputPath, err := testutils.CreateTestFile(parser.UTF8_BOM + input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "traced.txt")
+
+		var trace bytes.Buffer
+		opts := config.DefaultOptions()
+		opts.BOM, opts.Trace = config.BOM_STRIP, &trace
+		if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			t.Fatalf("ProcessFileWithOptions failed: %v", err)
+		}
+		var edits []transformer.Edit
+		if err := json.Unmarshal(trace.Bytes(), &edits); err != nil || len(edits) == 0 {
+			t.Fatalf("%d bytes: expected a JSON array of edits, got %v: %.200q", size, err, trace.String())
+		}
+
+		// Offsets are in the text after the BOM, replaying the edits gives the output
+		var replayed strings.Builder
+		at := 0
+		for _, edit := range edits {
+			if input[edit.Offset:edit.Offset+len(edit.Original)] != edit.Original {
+				t.Fatalf("%d bytes: edit %+v does not match the input", size, edit)
+			}
+			replayed.WriteString(input[at:edit.Offset] + edit.Replacement)
+			at = edit.Offset + len(edit.Original)
+		}
+		replayed.WriteString(input[at:])
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if replayed.String() != string(output) {
+			t.Errorf("%d bytes: the traced edits do not turn the input into the output", size)
+		}
+	}
+}
+
+// pieces of the generated inputs: commands reaching across lines and chunks, quotes and
+// forward commands over line breaks, whitespace runs and line endings
+var differentialPieces = []string{
+	"word", "other", "a", "an", "apple", "hour", "car", "FF", "1E (hex)", "101 (bin)", "12 (rom)",
+	"(up)", "(low)", "(cap)", "(up, 8)", "(low, 3)", "(title, 2)", "(cap>, 2)", "(up>)", "(rev)",
+	",", ".", "!", "?", "...", ":", "don't", "(", ")", "\\(up\\)", "(up,\n2)", "1 st",
+	"' quoted\nover lines '", "\n\" stop , \" she said", "\" wait \" asked John",
+	" ", " ", " ", " ", "  ", "\t", "\n", "\n", "\r\n", "\n\n",
+}
+
+// generates a reproducible input of at least size bytes from differentialPieces and extra
+func differentialInput(seed int64, size int, extra ...string) string {
+	random := rand.New(rand.NewSource(seed))
+	pieces := append(append([]string{}, differentialPieces...), extra...)
+	var input strings.Builder
+	for input.Len() < size {
+		input.WriteString(pieces[random.Intn(len(pieces))])
+		input.WriteByte(' ')
+	}
+	return input.String()
+}
+
+// The chunked path must write exactly what a single pass over the whole text produces
+func TestProcessFileChunkedMatchesSinglePass(t *testing.T) {
+	preserve := config.DefaultOptions()
+	preserve.PreserveWhitespace = true
+	dialogue := config.DefaultOptions()
+	dialogue.Dialogue = config.DIALOGUE_AMERICAN
+	dialogue.SmartQuotes = true
+	markdown := config.DefaultOptions()
+	markdown.Markdown = true
+	sentences := config.DefaultOptions()
+	sentences.CapitalizeSentences = true
+	sentences.NormalizeOrdinals = true
+	shortCounts := config.DefaultOptions()
+	shortCounts.ShortCountPolicy = config.SHORT_COUNT_WARN
+	substitutions := config.DefaultOptions()
+	substitutions.Substitutions = []config.Substitution{
+		{Name: "before", Pattern: `(\w)(\w*) (\w+)$`, Replacement: "$3 $1$2", Stage: config.SUBSTITUTE_BEFORE},
+		{Name: "after", Pattern: `([aeiou])`, Replacement: "$1$1"},
+	}
+	sentenceScope := config.DefaultOptions()
+	sentenceScope.CommandScope = config.SCOPE_SENTENCE
+	sentenceScope.ShortCountPolicy = config.SHORT_COUNT_WARN
+	paragraphScope := config.DefaultOptions()
+	paragraphScope.CommandScope = config.SCOPE_PARAGRAPH
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    config.Options
+		restart bool // a command reaches back past the first cut
+	}{
+		{"default", differentialInput(1, 60000), config.DefaultOptions(), false},
+		{"preserve whitespace", differentialInput(2, 60000), preserve, false},
+		{"dialogue", differentialInput(3, 60000), dialogue, false},
+		{"sentences", differentialInput(4, 60000), sentences, false},
+		{"long counts", differentialInput(5, 60000, "(up, 12)", "(low, 16)"), config.DefaultOptions(), false},
+		{"past the carry", differentialInput(6, 30000) + "\nend (up, 3000)", config.DefaultOptions(), true},
+		{"count all", differentialInput(7, 30000) + "\nend (low, all)", config.DefaultOptions(), true},
+		{"raw regions", differentialInput(10, 60000, "(raw)(up) ' kept\n  as is(endraw)", "(raw)a  ,\"(endraw)"), config.DefaultOptions(), false},
+		{"markdown", differentialInput(11, 60000, "\n```\ncode (up) ' x\n```\n", "`a ,b`", "[t](http://x.org/a,b)"), markdown, false},
+		{"single line", strings.ReplaceAll(differentialInput(8, 30000), "\n", " "), config.DefaultOptions(), false},
+		{"open quote", "' " + differentialInput(9, 30000), config.DefaultOptions(), false},
+		{"warnings", differentialInput(12, 60000, "(up, 0)", "zz (hex)", "(hex, 2)", "(rom)", "(bin>)"), config.DefaultOptions(), false},
+		{"short counts", "one (up, 5) " + differentialInput(14, 60000, "(up, 12)", "(low>, 16)") + "\nlast (cap>, 9) word", shortCounts, false},
+		{"substitutions", differentialInput(16, 60000, "(raw)kept as is(endraw)"), substitutions, false},
+		{"short counts restart", differentialInput(15, 30000, "(up, 12)") + "\nend (up, 3000)", shortCounts, true},
+		{"warnings restart", differentialInput(13, 30000, "(up, 0)", "(rom)") + "\nend (up, 3000)", config.DefaultOptions(), true},
+		{"sentence scope", differentialInput(17, 60000, "(up, 12)", "(low>, 16)") + "\nend (up, 3000)", sentenceScope, false},
+		{"paragraph scope", differentialInput(18, 30000, "(up, 12)", "(low>, 16)") + "\n\nend (up, 3000)", paragraphScope, false},
+		{"unbalanced quotes", "the boys ' toys\n\n" + differentialInput(20, 60000, "' open", "\n\n"), config.DefaultOptions(), false},
+		{"paragraph scope restart", strings.ReplaceAll(differentialInput(19, 30000), "\n", " x\n") + "\nend (up, all)", paragraphScope, true},
+	}
+
+	for _, test := range tests {
+		inputPath, err := testutils.CreateTestFile(test.input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "chunked.txt")
+
+		var trace, warnings bytes.Buffer
+		test.opts.DebugChunks = &trace
+		test.opts.Warnings = &warnings
+		if err := ProcessFileWithOptions(inputPath, outputPath, test.opts); err != nil {
+			t.Fatalf("%s: ProcessFileWithOptions failed: %v", test.name, err)
+		}
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		expected, expectedWarnings := transformer.ProcessTextWarnings(test.input, test.opts)
+		if string(output) != expected {
+			at := 0
+			for at < len(output) && at < len(expected) && output[at] == expected[at] {
+				at++
+			}
+			t.Errorf("%s: chunked output differs from single pass at byte %d: %q vs %q",
+				test.name, at, output[at:min(at+40, len(output))], expected[at:min(at+40, len(expected))])
+			continue
+		}
+		var expectedReport strings.Builder
+		for _, warning := range expectedWarnings {
+			fmt.Fprintf(&expectedReport, "%s:%s\n", inputPath, warning)
+		}
+		if warnings.String() != expectedReport.String() {
+			t.Errorf("%s: chunked warnings differ from single pass:\n%s\nexpected:\n%s", test.name, warnings.String(), expectedReport.String())
+		}
+
+		records, err := chunktrace.Read(&trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := chunktrace.Verify(records, openSection(t, inputPath), openSection(t, outputPath)); err != nil {
+			t.Errorf("%s: trace does not verify: %v", test.name, err)
+		}
+		restarted := false
+		for _, record := range records {
+			restarted = restarted || record.Restart
+		}
+		if restarted != test.restart {
+			t.Errorf("%s: expected restart %v, trace says %v", test.name, test.restart, restarted)
+		}
+	}
+}
+
+func openSection(t *testing.T, path string) *io.SectionReader {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return io.NewSectionReader(file, 0, info.Size())
+}
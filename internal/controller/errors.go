@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+)
+
+// Categories of ProcessFile errors, test them with errors.Is
+var (
+	ErrInput  = errors.New("input error")       // the input file is missing, unreadable or cannot be decoded
+	ErrOutput = errors.New("output error")      // the output cannot be encoded or written
+	ErrStrict = errors.New("strict mode")       // Options.Strict is set and a warning was reported
+	ErrVerify = errors.New("not a fixed point") // Options.Verify is set and transforming the output again changes it
+	ErrMemory = errors.New("memory limit")      // Options.MaxMemory is set and the text to transform at once does not fit
+
+	// Options.UnappliedCommandPolicy is error and a command was not applied, nothing from
+	// the chunk holding it on is written
+	ErrUnapplied = transformer.ErrUnapplied
+)
+
+// categorized is an error belonging to one of the categories, its message is unchanged
+type categorized struct {
+	category error
+	err      error
+}
+
+func (e *categorized) Error() string   { return e.err.Error() }
+func (e *categorized) Unwrap() []error { return []error{e.category, e.err} }
+
+// inputError puts err in the ErrInput category
+func inputError(err error) error {
+	return &categorized{category: ErrInput, err: err}
+}
+
+// outputError puts err in the ErrOutput category
+func outputError(err error) error {
+	return &categorized{category: ErrOutput, err: err}
+}
+
+// InputError puts err in the ErrInput category, for callers that read inputs of their own
+func InputError(err error) error {
+	return inputError(err)
+}
+
+// OutputError puts err in the ErrOutput category, for callers that write outputs of their own
+func OutputError(err error) error {
+	return outputError(err)
+}
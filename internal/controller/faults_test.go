@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/exporter"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// files opened by a run through injectFaults
+type openedFiles struct {
+	sources []*testutils.FaultySource
+	sinks   []*testutils.FaultySink
+}
+
+// closed reports whether every file opened by the run was closed
+func (f *openedFiles) closed() bool {
+	for _, source := range f.sources {
+		if !source.Closed() {
+			return false
+		}
+	}
+	for _, sink := range f.sinks {
+		if !sink.Closed() {
+			return false
+		}
+	}
+	return true
+}
+
+// injectFaults makes the runs of the test open their files through FaultySource and FaultySink
+// wrappers failing after failReads reads and failWrites written bytes, negative counts never fail
+func injectFaults(t *testing.T, failReads, failWrites int, failClose bool) *openedFiles {
+	opened := &openedFiles{}
+	t.Cleanup(func() { openSource, createSink = parser.OpenSource, exporter.CreateSink })
+	openSource = func(path string) (parser.Source, error) {
+		file, err := parser.OpenSource(path)
+		if err != nil {
+			return nil, err
+		}
+		faulty := &testutils.FaultySource{ReaderAt: file, FailAfter: failReads}
+		opened.sources = append(opened.sources, faulty)
+		return faulty, nil
+	}
+	createSink = func(path string) (exporter.Sink, error) {
+		file, err := exporter.CreateSink(path)
+		if err != nil {
+			return nil, err
+		}
+		faulty := &testutils.FaultySink{Writer: file, FailAfter: failWrites, FailClose: failClose}
+		opened.sinks = append(opened.sinks, faulty)
+		return faulty, nil
+	}
+	return opened
+}
+
+func TestProcessFileOpensOncePerRun(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "input.txt")
+	text := strings.Repeat("it was a apple (up) here\n", 1000)
+	if err := os.WriteFile(input, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opened := injectFaults(t, -1, -1, false)
+	output := filepath.Join(dir, "output.txt")
+	if err := ProcessFile(input, output); err != nil {
+		t.Fatal(err)
+	}
+	if len(opened.sources) != 1 || len(opened.sinks) != 1 {
+		t.Errorf("Expected one open of each file for %d chunks, got %d sources and %d sinks", len(text)/config.CHUNK_BYTES+1, len(opened.sources), len(opened.sinks))
+	}
+	if !opened.closed() {
+		t.Error("Files left open after a successful run")
+	}
+	result, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := strings.Repeat("it was an APPLE here\n", 1000); string(result) != expected {
+		t.Errorf("Unexpected output, %d bytes, expected %d", len(result), len(expected))
+	}
+}
+
+func TestProcessFileInjectedFaults(t *testing.T) {
+	dir := t.TempDir()
+	small := filepath.Join(dir, "small.txt")
+	large := filepath.Join(dir, "large.txt")
+	if err := os.WriteFile(small, []byte("a apple (up)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(large, []byte(strings.Repeat("it was a apple (up) here\n", 1000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	never := -1
+	tests := []struct {
+		name     string
+		input    string
+		reads    int
+		writes   int
+		close    bool
+		category error
+	}{
+		{"read fails", small, 0, never, false, ErrInput},
+		{"write fails", small, never, 3, false, ErrOutput},
+		{"close fails", small, never, never, true, ErrOutput},
+		{"read fails mid-run", large, 2, never, false, ErrInput},
+		{"write fails mid-run", large, never, config.CHUNK_BYTES + 100, false, ErrOutput},
+		{"close fails after the last chunk", large, never, never, true, ErrOutput},
+	}
+	for _, test := range tests {
+		opened := injectFaults(t, test.reads, test.writes, test.close)
+		err := ProcessFile(test.input, filepath.Join(dir, "output.txt"))
+		if !errors.Is(err, test.category) || !errors.Is(err, testutils.ErrInjected) {
+			t.Errorf("%s: expected %v from the injected fault, got %v", test.name, test.category, err)
+		}
+		if len(opened.sources) != 1 || len(opened.sinks) > 1 {
+			t.Errorf("%s: expected one open per file, got %d sources and %d sinks", test.name, len(opened.sources), len(opened.sinks))
+		}
+		if !opened.closed() {
+			t.Errorf("%s: files left open after the failure", test.name)
+		}
+	}
+}
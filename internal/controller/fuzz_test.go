@@ -0,0 +1,60 @@
+package controller
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// The chunked path must write what a single pass over the whole text produces, for any text.
+// The fuzzed piece is repeated with line breaks until the file spans a few chunks.
+func FuzzProcessFileChunked(f *testing.F) {
+	testutils.RequireSuite(f, testutils.SUITE_FUZZ_SMOKE)
+	for _, seed := range differentialPieces {
+		f.Add(seed, uint8(3))
+	}
+	f.Add("it was a apple (up, 2) , ' quoted\nover lines ' (low, all)", uint8(7))
+	f.Add("(up>, 3)\n' open", uint8(1))
+	f.Add("end (up, 3000)", uint8(0))
+
+	dir := f.TempDir()
+	f.Fuzz(func(t *testing.T, piece string, seed uint8) {
+		// The controller strips a leading byte order mark, a single pass keeps it
+		if piece == "" || !utf8.ValidString(piece) || strings.HasPrefix(piece, "\uFEFF") {
+			return
+		}
+		separators := []string{"\n", " ", "\r\n", "\n\n"}
+		var input strings.Builder
+		for i := 0; input.Len() < 3*config.CHUNK_BYTES; i++ {
+			input.WriteString(piece)
+			input.WriteString(separators[(i+int(seed))%len(separators)])
+		}
+		text := input.String()
+
+		inputPath := filepath.Join(dir, "input.txt")
+		outputPath := filepath.Join(dir, "output.txt")
+		if err := os.WriteFile(inputPath, []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := ProcessFile(inputPath, outputPath); err != nil {
+			t.Fatalf("ProcessFile failed for piece %q: %v", piece, err)
+		}
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := transformer.ProcessText(text); string(output) != expected {
+			at := 0
+			for at < len(output) && at < len(expected) && output[at] == expected[at] {
+				at++
+			}
+			t.Errorf("piece %q: chunked output differs from single pass at byte %d: %q vs %q",
+				piece, at, output[at:min(at+40, len(output))], expected[at:min(at+40, len(expected))])
+		}
+	})
+}
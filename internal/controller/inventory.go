@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"math"
+)
+
+// InventoryFile lists every command of the file at inputPath, see transformer.Inventory.
+// Nothing is written. A URL or compressed input is read as ProcessFileResult reads it, and
+// the file is read at once whatever its size; lines and columns count from after a BOM.
+func InventoryFile(inputPath string, opts config.Options) ([]transformer.Occurrence, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	if err := transformer.ValidateAliases(opts.CommandAliases); err != nil {
+		return nil, fmt.Errorf("invalid options: %w", err)
+	}
+	codec := opts.CompressionOf(inputPath)
+	if _, err := inputSize(inputPath, codec); err != nil {
+		return nil, err
+	}
+
+	source, err := openInput(inputPath, codec, opts)
+	if err != nil {
+		return nil, inputError(err)
+	}
+	raw, err := io.ReadAll(io.NewSectionReader(source, 0, math.MaxInt64))
+	source.Close()
+	if err != nil {
+		return nil, inputError(fmt.Errorf("failed to read file: %w", err))
+	}
+	data, _, err := parser.DecodeChunk(raw, resolveEncoding(opts.Encoding, raw))
+	if err != nil {
+		return nil, inputError(fmt.Errorf("failed to read file: %w", err))
+	}
+	data, _ = parser.StripBOM(data)
+	return transformer.Inventory(string(data), opts), nil
+}
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"log/slog"
+	"time"
+)
+
+// runStats adds up what one file went through, logged when it is done
+type runStats struct {
+	chunks    int
+	bytesIn   int64
+	bytesOut  int64
+	restarted bool
+	warnings  int                   // warnings reported
+	clamped   int                   // of them, short counts of commands that were applied
+	reported  []transformer.Warning // the warnings of this run, with their lines in the file
+	applied   map[string]int        // words changed per command name
+	read      time.Duration         // reading and decoding
+	transform time.Duration
+	write     time.Duration // encoding and writing
+}
+
+// countApplied adds the words changed per command name of a transformed text
+func (s *runStats) countApplied(applied map[string]int) {
+	for command, words := range applied {
+		s.count(command, words)
+	}
+}
+
+// countSegment adds the counts of the commands on the first lines of a segment, or of all
+// of them for the last one. The commands after those lines come again with the carried text.
+func (s *runStats) countSegment(counts []transformer.CommandCount, lines int, last bool) {
+	for _, count := range counts {
+		if last || count.Line <= lines {
+			s.count(count.Command, count.Words)
+		}
+	}
+}
+
+// count adds words changed by command
+func (s *runStats) count(command string, words int) {
+	if s.applied == nil {
+		s.applied = map[string]int{}
+	}
+	s.applied[command] += words
+}
+
+// since adds the time elapsed from start to stage
+func since(stage *time.Duration, start time.Time) {
+	*stage += time.Since(start)
+}
+
+// loggerOf returns the logger of opts, one that discards everything when there is none
+func loggerOf(opts config.Options) *slog.Logger {
+	if opts.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return opts.Logger
+}
+
+// logFile reports a processed file with its stage timings
+func logFile(logger *slog.Logger, inputPath, outputPath string, stats runStats, elapsed time.Duration) {
+	logger.Info("processed file",
+		"input", inputPath,
+		"output", outputPath,
+		"chunks", stats.chunks,
+		"bytes_in", stats.bytesIn,
+		"bytes_out", stats.bytesOut,
+		"restarted", stats.restarted,
+		"warnings", stats.warnings,
+		"clamped", stats.clamped,
+		slog.Group("stages",
+			"read", stats.read,
+			"transform", stats.transform,
+			"write", stats.write,
+		),
+		"duration", elapsed,
+	)
+}
@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessFileLogger(t *testing.T) {
+	for _, size := range []int{100, 25000} {
+		line := "it was a apple and the word went on\n"
+		input := "zz (hex)\n" + strings.Repeat(line, size/len(line))
+		inputPath, err := testutils.CreateTestFile(input)
+		if err != nil {
+			t.Fatalf("Failed to create input file: %v", err)
+		}
+		defer testutils.CleanupTestFile(inputPath)
+		outputPath := filepath.Join(t.TempDir(), "logged.txt")
+
+		var logs bytes.Buffer
+		opts := config.DefaultOptions()
+		opts.Logger = slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		if err := ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			t.Fatalf("ProcessFileWithOptions failed: %v", err)
+		}
+
+		messages := map[string]int{}
+		var done map[string]any
+		for _, record := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+			var fields map[string]any
+			if err := json.Unmarshal([]byte(record), &fields); err != nil {
+				t.Fatalf("Log record is not JSON: %q", record)
+			}
+			messages[fields["msg"].(string)]++
+			if fields["msg"] == "processed file" {
+				done = fields
+			}
+		}
+
+		if messages["command not applied"] != 1 || messages["processed file"] != 1 {
+			t.Errorf("%d bytes: unexpected log records %v", size, messages)
+		}
+		if done == nil || done["bytes_in"] != float64(len(input)) || done["stages"] == nil {
+			t.Errorf("%d bytes: unexpected file record %v", size, done)
+			continue
+		}
+		if chunks := int(done["chunks"].(float64)); size > config.CHUNK_BYTES && (chunks < 3 || messages["chunk"] != chunks) {
+			t.Errorf("%d bytes: %d chunks with %d chunk records", size, chunks, messages["chunk"])
+		}
+	}
+
+	// Without a logger nothing is logged and nothing fails
+	inputPath, err := testutils.CreateTestFile("(up) x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer testutils.CleanupTestFile(inputPath)
+	if err := ProcessFileWithOptions(inputPath, filepath.Join(t.TempDir(), "out.txt"), config.DefaultOptions()); err != nil {
+		t.Errorf("Processing without a logger failed: %v", err)
+	}
+}
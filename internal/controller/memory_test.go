@@ -0,0 +1,107 @@
+package controller
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessFileMaxMemory(t *testing.T) {
+	dir := t.TempDir()
+	limited := config.DefaultOptions()
+	limited.MaxMemory = config.MIN_MAX_MEMORY
+	limit := int(carryLimit(limited))
+
+	// Many short lines are cut after every chunk and fit any limit
+	lines := filepath.Join(dir, "lines.txt")
+	if err := os.WriteFile(lines, []byte(strings.Repeat("it was a apple (up) here , ' she said '\n", 4*limit/40)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	limitedOut, plainOut := filepath.Join(dir, "limited.txt"), filepath.Join(dir, "plain.txt")
+	if err := ProcessFileWithOptions(lines, limitedOut, limited); err != nil {
+		t.Fatalf("Expected short lines to fit the limit, got %v", err)
+	}
+	if err := ProcessFile(lines, plainOut); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(limitedOut)
+	want, _ := os.ReadFile(plainOut)
+	if !bytes.Equal(got, want) {
+		t.Error("Output under the memory limit differs from the unlimited run")
+	}
+
+	// A line without a cut must be transformed at once
+	long := filepath.Join(dir, "long.txt")
+	if err := os.WriteFile(long, []byte("first line\n"+strings.Repeat("word ", 2*limit/5)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := ProcessFileWithOptions(long, limitedOut, limited)
+	if !errors.Is(err, ErrMemory) || !strings.Contains(err.Error(), "from line 2") {
+		t.Errorf("Expected a memory error at line 2, got %v", err)
+	}
+	if err := ProcessFile(long, plainOut); err != nil {
+		t.Errorf("Expected the long line to pass without a limit, got %v", err)
+	}
+}
+
+// Commands reaching back past the carried words rewrite the output chunk by chunk: the run
+// fits the memory limit and writes what a single pass over the whole text writes
+func TestProcessFileMaxMemoryReachingBack(t *testing.T) {
+	limited := config.DefaultOptions()
+	limited.MaxMemory = config.MIN_MAX_MEMORY
+	limited.ShortCountPolicy = config.SHORT_COUNT_WARN
+	limit := int(carryLimit(limited))
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"long counts", differentialInput(26, 4*limit, "(cap, 50)", "(up, 300)")},
+		{"count all", differentialInput(27, 4*limit, "(cap, 50)") + "\nend (low, all)"},
+		{"counts all", differentialInput(28, 2*limit, "(low, 40)") + "\nmiddle (up, all)\n" + differentialInput(29, 2*limit) + "\nend (cap, all)"},
+	}
+	for _, test := range tests {
+		inputPath := filepath.Join(t.TempDir(), "input.txt")
+		if err := os.WriteFile(inputPath, []byte(test.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		outputPath := filepath.Join(t.TempDir(), "output.txt")
+		var warnings bytes.Buffer
+		opts := limited
+		opts.Warnings = &warnings
+		result, err := ProcessFileResult(inputPath, outputPath, opts)
+		if err != nil {
+			t.Fatalf("%s: expected the run to fit %d bytes, got %v", test.name, limited.MaxMemory, err)
+		}
+		if !result.Restarted {
+			t.Errorf("%s: expected a command to reach back into written output", test.name)
+		}
+
+		output, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected, expectedWarnings := transformer.ProcessTextWarnings(test.input, limited)
+		if string(output) != expected {
+			at := 0
+			for at < len(output) && at < len(expected) && output[at] == expected[at] {
+				at++
+			}
+			t.Errorf("%s: output differs from a single pass at byte %d: %q vs %q",
+				test.name, at, output[at:min(at+40, len(output))], expected[at:min(at+40, len(expected))])
+		}
+		var expectedReport strings.Builder
+		for _, warning := range expectedWarnings {
+			fmt.Fprintf(&expectedReport, "%s:%s\n", inputPath, warning)
+		}
+		if warnings.String() != expectedReport.String() {
+			t.Errorf("%s: warnings differ from a single pass:\n%s\nexpected:\n%s", test.name, warnings.String(), expectedReport.String())
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package controller
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+)
+
+// reach is a backward command that changes words of the output written before its segment
+type reach struct {
+	line, index       int   // it is Segment.Reaches[index] of the segment after line lines of the file
+	start, end        int64 // the words of the file it changes there, counted from the first one
+	transformer.Reach       // Changed and Problems collect what it did there while rewriting
+}
+
+// planReaches adds to plan the commands of reaches, those of the segment state starts, that
+// change words before the segment. A command goes no further back than the last boundary
+// of the command scope.
+func planReaches(plan []reach, reaches []transformer.Reach, state chunkState) []reach {
+	for i, r := range reaches {
+		if start := max(state.Scope, state.Words-int64(r.Words)); start < state.Words {
+			plan = append(plan, reach{line: state.LineBase, index: i, start: start, end: state.Words, Reach: r})
+		}
+	}
+	return plan
+}
+
+// rewrite writes the output again once the plan holds every command that changes words
+// written before its segment
+type rewrite struct {
+	plan []reach // in text order, from the first command whose segment is not written yet
+}
+
+// piece returns the transformation of the piece of a segment up to cut, which starts at the
+// word state.Words of the file: that of segment, the whole segment text transformed, unless
+// a planned command changes words of the piece or is written in it. The commands of
+// later pieces change the last words of the piece in text order, and their own pieces are
+// told what they did.
+func (rw *rewrite) piece(text string, cut transformer.Cut, segment transformer.Segment, state chunkState, opts config.Options) transformer.Segment {
+	var before, after []transformer.Reach
+	own := 0
+	for ; own < len(rw.plan) && rw.plan[own].line == state.LineBase; own++ {
+		if before == nil {
+			before = make([]transformer.Reach, len(segment.Reaches))
+		}
+		r := rw.plan[own]
+		before[r.index] = transformer.Reach{Words: int(r.end - r.start), Changed: r.Changed, Problems: r.Problems}
+	}
+	clear(rw.plan[:own])
+	rw.plan = rw.plan[own:]
+
+	end := state.Words + int64(cut.Words)
+	var targets []*reach
+	for i := range rw.plan {
+		if r := &rw.plan[i]; r.start < end {
+			after = append(after, transformer.Reach{Command: r.Command, Words: int(end - max(r.start, state.Words))})
+			targets = append(targets, r)
+		}
+	}
+	if before == nil && after == nil {
+		segment.Output = segment.Output[:cut.Output]
+		return segment
+	}
+	piece, reached := transformer.ProcessPiece(text[:cut.Input], opts, before, after)
+	for i, r := range reached {
+		targets[i].Changed += r.Changed
+		targets[i].Problems = append(targets[i].Problems, r.Problems...)
+	}
+	return piece
+}
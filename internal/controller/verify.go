@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"unicode/utf8"
+)
+
+// How much of a changed line the verify error quotes
+const VERIFY_QUOTE_RUNES = 60
+
+// verifyOutput transforms the written output again and fails when that changes it. A correct
+// run is a fixed point: no commands are left and spacing, quotes and articles are settled.
+func verifyOutput(outputPath string, opts config.Options) error {
+	dir, err := os.MkdirTemp("", "go-reloaded-verify-*")
+	if err != nil {
+		return fmt.Errorf("failed to verify output: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The second run only produces text to compare, it is not traced, logged or throttled
+	opts.Verify, opts.Strict, opts.IOThrottleMBps = false, false, 0
+	opts.DebugChunks, opts.Warnings, opts.Trace, opts.Logger = nil, nil, nil, nil
+	again := filepath.Join(dir, "again")
+	if err := ProcessFileWithOptions(outputPath, again, opts); err != nil {
+		return fmt.Errorf("failed to verify output: %w", err)
+	}
+
+	// A compressed output is compared as text
+	line, before, after, err := firstChangedLine(outputPath, again, opts)
+	if err != nil {
+		return fmt.Errorf("failed to verify output: %w", err)
+	}
+	if line == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: transforming %s again changes line %d: %q becomes %q", ErrVerify, outputPath, line, quoteLine(before), quoteLine(after))
+}
+
+// firstChangedLine returns the number and both versions of the first line that differs
+// between two files, decompressed as opts says, 0 when they are the same
+func firstChangedLine(a, b string, opts config.Options) (int, string, string, error) {
+	fileA, err := parser.OpenDecompressed(a, opts.CompressionOf(a))
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer fileA.Close()
+	fileB, err := parser.OpenDecompressed(b, opts.CompressionOf(b))
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer fileB.Close()
+
+	readerA, readerB := bufio.NewReader(fileA), bufio.NewReader(fileB)
+	for line := 1; ; line++ {
+		lineA, errA := readerA.ReadString('\n')
+		lineB, errB := readerB.ReadString('\n')
+		if errA != nil && errA != io.EOF {
+			return 0, "", "", errA
+		}
+		if errB != nil && errB != io.EOF {
+			return 0, "", "", errB
+		}
+		if lineA != lineB {
+			return line, lineA, lineB, nil
+		}
+		if errA == io.EOF || errB == io.EOF {
+			return 0, "", "", nil
+		}
+	}
+}
+
+// quoteLine shortens a line for an error message
+func quoteLine(line string) string {
+	if utf8.RuneCountInString(line) <= VERIFY_QUOTE_RUNES {
+		return line
+	}
+	return string([]rune(line)[:VERIFY_QUOTE_RUNES]) + "…"
+}
@@ -0,0 +1,83 @@
+package controller
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestProcessFileVerify(t *testing.T) {
+	dir := t.TempDir()
+	verify := config.DefaultOptions()
+	verify.Verify = true
+	grouped := verify
+	grouped.DigitGrouping = config.GROUPING_EN
+
+	tests := []struct {
+		name    string
+		input   string
+		opts    config.Options
+		changed string // line reported as changing, "" for a fixed point
+	}{
+		{"fixed point", "it was a apple (up) , ' right ' ?\n1E (hex) files\n", verify, ""},
+		{"chunked fixed point", strings.Repeat("He said : ' this is incredible (cap, 2) ! ' \n", 2*config.CHUNK_BYTES/40), verify, ""},
+		{"grouped digits", "first line\nFFFFF (hex), 1,048,575 (tohex) bytes\n", grouped, ""},
+		// An escaped command is written as a command the second run applies
+		{"escaped command", "first line\nsay it \\(up\\) bytes\n", verify, `line 2: "say it (up) bytes\n" becomes "say IT bytes\n"`},
+	}
+	for _, test := range tests {
+		input, output := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+		if err := os.WriteFile(input, []byte(test.input), 0644); err != nil {
+			t.Fatal(err)
+		}
+		err := ProcessFileWithOptions(input, output, test.opts)
+		if test.changed == "" {
+			if err != nil {
+				t.Errorf("%s: expected a fixed point, got %v", test.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrVerify) || !strings.Contains(err.Error(), test.changed) {
+			t.Errorf("%s: expected a verify error for %s, got %v", test.name, test.changed, err)
+		}
+		if data, readErr := os.ReadFile(output); readErr != nil || !strings.Contains(string(data), "(up)") {
+			t.Errorf("%s: the output should be written before verifying, got %q, %v", test.name, data, readErr)
+		}
+	}
+}
+
+func TestFirstChangedLine(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	base := write("base", "a\nb\nc")
+
+	tests := []struct {
+		other         string
+		line          int
+		before, after string
+	}{
+		{"a\nb\nc", 0, "", ""},
+		{"a\nB\nc", 2, "b\n", "B\n"},
+		{"a\nb\nc\n", 3, "c", "c\n"},
+		{"a\nb", 2, "b\n", "b"},
+	}
+	for i, test := range tests {
+		line, before, after, err := firstChangedLine(base, write("other", test.other), config.DefaultOptions())
+		if err != nil || line != test.line || before != test.before || after != test.after {
+			t.Errorf("Case %d: got line %d %q %q, %v, expected line %d %q %q", i, line, before, after, err, test.line, test.before, test.after)
+		}
+	}
+
+	if quoted := quoteLine(strings.Repeat("é", VERIFY_QUOTE_RUNES+5)); quoted != strings.Repeat("é", VERIFY_QUOTE_RUNES)+"…" {
+		t.Errorf("Unexpected shortened line %q", quoted)
+	}
+}
@@ -0,0 +1,38 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"io"
+)
+
+// CompressSink returns a sink compressing with codec, a COMPRESS_* value other than
+// COMPRESS_AUTO, into sink. Closing it finishes the stream and closes sink.
+func CompressSink(sink Sink, codec string) (Sink, error) {
+	switch codec {
+	case config.COMPRESS_GZIP:
+		return &compressed{WriteCloser: gzip.NewWriter(sink), sink: sink}, nil
+	case config.COMPRESS_ZSTD:
+		encoder, err := zstd.NewWriter(sink, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return &compressed{WriteCloser: encoder, sink: sink}, nil
+	}
+	return sink, nil
+}
+
+// compressed writes a gzip or zstd stream into a sink
+type compressed struct {
+	io.WriteCloser
+	sink Sink
+}
+
+func (c *compressed) Close() error {
+	err := c.WriteCloser.Close()
+	if closeErr := c.sink.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
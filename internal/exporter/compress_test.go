@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompressSink(t *testing.T) {
+	readers := map[string]func(io.Reader) (io.Reader, error){
+		config.COMPRESS_GZIP: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		config.COMPRESS_ZSTD: func(r io.Reader) (io.Reader, error) { return zstd.NewReader(r) },
+	}
+	for codec, newReader := range readers {
+		path := filepath.Join(t.TempDir(), "out")
+		file, err := CreateSink(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sink, err := CompressSink(file, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, piece := range []string{"first ", "second"} {
+			if _, err := io.WriteString(sink, piece); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("%s: Close failed: %v", codec, err)
+		}
+
+		compressed, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader, err := newReader(compressed)
+		if err != nil {
+			t.Fatalf("Output is not %s: %v", codec, err)
+		}
+		if data, err := io.ReadAll(reader); err != nil || string(data) != "first second" {
+			t.Errorf("%s: expected %q, got %q, %v", codec, "first second", data, err)
+		}
+		compressed.Close()
+	}
+}
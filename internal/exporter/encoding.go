@@ -0,0 +1,20 @@
+package exporter
+
+import (
+	"fmt"
+
+	"golang.org/x/text/encoding"
+)
+
+// Encode converts UTF-8 output back to the file encoding, a nil codec keeps UTF-8.
+// Characters the encoding cannot represent are an error rather than silently replaced.
+func Encode(content string, codec encoding.Encoding) (string, error) {
+	if codec == nil {
+		return content, nil
+	}
+	encoded, err := codec.NewEncoder().String(content)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode output: %w", err)
+	}
+	return encoded, nil
+}
@@ -0,0 +1,22 @@
+package exporter
+
+import (
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+func TestEncode(t *testing.T) {
+	encoded, err := Encode("café", charmap.ISO8859_1)
+	if err != nil || encoded != "caf\xe9" {
+		t.Errorf("Expected Latin-1 \"caf\\xe9\", got %q, %v", encoded, err)
+	}
+
+	if encoded, _ := Encode("café", nil); encoded != "café" {
+		t.Errorf("Expected UTF-8 passthrough, got %q", encoded)
+	}
+
+	if _, err := Encode("“quoted”", charmap.ISO8859_1); err == nil {
+		t.Error("Expected error for characters Latin-1 cannot represent")
+	}
+}
@@ -2,10 +2,30 @@ package exporter
 
 import (
 	"fmt"
+	"io"
 	"os"
 	filepath "path/filepath"
 )
 
+// Sink is an output written piece by piece through one descriptor for the whole run
+type Sink interface {
+	io.Writer
+	io.Closer
+}
+
+// CreateSink creates the file at path and its directory, an existing file is truncated
+func CreateSink(path string) (Sink, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file %s: %w", path, err)
+	}
+	return file, nil
+}
+
 // WriteChunk writes content to a file, creating it if it doesn't exist
 func WriteChunk(filePath, content string) error {
 	// Create directory if it doesn't exist
@@ -45,4 +65,27 @@ func AppendChunk(filePath, content string) error {
 	}
 	
 	return nil
-}
\ No newline at end of file
+}
+// ResumeSink opens the existing file at path to continue writing it after its first size
+// bytes, anything written after them is cut off
+func ResumeSink(path string, size int64) (Sink, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	info, err := file.Stat()
+	if err == nil && info.Size() < size {
+		err = fmt.Errorf("%s has %d bytes, expected at least %d", path, info.Size(), size)
+	}
+	if err == nil {
+		err = file.Truncate(size)
+	}
+	if err == nil {
+		_, err = file.Seek(size, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return file, nil
+}
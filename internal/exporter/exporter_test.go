@@ -1,7 +1,7 @@
 package exporter
 
 import (
-	"go-reloaded/internal/testutils"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -176,3 +176,28 @@ func TestAppendChunkMultiple(t *testing.T) {
 		t.Errorf("Multiple append content mismatch. Expected: %q, Got: %q", expected, string(data))
 	}
 }
+
+func TestCreateSinkTruncates(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "nested", "output.txt")
+	for _, content := range []string{"a longer first run", "second"} {
+		sink, err := CreateSink(outputPath)
+		if err != nil {
+			t.Fatalf("CreateSink failed: %v", err)
+		}
+		for _, piece := range []string{content[:3], content[3:]} {
+			if _, err := sink.Write([]byte(piece)); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		data, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("Failed to read output file: %v", err)
+		}
+		if string(data) != content {
+			t.Errorf("Content mismatch. Expected: %q, Got: %q", content, string(data))
+		}
+	}
+}
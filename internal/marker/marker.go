@@ -0,0 +1,79 @@
+package marker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"io"
+	"os"
+	"strings"
+)
+
+// Extended attribute holding the marker of a processed file
+const XATTR_NAME = "user.go-reloaded.processed"
+
+// Version prefix of the marker value, bumped when the transformation changes its output
+const MARKER_VERSION = "go-reloaded/1"
+
+// ErrUnsupported is returned where extended attributes are not available
+var ErrUnsupported = errors.New("processed markers are not supported on this platform")
+
+// Fingerprint identifies the options that shape the output, options that only affect
+// how fast a file is processed, traced or logged are left out
+func Fingerprint(opts config.Options) string {
+	opts.IOThrottleMBps, opts.Strict, opts.Verify = 0, false, false
+	opts.Checkpoint, opts.Resume, opts.MaxMemory = false, false, 0
+	opts.FetchTimeout, opts.MaxFetchBytes = 0, 0
+	opts.DebugChunks, opts.Warnings, opts.Trace, opts.Logger = nil, nil, nil, nil
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%+v", opts)))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Mark records that path holds output produced with opts.
+// The marker covers the current content, editing the file invalidates it.
+func Mark(path string, opts config.Options) error {
+	sum, err := contentHash(path)
+	if err != nil {
+		return err
+	}
+	value := MARKER_VERSION + " " + sum + " " + Fingerprint(opts)
+	if err := setAttr(path, XATTR_NAME, []byte(value)); err != nil {
+		return fmt.Errorf("failed to mark %s as processed: %w", path, err)
+	}
+	return nil
+}
+
+// IsProcessed reports whether path carries a marker written by Mark with the same options
+// and its content did not change since. Unmarked files and platforms without extended
+// attributes report false.
+func IsProcessed(path string, opts config.Options) (bool, error) {
+	value, err := getAttr(path, XATTR_NAME)
+	if err != nil || value == nil {
+		return false, err
+	}
+	fields := strings.Fields(string(value))
+	if len(fields) != 3 || fields[0] != MARKER_VERSION || fields[2] != Fingerprint(opts) {
+		return false, nil
+	}
+	sum, err := contentHash(path)
+	if err != nil {
+		return false, err
+	}
+	return sum == fields[1], nil
+}
+
+// contentHash returns the hex SHA-256 of a file
+func contentHash(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
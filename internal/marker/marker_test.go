@@ -0,0 +1,53 @@
+package marker
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarkAndIsProcessed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "clean.txt")
+	if err := os.WriteFile(path, []byte("HELLO world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	opts := config.DefaultOptions()
+
+	if processed, err := IsProcessed(path, opts); err != nil || processed {
+		t.Fatalf("Unmarked file reported as processed: %v, %v", processed, err)
+	}
+	if err := Mark(path, opts); err != nil {
+		if errors.Is(err, ErrUnsupported) || errors.Is(err, errors.ErrUnsupported) {
+			t.Skipf("extended attributes not available: %v", err)
+		}
+		t.Fatal(err)
+	}
+	if processed, err := IsProcessed(path, opts); err != nil || !processed {
+		t.Errorf("Marked file not reported as processed: %v, %v", processed, err)
+	}
+
+	// Throttling and logging do not change the output, other options do
+	throttled := opts
+	throttled.IOThrottleMBps = 5
+	throttled.Warnings = os.Stderr
+	throttled.Logger = slog.Default()
+	if processed, _ := IsProcessed(path, throttled); !processed {
+		t.Error("Throttled run should accept the marker")
+	}
+	smart := opts
+	smart.SmartQuotes = true
+	if processed, _ := IsProcessed(path, smart); processed {
+		t.Error("Marker written with other options was accepted")
+	}
+
+	// Editing the file invalidates the marker
+	if err := os.WriteFile(path, []byte("hello (up) world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if processed, _ := IsProcessed(path, opts); processed {
+		t.Error("Edited file still reported as processed")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package marker
+
+import (
+	"errors"
+	"syscall"
+)
+
+func setAttr(path, name string, value []byte) error {
+	return syscall.Setxattr(path, name, value, 0)
+}
+
+// getAttr returns nil without error when the file has no such attribute
+func getAttr(path, name string) ([]byte, error) {
+	buf := make([]byte, 256)
+	n, err := syscall.Getxattr(path, name, buf)
+	if errors.Is(err, syscall.ENODATA) || errors.Is(err, syscall.ENOTSUP) {
+		return nil, nil
+	}
+	if errors.Is(err, syscall.ERANGE) {
+		return nil, nil // longer than any marker, not ours
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
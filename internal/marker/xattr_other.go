@@ -0,0 +1,12 @@
+//go:build !linux
+
+package marker
+
+func setAttr(path, name string, value []byte) error {
+	return ErrUnsupported
+}
+
+// getAttr never finds a marker on this platform
+func getAttr(path, name string) ([]byte, error) {
+	return nil, nil
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Metric kinds, mapped to the closest type of each backend
+const (
+	KIND_COUNTER = iota // monotonic total: statsd "c", OTLP monotonic sum
+	KIND_GAUGE          // last value: statsd "g", OTLP gauge
+	KIND_TIMER          // duration in milliseconds: statsd "ms", OTLP gauge with unit ms
+)
+
+// Prefix of every metric name
+const METRIC_PREFIX = "go_reloaded"
+
+// Metric is one measurement of a run
+type Metric struct {
+	Name  string // without METRIC_PREFIX, e.g. "bytes_read"
+	Kind  int    // KIND_*
+	Unit  string // UCUM unit for OTLP: "By", "ms", "1"
+	Value float64
+}
+
+// Exporter sends metrics to an observability backend
+type Exporter interface {
+	Export(metrics []Metric) error
+	Close() error
+}
+
+// New returns the exporter for a destination URL: statsd://host:port, or otlp://host:port
+// and otlp+https://host:port for OTLP over HTTP (a path replaces the default /v1/metrics)
+func New(destination string) (Exporter, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics destination %q: %w", destination, err)
+	}
+	switch u.Scheme {
+	case "statsd":
+		return NewStatsd(u.Host)
+	case "otlp", "otlp+http", "otlp+https":
+		endpoint := url.URL{Scheme: "http", Host: u.Host, Path: u.Path}
+		if u.Scheme == "otlp+https" {
+			endpoint.Scheme = "https"
+		}
+		if endpoint.Path == "" {
+			endpoint.Path = "/v1/metrics"
+		}
+		return NewOTLP(endpoint.String()), nil
+	}
+	return nil, fmt.Errorf("unknown metrics destination %q, expected statsd://host:port or otlp://host:port", destination)
+}
+
+// FileRun returns the metrics of processing one file
+func FileRun(bytesRead, bytesWritten int64, elapsed time.Duration, failed bool) []Metric {
+	status := "files_processed"
+	if failed {
+		status = "files_failed"
+	}
+	metrics := []Metric{
+		{Name: status, Kind: KIND_COUNTER, Unit: "1", Value: 1},
+		{Name: "bytes_read", Kind: KIND_COUNTER, Unit: "By", Value: float64(bytesRead)},
+		{Name: "bytes_written", Kind: KIND_COUNTER, Unit: "By", Value: float64(bytesWritten)},
+		{Name: "duration", Kind: KIND_TIMER, Unit: "ms", Value: float64(elapsed.Microseconds()) / 1000},
+	}
+	if seconds := elapsed.Seconds(); seconds > 0 && !failed {
+		metrics = append(metrics, Metric{Name: "throughput", Kind: KIND_GAUGE, Unit: "By/s", Value: float64(bytesRead) / seconds})
+	}
+	return metrics
+}
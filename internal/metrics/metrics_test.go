@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileRun(t *testing.T) {
+	metrics := FileRun(2048, 1024, 2*time.Second, false)
+	values := map[string]float64{}
+	for _, metric := range metrics {
+		values[metric.Name] = metric.Value
+	}
+	if values["files_processed"] != 1 || values["bytes_read"] != 2048 || values["duration"] != 2000 || values["throughput"] != 1024 {
+		t.Errorf("Unexpected metrics: %v", values)
+	}
+
+	failed := FileRun(10, 0, time.Second, true)
+	if failed[0].Name != "files_failed" || len(failed) != 4 {
+		t.Errorf("Expected a failure counter and no throughput, got %+v", failed)
+	}
+}
+
+func TestNew(t *testing.T) {
+	otlp, err := New("otlp+https://collector:4318")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if endpoint := otlp.(*OTLP).endpoint; endpoint != "https://collector:4318/v1/metrics" {
+		t.Errorf("Unexpected OTLP endpoint %q", endpoint)
+	}
+	otlp, _ = New("otlp://collector:4318/custom")
+	if endpoint := otlp.(*OTLP).endpoint; endpoint != "http://collector:4318/custom" {
+		t.Errorf("Unexpected OTLP endpoint %q", endpoint)
+	}
+	if _, err := New("prometheus://localhost"); err == nil {
+		t.Error("Expected error for an unknown scheme")
+	}
+}
+
+func TestStatsdExport(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer listener.Close()
+
+	exporter, err := New("statsd://" + listener.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+	if err := exporter.Export(FileRun(4096, 4000, 1500*time.Microsecond, false)[:4]); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, STATSD_MAX_PACKET)
+	listener.SetDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := listener.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "go_reloaded.files_processed:1|c\ngo_reloaded.bytes_read:4096|c\ngo_reloaded.bytes_written:4000|c\ngo_reloaded.duration:1.5|ms"
+	if string(buf[:n]) != expected {
+		t.Errorf("Expected packet %q, got %q", expected, string(buf[:n]))
+	}
+}
+
+func TestStatsdSplitsPackets(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("UDP not available: %v", err)
+	}
+	defer listener.Close()
+	exporter, err := NewStatsd(listener.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer exporter.Close()
+
+	many := make([]Metric, 100)
+	for i := range many {
+		many[i] = Metric{Name: "metric_with_a_rather_long_name", Kind: KIND_GAUGE, Value: 1}
+	}
+	if err := exporter.Export(many); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := 0
+	buf := make([]byte, 65536)
+	listener.SetDeadline(time.Now().Add(5 * time.Second))
+	for lines < len(many) {
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("Received %d of %d lines: %v", lines, len(many), err)
+		}
+		if n > STATSD_MAX_PACKET {
+			t.Errorf("Packet of %d bytes exceeds the limit", n)
+		}
+		lines += strings.Count(string(buf[:n]), "\n") + 1
+	}
+}
+
+func TestOTLPExport(t *testing.T) {
+	var received otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/metrics" || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s %s", r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := New("otlp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exporter.(*OTLP).now = func() time.Time { return time.Unix(1, 0) }
+	if err := exporter.Export(FileRun(100, 90, time.Second, false)); err != nil {
+		t.Fatal(err)
+	}
+
+	metrics := received.ResourceMetrics[0].ScopeMetrics[0].Metrics
+	if len(metrics) != 5 {
+		t.Fatalf("Expected 5 metrics, got %+v", metrics)
+	}
+	read := metrics[1]
+	if read.Name != "go_reloaded.bytes_read" || read.Unit != "By" || read.Sum == nil || !read.Sum.IsMonotonic ||
+		read.Sum.DataPoints[0].AsDouble != 100 || read.Sum.DataPoints[0].TimeUnixNano != "1000000000" {
+		t.Errorf("Unexpected counter %+v", read)
+	}
+	if metrics[3].Gauge == nil || metrics[3].Unit != "ms" {
+		t.Errorf("Expected the duration as a gauge in ms, got %+v", metrics[3])
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "overloaded", http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	if err := NewOTLP(failing.URL).Export(FileRun(1, 1, time.Second, false)); err == nil || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the collector error, got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.Counter("requests_total", "Requests by code.", "code")
+	registry.Counter("errors_total", "Errors.")
+	latency := registry.Histogram("latency_seconds", "Latency.", []float64{0.1, 1})
+
+	requests.Add(1, "200")
+	requests.Add(2, "200")
+	requests.Add(1, "a\"b\\c\nd")
+	latency.Observe(0.05)
+	latency.Observe(0.1)
+	latency.Observe(3)
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# HELP go_reloaded_requests_total Requests by code.
+# TYPE go_reloaded_requests_total counter
+go_reloaded_requests_total{code="200"} 3
+go_reloaded_requests_total{code="a\"b\\c\nd"} 1
+# HELP go_reloaded_errors_total Errors.
+# TYPE go_reloaded_errors_total counter
+go_reloaded_errors_total 0
+# HELP go_reloaded_latency_seconds Latency.
+# TYPE go_reloaded_latency_seconds histogram
+go_reloaded_latency_seconds_bucket{le="0.1"} 2
+go_reloaded_latency_seconds_bucket{le="1"} 2
+go_reloaded_latency_seconds_bucket{le="+Inf"} 3
+go_reloaded_latency_seconds_sum 3.15
+go_reloaded_latency_seconds_count 3
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s", out.String())
+	}
+
+	recorder := httptest.NewRecorder()
+	registry.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Header().Get("Content-Type") != PROMETHEUS_CONTENT_TYPE || recorder.Body.String() != expected {
+		t.Errorf("Unexpected response %q", recorder.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,121 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// OTLP aggregation temporality of counters: every export carries the delta of one run
+const OTLP_TEMPORALITY_DELTA = 1
+
+// OTLP posts metrics to an OpenTelemetry collector with the OTLP/HTTP JSON encoding
+type OTLP struct {
+	endpoint string
+	client   *http.Client
+	now      func() time.Time // replaceable clock for tests
+}
+
+// NewOTLP returns an exporter posting to endpoint, e.g. http://localhost:4318/v1/metrics
+func NewOTLP(endpoint string) *OTLP {
+	return &OTLP{endpoint: endpoint, client: &http.Client{Timeout: 10 * time.Second}, now: time.Now}
+}
+
+// Export posts the metrics in one request
+func (o *OTLP) Export(metrics []Metric) error {
+	body, err := json.Marshal(o.request(metrics))
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Post(o.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send OTLP metrics: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP collector at %s answered %s", o.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// Close has nothing to release, requests are not kept open
+func (o *OTLP) Close() error {
+	return nil
+}
+
+// the subset of the OTLP metrics data model used by go-reloaded
+type otlpRequest struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   map[string]string `json:"scope"`
+	Metrics []otlpMetric      `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Unit  string     `json:"unit,omitempty"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+}
+
+type otlpSum struct {
+	DataPoints             []otlpDataPoint `json:"dataPoints"`
+	AggregationTemporality int             `json:"aggregationTemporality"`
+	IsMonotonic            bool            `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpDataPoint `json:"dataPoints"`
+}
+
+type otlpDataPoint struct {
+	AsDouble     float64 `json:"asDouble"`
+	TimeUnixNano string  `json:"timeUnixNano"` // 64-bit integers are strings in OTLP JSON
+}
+
+// builds the request body for one export
+func (o *OTLP) request(metrics []Metric) otlpRequest {
+	now := strconv.FormatInt(o.now().UnixNano(), 10)
+	converted := make([]otlpMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		points := []otlpDataPoint{{AsDouble: metric.Value, TimeUnixNano: now}}
+		m := otlpMetric{Name: METRIC_PREFIX + "." + metric.Name, Unit: metric.Unit}
+		if metric.Kind == KIND_COUNTER {
+			m.Sum = &otlpSum{DataPoints: points, AggregationTemporality: OTLP_TEMPORALITY_DELTA, IsMonotonic: true}
+		} else {
+			m.Gauge = &otlpGauge{DataPoints: points}
+		}
+		converted = append(converted, m)
+	}
+
+	return otlpRequest{ResourceMetrics: []otlpResourceMetrics{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": "go-reloaded"}},
+		}},
+		ScopeMetrics: []otlpScopeMetrics{{
+			Scope:   map[string]string{"name": "go-reloaded"},
+			Metrics: converted,
+		}},
+	}}}
+}
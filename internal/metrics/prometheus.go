@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content type of the Prometheus text exposition format
+const PROMETHEUS_CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// Upper bounds in seconds of the default latency histogram buckets
+var DefaultLatencyBuckets = []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Registry holds the metrics a long running process updates and serves them to a Prometheus
+// scraper. Metric names get METRIC_PREFIX. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []family // in registration order
+}
+
+// family is one metric with all its series
+type family interface {
+	write(w io.Writer) error
+}
+
+// NewRegistry returns an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers a counter with one series per combination of values of labels
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	counter := &Counter{name: METRIC_PREFIX + "_" + name, help: help, labels: labels, values: map[string]float64{}}
+	r.mu.Lock()
+	r.families = append(r.families, counter)
+	r.mu.Unlock()
+	return counter
+}
+
+// Histogram registers a histogram with the given ascending bucket upper bounds
+func (r *Registry) Histogram(name, help string, buckets []float64) *Histogram {
+	histogram := &Histogram{name: METRIC_PREFIX + "_" + name, help: help, buckets: buckets, counts: make([]uint64, len(buckets))}
+	r.mu.Lock()
+	r.families = append(r.families, histogram)
+	r.mu.Unlock()
+	return histogram
+}
+
+// WriteText writes every metric in the Prometheus text exposition format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := slices.Clone(r.families)
+	r.mu.Unlock()
+	for _, family := range families {
+		if err := family.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the metrics to a scraper, for GET /metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", PROMETHEUS_CONTENT_TYPE)
+		r.WriteText(w)
+	})
+}
+
+// Counter is a monotonic total, one series per combination of label values
+type Counter struct {
+	name, help string
+	labels     []string
+
+	mu     sync.Mutex
+	values map[string]float64 // by the rendered label set
+}
+
+// Add increases the series of labelValues, given in the order of the registered labels
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if len(labelValues) != len(c.labels) {
+		panic(fmt.Sprintf("metric %s has %d labels, got %d values", c.name, len(c.labels), len(labelValues)))
+	}
+	key := labelSet(c.labels, labelValues)
+	c.mu.Lock()
+	c.values[key] += value
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) error {
+	c.mu.Lock()
+	keys := make([]string, 0, len(c.values))
+	for key := range c.values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	// A counter without labels is reported before its first increase
+	if len(keys) == 0 && len(c.labels) == 0 {
+		fmt.Fprintf(&b, "%s 0\n", c.name)
+	}
+	for _, key := range keys {
+		fmt.Fprintf(&b, "%s%s %s\n", c.name, key, formatValue(c.values[key]))
+	}
+	c.mu.Unlock()
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Histogram counts observations in cumulative buckets
+type Histogram struct {
+	name, help string
+	buckets    []float64
+
+	mu     sync.Mutex
+	counts []uint64 // observations per bucket, not cumulative
+	count  uint64
+	sum    float64
+}
+
+// Observe adds one observation
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if i, _ := slices.BinarySearch(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.count++
+	h.sum += value
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	h.mu.Lock()
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	cumulative := uint64(0)
+	for i, bound := range h.buckets {
+		cumulative += h.counts[i]
+		fmt.Fprintf(&b, "%s_bucket{le=\"%s\"} %d\n", h.name, formatValue(bound), cumulative)
+	}
+	fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n", h.name, h.count)
+	fmt.Fprintf(&b, "%s_sum %s\n%s_count %d\n", h.name, formatValue(h.sum), h.name, h.count)
+	h.mu.Unlock()
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// labelSet renders {name="value",...}, "" without labels
+func labelSet(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// escapeLabel escapes a label value as the exposition format requires
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatValue writes a sample value, integers without an exponent
+func formatValue(value float64) string {
+	if value == math.Trunc(value) && math.Abs(value) < 1e15 {
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,72 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// largest datagram sent, stays below common MTUs after IP and UDP headers
+const STATSD_MAX_PACKET = 1432
+
+// Statsd sends metrics as statsd lines over UDP: "go_reloaded.bytes_read:4096|c"
+type Statsd struct {
+	conn net.Conn
+}
+
+// NewStatsd connects to a statsd daemon at addr (host:port)
+func NewStatsd(addr string) (*Statsd, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to statsd at %s: %w", addr, err)
+	}
+	return &Statsd{conn: conn}, nil
+}
+
+// Export sends the metrics, several lines per datagram
+func (s *Statsd) Export(metrics []Metric) error {
+	var packet strings.Builder
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := s.conn.Write([]byte(packet.String()))
+		packet.Reset()
+		if err != nil {
+			return fmt.Errorf("failed to send statsd metrics: %w", err)
+		}
+		return nil
+	}
+
+	for _, metric := range metrics {
+		line := statsdLine(metric)
+		if packet.Len() > 0 && packet.Len()+1+len(line) > STATSD_MAX_PACKET {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if packet.Len() > 0 {
+			packet.WriteByte('\n')
+		}
+		packet.WriteString(line)
+	}
+	return flush()
+}
+
+// Close closes the UDP socket
+func (s *Statsd) Close() error {
+	return s.conn.Close()
+}
+
+// formats one metric in the statsd line protocol
+func statsdLine(metric Metric) string {
+	kind := "g"
+	switch metric.Kind {
+	case KIND_COUNTER:
+		kind = "c"
+	case KIND_TIMER:
+		kind = "ms"
+	}
+	return METRIC_PREFIX + "." + metric.Name + ":" + strconv.FormatFloat(metric.Value, 'f', -1, 64) + "|" + kind
+}
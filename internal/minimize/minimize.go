@@ -0,0 +1,156 @@
+package minimize
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/parser"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Predicate reports whether a text still shows the failure being minimized
+type Predicate func(text string) (bool, error)
+
+// ErrNoFailure is returned when the input given to Minimize does not fail to begin with
+var ErrNoFailure = errors.New("input does not reproduce the failure")
+
+// Result is a minimized input
+type Result struct {
+	Text string
+	Runs int // predicate calls it took
+}
+
+// Minimize shrinks input to a small text for which fails still holds. It removes lines,
+// then words, by delta debugging. Removing text shifts the chunk boundaries, so a last pass
+// only replaces each remaining word by as many x as it has bytes, where that keeps the failure.
+func Minimize(input string, fails Predicate) (Result, error) {
+	result := Result{Text: input}
+	test := func(text string) (bool, error) {
+		result.Runs++
+		return fails(text)
+	}
+
+	ok, err := test(input)
+	if err != nil {
+		return result, err
+	}
+	if !ok {
+		return result, ErrNoFailure
+	}
+
+	for _, split := range []func(string) []string{lines, words} {
+		pieces, err := ddmin(split(result.Text), func(pieces []string) (bool, error) {
+			return test(strings.Join(pieces, ""))
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Text = strings.Join(pieces, "")
+	}
+
+	pieces := words(result.Text)
+	for i, piece := range pieces {
+		word := strings.TrimRightFunc(piece, unicode.IsSpace)
+		filler := strings.Repeat("x", len(word))
+		if word == filler {
+			continue
+		}
+		original := piece
+		pieces[i] = filler + piece[len(word):]
+		ok, err := test(strings.Join(pieces, ""))
+		if err != nil {
+			return result, err
+		}
+		if !ok {
+			pieces[i] = original
+		}
+	}
+	result.Text = strings.Join(pieces, "")
+	return result, nil
+}
+
+// ddmin removes ever smaller runs of pieces while test still holds for the pieces left
+func ddmin(pieces []string, test func([]string) (bool, error)) ([]string, error) {
+	parts := 2
+	for len(pieces) >= 2 {
+		size := (len(pieces) + parts - 1) / parts
+		reduced := false
+		for start := 0; start < len(pieces); start += size {
+			end := min(start+size, len(pieces))
+			rest := append(append([]string{}, pieces[:start]...), pieces[end:]...)
+			ok, err := test(rest)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				pieces, parts, reduced = rest, max(parts-1, 2), true
+				break
+			}
+		}
+		if !reduced {
+			if size == 1 {
+				break
+			}
+			parts = min(2*parts, len(pieces))
+		}
+	}
+	return pieces, nil
+}
+
+// lines splits text after every line break
+func lines(text string) []string {
+	return strings.SplitAfter(text, "\n")
+}
+
+// words splits text into words, each with the whitespace following it
+func words(text string) []string {
+	var pieces []string
+	start := 0
+	inSpace := false
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+		if !space && inSpace {
+			pieces = append(pieces, text[start:i])
+			start = i
+		}
+		inSpace = space
+	}
+	if start < len(text) {
+		pieces = append(pieces, text[start:])
+	}
+	return pieces
+}
+
+// ChunkedDivergence returns a predicate reporting whether processing a text as a file,
+// chunk by chunk once it is larger than config.CHUNK_BYTES, writes something else than
+// transforming the whole text in a single pass. The files are created in dir.
+func ChunkedDivergence(opts config.Options, dir string) Predicate {
+	opts.Encoding, opts.BOM = config.ENCODING_UTF8, config.BOM_STRIP
+	opts.DebugChunks, opts.Warnings, opts.Trace, opts.Logger = nil, nil, nil, nil
+	return func(text string) (bool, error) {
+		inputPath := filepath.Join(dir, "minimize-input.txt")
+		outputPath := filepath.Join(dir, "minimize-output.txt")
+		if err := os.WriteFile(inputPath, []byte(text), 0644); err != nil {
+			return false, fmt.Errorf("failed to write candidate: %w", err)
+		}
+		if err := controller.ProcessFileWithOptions(inputPath, outputPath, opts); err != nil {
+			return false, fmt.Errorf("failed to process candidate: %w", err)
+		}
+		chunked, err := os.ReadFile(outputPath)
+		if err != nil {
+			return false, fmt.Errorf("failed to read candidate output: %w", err)
+		}
+
+		data, _ := parser.StripBOM([]byte(text))
+		if !utf8.Valid(data) {
+			return false, errors.New("candidate is not valid UTF-8")
+		}
+		return string(chunked) != transformer.ProcessTextWithOptions(string(data), opts), nil
+	}
+}
@@ -0,0 +1,58 @@
+package minimize
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestMinimize(t *testing.T) {
+	input := strings.Repeat("some filler words here\n", 40) + "the foo line\n" +
+		strings.Repeat("more text around it\n", 40) + "a bar (up) here\n" + strings.Repeat("tail words\n", 20)
+	// fails when foo comes before bar in a text of at least 20 bytes
+	fails := func(text string) (bool, error) {
+		foo, bar := strings.Index(text, "foo"), strings.Index(text, "bar")
+		return foo >= 0 && bar > foo && len(text) >= 20, nil
+	}
+
+	result, err := Minimize(input, fails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := fails(result.Text); !ok {
+		t.Fatalf("Minimized text %q no longer fails", result.Text)
+	}
+	if len(result.Text) > 30 || strings.Contains(result.Text, "filler") {
+		t.Errorf("Expected a text of about 20 bytes, got %q", result.Text)
+	}
+	if result.Runs == 0 {
+		t.Error("Expected the predicate runs to be counted")
+	}
+
+	if _, err := Minimize("nothing here", fails); !errors.Is(err, ErrNoFailure) {
+		t.Errorf("Expected ErrNoFailure, got %v", err)
+	}
+	broken := errors.New("broken")
+	if _, err := Minimize(input, func(string) (bool, error) { return false, broken }); !errors.Is(err, broken) {
+		t.Errorf("Expected the predicate error, got %v", err)
+	}
+}
+
+func TestWords(t *testing.T) {
+	pieces := words("  one two\n\tthree ")
+	if strings.Join(pieces, "|") != "  |one |two\n\t|three " {
+		t.Errorf("Unexpected pieces %q", pieces)
+	}
+}
+
+func TestChunkedDivergence(t *testing.T) {
+	diverges := ChunkedDivergence(config.DefaultOptions(), t.TempDir())
+	text := strings.Repeat("it was a apple (up, 3) ' quoted\nover lines ' and (cap>) more\n", 200)
+	if failed, err := diverges(text); err != nil || failed {
+		t.Errorf("Chunked output should match a single pass, got %v, %v", failed, err)
+	}
+	if _, err := diverges("bad \xff byte"); err == nil {
+		t.Error("Expected an error for invalid UTF-8")
+	}
+}
@@ -0,0 +1,137 @@
+package parser
+
+import (
+	"compress/gzip"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+)
+
+// OpenDecompressed opens the file at path and streams its content decompressed with codec,
+// a COMPRESS_* value other than COMPRESS_AUTO
+func OpenDecompressed(path, codec string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	return decompress(file, path, codec)
+}
+
+// decompress streams the content of compressed decompressed with codec, closing the result
+// closes compressed. name names it in errors.
+func decompress(compressed io.ReadCloser, name, codec string) (io.ReadCloser, error) {
+	var reader io.ReadCloser
+	var err error
+	switch codec {
+	case config.COMPRESS_GZIP:
+		reader, err = gzip.NewReader(compressed)
+	case config.COMPRESS_ZSTD:
+		var decoder *zstd.Decoder
+		if decoder, err = zstd.NewReader(compressed, zstd.WithDecoderConcurrency(1)); err == nil {
+			reader = decoder.IOReadCloser()
+		}
+	default:
+		return compressed, nil
+	}
+	if err != nil {
+		compressed.Close()
+		return nil, fmt.Errorf("failed to read %s file %s: %w", codec, name, err)
+	}
+	return &decompressed{ReadCloser: reader, compressed: compressed}, nil
+}
+
+// decompressed reads a compressed stream, closing it closes the compressed one
+type decompressed struct {
+	io.ReadCloser
+	compressed io.Closer
+}
+
+func (d *decompressed) Close() error {
+	err := d.ReadCloser.Close()
+	if closeErr := d.compressed.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OpenStream opens the compressed file at path as a Source for ReadRawChunkAt. Its content is
+// decompressed as it is read, nothing is written to disk, so the size is not known up front.
+// Reading on from the last read, or from inside it, streams; reading before it decompresses
+// again from the start.
+func OpenStream(path, codec string) (Source, error) {
+	return openStream(path, codec, func() (io.ReadCloser, error) {
+		return OpenDecompressed(path, codec)
+	})
+}
+
+// openStream opens a stream reading name, decompressed with codec, from what open returns
+func openStream(name, codec string, open func() (io.ReadCloser, error)) (Source, error) {
+	stream := &stream{name: name, codec: codec, open: open}
+	if err := stream.rewind(); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// stream is a Source over a stream read once from the start, opened again to read back
+type stream struct {
+	name, codec string
+	open        func() (io.ReadCloser, error) // opens the stream at offset 0
+	reader      io.ReadCloser
+	start       int64  // offset of last
+	last        []byte // the bytes read last, a chunk read again from inside them needs no rewind
+}
+
+// rewind opens the stream again at offset 0
+func (s *stream) rewind() error {
+	if s.reader != nil {
+		s.reader.Close()
+	}
+	reader, err := s.open()
+	if err != nil {
+		return err
+	}
+	s.reader, s.start, s.last = reader, 0, nil
+	return nil
+}
+
+func (s *stream) ReadAt(p []byte, off int64) (int, error) {
+	if off < s.start {
+		if err := s.rewind(); err != nil {
+			return 0, err
+		}
+	}
+	if end := s.start + int64(len(s.last)); off > end {
+		_, err := io.CopyN(io.Discard, s.reader, off-end)
+		s.start, s.last = off, nil
+		if err != nil {
+			return 0, s.failed(err)
+		}
+	}
+	data := make([]byte, len(p))
+	kept := copy(data, s.last[off-s.start:])
+	n, err := io.ReadFull(s.reader, data[kept:])
+	s.start, s.last = off, data[:kept+n]
+	copy(p, s.last)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return kept + n, io.EOF
+	}
+	return kept + n, s.failed(err)
+}
+
+// failed names the stream and its codec in an error of the stream, io.EOF stays as it is
+func (s *stream) failed(err error) error {
+	if err == nil || err == io.EOF {
+		return err
+	}
+	if s.codec == config.COMPRESS_NONE {
+		return fmt.Errorf("failed to read %s: %w", s.name, err)
+	}
+	return fmt.Errorf("failed to read %s file %s: %w", s.codec, s.name, err)
+}
+
+func (s *stream) Close() error {
+	return s.reader.Close()
+}
@@ -0,0 +1,64 @@
+package parser
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenStream(t *testing.T) {
+	text := strings.Repeat("0123456789", 3*config.CHUNK_BYTES/10+7)
+	var gz, zst bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gz)
+	io.WriteString(gzipWriter, text)
+	gzipWriter.Close()
+	zstdWriter, err := zstd.NewWriter(&zst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(zstdWriter, text)
+	zstdWriter.Close()
+
+	for codec, data := range map[string][]byte{config.COMPRESS_GZIP: gz.Bytes(), config.COMPRESS_ZSTD: zst.Bytes()} {
+		path := filepath.Join(t.TempDir(), "in")
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		source, err := OpenStream(path, codec)
+		if err != nil {
+			t.Fatalf("%s: %v", codec, err)
+		}
+		// On, again from inside the last read, past it and back to the start
+		for _, offset := range []int64{0, int64(config.CHUNK_BYTES), int64(config.CHUNK_BYTES) + 3, int64(3 * config.CHUNK_BYTES), 5, int64(len(text))} {
+			chunk, err := ReadRawChunkAt(source, offset)
+			if err != nil {
+				t.Fatalf("%s: ReadRawChunkAt(%d): %v", codec, offset, err)
+			}
+			if expected := text[offset:min(offset+int64(config.CHUNK_BYTES), int64(len(text)))]; string(chunk) != expected {
+				t.Errorf("%s: chunk at %d has %d bytes, expected %d", codec, offset, len(chunk), len(expected))
+			}
+		}
+		source.Close()
+	}
+}
+
+func TestOpenStreamBroken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "in.zst")
+	if err := os.WriteFile(path, []byte("plain text"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source, err := OpenStream(path, config.COMPRESS_ZSTD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	if _, err := ReadRawChunkAt(source, 0); err == nil || !strings.Contains(err.Error(), "zstd") {
+		t.Errorf("Expected an error naming zstd, got %v", err)
+	}
+}
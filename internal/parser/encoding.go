@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// DetectEncoding guesses the encoding from the start of a file: a UTF-16 byte order
+// mark, valid UTF-8, or Latin-1 for anything else
+func DetectEncoding(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		return config.ENCODING_UTF16LE
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		return config.ENCODING_UTF16BE
+	case utf8.Valid(withoutPartialRune(data)):
+		return config.ENCODING_UTF8
+	}
+	return config.ENCODING_LATIN1
+}
+
+// drops the start of a multi-byte rune cut off by the end of data
+func withoutPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
+
+// Codec returns the x/text encoding for a config encoding name, nil for UTF-8
+func Codec(name string) (encoding.Encoding, error) {
+	switch name {
+	case config.ENCODING_UTF8:
+		return nil, nil
+	case config.ENCODING_LATIN1:
+		return charmap.ISO8859_1, nil
+	case config.ENCODING_UTF16LE:
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case config.ENCODING_UTF16BE:
+		return unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	}
+	return nil, fmt.Errorf("unsupported encoding %q", name)
+}
+
+// DecodeChunk converts a raw chunk to UTF-8 and returns how many input bytes it used.
+// That is less than len(data) when the chunk ends inside a character, the rest
+// belongs to the next chunk. A byte order mark is kept as U+FEFF for StripBOM.
+func DecodeChunk(data []byte, name string) ([]byte, int, error) {
+	codec, err := Codec(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	if codec == nil {
+		adjusted := AdjustToRuneBoundary(data)
+		return adjusted, len(adjusted), nil
+	}
+
+	used := len(data)
+	if name == config.ENCODING_UTF16LE || name == config.ENCODING_UTF16BE {
+		used = utf16Boundary(data, name == config.ENCODING_UTF16BE)
+	}
+	decoded, err := codec.NewDecoder().Bytes(data[:used])
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode %s: %w", name, err)
+	}
+	return decoded, used, nil
+}
+
+// returns the length of data without a trailing odd byte or lone high surrogate
+func utf16Boundary(data []byte, bigEndian bool) int {
+	n := len(data) &^ 1
+	if n < 2 {
+		return n
+	}
+	unit := uint16(data[n-2]) | uint16(data[n-1])<<8
+	if bigEndian {
+		unit = uint16(data[n-2])<<8 | uint16(data[n-1])
+	}
+	if unit >= 0xD800 && unit <= 0xDBFF {
+		// First half of a surrogate pair, the second half is in the next chunk
+		n -= 2
+	}
+	return n
+}
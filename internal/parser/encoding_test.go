@@ -0,0 +1,63 @@
+package parser
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestDetectEncoding(t *testing.T) {
+	tests := []struct {
+		data     []byte
+		expected string
+	}{
+		{[]byte{0xFF, 0xFE, 'h', 0}, config.ENCODING_UTF16LE},
+		{[]byte{0xFE, 0xFF, 0, 'h'}, config.ENCODING_UTF16BE},
+		{[]byte("café"), config.ENCODING_UTF8},
+		{[]byte("caf\xc3"), config.ENCODING_UTF8}, // cut inside a rune
+		{[]byte("caf\xe9 cr\xe8me"), config.ENCODING_LATIN1},
+	}
+
+	for _, test := range tests {
+		if result := DetectEncoding(test.data); result != test.expected {
+			t.Errorf("DetectEncoding(%q) = %s, expected %s", test.data, result, test.expected)
+		}
+	}
+}
+
+func TestDecodeChunkLatin1(t *testing.T) {
+	decoded, used, err := DecodeChunk([]byte("caf\xe9"), config.ENCODING_LATIN1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(decoded) != "café" || used != 4 {
+		t.Errorf("Expected \"café\" using 4 bytes, got %q using %d", decoded, used)
+	}
+}
+
+func TestDecodeChunkUTF16Boundary(t *testing.T) {
+	// "a😀" in UTF-16LE, cut after the high surrogate
+	data := []byte{'a', 0, 0x3D, 0xD8, 0x00, 0xDE}
+	decoded, used, err := DecodeChunk(data[:4], config.ENCODING_UTF16LE)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if string(decoded) != "a" || used != 2 {
+		t.Errorf("Expected \"a\" using 2 bytes, got %q using %d", decoded, used)
+	}
+
+	decoded, used, err = DecodeChunk(data, config.ENCODING_UTF16LE)
+	if err != nil || string(decoded) != "a😀" || used != 6 {
+		t.Errorf("Expected \"a😀\" using 6 bytes, got %q using %d, %v", decoded, used, err)
+	}
+
+	// Odd trailing byte belongs to the next chunk
+	if _, used, _ := DecodeChunk([]byte{0, 'a', 0}, config.ENCODING_UTF16BE); used != 2 {
+		t.Errorf("Expected 2 bytes used, got %d", used)
+	}
+}
+
+func TestDecodeChunkUnknownEncoding(t *testing.T) {
+	if _, _, err := DecodeChunk([]byte("x"), "ebcdic"); err == nil {
+		t.Error("Expected error for unknown encoding")
+	}
+}
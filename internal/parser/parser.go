@@ -2,42 +2,73 @@ package parser
 
 import (
 	"fmt"
-	"go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
 	"io"
 	"os"
 	"strings"
 	"unicode/utf8"
 )
 
-// ReadChunk reads a chunk of data from file starting at the given offset
+// UTF-8 encoded byte order mark some editors put at the start of a file
+const UTF8_BOM = "\uFEFF"
+
+// StripBOM removes a leading UTF-8 byte order mark and reports whether there was one
+func StripBOM(data []byte) ([]byte, bool) {
+	if len(data) >= len(UTF8_BOM) && string(data[:len(UTF8_BOM)]) == UTF8_BOM {
+		return data[len(UTF8_BOM):], true
+	}
+	return data, false
+}
+
+// ReadChunk reads a chunk of UTF-8 text from file starting at the given offset
 func ReadChunk(filepath string, offset int64) ([]byte, error) {
-	file, err := os.Open(filepath)
+	chunk, err := ReadRawChunk(filepath, offset)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open file %s: %w", filepath, err)
+		return nil, err
 	}
-	defer file.Close()
 
-	// Seek to offset
-	if offset > 0 {
-		_, err = file.Seek(offset, io.SeekStart)
-		if err != nil {
-			return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
-		}
+	// Adjust to rune boundary to avoid UTF-8 corruption
+	adjusted := AdjustToRuneBoundary(chunk)
+	return adjusted, nil
+}
+
+// Source is an input read chunk by chunk through one descriptor for the whole run
+type Source interface {
+	io.ReaderAt
+	io.Closer
+}
+
+// OpenSource opens the file at path for ReadRawChunkAt
+func OpenSource(path string) (Source, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
 	}
+	return file, nil
+}
 
-	// Read up to CHUNK_BYTES
+// ReadRawChunk reads up to CHUNK_BYTES bytes starting at the given offset, without
+// assuming any encoding
+func ReadRawChunk(filepath string, offset int64) ([]byte, error) {
+	source, err := OpenSource(filepath)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+	return ReadRawChunkAt(source, offset)
+}
+
+// ReadRawChunkAt reads up to CHUNK_BYTES bytes of source starting at the given offset.
+// Fewer bytes are only returned at the end of the input.
+func ReadRawChunkAt(source Source, offset int64) ([]byte, error) {
 	buffer := make([]byte, config.CHUNK_BYTES)
-	n, err := file.Read(buffer)
+	n, err := source.ReadAt(buffer, offset)
 	if err != nil && err != io.EOF {
 		return nil, fmt.Errorf("failed to read from file: %w", err)
 	}
 
 	// Return only the bytes that were actually read
-	chunk := buffer[:n]
-
-	// Adjust to rune boundary to avoid UTF-8 corruption
-	adjusted := AdjustToRuneBoundary(chunk)
-	return adjusted, nil
+	return buffer[:n], nil
 }
 
 // AdjustToRuneBoundary ensures the byte slice ends at a complete UTF-8 rune
@@ -1,8 +1,8 @@
 package parser
 
 import (
-	"go-reloaded/internal/config"
-	"go-reloaded/internal/testutils"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
 	"strings"
 	"testing"
 	"unicode/utf8"
@@ -247,3 +247,44 @@ func TestPrependOverlapWordsEmpty(t *testing.T) {
 func isValidUTF8(data []byte) bool {
 	return utf8.Valid(data)
 }
+
+func TestStripBOM(t *testing.T) {
+	data, hadBOM := StripBOM([]byte(UTF8_BOM + "text"))
+	if !hadBOM || string(data) != "text" {
+		t.Errorf("Expected BOM to be stripped, got %q, %v", data, hadBOM)
+	}
+
+	data, hadBOM = StripBOM([]byte("text"))
+	if hadBOM || string(data) != "text" {
+		t.Errorf("Expected data without BOM unchanged, got %q, %v", data, hadBOM)
+	}
+}
+
+func TestReadRawChunkAtSharedSource(t *testing.T) {
+	content := strings.Repeat("a", config.CHUNK_BYTES) + "tail"
+	filepath, err := testutils.CreateTestFile(content)
+	if err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	defer testutils.CleanupTestFile(filepath)
+
+	source, err := OpenSource(filepath)
+	if err != nil {
+		t.Fatalf("OpenSource failed: %v", err)
+	}
+	defer source.Close()
+
+	// Reads through one source do not depend on each other's position
+	for _, offset := range []int64{int64(config.CHUNK_BYTES), 0, int64(config.CHUNK_BYTES)} {
+		data, err := ReadRawChunkAt(source, offset)
+		if err != nil {
+			t.Fatalf("ReadRawChunkAt(%d) failed: %v", offset, err)
+		}
+		if expected := content[offset:min(offset+int64(config.CHUNK_BYTES), int64(len(content)))]; string(data) != expected {
+			t.Errorf("ReadRawChunkAt(%d) returned %d bytes, expected %d", offset, len(data), len(expected))
+		}
+	}
+	if _, err := OpenSource(filepath + ".missing"); err == nil {
+		t.Error("Expected error opening a missing file")
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrTooLarge reports a remote input larger than the size limit it was opened with
+var ErrTooLarge = errors.New("remote input too large")
+
+// IsURL reports whether an input names an http or https URL rather than a file
+func IsURL(input string) bool {
+	scheme, _, found := strings.Cut(input, "://")
+	return found && (strings.EqualFold(scheme, "http") || strings.EqualFold(scheme, "https"))
+}
+
+// OpenURL requests url and streams its body. The whole download must end within timeout,
+// 0 for none, and reading more than maxBytes fails with ErrTooLarge, 0 for no limit.
+func OpenURL(url string, timeout time.Duration, maxBytes int64) (io.ReadCloser, error) {
+	client := &http.Client{Timeout: timeout}
+	response, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", url, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", url, response.Status)
+	}
+	if maxBytes > 0 && response.ContentLength > maxBytes {
+		response.Body.Close()
+		return nil, fmt.Errorf("%w: %s has %d bytes, the limit is %d", ErrTooLarge, url, response.ContentLength, maxBytes)
+	}
+	return &limitedBody{body: response.Body, url: url, limit: maxBytes}, nil
+}
+
+// limitedBody fails a read past the size limit instead of ending the body early
+type limitedBody struct {
+	body  io.ReadCloser
+	url   string
+	limit int64 // 0 for none
+	read  int64
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if left := l.limit - l.read; l.limit > 0 && int64(len(p)) > left+1 {
+		// One byte past the limit is enough to tell the body is too large
+		p = p[:left+1]
+	}
+	n, err := l.body.Read(p)
+	l.read += int64(n)
+	if l.limit > 0 && l.read > l.limit {
+		return 0, fmt.Errorf("%w: %s has more than %d bytes", ErrTooLarge, l.url, l.limit)
+	}
+	return n, err
+}
+
+func (l *limitedBody) Close() error {
+	return l.body.Close()
+}
+
+// OpenURLStream opens url as a Source for ReadRawChunkAt, its body decompressed with codec
+// as it is read, like OpenStream reads a file. Nothing is downloaded ahead: the body is
+// read as the chunks are, with the timeout and size limit of OpenURL, and reading back
+// before the last read fetches url again.
+func OpenURLStream(url, codec string, timeout time.Duration, maxBytes int64) (Source, error) {
+	return openStream(url, codec, func() (io.ReadCloser, error) {
+		body, err := OpenURL(url, timeout, maxBytes)
+		if err != nil {
+			return nil, err
+		}
+		return decompress(body, url, codec)
+	})
+}
@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsURL(t *testing.T) {
+	for input, expected := range map[string]bool{
+		"http://example.com/a.txt": true,
+		"HTTPS://example.com":      true,
+		"ftp://example.com/a.txt":  false,
+		"notes/http.txt":           false,
+		"c:/docs/a.txt":            false,
+	} {
+		if IsURL(input) != expected {
+			t.Errorf("IsURL(%q) = %v, expected %v", input, !expected, expected)
+		}
+	}
+}
+
+func TestOpenURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/doc.txt":
+			io.WriteString(w, "a apple (up)")
+		case "/streamed.txt":
+			// Flushing before the end leaves the length unknown
+			io.WriteString(w, strings.Repeat("word ", 10))
+			w.(http.Flusher).Flush()
+			io.WriteString(w, strings.Repeat("word ", 10))
+		case "/slow.txt":
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	body, err := OpenURL(server.URL+"/doc.txt", time.Second, 0)
+	if err != nil {
+		t.Fatalf("OpenURL failed: %v", err)
+	}
+	content, err := io.ReadAll(body)
+	body.Close()
+	if err != nil || string(content) != "a apple (up)" {
+		t.Errorf("Expected the body, got %q, %v", content, err)
+	}
+
+	if _, err := OpenURL(server.URL+"/doc.txt", time.Second, 5); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge from the content length, got %v", err)
+	}
+	body, err = OpenURL(server.URL+"/streamed.txt", time.Second, 60)
+	if err != nil {
+		t.Fatalf("OpenURL failed: %v", err)
+	}
+	if _, err := io.ReadAll(body); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge while reading, got %v", err)
+	}
+	body.Close()
+	body, err = OpenURL(server.URL+"/streamed.txt", time.Second, 100)
+	if err != nil {
+		t.Fatalf("OpenURL failed: %v", err)
+	}
+	if content, err := io.ReadAll(body); err != nil || len(content) != 100 {
+		t.Errorf("Expected a body of exactly the limit, got %d bytes, %v", len(content), err)
+	}
+	body.Close()
+
+	if _, err := OpenURL(server.URL+"/missing.txt", time.Second, 0); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Expected the status of a missing document, got %v", err)
+	}
+	if _, err := OpenURL(server.URL+"/slow.txt", 50*time.Millisecond, 0); err == nil {
+		t.Error("Expected a timeout")
+	}
+}
@@ -0,0 +1,373 @@
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Decisions on a hunk in the browser
+const (
+	VERDICT_UNDECIDED = iota
+	VERDICT_ACCEPTED
+	VERDICT_REJECTED
+)
+
+// Keys of the browser other than plain characters, as readKey returns them
+const (
+	KEY_UP    = "up"
+	KEY_DOWN  = "down"
+	KEY_LEFT  = "left"
+	KEY_RIGHT = "right"
+	KEY_ESC   = "esc"
+)
+
+// ANSI sequences the browser draws with
+const (
+	ansiHome       = "\x1b[H"
+	ansiClearLine  = "\x1b[K"
+	ansiClearDown  = "\x1b[J"
+	ansiAltScreen  = "\x1b[?1049h\x1b[?25l" // alternate screen, cursor hidden
+	ansiMainScreen = "\x1b[?25h\x1b[?1049l" // cursor shown, back to the main screen
+	ansiReverse    = "\x1b[7m"
+	ansiRed        = "\x1b[31m"
+	ansiGreen      = "\x1b[32m"
+	ansiReset      = "\x1b[0m"
+)
+
+// Smallest screen the browser draws on, a smaller one is drawn at this size
+const (
+	MIN_BROWSE_WIDTH  = 40
+	MIN_BROWSE_HEIGHT = 6
+)
+
+// browser is the state of a Browse session: the file selected in the list, the hunk selected
+// in its diff and the decision on every hunk
+type browser struct {
+	changes  []FileChange
+	verdicts [][]int // VERDICT_* of every hunk of every file
+	file     int
+	hunk     int
+}
+
+// Browse shows the changed files in a terminal UI, a list of files next to the diff of the
+// selected one, and writes each file with only its accepted hunks when the user writes.
+// in must deliver keys as they are typed, a terminal in raw mode; size returns the screen
+// size before every redraw, so a resized terminal is drawn at its new size. Keys: up/down or
+// k/j select a file, left/right or h/l a hunk, y and n accept and reject the hunk and move on,
+// a and d decide every hunk of the file, w writes and quits, q or Esc quit without writing.
+func Browse(changes []FileChange, in io.Reader, out io.Writer, size func() (width, height int)) (Summary, error) {
+	b := &browser{changes: changes, verdicts: make([][]int, len(changes))}
+	for i, change := range changes {
+		b.verdicts[i] = make([]int, len(change.Hunks))
+	}
+	reader := bufio.NewReader(in)
+
+	fmt.Fprint(out, ansiAltScreen)
+	defer fmt.Fprint(out, ansiMainScreen)
+	for {
+		width, height := size()
+		if _, err := io.WriteString(out, b.draw(width, height)); err != nil {
+			return Summary{}, fmt.Errorf("failed to draw review: %w", err)
+		}
+		key, err := readKey(reader)
+		if err == io.EOF {
+			return b.summary(), nil
+		}
+		if err != nil {
+			return Summary{}, fmt.Errorf("failed to read key: %w", err)
+		}
+		switch key {
+		case KEY_UP, "k":
+			b.selectFile(b.file - 1)
+		case KEY_DOWN, "j":
+			b.selectFile(b.file + 1)
+		case KEY_LEFT, "h":
+			b.hunk = max(b.hunk-1, 0)
+		case KEY_RIGHT, "l":
+			b.hunk = min(b.hunk+1, len(b.verdicts[b.file])-1)
+		case "y", "n":
+			b.verdicts[b.file][b.hunk] = verdictOf(key == "y")
+			b.next()
+		case "a", "d":
+			for i := range b.verdicts[b.file] {
+				b.verdicts[b.file][i] = verdictOf(key == "a")
+			}
+			b.selectFile(b.file + 1)
+		case "w":
+			return b.write()
+		case "q", KEY_ESC:
+			return b.summary(), nil
+		}
+	}
+}
+
+// verdictOf returns VERDICT_ACCEPTED for an accepted hunk, VERDICT_REJECTED otherwise
+func verdictOf(accepted bool) int {
+	if accepted {
+		return VERDICT_ACCEPTED
+	}
+	return VERDICT_REJECTED
+}
+
+// selectFile selects file i, kept inside the list, at its first hunk
+func (b *browser) selectFile(i int) {
+	b.file, b.hunk = min(max(i, 0), len(b.changes)-1), 0
+}
+
+// next selects the next undecided hunk after the selected one, in this file or a later one,
+// and stays where it is when every later hunk is decided
+func (b *browser) next() {
+	for file := b.file; file < len(b.changes); file++ {
+		from := 0
+		if file == b.file {
+			from = b.hunk + 1
+		}
+		for hunk := from; hunk < len(b.verdicts[file]); hunk++ {
+			if b.verdicts[file][hunk] == VERDICT_UNDECIDED {
+				b.file, b.hunk = file, hunk
+				return
+			}
+		}
+	}
+}
+
+// summary counts the decisions taken so far, nothing written
+func (b *browser) summary() Summary {
+	var summary Summary
+	for _, verdicts := range b.verdicts {
+		reviewed := false
+		for _, verdict := range verdicts {
+			switch verdict {
+			case VERDICT_ACCEPTED:
+				summary.Accepted++
+			case VERDICT_REJECTED:
+				summary.Rejected++
+			default:
+				continue
+			}
+			reviewed = true
+		}
+		if reviewed {
+			summary.FilesReviewed++
+		}
+	}
+	return summary
+}
+
+// write writes every file with its accepted hunks, undecided hunks are left out like rejected ones
+func (b *browser) write() (Summary, error) {
+	summary := b.summary()
+	for i, change := range b.changes {
+		accepted := make([]bool, len(change.Hunks))
+		for j, verdict := range b.verdicts[i] {
+			accepted[j] = verdict == VERDICT_ACCEPTED
+		}
+		written, err := writeAccepted(change, accepted)
+		if err != nil {
+			return summary, err
+		}
+		if written {
+			summary.FilesWritten++
+		}
+	}
+	return summary, nil
+}
+
+// draw returns the screen: a status line, the file list next to the diff of the selected
+// file, and the keys. Every line is cut to width, lines end with \r\n as raw mode needs.
+func (b *browser) draw(width, height int) string {
+	width, height = max(width, MIN_BROWSE_WIDTH), max(height, MIN_BROWSE_HEIGHT)
+	summary := b.summary()
+	undecided := 0
+	for _, verdicts := range b.verdicts {
+		for _, verdict := range verdicts {
+			if verdict == VERDICT_UNDECIDED {
+				undecided++
+			}
+		}
+	}
+
+	var screen strings.Builder
+	screen.WriteString(ansiHome)
+	line := func(text string) {
+		screen.WriteString(text)
+		screen.WriteString(ansiClearLine + "\r\n")
+	}
+	line(fit(fmt.Sprintf("%d file(s): %d hunk(s) accepted, %d rejected, %d undecided",
+		len(b.changes), summary.Accepted, summary.Rejected, undecided), width))
+
+	listWidth := min(max(width/3, 20), 40)
+	diffWidth := width - listWidth - 3
+	files, diff := b.fileList(listWidth), b.diffLines()
+	rows := height - 2
+	// The diff scrolls so the selected hunk starts at the top, unless the file fits
+	scroll := 0
+	if len(diff) > rows {
+		scroll = min(b.hunkLine(), len(diff)-rows)
+	}
+	fileScroll := max(0, b.file-rows+1)
+	for row := 0; row < rows; row++ {
+		left := strings.Repeat(" ", listWidth)
+		if i := fileScroll + row; i < len(files) {
+			left = files[i]
+		}
+		right := ""
+		if i := scroll + row; i < len(diff) {
+			right = diff[i].render(diffWidth)
+		}
+		line(left + " │ " + right)
+	}
+	screen.WriteString(fit("↑↓ file  ←→ hunk  y/n accept/reject  a/d whole file  w write and quit  q quit", width))
+	screen.WriteString(ansiClearLine + ansiClearDown)
+	return screen.String()
+}
+
+// fileList returns a row per file, listWidth wide: the selected one marked and reversed,
+// each with its decided and total hunks
+func (b *browser) fileList(listWidth int) []string {
+	rows := make([]string, len(b.changes))
+	for i, change := range b.changes {
+		decided := 0
+		for _, verdict := range b.verdicts[i] {
+			if verdict != VERDICT_UNDECIDED {
+				decided++
+			}
+		}
+		counts := fmt.Sprintf(" %d/%d", decided, len(change.Hunks))
+		name := fitLeft(change.Path, listWidth-2-utf8.RuneCountInString(counts))
+		rows[i] = fit("  "+name+counts, listWidth)
+		if i == b.file {
+			rows[i] = ansiReverse + fit("> "+name+counts, listWidth) + ansiReset
+		}
+	}
+	return rows
+}
+
+// diffLine is a line of the diff pane, kind is ' ', '-', '+' or '@' for a hunk header
+type diffLine struct {
+	kind     rune
+	text     string
+	selected bool // the header of the selected hunk
+}
+
+// render cuts the line to width and colors it
+func (l diffLine) render(width int) string {
+	switch {
+	case l.selected:
+		return ansiReverse + fit(l.text, width) + ansiReset
+	case l.kind == '-':
+		return ansiRed + fit("- "+l.text, width) + ansiReset
+	case l.kind == '+':
+		return ansiGreen + fit("+ "+l.text, width) + ansiReset
+	case l.kind == '@':
+		return fit(l.text, width)
+	}
+	return fit("  "+l.text, width)
+}
+
+// diffLines returns the diff of the selected file: every hunk with its header, its decision
+// and CONTEXT_LINES lines of context, context shared by two hunks shown once
+func (b *browser) diffLines() []diffLine {
+	change := b.changes[b.file]
+	var lines []diffLine
+	shown := 0 // old lines before this index are shown
+	for i, hunk := range change.Hunks {
+		start := max(hunk.OldStart-CONTEXT_LINES, shown)
+		if start > shown && len(lines) > 0 {
+			lines = append(lines, diffLine{kind: ' ', text: "⋮"})
+		}
+		decision := [...]string{"undecided", "accepted", "rejected"}[b.verdicts[b.file][i]]
+		header := fmt.Sprintf("@@ hunk %d/%d, line %d, %s @@", i+1, len(change.Hunks), hunk.OldStart+1, decision)
+		lines = append(lines, diffLine{kind: '@', text: header, selected: i == b.hunk})
+		for _, line := range change.OldLines[start:hunk.OldStart] {
+			lines = append(lines, diffLine{kind: ' ', text: line})
+		}
+		for _, line := range hunk.OldLines {
+			lines = append(lines, diffLine{kind: '-', text: line})
+		}
+		for _, line := range hunk.NewLines {
+			lines = append(lines, diffLine{kind: '+', text: line})
+		}
+		end := hunk.OldStart + len(hunk.OldLines)
+		contextEnd := min(end+CONTEXT_LINES, len(change.OldLines))
+		if i+1 < len(change.Hunks) {
+			contextEnd = min(contextEnd, change.Hunks[i+1].OldStart)
+		}
+		for _, line := range change.OldLines[end:contextEnd] {
+			lines = append(lines, diffLine{kind: ' ', text: line})
+		}
+		shown = contextEnd
+	}
+	return lines
+}
+
+// hunkLine returns the index of the header of the selected hunk in diffLines
+func (b *browser) hunkLine() int {
+	for i, line := range b.diffLines() {
+		if line.selected {
+			return i
+		}
+	}
+	return 0
+}
+
+// fit cuts text to width characters, or pads it with spaces to width
+func fit(text string, width int) string {
+	text = strings.ReplaceAll(text, "\t", " ")
+	if n := utf8.RuneCountInString(text); n <= width {
+		return text + strings.Repeat(" ", width-n)
+	}
+	return string([]rune(text)[:width])
+}
+
+// fitLeft cuts text to width characters from the left, so the end of a path stays: …/notes/a.txt
+func fitLeft(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text
+	}
+	if width <= 1 {
+		return string(runes[len(runes)-max(width, 0):])
+	}
+	return "…" + string(runes[len(runes)-width+1:])
+}
+
+// readKey reads one key: a character, or KEY_* for an arrow key or Escape. Ctrl-C reads as q.
+func readKey(reader *bufio.Reader) (string, error) {
+	r, _, err := reader.ReadRune()
+	if err != nil {
+		return "", err
+	}
+	switch r {
+	case 3:
+		return "q", nil
+	case 0x1b:
+		// An arrow key arrives as one write, Escape on its own has nothing after it
+		if reader.Buffered() == 0 {
+			return KEY_ESC, nil
+		}
+		if next, _ := reader.Peek(1); next[0] != '[' && next[0] != 'O' {
+			return KEY_ESC, nil
+		}
+		reader.ReadByte()
+		code, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch code {
+		case 'A':
+			return KEY_UP, nil
+		case 'B':
+			return KEY_DOWN, nil
+		case 'C':
+			return KEY_RIGHT, nil
+		case 'D':
+			return KEY_LEFT, nil
+		}
+		return "", nil
+	}
+	return string(r), nil
+}
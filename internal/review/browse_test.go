@@ -0,0 +1,129 @@
+package review
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// screenSize is a fixed terminal size for Browse
+func screenSize() (int, int) { return 100, 20 }
+
+// plain strips the ANSI sequences of a drawn screen
+func plain(screen string) string {
+	return regexp.MustCompile("\x1b\\[[?0-9;]*[a-zA-Z]").ReplaceAllString(screen, "")
+}
+
+func TestBrowseWritesAcceptedHunks(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt": "first (up)\nkeep\nkeep\nkeep\nkeep\nkeep\nsecond (up)",
+		"b.txt": "other (up)",
+		"c.txt": "left (up)",
+	})
+	changes, err := Collect(root, []string{".txt"}, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	// Accept the first hunk of a.txt and reject the second, which moves on to b.txt, go down
+	// to c.txt and back up, accept all of b.txt, leave c.txt undecided and write
+	var out bytes.Buffer
+	summary, err := Browse(changes, strings.NewReader("yn\x1b[B\x1b[Aaw"), &out, screenSize)
+	if err != nil {
+		t.Fatalf("Browse failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"a.txt": "FIRST\nkeep\nkeep\nkeep\nkeep\nkeep\nsecond (up)",
+		"b.txt": "OTHER",
+		"c.txt": "left (up)",
+	}
+	for name, content := range expected {
+		if data, _ := os.ReadFile(filepath.Join(root, name)); string(data) != content {
+			t.Errorf("%s: expected %q, got %q", name, content, data)
+		}
+	}
+	if summary.Accepted != 2 || summary.Rejected != 1 || summary.FilesReviewed != 2 || summary.FilesWritten != 2 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
+
+func TestBrowseQuitWritesNothing(t *testing.T) {
+	root := writeTree(t, map[string]string{"a.txt": "one (up)"})
+	changes, _ := Collect(root, []string{".txt"}, config.DefaultOptions())
+
+	for _, keys := range []string{"yq", "y\x1b", "y\x03", "y"} {
+		summary, err := Browse(changes, strings.NewReader(keys), &bytes.Buffer{}, screenSize)
+		if err != nil {
+			t.Fatalf("Browse failed: %v", err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(data) != "one (up)" {
+			t.Errorf("%q: the file should not have been written, got %q", keys, data)
+		}
+		if summary.Accepted != 1 || summary.FilesWritten != 0 {
+			t.Errorf("%q: unexpected summary %+v", keys, summary)
+		}
+	}
+}
+
+func TestBrowseDraw(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"notes/a.txt": "intro\nfirst (up)\nkeep",
+		"notes/b.txt": "other (up)",
+	})
+	changes, _ := Collect(root, []string{".txt"}, config.DefaultOptions())
+
+	var out bytes.Buffer
+	if _, err := Browse(changes, strings.NewReader("y"), &out, screenSize); err != nil {
+		t.Fatalf("Browse failed: %v", err)
+	}
+	screens := strings.Split(out.String(), ansiHome)
+	last := plain(screens[len(screens)-1])
+	lines := strings.Split(last, "\r\n")
+	if len(lines) != 20 {
+		t.Errorf("Expected a screen of 20 lines, got %d:\n%s", len(lines), last)
+	}
+	for _, want := range []string{
+		"2 file(s): 1 hunk(s) accepted, 0 rejected, 1 undecided",
+		"a.txt 1/1",
+		"> ",
+		"b.txt 0/1",
+		"@@ hunk 1/1, line 1, undecided @@",
+		"- other (up)",
+		"+ OTHER",
+		"w write and quit",
+	} {
+		if !strings.Contains(last, want) {
+			t.Errorf("Expected %q on the screen:\n%s", want, last)
+		}
+	}
+	for _, line := range lines {
+		if n := len([]rune(line)); n > 100 {
+			t.Errorf("Line wider than the screen (%d): %q", n, line)
+		}
+	}
+}
+
+func TestBrowseScrollsToTheHunk(t *testing.T) {
+	lines := make([]string, 60)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	lines[5], lines[50] = "early (up)", "late (up)"
+	root := writeTree(t, map[string]string{"long.txt": strings.Join(lines, "\n")})
+	changes, _ := Collect(root, []string{".txt"}, config.DefaultOptions())
+
+	var out bytes.Buffer
+	small := func() (int, int) { return 80, 10 }
+	if _, err := Browse(changes, strings.NewReader("l"), &out, small); err != nil {
+		t.Fatalf("Browse failed: %v", err)
+	}
+	screens := strings.Split(out.String(), ansiHome)
+	if last := plain(screens[len(screens)-1]); !strings.Contains(last, "+ LATE") || strings.Contains(last, "+ EARLY") {
+		t.Errorf("Expected the diff scrolled to the second hunk:\n%s", last)
+	}
+}
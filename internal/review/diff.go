@@ -0,0 +1,143 @@
+package review
+
+// Edit operations of a line diff
+const (
+	OP_EQUAL = iota
+	OP_DELETE
+	OP_INSERT
+)
+
+type edit struct {
+	op   int
+	line string
+}
+
+// Hunk is one contiguous run of changed lines
+type Hunk struct {
+	OldStart int      // index of the first replaced line in the original
+	OldLines []string // lines removed from the original
+	NewLines []string // lines inserted in their place
+}
+
+// Diff returns the hunks turning the old lines into the new lines
+func Diff(oldLines, newLines []string) []Hunk {
+	var hunks []Hunk
+	var current *Hunk
+	oldIdx := 0
+
+	for _, e := range myers(oldLines, newLines) {
+		if e.op == OP_EQUAL {
+			if current != nil {
+				hunks = append(hunks, *current)
+				current = nil
+			}
+			oldIdx++
+			continue
+		}
+
+		if current == nil {
+			current = &Hunk{OldStart: oldIdx}
+		}
+		if e.op == OP_DELETE {
+			current.OldLines = append(current.OldLines, e.line)
+			oldIdx++
+		} else {
+			current.NewLines = append(current.NewLines, e.line)
+		}
+	}
+	if current != nil {
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// Apply rebuilds the document keeping only the accepted hunks
+func Apply(oldLines []string, hunks []Hunk, accepted []bool) []string {
+	var result []string
+	oldIdx := 0
+	for i, hunk := range hunks {
+		result = append(result, oldLines[oldIdx:hunk.OldStart]...)
+		if accepted[i] {
+			result = append(result, hunk.NewLines...)
+		} else {
+			result = append(result, hunk.OldLines...)
+		}
+		oldIdx = hunk.OldStart + len(hunk.OldLines)
+	}
+	return append(result, oldLines[oldIdx:]...)
+}
+
+// myers computes a shortest edit script with the Myers O(ND) algorithm
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // move down: insertion
+			} else {
+				x = v[offset+k-1] + 1 // move right: deletion
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d, offset)
+			}
+		}
+	}
+	return nil
+}
+
+// backtrack walks the saved V arrays from the end to rebuild the edit script
+func backtrack(a, b []string, trace [][]int, d, offset int) []edit {
+	x, y := len(a), len(b)
+	var edits []edit
+
+	for ; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{OP_EQUAL, a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				edits = append(edits, edit{OP_INSERT, b[y]})
+			} else {
+				x--
+				edits = append(edits, edit{OP_DELETE, a[x]})
+			}
+		}
+	}
+
+	// Reverse into forward order
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
@@ -0,0 +1,47 @@
+package review
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffAndApply(t *testing.T) {
+	oldLines := strings.Split("a\nb\nc\nd\ne", "\n")
+	newLines := strings.Split("a\nB\nc\ne\nf", "\n")
+
+	hunks := Diff(oldLines, newLines)
+	if len(hunks) != 3 {
+		t.Fatalf("Expected 3 hunks, got %d: %+v", len(hunks), hunks)
+	}
+
+	all := Apply(oldLines, hunks, []bool{true, true, true})
+	if !reflect.DeepEqual(all, newLines) {
+		t.Errorf("Accepting all hunks: expected %v, got %v", newLines, all)
+	}
+
+	none := Apply(oldLines, hunks, []bool{false, false, false})
+	if !reflect.DeepEqual(none, oldLines) {
+		t.Errorf("Rejecting all hunks: expected %v, got %v", oldLines, none)
+	}
+
+	partial := Apply(oldLines, hunks, []bool{true, false, false})
+	expected := strings.Split("a\nB\nc\nd\ne", "\n")
+	if !reflect.DeepEqual(partial, expected) {
+		t.Errorf("Accepting first hunk: expected %v, got %v", expected, partial)
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	lines := []string{"same", "lines"}
+	if hunks := Diff(lines, lines); len(hunks) != 0 {
+		t.Errorf("Expected no hunks for identical input, got %+v", hunks)
+	}
+}
+
+func TestDiffEmpty(t *testing.T) {
+	hunks := Diff(nil, []string{"new"})
+	if len(hunks) != 1 || hunks[0].OldStart != 0 || len(hunks[0].NewLines) != 1 {
+		t.Errorf("Expected one insertion hunk, got %+v", hunks)
+	}
+}
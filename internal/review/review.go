@@ -0,0 +1,226 @@
+package review
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Lines of unchanged text shown around each hunk
+const CONTEXT_LINES = 2
+
+// FileChange is the proposed transformation of one file
+type FileChange struct {
+	Path     string
+	OldLines []string
+	NewLines []string
+	Hunks    []Hunk
+}
+
+// Summary counts the decisions taken during a review session
+type Summary struct {
+	FilesReviewed int
+	FilesWritten  int
+	Accepted      int
+	Rejected      int
+}
+
+// Collect transforms every file below root whose extension is listed in exts and
+// returns the files whose content would change, nothing is written
+func Collect(root string, exts []string, opts config.Options) ([]FileChange, error) {
+	tmpDir, err := os.MkdirTemp("", "go-reloaded-review-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var paths []string
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() && hasExtension(path, exts) {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk %s: %w", root, err)
+	}
+	sort.Strings(paths)
+
+	var changes []FileChange
+	outputPath := filepath.Join(tmpDir, "output.txt")
+	for _, path := range paths {
+		original, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if err := controller.ProcessFileWithOptions(path, outputPath, opts); err != nil {
+			return nil, fmt.Errorf("failed to transform %s: %w", path, err)
+		}
+		transformed, err := os.ReadFile(outputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transformed %s: %w", path, err)
+		}
+
+		oldLines := strings.Split(string(original), "\n")
+		newLines := strings.Split(string(transformed), "\n")
+		if hunks := Diff(oldLines, newLines); len(hunks) > 0 {
+			changes = append(changes, FileChange{Path: path, OldLines: oldLines, NewLines: newLines, Hunks: hunks})
+		}
+	}
+	return changes, nil
+}
+
+// Run asks for a decision on every hunk and writes each file with only its accepted hunks.
+// Answers: y accept, n reject, a accept the rest of the file, d reject the rest of the file, q quit.
+func Run(changes []FileChange, in io.Reader, out io.Writer) (Summary, error) {
+	var summary Summary
+	reader := bufio.NewReader(in)
+
+	fmt.Fprintf(out, "%d file(s) with changes:\n", len(changes))
+	for _, change := range changes {
+		fmt.Fprintf(out, "  %s (%d hunk(s))\n", change.Path, len(change.Hunks))
+	}
+
+	quit := false
+	for _, change := range changes {
+		if quit {
+			break
+		}
+		summary.FilesReviewed++
+		fmt.Fprintf(out, "\n=== %s ===\n", change.Path)
+
+		accepted := make([]bool, len(change.Hunks))
+		decideRest := ""
+		for i, hunk := range change.Hunks {
+			answer := decideRest
+			if answer == "" {
+				writeHunk(out, change, hunk, i)
+				var err error
+				if answer, err = ask(reader, out); err != nil {
+					return summary, err
+				}
+			}
+
+			switch answer {
+			case "a":
+				decideRest = "y"
+				answer = "y"
+			case "d":
+				decideRest = "n"
+				answer = "n"
+			case "q":
+				quit = true
+			}
+			if quit {
+				break
+			}
+
+			accepted[i] = answer == "y"
+			if accepted[i] {
+				summary.Accepted++
+			} else {
+				summary.Rejected++
+			}
+		}
+
+		written, err := writeAccepted(change, accepted)
+		if err != nil {
+			return summary, err
+		}
+		if written {
+			summary.FilesWritten++
+		}
+	}
+
+	return summary, nil
+}
+
+// ask reads answers until a valid one is given, end of input counts as quit
+func ask(reader *bufio.Reader, out io.Writer) (string, error) {
+	for {
+		fmt.Fprint(out, "Apply this change? [y,n,a,d,q] ")
+		line, err := reader.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch answer {
+		case "y", "n", "a", "d", "q":
+			return answer, nil
+		}
+		if err == io.EOF {
+			fmt.Fprintln(out)
+			return "q", nil
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to read answer: %w", err)
+		}
+		fmt.Fprintln(out, "y - accept, n - reject, a - accept rest of file, d - reject rest of file, q - quit")
+	}
+}
+
+// writeHunk prints a hunk with a few lines of context
+func writeHunk(out io.Writer, change FileChange, hunk Hunk, index int) {
+	fmt.Fprintf(out, "@@ hunk %d/%d, line %d @@\n", index+1, len(change.Hunks), hunk.OldStart+1)
+
+	start := hunk.OldStart - CONTEXT_LINES
+	if start < 0 {
+		start = 0
+	}
+	for _, line := range change.OldLines[start:hunk.OldStart] {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+	for _, line := range hunk.OldLines {
+		fmt.Fprintf(out, "- %s\n", line)
+	}
+	for _, line := range hunk.NewLines {
+		fmt.Fprintf(out, "+ %s\n", line)
+	}
+
+	end := hunk.OldStart + len(hunk.OldLines)
+	contextEnd := end + CONTEXT_LINES
+	if contextEnd > len(change.OldLines) {
+		contextEnd = len(change.OldLines)
+	}
+	for _, line := range change.OldLines[end:contextEnd] {
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+}
+
+// writeAccepted rewrites the file when at least one hunk was accepted
+func writeAccepted(change FileChange, accepted []bool) (bool, error) {
+	anyAccepted := false
+	for _, ok := range accepted {
+		anyAccepted = anyAccepted || ok
+	}
+	if !anyAccepted {
+		return false, nil
+	}
+
+	info, err := os.Stat(change.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat %s: %w", change.Path, err)
+	}
+	content := strings.Join(Apply(change.OldLines, change.Hunks, accepted), "\n")
+	if err := os.WriteFile(change.Path, []byte(content), info.Mode().Perm()); err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", change.Path, err)
+	}
+	return true, nil
+}
+
+// hasExtension reports whether path ends with one of the extensions (case-insensitive)
+func hasExtension(path string, exts []string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, allowed := range exts {
+		if ext == strings.ToLower(allowed) {
+			return true
+		}
+	}
+	return false
+}
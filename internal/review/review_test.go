@@ -0,0 +1,92 @@
+package review
+
+import (
+	"bytes"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files below a temp dir and returns the dir
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	return root
+}
+
+func TestCollectFindsChangedFiles(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"clean.txt":      "already clean",
+		"notes/todo.txt": "fix (up) this",
+		"image.png":      "hello (up)",
+	})
+
+	changes, err := Collect(root, []string{".txt"}, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	if len(changes) != 1 || !strings.HasSuffix(changes[0].Path, "todo.txt") {
+		t.Fatalf("Expected only todo.txt to change, got %+v", changes)
+	}
+}
+
+func TestRunWritesOnlyAcceptedHunks(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"doc.txt": "first (up)\nkeep\nsecond (up)",
+	})
+	changes, err := Collect(root, []string{".txt"}, config.DefaultOptions())
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+
+	var out bytes.Buffer
+	summary, err := Run(changes, strings.NewReader("y\nn\n"), &out)
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(root, "doc.txt"))
+	expected := "FIRST\nkeep\nsecond (up)"
+	if string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, string(data))
+	}
+	if summary.Accepted != 1 || summary.Rejected != 1 || summary.FilesWritten != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+	if !strings.Contains(out.String(), "+ FIRST") {
+		t.Errorf("Expected diff in output, got %s", out.String())
+	}
+}
+
+func TestRunQuitLeavesFilesUntouched(t *testing.T) {
+	root := writeTree(t, map[string]string{
+		"a.txt": "one (up)",
+		"b.txt": "two (up)",
+	})
+	changes, _ := Collect(root, []string{".txt"}, config.DefaultOptions())
+
+	summary, err := Run(changes, strings.NewReader("q\n"), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		data, _ := os.ReadFile(filepath.Join(root, name))
+		if strings.ToUpper(string(data)) == string(data) {
+			t.Errorf("%s should not have been written, got %q", name, string(data))
+		}
+	}
+	if summary.FilesWritten != 0 {
+		t.Errorf("Expected no files written, got %+v", summary)
+	}
+}
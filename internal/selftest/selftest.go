@@ -0,0 +1,109 @@
+package selftest
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/docs"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"os"
+	"strings"
+)
+
+// The canonical corpus is built from the golden test inputs and the docs/samples
+// files, repeated until it spans several chunks so the overlap logic is exercised.
+//
+//go:embed corpus.txt
+var corpus string
+
+// Hash of the expected output for corpus.txt, regenerate it whenever a behavior change is intended
+//
+//go:embed corpus.sha256
+var expectedHash string
+
+// Result describes the outcome of a self-test run
+type Result struct {
+	Expected    string // hash recorded in corpus.sha256
+	Actual      string // hash of the output produced on this machine
+	InputBytes  int
+	OutputBytes int
+}
+
+// Passed reports whether the produced output matches the recorded artifact
+func (r Result) Passed() bool {
+	return r.Expected == r.Actual
+}
+
+// Run processes the embedded corpus through the full file pipeline and
+// compares the hash of the output against the recorded conformance artifact
+func Run() (Result, error) {
+	output, err := ProcessCorpus()
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		Expected:    strings.TrimSpace(expectedHash),
+		Actual:      Hash(output),
+		InputBytes:  len(corpus),
+		OutputBytes: len(output),
+	}, nil
+}
+
+// ProcessCorpus runs the embedded corpus through controller.ProcessFile and returns the output
+func ProcessCorpus() (string, error) {
+	output, err := ProcessText(corpus)
+	if err != nil {
+		return "", fmt.Errorf("failed to process corpus: %w", err)
+	}
+	return output, nil
+}
+
+// Golden runs every case of docs/golden_tests.md, embedded in the binary, through
+// controller.ProcessFile, so the golden suite also runs where there is no go tool
+func Golden() (testutils.GoldenReport, error) {
+	tests, err := testutils.ParseGolden(strings.NewReader(docs.GoldenTests))
+	if err != nil {
+		return testutils.GoldenReport{}, err
+	}
+	return testutils.RunGolden(tests, ProcessText)
+}
+
+// ProcessText writes input to a temp file, runs it through controller.ProcessFile and returns the output
+func ProcessText(input string) (string, error) {
+	inputFile, err := os.CreateTemp("", "go-reloaded-selftest-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("failed to create input file: %w", err)
+	}
+	inputPath := inputFile.Name()
+	defer os.Remove(inputPath)
+
+	if _, err := inputFile.WriteString(input); err != nil {
+		inputFile.Close()
+		return "", fmt.Errorf("failed to write input file: %w", err)
+	}
+	if err := inputFile.Close(); err != nil {
+		return "", fmt.Errorf("failed to close input file: %w", err)
+	}
+
+	outputPath := inputPath + ".out"
+	defer os.Remove(outputPath)
+
+	if err := controller.ProcessFile(inputPath, outputPath); err != nil {
+		return "", err
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read output: %w", err)
+	}
+	return string(data), nil
+}
+
+// Hash returns the hex encoded SHA-256 of the given output
+func Hash(output string) string {
+	sum := sha256.Sum256([]byte(output))
+	return hex.EncodeToString(sum[:])
+}
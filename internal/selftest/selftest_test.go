@@ -0,0 +1,49 @@
+package selftest
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"testing"
+)
+
+func TestCorpusMatchesArtifact(t *testing.T) {
+	result, err := Run()
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	if !result.Passed() {
+		t.Errorf("Corpus output hash changed\nExpected: %s\nActual:   %s\nUpdate corpus.sha256 if the change is intended", result.Expected, result.Actual)
+	}
+}
+
+func TestCorpusIsDeterministic(t *testing.T) {
+	first, err := ProcessCorpus()
+	if err != nil {
+		t.Fatalf("ProcessCorpus failed: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		again, err := ProcessCorpus()
+		if err != nil {
+			t.Fatalf("ProcessCorpus failed: %v", err)
+		}
+		if again != first {
+			t.Fatalf("Run %d produced different output than the first run", i+2)
+		}
+	}
+}
+
+func TestGoldenCasesPass(t *testing.T) {
+	testutils.RequireSuite(t, testutils.SUITE_GOLDEN)
+	result, err := Golden()
+	if err != nil {
+		t.Fatalf("Golden failed: %v", err)
+	}
+
+	if result.Cases == 0 {
+		t.Fatalf("No golden cases were embedded")
+	}
+	for _, failure := range result.Failures {
+		t.Errorf("%s\nExpected: %q\nActual:   %q", failure.Test.Name, failure.Test.Expected, failure.Actual)
+	}
+}
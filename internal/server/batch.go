@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/tracing"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+// batchRequest is the body of POST /transform/batch. The query string options apply to
+// every document, a document setting language, enable or disable replaces them for itself.
+//
+//	{"documents": [{"id": "a", "text": "it (up) works"}, {"id": "b", "text": "Quoi?oui", "language": "fr"}]}
+type batchRequest struct {
+	Documents []batchDocument `json:"documents"`
+}
+
+type batchDocument struct {
+	ID       string `json:"id,omitempty"`
+	Text     string `json:"text"`
+	Language string `json:"language,omitempty"`
+	Enable   string `json:"enable,omitempty"`
+	Disable  string `json:"disable,omitempty"`
+}
+
+// batchResponse has one result per document, in request order
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// batchResult carries either the output and warnings of a document or its error
+type batchResult struct {
+	ID       string         `json:"id,omitempty"`
+	Output   string         `json:"output"`
+	Warnings []batchWarning `json:"warnings,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+type batchWarning struct {
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Command string `json:"command"`
+	Reason  string `json:"reason"`
+}
+
+// batch decodes the documents, transforms them on the processor's worker pool and returns
+// the response status. A document that fails gets an error in its result, the response is
+// still 200.
+func (s *Server) batch(ctx context.Context, w http.ResponseWriter, r *http.Request) int {
+	_, parse := s.tracer.Start(ctx, "parse", tracing.SPAN_KIND_INTERNAL)
+	defaults, err := perCallOptions(r)
+	if err != nil {
+		parse.Finish()
+		return fail(w, http.StatusBadRequest, err)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	parse.SetAttribute("request.bytes", strconv.Itoa(len(body)))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		parse.Finish()
+		return fail(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", s.maxBody))
+	}
+	if err != nil {
+		parse.Finish()
+		return fail(w, http.StatusBadRequest, err)
+	}
+	s.metrics.requestBytes.Add(float64(len(body)))
+	var request batchRequest
+	if err := json.Unmarshal(body, &request); err != nil {
+		parse.Finish()
+		return fail(w, http.StatusBadRequest, fmt.Errorf("invalid batch request: %w", err))
+	}
+	parse.SetAttribute("batch.documents", strconv.Itoa(len(request.Documents)))
+	parse.Finish()
+
+	inputs := make([]transformer.Input, len(request.Documents))
+	failed := make([]error, len(request.Documents))
+	for i, document := range request.Documents {
+		// a document with invalid options is left empty, it costs nothing to process
+		if inputs[i].Options, failed[i] = documentOptions(document, defaults); failed[i] == nil {
+			inputs[i].Text = document.Text
+		}
+	}
+	results := s.processor.ProcessAll(ctx, inputs, 0)
+	if err := ctx.Err(); err != nil {
+		return fail(w, http.StatusServiceUnavailable, err)
+	}
+
+	response := batchResponse{Results: make([]batchResult, len(results))}
+	for i, result := range results {
+		out := batchResult{ID: request.Documents[i].ID}
+		switch {
+		case failed[i] != nil:
+			out.Error = failed[i].Error()
+		case result.Err != nil:
+			out.Error = result.Err.Error()
+		default:
+			s.count(result.Report)
+			out.Output = result.Output
+			for _, warning := range result.Warnings {
+				out.Warnings = append(out.Warnings, batchWarning{warning.Line, warning.Column, warning.Command, warning.Reason})
+			}
+		}
+		response.Results[i] = out
+	}
+
+	_, write := s.tracer.Start(ctx, "write", tracing.SPAN_KIND_INTERNAL)
+	defer write.Finish()
+	data, err := json.Marshal(response)
+	if err != nil {
+		return fail(w, http.StatusInternalServerError, err)
+	}
+	write.SetAttribute("response.bytes", strconv.Itoa(len(data)))
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(data)
+	return http.StatusOK
+}
+
+// documentOptions replaces the options of the query string with those a document sets
+func documentOptions(document batchDocument, call transformer.PerCallOptions) (transformer.PerCallOptions, error) {
+	var err error
+	if document.Language != "" {
+		call.Language = document.Language
+	}
+	if document.Enable != "" {
+		if call.Enable, err = transformer.ParseStages(document.Enable); err != nil {
+			return call, err
+		}
+	}
+	if document.Disable != "" {
+		if call.Disable, err = transformer.ParseStages(document.Disable); err != nil {
+			return call, err
+		}
+	}
+	return call, nil
+}
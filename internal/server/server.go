@@ -0,0 +1,190 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/metrics"
+	"github.com/GiannisPettas/go-reloaded/internal/tracing"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Largest request body accepted by default, larger texts belong in the file mode
+const MAX_BODY_BYTES = 10 << 20
+
+// Server exposes a shared Processor over HTTP:
+//
+//	POST /transform?language=fr&enable=sentences&disable=articles  text in, text out
+//	POST /transform/batch  JSON documents in, JSON results out, see batchRequest
+//	GET  /healthz
+//	GET  /metrics  Prometheus counters and latency histogram of the transform requests
+type Server struct {
+	processor *transformer.Processor
+	tracer    *tracing.Tracer
+	maxBody   int64
+	metrics   *serverMetrics
+}
+
+// serverMetrics are the series served on /metrics
+type serverMetrics struct {
+	registry      *metrics.Registry
+	requests      *metrics.Counter
+	requestBytes  *metrics.Counter
+	responseBytes *metrics.Counter
+	applied       *metrics.Counter
+	warnings      *metrics.Counter
+	latency       *metrics.Histogram
+}
+
+// newServerMetrics registers the series of a server
+func newServerMetrics() *serverMetrics {
+	registry := metrics.NewRegistry()
+	return &serverMetrics{
+		registry:      registry,
+		requests:      registry.Counter("http_requests_total", "Transform requests by route and response status code.", "route", "code"),
+		requestBytes:  registry.Counter("request_bytes_total", "Bytes of text received for transforming."),
+		responseBytes: registry.Counter("response_bytes_total", "Bytes of transformed text sent back."),
+		applied:       registry.Counter("transformations_total", "Words changed by each command.", "command"),
+		warnings:      registry.Counter("warnings_total", "Commands dropped or left in the text."),
+		latency:       registry.Histogram("request_duration_seconds", "Time to answer a transform request.", metrics.DefaultLatencyBuckets),
+	}
+}
+
+// New returns a server transforming with processor and tracing requests with tracer (nil disables tracing).
+// maxBody limits request bodies, 0 means MAX_BODY_BYTES.
+func New(processor *transformer.Processor, tracer *tracing.Tracer, maxBody int64) *Server {
+	if maxBody <= 0 {
+		maxBody = MAX_BODY_BYTES
+	}
+	return &Server{processor: processor, tracer: tracer, maxBody: maxBody, metrics: newServerMetrics()}
+}
+
+// Handler returns the HTTP routes of the server
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transform", s.handleTransform)
+	mux.HandleFunc("POST /transform/batch", s.handleBatch)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "ok\n")
+	})
+	mux.Handle("GET /metrics", s.metrics.registry.Handler())
+	return mux
+}
+
+// handleTransform traces one request as a server span with parse, transform, post-process
+// and write children. An incoming traceparent header makes it part of the caller's trace.
+func (s *Server) handleTransform(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if parent, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+		ctx = tracing.ContextWithRemoteParent(ctx, parent)
+	}
+	ctx, span := s.tracer.Start(ctx, "POST /transform", tracing.SPAN_KIND_SERVER)
+	defer span.Finish()
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("http.route", "/transform")
+
+	start := time.Now()
+	status := s.transform(ctx, w, r)
+	span.SetAttribute("http.response.status_code", strconv.Itoa(status))
+	s.metrics.requests.Add(1, "/transform", strconv.Itoa(status))
+	s.metrics.latency.Observe(time.Since(start).Seconds())
+}
+
+// handleBatch traces one batch request as a server span with parse and write children
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if parent, ok := tracing.ParseTraceparent(r.Header.Get("traceparent")); ok {
+		ctx = tracing.ContextWithRemoteParent(ctx, parent)
+	}
+	ctx, span := s.tracer.Start(ctx, "POST /transform/batch", tracing.SPAN_KIND_SERVER)
+	defer span.Finish()
+	span.SetAttribute("http.request.method", r.Method)
+	span.SetAttribute("http.route", "/transform/batch")
+
+	start := time.Now()
+	status := s.batch(ctx, w, r)
+	span.SetAttribute("http.response.status_code", strconv.Itoa(status))
+	s.metrics.requests.Add(1, "/transform/batch", strconv.Itoa(status))
+	s.metrics.latency.Observe(time.Since(start).Seconds())
+}
+
+// transform runs the traced stages of a request and returns the response status
+func (s *Server) transform(ctx context.Context, w http.ResponseWriter, r *http.Request) int {
+	_, parse := s.tracer.Start(ctx, "parse", tracing.SPAN_KIND_INTERNAL)
+	call, err := perCallOptions(r)
+	if err != nil {
+		parse.Finish()
+		return fail(w, http.StatusBadRequest, err)
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBody))
+	parse.SetAttribute("request.bytes", strconv.Itoa(len(body)))
+	parse.Finish()
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return fail(w, http.StatusRequestEntityTooLarge, fmt.Errorf("request body exceeds %d bytes", s.maxBody))
+	}
+	if err != nil {
+		return fail(w, http.StatusBadRequest, err)
+	}
+	s.metrics.requestBytes.Add(float64(len(body)))
+	if !utf8.Valid(body) {
+		return fail(w, http.StatusBadRequest, errors.New("request body is not valid UTF-8"))
+	}
+
+	// transform and post-process spans come from the processor phases
+	ctx = transformer.WithPhaseHook(ctx, func(ctx context.Context, phase string) func() {
+		_, span := s.tracer.Start(ctx, phase, tracing.SPAN_KIND_INTERNAL)
+		return span.Finish
+	})
+	report, err := s.processor.ProcessReport(ctx, string(body), call)
+	if errors.Is(err, transformer.ErrUnapplied) {
+		// The text was fine, the configured policy refuses it
+		return fail(w, http.StatusUnprocessableEntity, err)
+	}
+	if err != nil {
+		return fail(w, http.StatusBadRequest, err)
+	}
+	result := report.Output
+	s.count(report)
+
+	_, write := s.tracer.Start(ctx, "write", tracing.SPAN_KIND_INTERNAL)
+	defer write.Finish()
+	write.SetAttribute("response.bytes", strconv.Itoa(len(result)))
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	io.WriteString(w, result)
+	return http.StatusOK
+}
+
+// count adds what one document did to the metrics
+func (s *Server) count(report transformer.Report) {
+	for command, words := range report.Applied {
+		s.metrics.applied.Add(float64(words), command)
+	}
+	s.metrics.warnings.Add(float64(len(report.Warnings)))
+	s.metrics.responseBytes.Add(float64(len(report.Output)))
+}
+
+// reads the per-request options from the query string
+func perCallOptions(r *http.Request) (transformer.PerCallOptions, error) {
+	query := r.URL.Query()
+	call := transformer.PerCallOptions{Language: query.Get("language")}
+	var err error
+	if call.Enable, err = transformer.ParseStages(query.Get("enable")); err != nil {
+		return call, err
+	}
+	if call.Disable, err = transformer.ParseStages(query.Get("disable")); err != nil {
+		return call, err
+	}
+	return call, nil
+}
+
+// writes a plain text error and returns its status
+func fail(w http.ResponseWriter, status int, err error) int {
+	http.Error(w, err.Error(), status)
+	return status
+}
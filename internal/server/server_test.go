@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/tracing"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder keeps exported spans in memory
+type recorder struct {
+	mu    sync.Mutex
+	spans []*tracing.Span
+}
+
+func (r *recorder) ExportSpans(spans []*tracing.Span) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return nil
+}
+
+func newTestServer(t *testing.T, tracer *tracing.Tracer, maxBody int64) *httptest.Server {
+	processor, err := transformer.NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(New(processor, tracer, maxBody).Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, url, body string, header http.Header) (int, string) {
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(data)
+}
+
+func TestTransform(t *testing.T) {
+	server := newTestServer(t, nil, 64)
+
+	tests := []struct {
+		name   string
+		query  string
+		body   string
+		status int
+		expect string
+	}{
+		{"defaults", "", "a apple (up) , ok", http.StatusOK, "an APPLE, ok"},
+		{"language", "?language=fr", "Quoi?oui", http.StatusOK, "Quoi ? oui"},
+		{"stages", "?enable=sentences&disable=articles", "a apple. b", http.StatusOK, "A apple. B"},
+		{"unknown stage", "?enable=spelling", "text", http.StatusBadRequest, "unknown stage"},
+		{"unknown language", "?language=xx", "text", http.StatusBadRequest, "xx"},
+		{"too large", "", strings.Repeat("word ", 20), http.StatusRequestEntityTooLarge, "exceeds 64 bytes"},
+		{"invalid utf8", "", "caf\xe9", http.StatusBadRequest, "UTF-8"},
+	}
+	for _, test := range tests {
+		status, body := post(t, server.URL+"/transform"+test.query, test.body, nil)
+		if status != test.status || !strings.Contains(body, test.expect) {
+			t.Errorf("%s: got %d %q, expected %d containing %q", test.name, status, body, test.status, test.expect)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/transform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET /transform, got %d", resp.StatusCode)
+	}
+}
+
+func TestTransformUnapplied(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.UnappliedCommandPolicy = config.UNAPPLIED_ERROR
+	processor, err := transformer.NewProcessor(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(New(processor, nil, 0).Handler())
+	defer server.Close()
+
+	if status, body := post(t, server.URL+"/transform", "fine (up)", nil); status != http.StatusOK || body != "FINE" {
+		t.Errorf("Expected 200 FINE, got %d %q", status, body)
+	}
+	if status, body := post(t, server.URL+"/transform", "zz (hex)", nil); status != http.StatusUnprocessableEntity || !strings.Contains(body, "(hex)") {
+		t.Errorf("Expected 422 naming the command, got %d %q", status, body)
+	}
+}
+
+func TestTransformSpans(t *testing.T) {
+	rec := &recorder{}
+	tracer := tracing.NewTracer(rec, nil)
+	server := newTestServer(t, tracer, 0)
+
+	header := http.Header{"Traceparent": {"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}}
+	if status, body := post(t, server.URL+"/transform", "hello (up) world", header); status != http.StatusOK || body != "HELLO world" {
+		t.Fatalf("Unexpected response %d %q", status, body)
+	}
+	server.Close() // waits for the handler, its spans are finished
+	tracer.Close()
+
+	byName := map[string]*tracing.Span{}
+	for _, span := range rec.spans {
+		byName[span.Name] = span
+	}
+	root := byName["POST /transform"]
+	if root == nil || root.Kind != tracing.SPAN_KIND_SERVER || root.Attributes["http.response.status_code"] != "200" {
+		t.Fatalf("Missing or incomplete server span: %+v", rec.spans)
+	}
+	remote, _ := tracing.ParseTraceparent(header.Get("Traceparent"))
+	if root.TraceID != remote.TraceID || root.ParentID != remote.SpanID {
+		t.Errorf("Server span does not continue the incoming trace: %+v", root.SpanContext)
+	}
+
+	for _, name := range []string{"parse", transformer.PHASE_TRANSFORM, transformer.PHASE_POST_PROCESS, "write"} {
+		span := byName[name]
+		if span == nil {
+			t.Errorf("Missing %s span", name)
+			continue
+		}
+		if span.TraceID != root.TraceID || span.ParentID != root.SpanID {
+			t.Errorf("%s span is not a child of the server span", name)
+		}
+	}
+	if byName["parse"].Attributes["request.bytes"] != "16" || byName["write"].Attributes["response.bytes"] != "11" {
+		t.Errorf("Unexpected size attributes: %v %v", byName["parse"].Attributes, byName["write"].Attributes)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	server := newTestServer(t, nil, 64)
+	post(t, server.URL+"/transform", "a apple (up, 2) and 12 (bin)", nil)
+	post(t, server.URL+"/transform?language=xx", "text", nil)
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	for _, line := range []string{
+		`go_reloaded_http_requests_total{route="/transform",code="200"} 1`,
+		`go_reloaded_http_requests_total{route="/transform",code="400"} 1`,
+		`go_reloaded_request_bytes_total 32`,
+		`go_reloaded_transformations_total{command="up"} 2`,
+		`go_reloaded_warnings_total 1`,
+		`go_reloaded_request_duration_seconds_count 2`,
+	} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("Missing %q in:\n%s", line, data)
+		}
+	}
+}
+
+func TestTransformBatch(t *testing.T) {
+	server := newTestServer(t, nil, 0)
+	documents := []string{`{"id": "up", "text": "a apple (up)"}`, `{"id": "fr", "text": "Quoi?oui", "language": "fr"}`,
+		`{"id": "bad", "text": "x", "enable": "spelling"}`, `{"text": "zz (hex) left"}`}
+	status, body := post(t, server.URL+"/transform/batch?disable=articles", `{"documents": [`+strings.Join(documents, ",")+`]}`, nil)
+	if status != http.StatusOK {
+		t.Fatalf("Unexpected response %d %q", status, body)
+	}
+	var response batchResponse
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		t.Fatal(err)
+	}
+	expected := []batchResult{
+		{ID: "up", Output: "a APPLE"},
+		{ID: "fr", Output: "Quoi ? oui"},
+		{ID: "bad", Error: `unknown stage "spelling", expected articles, quotes, ordinals or sentences`},
+		{Output: "zz left", Warnings: []batchWarning{{1, 4, "(hex)", `"zz" is not a hexadecimal number`}}},
+	}
+	if fmt.Sprint(response.Results) != fmt.Sprint(expected) {
+		t.Errorf("Expected results %+v, got %+v", expected, response.Results)
+	}
+
+	for _, invalid := range []string{"not json", `{"documents": "text"}`} {
+		if status, _ := post(t, server.URL+"/transform/batch", invalid, nil); status != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %q, got %d", invalid, status)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package testutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// CorpusText describes a public-domain text used for large-file tests and benchmarks
+type CorpusText struct {
+	Name   string // cache file name, also used to look the text up
+	URL    string
+	SHA256 string // pinned SHA-256 of the file as downloaded, a text without one is never used
+}
+
+// Corpus lists the realistic large inputs available to tests (Project Gutenberg plain-text editions).
+// A new or updated edition fails with ErrCorpusChecksum naming the checksum to pin here.
+var Corpus = []CorpusText{
+	{Name: "pride-and-prejudice.txt", URL: "https://www.gutenberg.org/cache/epub/1342/pg1342.txt"},
+	{Name: "moby-dick.txt", URL: "https://www.gutenberg.org/cache/epub/2701/pg2701.txt"},
+	{Name: "frankenstein.txt", URL: "https://www.gutenberg.org/cache/epub/84/pg84.txt"},
+}
+
+// Environment variables controlling the corpus cache
+const (
+	CORPUS_DIR_ENV     = "GO_RELOADED_CORPUS_DIR" // overrides the cache location
+	CORPUS_OFFLINE_ENV = "GO_RELOADED_OFFLINE"    // set to skip corpus tests without touching the network
+	CORPUS_TIMEOUT     = 60 * time.Second
+)
+
+// ErrCorpusChecksum reports a corpus file whose content does not match its checksum
+var ErrCorpusChecksum = errors.New("corpus checksum mismatch")
+
+// CorpusDir returns the directory where corpus texts are cached
+func CorpusDir() (string, error) {
+	if dir := os.Getenv(CORPUS_DIR_ENV); dir != "" {
+		return dir, nil
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user cache dir: %w", err)
+	}
+	return filepath.Join(cacheDir, "go-reloaded", "corpus"), nil
+}
+
+// FindCorpusText looks up a corpus entry by name
+func FindCorpusText(name string) (CorpusText, error) {
+	for _, text := range Corpus {
+		if text.Name == name {
+			return text, nil
+		}
+	}
+	return CorpusText{}, fmt.Errorf("unknown corpus text %s", name)
+}
+
+// FetchCorpus returns the path of the cached text, downloading it first if needed.
+// Both the cached file and a fresh download are verified against the pinned checksum,
+// a download that does not match it is removed again.
+func FetchCorpus(text CorpusText) (string, error) {
+	dir, err := CorpusDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, text.Name)
+
+	if _, err := os.Stat(path); err == nil {
+		if err := verifyCorpusFile(text, path); err != nil {
+			return "", err
+		}
+		return path, nil
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create corpus dir %s: %w", dir, err)
+	}
+	if err := downloadCorpusFile(text.URL, path); err != nil {
+		return "", err
+	}
+
+	if err := verifyCorpusFile(text, path); err != nil {
+		os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// RequireCorpus fetches a corpus text for a test, skipping the test outside the integration
+// suite, when running with -short, when GO_RELOADED_OFFLINE is set or when the download fails
+func RequireCorpus(tb testing.TB, name string) string {
+	tb.Helper()
+	RequireSuite(tb, SUITE_INTEGRATION)
+	if testing.Short() {
+		tb.Skip("skipping corpus test in short mode")
+	}
+	if os.Getenv(CORPUS_OFFLINE_ENV) != "" {
+		tb.Skipf("skipping corpus test, %s is set", CORPUS_OFFLINE_ENV)
+	}
+
+	text, err := FindCorpusText(name)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	path, err := FetchCorpus(text)
+	if err != nil {
+		if errors.Is(err, ErrCorpusChecksum) {
+			tb.Fatal(err)
+		}
+		tb.Skipf("corpus text %s unavailable (offline?): %v", name, err)
+	}
+	return path
+}
+
+// downloadCorpusFile streams url into path through a temp file so partial downloads never land in the cache
+func downloadCorpusFile(url, path string) error {
+	client := http.Client{Timeout: CORPUS_TIMEOUT}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download %s: status %s", url, resp.Status)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, resp.Body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to move download into cache: %w", err)
+	}
+	return nil
+}
+
+// verifyCorpusFile checks a corpus file against the pinned checksum of its text
+func verifyCorpusFile(text CorpusText, path string) error {
+	sum, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if text.SHA256 == "" {
+		return fmt.Errorf("%w for %s: no checksum pinned in Corpus, got %s", ErrCorpusChecksum, text.Name, sum)
+	}
+	if sum != text.SHA256 {
+		return fmt.Errorf("%w for %s: expected %s, got %s", ErrCorpusChecksum, text.Name, text.SHA256, sum)
+	}
+	return nil
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of a file
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
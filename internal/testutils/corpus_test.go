@@ -0,0 +1,75 @@
+package testutils
+
+import (
+	"errors"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestFetchCorpus downloads and verifies every corpus text, the equivalent of "make corpus"
+func TestFetchCorpus(t *testing.T) {
+	for _, text := range Corpus {
+		t.Run(text.Name, func(t *testing.T) {
+			path := RequireCorpus(t, text.Name)
+			if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+				t.Errorf("Cached corpus file %s is missing or empty", path)
+			}
+		})
+	}
+}
+
+func TestCorpusChecksumMismatch(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv(CORPUS_DIR_ENV, dir)
+
+	text := CorpusText{Name: "tampered.txt", URL: "http://invalid.invalid/", SHA256: strings.Repeat("0", 64)}
+	os.WriteFile(filepath.Join(dir, text.Name), []byte("tampered"), 0644)
+
+	_, err := FetchCorpus(text)
+	if !errors.Is(err, ErrCorpusChecksum) {
+		t.Errorf("Expected checksum mismatch error, got %v", err)
+	}
+
+	// A text without a pinned checksum is never trusted, not even once cached
+	text.SHA256 = ""
+	if _, err := FetchCorpus(text); !errors.Is(err, ErrCorpusChecksum) {
+		t.Errorf("Expected an error for an unpinned text, got %v", err)
+	}
+}
+
+func TestProcessLargeCorpus(t *testing.T) {
+	inputPath := RequireCorpus(t, "moby-dick.txt")
+	outputPath := filepath.Join(t.TempDir(), "moby-dick.out.txt")
+
+	if err := controller.ProcessFile(inputPath, outputPath); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	output, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Failed to read output: %v", err)
+	}
+	if len(output) == 0 {
+		t.Errorf("Output is empty")
+	}
+	if !utf8.Valid(output) {
+		t.Errorf("Output is not valid UTF-8")
+	}
+}
+
+func BenchmarkProcessCorpus(b *testing.B) {
+	inputPath := RequireCorpus(b, "pride-and-prejudice.txt")
+	outputPath := filepath.Join(b.TempDir(), "out.txt")
+	info, _ := os.Stat(inputPath)
+	b.SetBytes(info.Size())
+
+	for i := 0; i < b.N; i++ {
+		if err := controller.ProcessFile(inputPath, outputPath); err != nil {
+			b.Fatalf("ProcessFile failed: %v", err)
+		}
+	}
+}
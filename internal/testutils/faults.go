@@ -0,0 +1,107 @@
+package testutils
+
+import (
+	"errors"
+	"io"
+	"sync"
+)
+
+// ErrInjected is the error returned by FaultySource and FaultySink once their fault triggers
+var ErrInjected = errors.New("injected IO fault")
+
+// FaultySource reads from an io.ReaderAt and fails every read after the first FailAfter ones.
+// A negative FailAfter never fails. It records whether it was closed.
+type FaultySource struct {
+	io.ReaderAt
+	FailAfter int
+
+	mu     sync.Mutex
+	reads  int
+	closed bool
+}
+
+// ReadAt counts the read and fails it once FailAfter reads were served
+func (s *FaultySource) ReadAt(p []byte, offset int64) (int, error) {
+	s.mu.Lock()
+	s.reads++
+	fail := s.FailAfter >= 0 && s.reads > s.FailAfter
+	s.mu.Unlock()
+	if fail {
+		return 0, ErrInjected
+	}
+	return s.ReaderAt.ReadAt(p, offset)
+}
+
+// Close marks the source closed and closes the underlying reader when it is an io.Closer
+func (s *FaultySource) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	if closer, ok := s.ReaderAt.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Closed reports whether Close was called
+func (s *FaultySource) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// FaultySink writes to an io.Writer and fails every write once FailAfter bytes were written,
+// the write crossing the limit is cut short. A negative FailAfter never fails writes.
+// FailClose makes Close fail after closing the underlying writer.
+type FaultySink struct {
+	io.Writer
+	FailAfter int
+	FailClose bool
+
+	mu      sync.Mutex
+	written int
+	closed  bool
+}
+
+// Write passes p on up to the FailAfter limit
+func (s *FaultySink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.FailAfter >= 0 && s.written+len(p) > s.FailAfter {
+		n, _ := s.Writer.Write(p[:s.FailAfter-s.written])
+		s.written += n
+		return n, ErrInjected
+	}
+	n, err := s.Writer.Write(p)
+	s.written += n
+	return n, err
+}
+
+// Close marks the sink closed and closes the underlying writer when it is an io.Closer
+func (s *FaultySink) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	var err error
+	if closer, ok := s.Writer.(io.Closer); ok {
+		err = closer.Close()
+	}
+	if s.FailClose && err == nil {
+		err = ErrInjected
+	}
+	return err
+}
+
+// Closed reports whether Close was called
+func (s *FaultySink) Closed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// Written returns the number of bytes passed to the underlying writer
+func (s *FaultySink) Written() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.written
+}
@@ -0,0 +1,42 @@
+package testutils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFaultySource(t *testing.T) {
+	source := &FaultySource{ReaderAt: strings.NewReader("hello world"), FailAfter: 1}
+	buf := make([]byte, 5)
+	if n, err := source.ReadAt(buf, 6); err != nil || string(buf[:n]) != "world" {
+		t.Errorf("First read = %q, %v, expected \"world\"", buf[:n], err)
+	}
+	if _, err := source.ReadAt(buf, 0); !errors.Is(err, ErrInjected) {
+		t.Errorf("Expected ErrInjected on the second read, got %v", err)
+	}
+	if source.Closed() {
+		t.Error("Source reported closed before Close")
+	}
+	source.Close()
+	if !source.Closed() {
+		t.Error("Source not reported closed after Close")
+	}
+}
+
+func TestFaultySink(t *testing.T) {
+	var out strings.Builder
+	sink := &FaultySink{Writer: &out, FailAfter: 8, FailClose: true}
+	if _, err := sink.Write([]byte("hello ")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := sink.Write([]byte("world")); n != 2 || !errors.Is(err, ErrInjected) {
+		t.Errorf("Write crossing the limit = %d, %v, expected 2, ErrInjected", n, err)
+	}
+	if out.String() != "hello wo" || sink.Written() != 8 {
+		t.Errorf("Sink wrote %q (%d bytes), expected \"hello wo\"", out.String(), sink.Written())
+	}
+	if err := sink.Close(); !errors.Is(err, ErrInjected) || !sink.Closed() {
+		t.Errorf("Close = %v, closed %v, expected ErrInjected and closed", err, sink.Closed())
+	}
+}
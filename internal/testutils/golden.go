@@ -3,7 +3,10 @@ package testutils
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -11,6 +14,7 @@ type GoldenTest struct {
 	Name     string
 	Input    string
 	Expected string
+	Path     string // file holding the expected output, "" when parsed from a reader
 }
 
 // ParseGoldenTests reads and parses golden_tests.md file
@@ -21,25 +25,80 @@ func ParseGoldenTests(filePath string) ([]GoldenTest, error) {
 	}
 	defer file.Close()
 
+	tests, err := ParseGolden(file)
+	for i := range tests {
+		tests[i].Path = filePath
+	}
+	return tests, err
+}
+
+// ParseGolden parses golden tests in the golden_tests.md format from r.
+// Lines are read whole, so inputs and expected outputs can be arbitrarily long.
+// Plain sections are trimmed and lose their blank lines; a section written as a
+// fenced block (```text ... ```) is taken exactly, whitespace and blank lines included.
+func ParseGolden(r io.Reader) ([]GoldenTest, error) {
 	var tests []GoldenTest
-	scanner := bufio.NewScanner(file)
-	
+	reader := bufio.NewReader(r)
+
 	var currentTest GoldenTest
 	var inInput, inExpected bool
 	var inputBuilder, expectedBuilder strings.Builder
+	var inputExact, expectedExact bool // the section was a fenced block
+	var fence *strings.Builder         // builder of the open fenced block, nil outside fences
+	fenceEmpty := false                // nothing written to the open fenced block yet
+
+	section := func(text string, exact bool) string {
+		if exact {
+			return text
+		}
+		return strings.TrimSpace(text)
+	}
+
+	for done := false; !done; {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("error reading file: %w", readErr)
+		}
+		done = readErr == io.EOF
+		if done && line == "" {
+			break
+		}
+		// Same line ending handling as bufio.ScanLines
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
+
+		// Fenced block content is taken verbatim until the closing fence
+		if fence != nil {
+			if strings.TrimSpace(line) == "```" {
+				fence = nil
+			} else {
+				if !fenceEmpty {
+					fence.WriteByte('\n')
+				}
+				fence.WriteString(line)
+				fenceEmpty = false
+			}
+			continue
+		}
+		if (inInput || inExpected) && strings.HasPrefix(strings.TrimSpace(line), "```") {
+			if inInput {
+				fence, inputExact = &inputBuilder, true
+			} else {
+				fence, expectedExact = &expectedBuilder, true
+			}
+			fence.Reset()
+			fenceEmpty = true
+			continue
+		}
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		
 		// Parse test name
 		if strings.HasPrefix(line, "## T") && strings.Contains(line, "—") {
 			// Save previous test if exists
 			if currentTest.Name != "" {
-				currentTest.Input = strings.TrimSpace(inputBuilder.String())
-				currentTest.Expected = strings.TrimSpace(expectedBuilder.String())
+				currentTest.Input = section(inputBuilder.String(), inputExact)
+				currentTest.Expected = section(expectedBuilder.String(), expectedExact)
 				tests = append(tests, currentTest)
 			}
-			
+
 			// Start new test
 			parts := strings.Split(line, "—")
 			if len(parts) >= 2 {
@@ -48,9 +107,11 @@ func ParseGoldenTests(filePath string) ([]GoldenTest, error) {
 				expectedBuilder.Reset()
 				inInput = false
 				inExpected = false
+				inputExact = false
+				expectedExact = false
 			}
 		}
-		
+
 		// Parse input section
 		trimmedLine := strings.TrimSpace(line)
 		if trimmedLine == "**Input:**" {
@@ -58,46 +119,268 @@ func ParseGoldenTests(filePath string) ([]GoldenTest, error) {
 			inExpected = false
 			continue
 		}
-		
+
 		// Parse expected output section
 		if trimmedLine == "**Expected Output:**" {
 			inInput = false
 			inExpected = true
 			continue
 		}
-		
+
 		// Stop parsing when hitting next section
 		if strings.HasPrefix(trimmedLine, "**") && trimmedLine != "**Input:**" && trimmedLine != "**Expected Output:**" {
 			inInput = false
 			inExpected = false
 		}
-		
-		// Collect input/expected content
-		if inInput && line != "" {
+
+		// Collect input/expected content, a fenced section ignores lines after its fence
+		if inInput && !inputExact && line != "" {
 			if inputBuilder.Len() > 0 {
 				inputBuilder.WriteByte('\n')
 			}
 			inputBuilder.WriteString(line)
 		}
-		
-		if inExpected && line != "" {
+
+		if inExpected && !expectedExact && line != "" {
 			if expectedBuilder.Len() > 0 {
 				expectedBuilder.WriteByte('\n')
 			}
 			expectedBuilder.WriteString(line)
 		}
 	}
-	
+
+	if fence != nil {
+		return nil, fmt.Errorf("golden test %s: fenced block is never closed", currentTest.Name)
+	}
+
 	// Save last test
 	if currentTest.Name != "" {
-		currentTest.Input = strings.TrimSpace(inputBuilder.String())
-		currentTest.Expected = strings.TrimSpace(expectedBuilder.String())
+		currentTest.Input = section(inputBuilder.String(), inputExact)
+		currentTest.Expected = section(expectedBuilder.String(), expectedExact)
 		tests = append(tests, currentTest)
 	}
-	
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+
+	return tests, nil
+}
+
+const (
+	GOLDEN_INPUT_SUFFIX    = ".input.txt"
+	GOLDEN_EXPECTED_SUFFIX = ".expected.txt"
+	GOLDEN_CASES_DIR       = "cases"           // pair directory below a golden directory
+	GOLDEN_MARKDOWN        = "golden_tests.md" // markdown cases read from a golden directory
+)
+
+// LoadGolden reads the golden tests at path. A file is parsed in the golden_tests.md format.
+// A directory contributes its golden_tests.md, then every <name>.input.txt with its
+// <name>.expected.txt, from the directory itself and from its cases subdirectory.
+// Pair files are taken byte for byte and named after the file.
+func LoadGolden(path string) ([]GoldenTest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open golden tests: %w", err)
+	}
+	if !info.IsDir() {
+		return ParseGoldenTests(path)
+	}
+
+	var tests []GoldenTest
+	markdown := filepath.Join(path, GOLDEN_MARKDOWN)
+	if _, err := os.Stat(markdown); err == nil {
+		if tests, err = ParseGoldenTests(markdown); err != nil {
+			return nil, err
+		}
+	}
+	for _, dir := range []string{path, filepath.Join(path, GOLDEN_CASES_DIR)} {
+		pairs, err := loadGoldenPairs(dir)
+		if err != nil {
+			return nil, err
+		}
+		tests = append(tests, pairs...)
+	}
+	if len(tests) == 0 {
+		return nil, fmt.Errorf("no golden tests found in %s", path)
 	}
-	
 	return tests, nil
-}
\ No newline at end of file
+}
+
+// loadGoldenPairs reads the input and expected file pairs of dir in name order
+func loadGoldenPairs(dir string) ([]GoldenTest, error) {
+	inputs, err := filepath.Glob(filepath.Join(dir, "*"+GOLDEN_INPUT_SUFFIX))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list golden cases: %w", err)
+	}
+	sort.Strings(inputs)
+
+	var tests []GoldenTest
+	for _, inputPath := range inputs {
+		base := strings.TrimSuffix(inputPath, GOLDEN_INPUT_SUFFIX)
+		input, err := os.ReadFile(inputPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read golden input: %w", err)
+		}
+		expected, err := os.ReadFile(base + GOLDEN_EXPECTED_SUFFIX)
+		if err != nil {
+			return nil, fmt.Errorf("golden case %s has no expected output: %w", filepath.Base(base), err)
+		}
+		tests = append(tests, GoldenTest{Name: filepath.Base(base), Input: string(input), Expected: string(expected), Path: base + GOLDEN_EXPECTED_SUFFIX})
+	}
+	return tests, nil
+}
+
+// GoldenFailure is a golden test whose output differs from the expected one
+type GoldenFailure struct {
+	Test   GoldenTest
+	Actual string
+}
+
+// GoldenReport describes the outcome of running golden tests
+type GoldenReport struct {
+	Cases    int
+	Failures []GoldenFailure
+}
+
+// Passed reports whether there were tests and every one produced its expected output
+func (r GoldenReport) Passed() bool {
+	return r.Cases > 0 && len(r.Failures) == 0
+}
+
+// RunGolden runs every test through process and collects the ones whose output differs.
+// An error from process stops the run.
+func RunGolden(tests []GoldenTest, process func(input string) (string, error)) (GoldenReport, error) {
+	report := GoldenReport{Cases: len(tests)}
+	for _, test := range tests {
+		actual, err := process(test.Input)
+		if err != nil {
+			return GoldenReport{}, fmt.Errorf("golden case %s: %w", test.Name, err)
+		}
+		if actual != test.Expected {
+			report.Failures = append(report.Failures, GoldenFailure{Test: test, Actual: actual})
+		}
+	}
+	return report, nil
+}
+
+// UpdateGoldenTests runs every test through process and writes the output back as its expected
+// output, for behavior changes that are intended. A pair case gets a new expected file; a
+// markdown file gets new expected sections, written as fenced blocks when the exact whitespace
+// matters, and is otherwise left as it was. It returns the tests whose expectation changed.
+func UpdateGoldenTests(tests []GoldenTest, process func(input string) (string, error)) ([]GoldenTest, error) {
+	var changed []GoldenTest
+	markdown := map[string]map[string]string{} // path -> test name -> new expected output
+	var order []string
+	for _, test := range tests {
+		if test.Path == "" {
+			return nil, fmt.Errorf("golden case %s: no file to update", test.Name)
+		}
+		actual, err := process(test.Input)
+		if err != nil {
+			return nil, fmt.Errorf("golden case %s: %w", test.Name, err)
+		}
+		if actual == test.Expected {
+			continue
+		}
+		test.Expected = actual
+		changed = append(changed, test)
+
+		if strings.HasSuffix(test.Path, GOLDEN_EXPECTED_SUFFIX) {
+			if err := os.WriteFile(test.Path, []byte(actual), 0644); err != nil {
+				return nil, fmt.Errorf("failed to update golden case %s: %w", test.Name, err)
+			}
+			continue
+		}
+		if markdown[test.Path] == nil {
+			markdown[test.Path] = map[string]string{}
+			order = append(order, test.Path)
+		}
+		markdown[test.Path][test.Name] = actual
+	}
+
+	for _, path := range order {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read golden tests file: %w", err)
+		}
+		doc, err := rewriteGoldenExpected(string(data), markdown[path])
+		if err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+			return nil, fmt.Errorf("failed to update golden tests file: %w", err)
+		}
+	}
+	return changed, nil
+}
+
+// rewriteGoldenExpected replaces the expected output section of every test named in expected.
+// A section runs from its marker to the next test heading or bold marker, its trailing blank
+// lines are kept as the separator.
+func rewriteGoldenExpected(doc string, expected map[string]string) (string, error) {
+	lines := strings.SplitAfter(doc, "\n")
+	var out strings.Builder
+	name := ""
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(line, "## T") && strings.Contains(line, "—") {
+			name = strings.TrimSpace(strings.Split(line, "—")[0][3:])
+		}
+		out.WriteString(line)
+		replacement, ok := expected[name]
+		if trimmed != "**Expected Output:**" || !ok {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			out.WriteString("\n")
+		}
+
+		// Skip the old section, a fence is skipped whole even when it holds marker lines
+		end, inFence := i+1, false
+		for ; end < len(lines); end++ {
+			current := strings.TrimSpace(lines[end])
+			if strings.HasPrefix(current, "```") {
+				inFence = !inFence
+				continue
+			}
+			if !inFence && (strings.HasPrefix(lines[end], "## T") && strings.Contains(lines[end], "—") || strings.HasPrefix(current, "**")) {
+				break
+			}
+		}
+		separator := end
+		for separator > i+1 && strings.TrimSpace(lines[separator-1]) == "" {
+			separator--
+		}
+
+		section, err := goldenSection(name, replacement)
+		if err != nil {
+			return "", err
+		}
+		out.WriteString(section)
+		for _, blank := range lines[separator:end] {
+			out.WriteString(blank)
+		}
+		i = end - 1
+		delete(expected, name)
+	}
+	for name := range expected {
+		return "", fmt.Errorf("golden case %s: expected output section not found", name)
+	}
+	return out.String(), nil
+}
+
+// goldenSection writes text as an expected output section that parses back to text exactly
+func goldenSection(name, text string) (string, error) {
+	plain := text != "" && text == strings.TrimSpace(text) && !strings.Contains(text, "\n\n")
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			return "", fmt.Errorf("golden case %s: output holds a fence line and cannot be written as markdown", name)
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "**") || strings.HasPrefix(line, "## T") {
+			plain = false
+		}
+	}
+	if plain {
+		return text + "\n", nil
+	}
+	return "```text\n" + text + "\n```\n", nil
+}
@@ -1,20 +1,23 @@
 package testutils
 
 import (
-	"go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
 func TestGoldenCases(t *testing.T) {
+	RequireSuite(t, SUITE_GOLDEN)
 	// Force no cache for golden tests - they are core to the program
 	t.Setenv("GOCACHE", "off")
-	
+
 	tests, err := ParseGoldenTests("../../docs/golden_tests.md")
 	if err != nil {
 		t.Fatalf("Failed to parse golden tests: %v", err)
 	}
-	
+
 	for _, test := range tests {
 		t.Run(test.Name, func(t *testing.T) {
 			inputPath, err := CreateTestFile(test.Input)
@@ -22,27 +25,232 @@ func TestGoldenCases(t *testing.T) {
 				t.Fatalf("Failed to create input file: %v", err)
 			}
 			defer CleanupTestFile(inputPath)
-			
+
 			outputPath, err := CreateTestFile("")
 			if err != nil {
 				t.Fatalf("Failed to create output file: %v", err)
 			}
 			defer CleanupTestFile(outputPath)
-			
+
 			err = controller.ProcessFile(inputPath, outputPath)
 			if err != nil {
 				t.Fatalf("ProcessFile failed: %v", err)
 			}
-			
+
 			actualData, err := os.ReadFile(outputPath)
 			if err != nil {
 				t.Fatalf("Failed to read output: %v", err)
 			}
-			
+
 			actual := string(actualData)
 			if actual != test.Expected {
 				t.Errorf("\nExpected: %q\nActual:   %q", test.Expected, actual)
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestParseGoldenLongLines(t *testing.T) {
+	// Far beyond the 64 KiB token limit of bufio.Scanner
+	long := strings.Repeat("word ", 1<<18)
+	doc := "## T1 — Long line\r\n\r\n**Input:**\r\n" + long + "(up)\r\n\r\n**Expected Output:**\n" + long + "\n\n" +
+		"## T2 — Last line without newline\n**Input:**\nx\n**Expected Output:**\nX"
+
+	tests, err := ParseGolden(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ParseGolden failed: %v", err)
+	}
+	if len(tests) != 2 {
+		t.Fatalf("Expected 2 tests, got %d", len(tests))
+	}
+	if tests[0].Name != "T1" || tests[0].Input != strings.TrimSpace(long)+" (up)" || tests[0].Expected != strings.TrimSpace(long) {
+		t.Errorf("Long test parsed as %q with %d/%d bytes", tests[0].Name, len(tests[0].Input), len(tests[0].Expected))
+	}
+	if tests[1] != (GoldenTest{Name: "T2", Input: "x", Expected: "X"}) {
+		t.Errorf("Unexpected last test %+v", tests[1])
+	}
+}
+
+func TestParseGoldenFencedBlocks(t *testing.T) {
+	doc := "## T1 — Fenced\n**Input:**\n```text\n  lead\tand trail  \n\n## T9 — not a heading in a fence\n\n```\n" +
+		"**Expected Output:**\n```\n\nexact\n```\n" +
+		"## T2 — Plain\n**Input:**\n  trimmed  \n\n**Expected Output:**\n```text\n```\n"
+
+	tests, err := ParseGolden(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("ParseGolden failed: %v", err)
+	}
+	expected := []GoldenTest{
+		{Name: "T1", Input: "  lead\tand trail  \n\n## T9 — not a heading in a fence\n", Expected: "\nexact"},
+		{Name: "T2", Input: "trimmed", Expected: ""},
+	}
+	if len(tests) != len(expected) {
+		t.Fatalf("Expected %d tests, got %+v", len(expected), tests)
+	}
+	for i := range expected {
+		if tests[i] != expected[i] {
+			t.Errorf("Test %d: expected %+v, got %+v", i, expected[i], tests[i])
+		}
+	}
+
+	if _, err := ParseGolden(strings.NewReader("## T1 — Open\n**Input:**\n```text\nnever closed\n")); err == nil {
+		t.Error("Expected an error for an unclosed fence")
+	}
+}
+
+// TestGoldenFileCases runs the file pair cases of testdata/cases at the project root
+func TestGoldenFileCases(t *testing.T) {
+	RequireSuite(t, SUITE_GOLDEN)
+	tests, err := LoadGolden("../../testdata")
+	if err != nil {
+		t.Fatalf("LoadGolden failed: %v", err)
+	}
+
+	report, err := RunGolden(tests, processThroughFile)
+	if err != nil {
+		t.Fatalf("RunGolden failed: %v", err)
+	}
+	for _, failure := range report.Failures {
+		t.Errorf("%s\nExpected: %q\nActual:   %q", failure.Test.Name, failure.Test.Expected, failure.Actual)
+	}
+}
+
+func TestLoadGolden(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		GOLDEN_MARKDOWN:                    "## T1 — Markdown\n**Input:**\nx (up)\n**Expected Output:**\nX\n",
+		"top" + GOLDEN_INPUT_SUFFIX:        "top (up)",
+		"top" + GOLDEN_EXPECTED_SUFFIX:     "TOP",
+		"cases/b" + GOLDEN_INPUT_SUFFIX:    "  kept  \n\n",
+		"cases/b" + GOLDEN_EXPECTED_SUFFIX: "kept\n\n",
+		"cases/a" + GOLDEN_INPUT_SUFFIX:    "a",
+		"cases/a" + GOLDEN_EXPECTED_SUFFIX: "a",
+		"cases/notes.txt":                  "not a case",
+	}
+	os.Mkdir(filepath.Join(dir, GOLDEN_CASES_DIR), 0755)
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests, err := LoadGolden(dir)
+	if err != nil {
+		t.Fatalf("LoadGolden failed: %v", err)
+	}
+	expected := []GoldenTest{
+		{Name: "T1", Input: "x (up)", Expected: "X", Path: filepath.Join(dir, GOLDEN_MARKDOWN)},
+		{Name: "top", Input: "top (up)", Expected: "TOP", Path: filepath.Join(dir, "top"+GOLDEN_EXPECTED_SUFFIX)},
+		{Name: "a", Input: "a", Expected: "a", Path: filepath.Join(dir, "cases/a"+GOLDEN_EXPECTED_SUFFIX)},
+		{Name: "b", Input: "  kept  \n\n", Expected: "kept\n\n", Path: filepath.Join(dir, "cases/b"+GOLDEN_EXPECTED_SUFFIX)},
+	}
+	if len(tests) != len(expected) {
+		t.Fatalf("Expected %d tests, got %+v", len(expected), tests)
+	}
+	for i := range expected {
+		if tests[i] != expected[i] {
+			t.Errorf("Test %d: expected %+v, got %+v", i, expected[i], tests[i])
+		}
+	}
+
+	os.Remove(filepath.Join(dir, "cases/a"+GOLDEN_EXPECTED_SUFFIX))
+	if _, err := LoadGolden(dir); err == nil || !strings.Contains(err.Error(), "golden case a has no expected output") {
+		t.Errorf("Expected an error for a missing expected file, got %v", err)
+	}
+	if _, err := LoadGolden(t.TempDir()); err == nil {
+		t.Error("Expected an error for a directory without cases")
+	}
+}
+
+func TestRunGolden(t *testing.T) {
+	tests := []GoldenTest{
+		{Name: "same", Input: "a", Expected: "a"},
+		{Name: "upper", Input: "b", Expected: "B"},
+	}
+	report, err := RunGolden(tests, func(input string) (string, error) { return input, nil })
+	if err != nil {
+		t.Fatalf("RunGolden failed: %v", err)
+	}
+	if report.Passed() || report.Cases != 2 || len(report.Failures) != 1 || report.Failures[0].Test.Name != "upper" || report.Failures[0].Actual != "b" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+
+	if _, err := RunGolden(tests, func(string) (string, error) { return "", os.ErrInvalid }); err == nil || !strings.Contains(err.Error(), "golden case same") {
+		t.Errorf("Expected the error of the first case, got %v", err)
+	}
+	if (GoldenReport{}).Passed() {
+		t.Error("A report without cases should not pass")
+	}
+}
+
+func TestUpdateGoldenTests(t *testing.T) {
+	dir := t.TempDir()
+	doc := "# Cases\n\n## T1 — Plain\n\n**Input:**  \nold (up)\n\n**Expected Output:**  \nold\n\n\n" +
+		"## T2 — Unchanged\n**Input:**\nSAME\n**Expected Output:**\nSAME\n\n" +
+		"## T3 — Fenced\n**Input:**\n```text\n  pad (up)\n\n```\n**Expected Output:**\n```text\n**not a marker**\n```\n**Notes:**\nkept\n"
+	files := map[string]string{
+		GOLDEN_MARKDOWN:              doc,
+		"a" + GOLDEN_INPUT_SUFFIX:    "a (up)\n",
+		"a" + GOLDEN_EXPECTED_SUFFIX: "a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	upper := func(input string) (string, error) { return strings.ToUpper(input), nil }
+
+	tests, err := LoadGolden(dir)
+	if err != nil {
+		t.Fatalf("LoadGolden failed: %v", err)
+	}
+	changed, err := UpdateGoldenTests(tests, upper)
+	if err != nil {
+		t.Fatalf("UpdateGoldenTests failed: %v", err)
+	}
+	if len(changed) != 3 || changed[0].Name != "T1" || changed[1].Name != "T3" || changed[2].Name != "a" {
+		t.Errorf("Expected T1, T3 and a to change, got %+v", changed)
+	}
+
+	updated, _ := os.ReadFile(filepath.Join(dir, GOLDEN_MARKDOWN))
+	expectedDoc := "# Cases\n\n## T1 — Plain\n\n**Input:**  \nold (up)\n\n**Expected Output:**  \nOLD (UP)\n\n\n" +
+		"## T2 — Unchanged\n**Input:**\nSAME\n**Expected Output:**\nSAME\n\n" +
+		"## T3 — Fenced\n**Input:**\n```text\n  pad (up)\n\n```\n**Expected Output:**\n```text\n  PAD (UP)\n\n```\n**Notes:**\nkept\n"
+	if string(updated) != expectedDoc {
+		t.Errorf("Unexpected markdown after the update:\n%s", updated)
+	}
+
+	// The updated files now pass as they are
+	tests, err = LoadGolden(dir)
+	if err != nil {
+		t.Fatalf("LoadGolden failed: %v", err)
+	}
+	if report, err := RunGolden(tests, upper); err != nil || !report.Passed() {
+		t.Errorf("Updated cases should pass, got %+v, %v", report, err)
+	}
+
+	if _, err := UpdateGoldenTests([]GoldenTest{{Name: "x"}}, upper); err == nil {
+		t.Error("Expected an error for a test without a file")
+	}
+	fence := []GoldenTest{{Name: "T1", Input: "```", Path: filepath.Join(dir, GOLDEN_MARKDOWN)}}
+	if _, err := UpdateGoldenTests(fence, upper); err == nil || !strings.Contains(err.Error(), "fence") {
+		t.Errorf("Expected an error for an output with a fence line, got %v", err)
+	}
+}
+
+// processThroughFile runs input through controller.ProcessFile like the golden cases
+func processThroughFile(input string) (string, error) {
+	inputPath, err := CreateTestFile(input)
+	if err != nil {
+		return "", err
+	}
+	defer CleanupTestFile(inputPath)
+	outputPath := inputPath + ".out"
+	defer CleanupTestFile(outputPath)
+
+	if err := controller.ProcessFile(inputPath, outputPath); err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(outputPath)
+	return string(data), err
+}
@@ -0,0 +1,69 @@
+package testutils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Environment variables of the end-to-end memory ceiling test
+const (
+	MEMORY_SIZE_ENV  = "GO_RELOADED_MEMORY_TEST_MB"  // synthetic input size, set 1024 for the full 1 GB run
+	MEMORY_LIMIT_ENV = "GO_RELOADED_MEMORY_LIMIT_MB" // peak RSS allowed while processing it
+)
+
+// Defaults keep the test fast while the input is still larger than the limit,
+// so buffering the whole output fails it
+const (
+	DEFAULT_MEMORY_SIZE_MB  = 48
+	DEFAULT_MEMORY_LIMIT_MB = 32
+)
+
+// one paragraph of the synthetic input, exercising every stage of the pipeline. Its
+// (cap, 50) reaches back over the line breaks of the paragraphs before it.
+const syntheticParagraph = "It was a apple , 1E (hex) files and 10 (bin) more (up, 2) . " +
+	"She said ' hello there ' and left (cap) !\nthe end , of the line (title, 3)\n" +
+	"and the words before (cap, 50)\n"
+
+// the last line of the synthetic input, reaching back over the whole output written before it
+const syntheticEnd = "the end (low, all)\n"
+
+// WriteSyntheticFile streams size bytes of repeated text to path without holding it in memory,
+// ending with a command that makes the output be written again
+func WriteSyntheticFile(path string, size int64) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create synthetic file: %w", err)
+	}
+	writer := bufio.NewWriter(file)
+
+	for written := int64(0); written < size; written += int64(len(syntheticParagraph)) {
+		if _, err := writer.WriteString(syntheticParagraph); err != nil {
+			file.Close()
+			return fmt.Errorf("failed to write synthetic file: %w", err)
+		}
+	}
+	if _, err := writer.WriteString(syntheticEnd); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write synthetic file: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write synthetic file: %w", err)
+	}
+	return file.Close()
+}
+
+// EnvMegabytes reads a size in megabytes from the environment, or returns the default
+func EnvMegabytes(name string, defaultMB int64) (int64, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultMB, nil
+	}
+	mb, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || mb <= 0 {
+		return 0, fmt.Errorf("%s must be a positive number of megabytes, got %q", name, value)
+	}
+	return mb, nil
+}
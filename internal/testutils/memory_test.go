@@ -0,0 +1,88 @@
+package testutils
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/bench"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// TestMemoryCeiling processes a large synthetic file with the CLI under --max-memory and checks
+// its peak RSS, guarding against stages that buffer the whole input or output. Its commands reach
+// back into written output, so the ceiling also holds while the output is rewritten.
+// Full run: GO_RELOADED_MEMORY_TEST_MB=1024 GO_RELOADED_MEMORY_LIMIT_MB=64 go test -run TestMemoryCeiling ./internal/testutils
+func TestMemoryCeiling(t *testing.T) {
+	RequireSuite(t, SUITE_INTEGRATION)
+	if testing.Short() {
+		t.Skip("memory ceiling test skipped in -short mode")
+	}
+	sizeMB, err := EnvMegabytes(MEMORY_SIZE_ENV, DEFAULT_MEMORY_SIZE_MB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	limitMB, err := EnvMegabytes(MEMORY_LIMIT_ENV, DEFAULT_MEMORY_LIMIT_MB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectRoot, err := findProjectRoot()
+	if err != nil {
+		t.Fatalf("Failed to find project root: %v", err)
+	}
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "go-reloaded")
+	build := exec.Command("go", "build", "-o", binary, "./cmd/go-reloaded")
+	build.Dir = projectRoot
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("Failed to build CLI: %v\n%s", err, output)
+	}
+
+	inputPath := filepath.Join(dir, "synthetic.txt")
+	outputPath := filepath.Join(dir, "synthetic-out.txt")
+	if err := WriteSyntheticFile(inputPath, sizeMB<<20); err != nil {
+		t.Fatal(err)
+	}
+
+	// --max-memory is a soft limit for the garbage collector, the peak RSS may pass it by a
+	// few MB, so the run gets a quarter of the ceiling as headroom
+	cmd := exec.Command(binary, "--max-memory", fmt.Sprintf("%dMB", limitMB*3/4), inputPath, outputPath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("Processing failed: %v\n%s", err, output)
+	}
+
+	rss := bench.PeakRSS(cmd.ProcessState)
+	if rss == 0 {
+		t.Skip("peak RSS is not available on this platform")
+	}
+	t.Logf("processed %d MB with peak RSS %.1f MB (limit %d MB)", sizeMB, float64(rss)/(1<<20), limitMB)
+	if rss > limitMB<<20 {
+		t.Errorf("Peak RSS %.1f MB exceeds the %d MB ceiling", float64(rss)/(1<<20), limitMB)
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil || info.Size() == 0 {
+		t.Errorf("Expected a non-empty output file, got %v", err)
+	}
+}
+
+// findProjectRoot finds the project root by looking for go.mod
+func findProjectRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("go.mod not found")
+		}
+		dir = parent
+	}
+}
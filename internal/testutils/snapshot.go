@@ -0,0 +1,80 @@
+package testutils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Snapshots live below the package directory of the test
+const (
+	SNAPSHOT_DIR        = "testdata/snapshots"
+	SNAPSHOT_UPDATE_ENV = "UPDATE_SNAPSHOTS" // set to rewrite the snapshots with the current output
+)
+
+// Snapshot compares got with the snapshot SNAPSHOT_DIR/name.snap and reports a difference
+// as a test error. With UPDATE_SNAPSHOTS set the snapshot is written instead; a missing
+// snapshot fails the test until it is created that way. name may contain slashes.
+func Snapshot(t testing.TB, name, got string) {
+	t.Helper()
+	compareSnapshot(t, SNAPSHOT_DIR, name, got, os.Getenv(SNAPSHOT_UPDATE_ENV) != "")
+}
+
+// compareSnapshot is Snapshot with the directory and the update toggle given
+func compareSnapshot(t testing.TB, dir, name, got string, update bool) {
+	t.Helper()
+	if name == "" || filepath.IsAbs(name) || strings.Contains(name, "..") {
+		t.Fatalf("invalid snapshot name %q", name)
+		return
+	}
+	path := filepath.Join(dir, filepath.FromSlash(name)+".snap")
+
+	if update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create snapshot dir: %v", err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("failed to write snapshot: %v", err)
+			return
+		}
+		t.Logf("updated snapshot %s", path)
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("snapshot %s does not exist, run the test with %s=1 to create it", path, SNAPSHOT_UPDATE_ENV)
+		return
+	}
+	if err != nil {
+		t.Fatalf("failed to read snapshot: %v", err)
+		return
+	}
+	if string(want) != got {
+		line, wantLine, gotLine := firstDifference(string(want), got)
+		t.Errorf("output differs from snapshot %s at line %d:\nsnapshot: %q\ngot:      %q\nrun the test with %s=1 if the change is intended",
+			path, line, wantLine, gotLine, SNAPSHOT_UPDATE_ENV)
+	}
+}
+
+// firstDifference returns the first line, counted from 1, where two texts differ and
+// both versions of it; a missing line is empty
+func firstDifference(a, b string) (int, string, string) {
+	aLines, bLines := strings.Split(a, "\n"), strings.Split(b, "\n")
+	for i := 0; ; i++ {
+		var aLine, bLine string
+		if i < len(aLines) {
+			aLine = aLines[i]
+		}
+		if i < len(bLines) {
+			bLine = bLines[i]
+		}
+		if aLine != bLine || i >= len(aLines) || i >= len(bLines) {
+			return i + 1, aLine, bLine
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package testutils
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recordingTB collects the failures of a snapshot comparison instead of failing the test
+type recordingTB struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingTB) Helper()                         {}
+func (r *recordingTB) Logf(format string, args ...any) {}
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+func (r *recordingTB) Fatalf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	compare := func(name, got string, update bool) []string {
+		tb := &recordingTB{}
+		compareSnapshot(tb, dir, name, got, update)
+		return tb.failures
+	}
+
+	if failures := compare("modes/new", "text", false); len(failures) != 1 || !strings.Contains(failures[0], SNAPSHOT_UPDATE_ENV+"=1") {
+		t.Errorf("Expected a missing snapshot to fail with a hint, got %v", failures)
+	}
+	if failures := compare("modes/new", "one\ntwo", true); len(failures) != 0 {
+		t.Fatalf("Update failed: %v", failures)
+	}
+	if data, err := os.ReadFile(filepath.Join(dir, "modes", "new.snap")); err != nil || string(data) != "one\ntwo" {
+		t.Errorf("Expected the snapshot to be written, got %q, %v", data, err)
+	}
+	if failures := compare("modes/new", "one\ntwo", false); len(failures) != 0 {
+		t.Errorf("Matching output failed: %v", failures)
+	}
+	if failures := compare("modes/new", "one\n2", false); len(failures) != 1 || !strings.Contains(failures[0], "at line 2") {
+		t.Errorf("Expected a difference at line 2, got %v", failures)
+	}
+	if failures := compare("../escape", "x", true); len(failures) != 1 {
+		t.Errorf("Expected a name leaving the snapshot dir to fail, got %v", failures)
+	}
+}
+
+// sample text for the mode snapshots, touching every stage of the pipeline
+const snapshotSample = "it was a apple , 1E (hex) files (up, 2) .\n" +
+	"\" wait \" She said ,  and left (cap) ! the 2 nd   time\r\n" +
+	"he said \" go . \" (raw)keep ' this ' (up)(endraw) now\n" +
+	"  indented ' quote ' line (title, 3)\n" +
+	"```\ncode , ' here ' (up)\n```\nsee `a ,b` and [the docs](http://x.org/?a=1,2) .\n"
+
+// Every output mode keeps a snapshot, run with UPDATE_SNAPSHOTS=1 after an intended change
+func TestModeSnapshots(t *testing.T) {
+	RequireSuite(t, SUITE_GOLDEN)
+	modes := []struct {
+		name   string
+		change func(opts *config.Options)
+	}{
+		{"default", func(opts *config.Options) {}},
+		{"preserve-whitespace", func(opts *config.Options) { opts.PreserveWhitespace = true }},
+		{"smart-quotes", func(opts *config.Options) { opts.SmartQuotes = true }},
+		{"dialogue-american", func(opts *config.Options) { opts.Dialogue = config.DIALOGUE_AMERICAN }},
+		{"dialogue-logical", func(opts *config.Options) { opts.Dialogue = config.DIALOGUE_LOGICAL }},
+		{"sentences-ordinals", func(opts *config.Options) { opts.CapitalizeSentences, opts.NormalizeOrdinals = true, true }},
+		{"crlf", func(opts *config.Options) { opts.LineEnding = config.LINE_ENDING_CRLF }},
+		{"no-raw", func(opts *config.Options) { opts.RawStart, opts.RawEnd = "", "" }},
+		{"markdown", func(opts *config.Options) { opts.Markdown = true }},
+	}
+
+	for _, mode := range modes {
+		t.Run(mode.name, func(t *testing.T) {
+			opts := config.DefaultOptions()
+			mode.change(&opts)
+			Snapshot(t, "modes/"+mode.name, transformer.ProcessTextWithOptions(snapshotSample, opts))
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package testutils
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Test suites selected with build tags, e.g. go test -tags integration,golden ./...
+// Without any suite tag every suite runs. Unit tests are not gated and run with every selection.
+const (
+	SUITE_UNIT        = "unit"        // in-process tests only, nothing below runs
+	SUITE_INTEGRATION = "integration" // tests building or running the CLI with the go tool, or using the network
+	SUITE_GOLDEN      = "golden"      // golden cases and output snapshots
+	SUITE_FUZZ_SMOKE  = "fuzzsmoke"   // the seed corpora of the fuzz targets
+)
+
+// selectedSuites holds the suites whose build tag is set, filled by the suite_*.go files
+var selectedSuites = map[string]bool{}
+
+// SuiteSelected reports whether the tests of suite run in this build
+func SuiteSelected(suite string) bool {
+	return len(selectedSuites) == 0 || selectedSuites[suite]
+}
+
+// SelectedSuites describes the suites of this build for test output
+func SelectedSuites() string {
+	if len(selectedSuites) == 0 {
+		return "all"
+	}
+	var names []string
+	for name := range selectedSuites {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+// RequireSuite skips the test unless suite was selected
+func RequireSuite(tb testing.TB, suite string) {
+	tb.Helper()
+	if !SuiteSelected(suite) {
+		tb.Skipf("skipping %s test, suites selected: %s", suite, SelectedSuites())
+	}
+}
@@ -0,0 +1,5 @@
+//go:build fuzzsmoke
+
+package testutils
+
+func init() { selectedSuites[SUITE_FUZZ_SMOKE] = true }
@@ -0,0 +1,5 @@
+//go:build golden
+
+package testutils
+
+func init() { selectedSuites[SUITE_GOLDEN] = true }
@@ -0,0 +1,5 @@
+//go:build integration
+
+package testutils
+
+func init() { selectedSuites[SUITE_INTEGRATION] = true }
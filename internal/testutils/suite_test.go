@@ -0,0 +1,21 @@
+package testutils
+
+import "testing"
+
+func TestSuiteSelected(t *testing.T) {
+	saved := selectedSuites
+	defer func() { selectedSuites = saved }()
+
+	selectedSuites = map[string]bool{}
+	if !SuiteSelected(SUITE_INTEGRATION) || SelectedSuites() != "all" {
+		t.Errorf("Without suite tags every suite should run, got %s", SelectedSuites())
+	}
+
+	selectedSuites = map[string]bool{SUITE_UNIT: true, SUITE_GOLDEN: true}
+	if SuiteSelected(SUITE_INTEGRATION) || !SuiteSelected(SUITE_GOLDEN) {
+		t.Errorf("Only the tagged suites should run")
+	}
+	if got := SelectedSuites(); got != "golden,unit" {
+		t.Errorf("Expected golden,unit, got %s", got)
+	}
+}
@@ -0,0 +1,5 @@
+//go:build unit
+
+package testutils
+
+func init() { selectedSuites[SUITE_UNIT] = true }
@@ -0,0 +1,82 @@
+package throttle
+
+import (
+	"io"
+	"time"
+)
+
+// Limiter keeps the average IO rate at or below a fixed number of bytes per second.
+// A nil *Limiter does not limit anything, so callers can use it unconditionally.
+type Limiter struct {
+	bytesPerSec float64
+	start       time.Time
+	total       int64
+
+	now   func() time.Time    // replaceable clock for tests
+	sleep func(time.Duration) // replaceable sleeper for tests
+}
+
+// NewLimiter returns a limiter for the given rate in MB/s, or nil when mbps <= 0 (unlimited)
+func NewLimiter(mbps float64) *Limiter {
+	if mbps <= 0 {
+		return nil
+	}
+	return &Limiter{
+		bytesPerSec: mbps * 1024 * 1024,
+		start:       time.Now(),
+		now:         time.Now,
+		sleep:       time.Sleep,
+	}
+}
+
+// Wait accounts for n transferred bytes and sleeps until the average rate is back under the limit
+func (l *Limiter) Wait(n int) {
+	if l == nil || n <= 0 {
+		return
+	}
+
+	l.total += int64(n)
+	due := time.Duration(float64(l.total) / l.bytesPerSec * float64(time.Second))
+	if elapsed := l.now().Sub(l.start); due > elapsed {
+		l.sleep(due - elapsed)
+	}
+}
+
+// Reader wraps an io.Reader so that reads go through a Limiter
+type Reader struct {
+	r       io.Reader
+	limiter *Limiter
+}
+
+// NewReader returns r unchanged when limiter is nil
+func NewReader(r io.Reader, limiter *Limiter) io.Reader {
+	if limiter == nil {
+		return r
+	}
+	return &Reader{r: r, limiter: limiter}
+}
+
+func (tr *Reader) Read(p []byte) (int, error) {
+	n, err := tr.r.Read(p)
+	tr.limiter.Wait(n)
+	return n, err
+}
+
+// Writer wraps an io.Writer so that writes go through a Limiter
+type Writer struct {
+	w       io.Writer
+	limiter *Limiter
+}
+
+// NewWriter returns w unchanged when limiter is nil
+func NewWriter(w io.Writer, limiter *Limiter) io.Writer {
+	if limiter == nil {
+		return w
+	}
+	return &Writer{w: w, limiter: limiter}
+}
+
+func (tw *Writer) Write(p []byte) (int, error) {
+	tw.limiter.Wait(len(p))
+	return tw.w.Write(p)
+}
@@ -0,0 +1,68 @@
+package throttle
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeLimiter returns a limiter with a frozen clock that records requested sleeps
+func fakeLimiter(mbps float64, slept *time.Duration) *Limiter {
+	l := NewLimiter(mbps)
+	start := l.start
+	l.now = func() time.Time { return start.Add(*slept) }
+	l.sleep = func(d time.Duration) { *slept += d }
+	return l
+}
+
+func TestNewLimiterUnlimited(t *testing.T) {
+	if NewLimiter(0) != nil {
+		t.Errorf("Expected nil limiter for zero rate")
+	}
+
+	var l *Limiter
+	l.Wait(1 << 20) // must not panic
+}
+
+func TestLimiterWait(t *testing.T) {
+	var slept time.Duration
+	l := fakeLimiter(1, &slept)
+
+	l.Wait(512 * 1024)
+	if slept != 500*time.Millisecond {
+		t.Errorf("Expected 500ms after half a MB at 1 MB/s, slept %v", slept)
+	}
+
+	l.Wait(512 * 1024)
+	if slept != time.Second {
+		t.Errorf("Expected 1s after one MB at 1 MB/s, slept %v", slept)
+	}
+}
+
+func TestReaderWriterThrottled(t *testing.T) {
+	var slept time.Duration
+	l := fakeLimiter(1, &slept)
+
+	data := strings.Repeat("x", 1024*1024)
+	var out bytes.Buffer
+	if _, err := io.Copy(NewWriter(&out, l), NewReader(strings.NewReader(data), l)); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+
+	if out.String() != data {
+		t.Errorf("Data was altered by the throttled copy")
+	}
+	// One MB read plus one MB written at 1 MB/s
+	if slept != 2*time.Second {
+		t.Errorf("Expected 2s of throttling, got %v", slept)
+	}
+}
+
+func TestWrappersUnlimited(t *testing.T) {
+	r := strings.NewReader("abc")
+	if NewReader(r, nil) != io.Reader(r) {
+		t.Errorf("Expected unwrapped reader without a limiter")
+	}
+}
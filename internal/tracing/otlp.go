@@ -0,0 +1,130 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// OTLP posts spans to an OpenTelemetry collector with the OTLP/HTTP JSON encoding
+type OTLP struct {
+	endpoint string
+	client   *http.Client
+}
+
+// NewOTLP returns an exporter for otlp://host:port or otlp+https://host:port,
+// a path replaces the default /v1/traces
+func NewOTLP(destination string) (*OTLP, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return nil, fmt.Errorf("invalid trace destination %q: %w", destination, err)
+	}
+	endpoint := url.URL{Scheme: "http", Host: u.Host, Path: u.Path}
+	switch u.Scheme {
+	case "otlp", "otlp+http":
+	case "otlp+https":
+		endpoint.Scheme = "https"
+	default:
+		return nil, fmt.Errorf("unknown trace destination %q, expected otlp://host:port", destination)
+	}
+	if endpoint.Path == "" {
+		endpoint.Path = "/v1/traces"
+	}
+	return &OTLP{endpoint: endpoint.String(), client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+// ExportSpans posts the spans in one request
+func (o *OTLP) ExportSpans(spans []*Span) error {
+	body, err := json.Marshal(otlpRequestFor(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := o.client.Post(o.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to send OTLP spans: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("OTLP collector at %s answered %s", o.endpoint, resp.Status)
+	}
+	return nil
+}
+
+// the subset of the OTLP trace data model used by go-reloaded
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpAttribute struct {
+	Key   string            `json:"key"`
+	Value map[string]string `json:"value"`
+}
+
+type otlpScopeSpans struct {
+	Scope map[string]string `json:"scope"`
+	Spans []otlpSpan        `json:"spans"`
+}
+
+// trace and span ids are hex strings and 64-bit times decimal strings in OTLP JSON
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+}
+
+// builds the request body for one export
+func otlpRequestFor(spans []*Span) otlpRequest {
+	converted := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		s := otlpSpan{
+			TraceID:           hex.EncodeToString(span.TraceID[:]),
+			SpanID:            hex.EncodeToString(span.SpanID[:]),
+			Name:              span.Name,
+			Kind:              span.Kind,
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+		}
+		if span.ParentID != [8]byte{} {
+			s.ParentSpanID = hex.EncodeToString(span.ParentID[:])
+		}
+		span.mu.Lock()
+		for key, value := range span.Attributes {
+			s.Attributes = append(s.Attributes, otlpAttribute{Key: key, Value: map[string]string{"stringValue": value}})
+		}
+		span.mu.Unlock()
+		sort.Slice(s.Attributes, func(i, j int) bool { return s.Attributes[i].Key < s.Attributes[j].Key })
+		converted = append(converted, s)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpAttribute{
+			{Key: "service.name", Value: map[string]string{"stringValue": "go-reloaded"}},
+		}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: map[string]string{"name": "go-reloaded"},
+			Spans: converted,
+		}},
+	}}}
+}
@@ -0,0 +1,230 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Span kinds, numbered like the OTLP SpanKind enum
+const (
+	SPAN_KIND_INTERNAL = 1
+	SPAN_KIND_SERVER   = 2
+)
+
+// Ended spans waiting for export before new ones are dropped
+const SPAN_QUEUE_SIZE = 2048
+
+// Spans sent per export, a partial batch is sent after EXPORT_INTERVAL
+const EXPORT_BATCH_SIZE = 256
+
+// Longest wait before queued spans are exported
+const EXPORT_INTERVAL = 2 * time.Second
+
+// SpanContext identifies a span across process boundaries
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Sampled bool
+}
+
+// Span is one timed operation of a trace
+type Span struct {
+	SpanContext
+	ParentID   [8]byte // zero for a root span
+	Name       string
+	Kind       int // SPAN_KIND_*
+	Start      time.Time
+	End        time.Time
+	Attributes map[string]string
+
+	tracer *Tracer
+	mu     sync.Mutex
+}
+
+// Exporter sends ended spans to a tracing backend
+type Exporter interface {
+	ExportSpans(spans []*Span) error
+}
+
+// Tracer creates spans and exports them in the background.
+// A nil *Tracer records nothing, so callers can use it unconditionally.
+type Tracer struct {
+	exporter Exporter
+	queue    chan *Span
+	done     chan struct{}
+	errors   func(error) // receives export errors, they never reach the traced code
+
+	mu     sync.RWMutex // guards closed against spans finishing during Close
+	closed bool
+}
+
+// NewTracer starts a tracer exporting to exporter, or returns nil when exporter is nil.
+// onError receives failed exports and may be nil.
+func NewTracer(exporter Exporter, onError func(error)) *Tracer {
+	if exporter == nil {
+		return nil
+	}
+	if onError == nil {
+		onError = func(error) {}
+	}
+	t := &Tracer{exporter: exporter, queue: make(chan *Span, SPAN_QUEUE_SIZE), done: make(chan struct{}), errors: onError}
+	go t.run()
+	return t
+}
+
+type spanKey struct{}
+type remoteKey struct{}
+
+// ContextWithRemoteParent returns ctx carrying a parent span received from another service
+func ContextWithRemoteParent(ctx context.Context, parent SpanContext) context.Context {
+	return context.WithValue(ctx, remoteKey{}, parent)
+}
+
+// Start begins a span as a child of the span in ctx, or of a remote parent, or as a new trace.
+// The returned context carries the new span.
+func (t *Tracer) Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+
+	span := &Span{Name: name, Kind: kind, Start: time.Now(), tracer: t}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.TraceID, span.ParentID, span.Sampled = parent.TraceID, parent.SpanID, parent.Sampled
+	} else if remote, ok := ctx.Value(remoteKey{}).(SpanContext); ok {
+		span.TraceID, span.ParentID, span.Sampled = remote.TraceID, remote.SpanID, remote.Sampled
+	} else {
+		rand.Read(span.TraceID[:])
+		span.Sampled = true
+	}
+	rand.Read(span.SpanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttribute records a string attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]string)
+	}
+	s.Attributes[key] = value
+}
+
+// Finish ends the span and queues it for export. A full queue drops the span
+// rather than slowing down the traced request.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.End = time.Now()
+	if !s.Sampled {
+		return
+	}
+	t := s.tracer
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.closed {
+		return
+	}
+	select {
+	case t.queue <- s:
+	default:
+	}
+}
+
+// Traceparent formats the span for the W3C traceparent header
+func (s *Span) Traceparent() string {
+	if s == nil {
+		return ""
+	}
+	return FormatTraceparent(s.SpanContext)
+}
+
+// Close exports the queued spans and stops the tracer
+func (t *Tracer) Close() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		close(t.queue)
+	}
+	t.mu.Unlock()
+	<-t.done
+}
+
+// run exports spans in batches until the queue is closed
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(EXPORT_INTERVAL)
+	defer ticker.Stop()
+
+	batch := make([]*Span, 0, EXPORT_BATCH_SIZE)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := t.exporter.ExportSpans(batch); err != nil {
+			t.errors(err)
+		}
+		batch = make([]*Span, 0, EXPORT_BATCH_SIZE)
+	}
+
+	for {
+		select {
+		case span, ok := <-t.queue:
+			if !ok {
+				export()
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) == EXPORT_BATCH_SIZE {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		}
+	}
+}
+
+// ParseTraceparent reads a W3C traceparent header: "00-<trace id>-<parent id>-<flags>"
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	// version 00 has exactly four fields, later versions may append more
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil || sc.TraceID == [16]byte{} {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil || sc.SpanID == [8]byte{} {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, true
+}
+
+// FormatTraceparent formats a span context as a version 00 traceparent header
+func FormatTraceparent(sc SpanContext) string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + flags
+}
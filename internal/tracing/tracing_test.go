@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recorder keeps exported spans in memory
+type recorder struct {
+	mu    sync.Mutex
+	spans []*Span
+	err   error
+}
+
+func (r *recorder) ExportSpans(spans []*Span) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.spans = append(r.spans, spans...)
+	return r.err
+}
+
+func TestTraceparent(t *testing.T) {
+	header := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	sc, ok := ParseTraceparent(header)
+	if !ok || !sc.Sampled || sc.TraceID[0] != 0x4b || sc.SpanID[7] != 0xb7 {
+		t.Fatalf("Failed to parse %q: %+v, %v", header, sc, ok)
+	}
+	if formatted := FormatTraceparent(sc); formatted != header {
+		t.Errorf("Expected %q, got %q", header, formatted)
+	}
+
+	for _, invalid := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",          // missing flags
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",       // zero trace id
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",       // zero span id
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",       // forbidden version
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", // extra field in version 00
+		"00-xyz92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",       // not hex
+	} {
+		if _, ok := ParseTraceparent(invalid); ok {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+	// future versions may add fields
+	if _, ok := ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); !ok {
+		t.Error("Expected a later version with extra fields to be accepted")
+	}
+}
+
+func TestTracerParents(t *testing.T) {
+	rec := &recorder{}
+	tracer := NewTracer(rec, nil)
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	ctx, root := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "request", SPAN_KIND_SERVER)
+	_, child := tracer.Start(ctx, "child", SPAN_KIND_INTERNAL)
+	child.SetAttribute("key", "value")
+	child.Finish()
+	root.Finish()
+	_, fresh := tracer.Start(context.Background(), "fresh", SPAN_KIND_INTERNAL)
+	fresh.Finish()
+	tracer.Close()
+
+	if len(rec.spans) != 3 {
+		t.Fatalf("Expected 3 exported spans, got %d", len(rec.spans))
+	}
+	if root.TraceID != remote.TraceID || root.ParentID != remote.SpanID {
+		t.Errorf("Root span does not continue the remote trace: %+v", root.SpanContext)
+	}
+	if child.TraceID != root.TraceID || child.ParentID != root.SpanID || child.Attributes["key"] != "value" {
+		t.Errorf("Child span is not a child of the root: %+v", child)
+	}
+	if fresh.TraceID == root.TraceID || fresh.ParentID != [8]byte{} || !fresh.Sampled {
+		t.Errorf("Span without parent should start a new sampled trace: %+v", fresh)
+	}
+	if !strings.HasPrefix(root.Traceparent(), "00-4bf92f3577b34da6a3ce929d0e0e4736-") {
+		t.Errorf("Unexpected traceparent %q", root.Traceparent())
+	}
+}
+
+func TestTracerSkipsUnsampled(t *testing.T) {
+	rec := &recorder{err: errors.New("backend down")}
+	var exportErrors int
+	tracer := NewTracer(rec, func(error) { exportErrors++ })
+
+	remote, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, span := tracer.Start(ContextWithRemoteParent(context.Background(), remote), "ignored", SPAN_KIND_SERVER)
+	span.Finish()
+	_, span = tracer.Start(context.Background(), "kept", SPAN_KIND_SERVER)
+	span.Finish()
+	tracer.Close()
+	span.Finish() // finishing after Close is ignored
+
+	if len(rec.spans) != 1 || rec.spans[0].Name != "kept" {
+		t.Errorf("Expected only the sampled span, got %+v", rec.spans)
+	}
+	if exportErrors != 1 {
+		t.Errorf("Expected the export error to be reported once, got %d", exportErrors)
+	}
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.Start(context.Background(), "noop", SPAN_KIND_INTERNAL)
+	span.SetAttribute("key", "value")
+	span.Finish()
+	tracer.Close()
+	if span != nil || ctx != context.Background() || span.Traceparent() != "" {
+		t.Error("Nil tracer should not record anything")
+	}
+	if NewTracer(nil, nil) != nil {
+		t.Error("Expected a nil tracer without exporter")
+	}
+}
+
+func TestOTLPExportSpans(t *testing.T) {
+	var received otlpRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Errorf("Invalid OTLP JSON: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	exporter, err := NewOTLP("otlp://" + strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer := NewTracer(exporter, func(err error) { t.Error(err) })
+	ctx, root := tracer.Start(context.Background(), "request", SPAN_KIND_SERVER)
+	_, child := tracer.Start(ctx, "parse", SPAN_KIND_INTERNAL)
+	child.SetAttribute("request.bytes", "12")
+	child.Finish()
+	root.Finish()
+	tracer.Close()
+
+	spans := received.ResourceSpans[0].ScopeSpans[0].Spans
+	if len(spans) != 2 {
+		t.Fatalf("Expected 2 spans, got %+v", spans)
+	}
+	if spans[0].Name != "parse" || spans[0].ParentSpanID != spans[1].SpanID || spans[0].TraceID != spans[1].TraceID ||
+		len(spans[0].TraceID) != 32 || spans[1].ParentSpanID != "" || spans[1].Kind != SPAN_KIND_SERVER {
+		t.Errorf("Unexpected spans %+v", spans)
+	}
+	if len(spans[0].Attributes) != 1 || spans[0].Attributes[0].Value["stringValue"] != "12" {
+		t.Errorf("Unexpected attributes %+v", spans[0].Attributes)
+	}
+
+	if _, err := NewOTLP("jaeger://localhost"); err == nil {
+		t.Error("Expected error for an unknown scheme")
+	}
+}
@@ -0,0 +1,40 @@
+package transformer
+
+import (
+	"strings"
+	"unicode"
+)
+
+// autocorrect replaces the word at idx with its correction from opts.Autocorrect, before
+// any command reaches it
+func (tp *TokenProcessor) autocorrect(idx int) {
+	word := tp.tokens[idx].Value
+	correction, ok := tp.opts.Autocorrect[strings.ToLower(withoutSoftHyphens(word))]
+	if !ok {
+		return
+	}
+	tp.tokens[idx].Value = matchCase(word, correction)
+	tp.recordToken(idx, word, RULE_AUTOCORRECT, "")
+}
+
+// matchCase writes correction in the case of word: "TEH" -> "THE", "Teh" -> "The". Any
+// other word takes the correction as the dictionary has it.
+func matchCase(word, correction string) string {
+	letters, lower, first := 0, false, rune(0)
+	for _, r := range word {
+		if unicode.IsLetter(r) {
+			if letters == 0 {
+				first = r
+			}
+			letters++
+			lower = lower || unicode.IsLower(r)
+		}
+	}
+	switch {
+	case letters > 1 && !lower:
+		return strings.ToUpper(correction)
+	case unicode.IsUpper(first):
+		return capitalizeFirstLetter(correction)
+	}
+	return correction
+}
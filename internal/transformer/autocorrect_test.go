@@ -0,0 +1,33 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextAutocorrect(t *testing.T) {
+	corrections := map[string]string{"teh": "the", "alot": "a lot", "dont": "don't", "recieve": "receive", "aple": "apple"}
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"case of the word", "teh cat, Teh dog, TEH bird", "the cat, The dog, THE bird"},
+		{"before the commands", "I dont (up) recieve (cap)", "I DON'T Receive"},
+		{"several words", "thanks alot.", "thanks a lot."},
+		{"articles after", "a aple", "an apple"},
+		{"whole words only", "tehran and teh", "tehran and the"},
+		{"raw regions", "(raw)teh(endraw) teh", "teh the"},
+	}
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.Autocorrect = corrections
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+
+	if result := ProcessText("teh cat"); result != "teh cat" {
+		t.Errorf("Expected no autocorrect by default, got %q", result)
+	}
+}
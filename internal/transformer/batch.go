@@ -0,0 +1,47 @@
+package transformer
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// Input is one document of a ProcessAll batch
+type Input struct {
+	Text    string
+	Options PerCallOptions
+}
+
+// Result is what ProcessAll did with the Input at the same index
+type Result struct {
+	Report
+	Err error // invalid per-call options or a cancelled context, the report is then empty, or ErrUnapplied
+}
+
+// ProcessAll transforms every input with ProcessReport on at most workers goroutines,
+// runtime.GOMAXPROCS when workers is 0 or less. A failing input does not stop the others.
+func (p *Processor) ProcessAll(ctx context.Context, inputs []Input, workers int) []Result {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	workers = min(workers, len(inputs))
+	results := make([]Result, len(inputs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				report, err := p.ProcessReport(ctx, inputs[i].Text, inputs[i].Options)
+				results[i] = Result{Report: report, Err: err}
+			}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return results
+}
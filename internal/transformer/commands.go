@@ -0,0 +1,79 @@
+package transformer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// CommandInfo describes a command that can appear inside parentheses
+type CommandInfo struct {
+	Name      string
+	MultiWord bool   // accepts a count: (up, 3) or (up, all)
+	Summary   string // one line description used by the help system
+	Example   string // input and output separated by " -> "
+}
+
+// commandRegistry lists every supported command, in the order shown by the help system
+var commandRegistry = []CommandInfo{
+	{Name: "hex", Summary: "convert the previous hexadecimal word to decimal", Example: "1E (hex) -> 30"},
+	{Name: "bin", Summary: "convert the previous binary word to decimal", Example: "10 (bin) -> 2"},
+	{Name: "tohex", Summary: "convert the previous decimal word to hexadecimal", Example: "255 (tohex) -> ff"},
+	{Name: "tobin", Summary: "convert the previous decimal word to binary", Example: "10 (tobin) -> 1010"},
+	{Name: "rom", Summary: "convert the previous decimal word (1-3999) to roman numerals", Example: "14 (rom) -> XIV"},
+	{Name: "unrom", Summary: "convert the previous roman numeral to decimal", Example: "XIV (unrom) -> 14"},
+	{Name: "spell", Summary: "spell the previous decimal word in English words", Example: "42 (spell) -> forty-two"},
+	{Name: "up", MultiWord: true, Summary: "uppercase the previous word(s)", Example: "go now (up, 2) -> GO NOW"},
+	{Name: "low", MultiWord: true, Summary: "lowercase the previous word(s)", Example: "STOP (low) -> stop"},
+	{Name: "cap", MultiWord: true, Summary: "capitalize the previous word(s)", Example: "hello world (cap, 2) -> Hello World"},
+	{Name: "title", MultiWord: true, Summary: "title-case the previous word(s), hyphen and apostrophe aware", Example: "o'brien (title) -> O'Brien"},
+	{Name: "rev", MultiWord: true, Summary: "reverse the characters of the previous word(s)", Example: "stressed (rev) -> desserts"},
+}
+
+// Commands returns the registered commands
+func Commands() []CommandInfo {
+	commands := make([]CommandInfo, len(commandRegistry))
+	copy(commands, commandRegistry)
+	return commands
+}
+
+// lookupCommand finds a registered command by name or by one of aliases, whatever its case
+func lookupCommand(name string, aliases map[string]string) (CommandInfo, bool) {
+	name = strings.ToLower(name)
+	if command, ok := aliases[name]; ok {
+		name = command
+	}
+	for _, info := range commandRegistry {
+		if info.Name == name {
+			return info, true
+		}
+	}
+	return CommandInfo{}, false
+}
+
+// ValidateAliases checks that every alias stands for a registered command and does not
+// hide one
+func ValidateAliases(aliases map[string]string) error {
+	for alias, command := range aliases {
+		if _, ok := lookupCommand(alias, nil); ok {
+			return fmt.Errorf("alias %q is already a command", alias)
+		}
+		if _, ok := lookupCommand(command, nil); !ok {
+			return fmt.Errorf("alias %q stands for unknown command %q", alias, command)
+		}
+	}
+	return nil
+}
+
+// commandRunes is how far past an opening parenthesis the closing one of a command is
+// looked for: the longest command or alias name, forward and with a count, and spaces
+func commandRunes(aliases map[string]string) int {
+	longest := 0
+	for _, info := range commandRegistry {
+		longest = max(longest, utf8.RuneCountInString(info.Name))
+	}
+	for alias := range aliases {
+		longest = max(longest, utf8.RuneCountInString(alias))
+	}
+	return longest + len(FORWARD_MARKER+", ") + COUNT_RUNES + COMMAND_SPACES + len(")")
+}
@@ -0,0 +1,174 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"unicode"
+)
+
+// verbs that turn the words around them into a dialogue tag: "she said", "asked John"
+var dialogueTags = map[string]bool{
+	"said": true, "says": true, "asked": true, "asks": true, "replied": true, "replies": true,
+	"answered": true, "added": true, "continued": true, "began": true, "explained": true,
+	"whispered": true, "murmured": true, "muttered": true, "shouted": true, "yelled": true,
+	"cried": true, "called": true, "exclaimed": true, "insisted": true, "told": true,
+}
+
+// pronouns lowercased when they start an attribution: `"Hi," She said` -> `"Hi," she said`
+var dialoguePronouns = map[string]bool{
+	"he": true, "she": true, "they": true, "we": true, "you": true, "it": true,
+}
+
+// normalizes the punctuation between a quotation and its attribution. The american
+// style puts the comma inside the closing quote (`"Wait," she said`), the logical
+// style outside (`"Wait", she said`). A quotation introduced by a tag gets a comma
+// after the tag and starts with a capital: `he said "go."` -> `he said, "Go."`.
+// open gets marked at every line break, and last at the end, where the last opening quote
+// neither started a sentence nor followed a tag: a closing quote after it is handled differently.
+func formatDialogue(text string, style string, open []bool) string {
+	runes := []rune(text)
+	out := make([]rune, 0, len(runes)+16)
+	lowerAt := -1          // index of a pronoun to lowercase
+	quotationStart := true // the last opening quote started a sentence or followed a tag
+	line := 0
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if r == '\n' {
+			markOpen(open, line, !quotationStart)
+			line++
+		}
+		if !isDoubleQuote(r) {
+			if i == lowerAt {
+				r = unicode.ToLower(r)
+			}
+			out = append(out, r)
+			continue
+		}
+
+		closing := r == '”' || (r != '“' && len(out) > 0 && !unicode.IsSpace(out[len(out)-1]))
+		if !closing {
+			quotationStart = startsSentence(out) || tagBefore(out) || endsWithTagComma(out)
+			// Opening quote after a tag: he said "go" -> he said, "Go"
+			if tagBefore(out) {
+				out = append(out[:len(out)-1], ',', ' ')
+			}
+			if tagBefore(out) || endsWithTagComma(out) {
+				out = append(out, r)
+				if i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+					out = append(out, unicode.ToUpper(runes[i+1]))
+					i++
+				}
+				continue
+			}
+			out = append(out, r)
+			continue
+		}
+
+		next := i + 1
+		if next < len(runes) && runes[next] == ',' {
+			next++ // comma already outside the quote
+		}
+		pronoun, ok := attributionAt(runes, next)
+		if !ok || !quotationStart {
+			// Not followed by a tag, or quoted words inside a sentence: the "word" said nothing
+			out = append(out, r)
+			continue
+		}
+
+		last := out[len(out)-1]
+		switch {
+		case last == '!' || last == '?':
+			// "Stop!" she said - the mark replaces the comma
+			out = append(out, r)
+		case style == config.DIALOGUE_LOGICAL:
+			if last == '.' || last == ',' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, r, ',')
+		default:
+			if last == '.' || last == ',' {
+				out[len(out)-1] = ','
+			} else {
+				out = append(out, ',')
+			}
+			out = append(out, r)
+		}
+		if pronoun {
+			lowerAt = next + 1
+		}
+		i = next - 1 // continue with the space before the attribution
+	}
+	markOpen(open, len(open)-1, !quotationStart)
+	return string(out)
+}
+
+// reports whether out is empty or ends with a sentence end, a line break or another quote
+func startsSentence(out []rune) bool {
+	end := len(out)
+	for end > 0 && out[end-1] == ' ' {
+		end--
+	}
+	return end == 0 || strings.ContainsRune(".!?:\n", out[end-1]) || isDoubleQuote(out[end-1])
+}
+
+func isDoubleQuote(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// reports whether a space and a dialogue tag start at runes[start]: ` she said`, ` asked John`.
+// pronoun is set when the tag starts with a capitalized pronoun that should be lowercased.
+func attributionAt(runes []rune, start int) (pronoun bool, ok bool) {
+	if start >= len(runes) || runes[start] != ' ' {
+		return false, false
+	}
+	first, end := wordAt(runes, start+1)
+	if first == "" {
+		return false, false
+	}
+	if dialogueTags[strings.ToLower(first)] {
+		return false, true
+	}
+	if end >= len(runes) || runes[end] != ' ' {
+		return false, false
+	}
+	second, _ := wordAt(runes, end+1)
+	if !dialogueTags[strings.ToLower(second)] {
+		return false, false
+	}
+	return dialoguePronouns[strings.ToLower(first)] && unicode.IsUpper([]rune(first)[0]), true
+}
+
+// returns the letters starting at runes[start] and the index after them
+func wordAt(runes []rune, start int) (string, int) {
+	end := start
+	for end < len(runes) && unicode.IsLetter(runes[end]) {
+		end++
+	}
+	return string(runes[start:end]), end
+}
+
+// reports whether out ends with a dialogue tag followed by one space: `he said `
+func tagBefore(out []rune) bool {
+	if len(out) < 2 || out[len(out)-1] != ' ' {
+		return false
+	}
+	return dialogueTags[strings.ToLower(lastLetters(out[:len(out)-1]))]
+}
+
+// reports whether out ends with a dialogue tag, a comma and a space: `he said, `
+func endsWithTagComma(out []rune) bool {
+	if len(out) < 3 || out[len(out)-1] != ' ' || out[len(out)-2] != ',' {
+		return false
+	}
+	return dialogueTags[strings.ToLower(lastLetters(out[:len(out)-2]))]
+}
+
+// returns the trailing run of letters
+func lastLetters(runes []rune) string {
+	start := len(runes)
+	for start > 0 && unicode.IsLetter(runes[start-1]) {
+		start--
+	}
+	return string(runes[start:])
+}
@@ -0,0 +1,60 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextDialogueAmerican(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.Dialogue = config.DIALOGUE_AMERICAN
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`" I'm tired " , she said .`, `"I'm tired," she said.`},
+		{`" I'm tired . " She said .`, `"I'm tired," she said.`},
+		{`" Fine " John replied`, `"Fine," John replied`},
+		{`" Stop ! " he shouted .`, `"Stop!" he shouted.`},
+		{`he said " go home . "`, `he said, "Go home."`},
+		{`The " word " said nothing`, `The "word" said nothing`},
+		{`" Yes . " She left .`, `"Yes." She left.`},
+	}
+
+	for _, test := range tests {
+		result := ProcessTextWithOptions(test.input, opts)
+		if result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextDialogueLogical(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.Dialogue = config.DIALOGUE_LOGICAL
+	opts.SmartQuotes = true
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`" I'm tired , " she said .`, `“I’m tired”, she said.`},
+		{`" Fine " John replied`, `“Fine”, John replied`},
+		{`She asked , " why ? "`, `She asked, “Why?”`},
+	}
+
+	for _, test := range tests {
+		result := ProcessTextWithOptions(test.input, opts)
+		if result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextDialogueOffByDefault(t *testing.T) {
+	text := `" Fine " John replied`
+	if result, expected := ProcessText(text), `"Fine" John replied`; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
@@ -0,0 +1,188 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// separators between the digit groups of each GROUPING_* style
+var groupingSeparators = map[string]string{
+	config.GROUPING_EN: ",",
+	config.GROUPING_DE: ".",
+	config.GROUPING_FR: "\u202F",
+	config.GROUPING_CH: "'",
+	config.GROUPING_IN: ",",
+}
+
+// prefixes of the literals of a base, as Go writes them
+var radixPrefixes = map[int]string{16: "0x", 2: "0b"}
+
+// numberText prepares word for numeric parsing: soft hyphens are removed and the decimal
+// digits of any script are replaced by ASCII digits, "١٢" -> "12". zero is the zero digit
+// of the script the word was written in, '0' for ASCII digits or no digits at all.
+// ok is false when the word mixes the digits of two scripts.
+func numberText(word string) (text string, zero rune, ok bool) {
+	zero, ascii := '0', false
+	var builder strings.Builder
+	for _, r := range withoutSoftHyphens(word) {
+		switch {
+		case r >= '0' && r <= '9':
+			ascii = true
+		case r > unicode.MaxASCII && unicode.IsDigit(r):
+			script := digitZero(r)
+			if zero != '0' && script != zero {
+				return "", 0, false
+			}
+			zero, r = script, '0'+r-script
+		}
+		builder.WriteRune(r)
+	}
+	if ascii && zero != '0' {
+		return "", 0, false
+	}
+	return builder.String(), zero, true
+}
+
+// digitZero returns the zero of the script of the decimal digit r. Unicode keeps the digits
+// of every script in runs of ten from zero to nine, so r-zero is its value.
+func digitZero(r rune) rune {
+	for _, run := range unicode.Nd.R16 {
+		if r >= rune(run.Lo) && r <= rune(run.Hi) {
+			return r - (r-rune(run.Lo))%10
+		}
+	}
+	for _, run := range unicode.Nd.R32 {
+		if r >= rune(run.Lo) && r <= rune(run.Hi) {
+			return r - (r-rune(run.Lo))%10
+		}
+	}
+	return '0'
+}
+
+// literalDigits reads number like a Go integer literal of base: the 0x prefix of base 16
+// or 0b of base 2 after the sign is dropped, and so are _ separators between digits or
+// right after the prefix, "-0xFF_FF" -> "-FFFF". ok is false for a misplaced separator
+// or a second sign.
+func literalDigits(number string, base int) (string, bool) {
+	sign, digits := "", number
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		sign, digits = digits[:1], digits[1:]
+	}
+	prefixed := false
+	if prefix := radixPrefixes[base]; prefix != "" && len(digits) > len(prefix) && strings.EqualFold(digits[:len(prefix)], prefix) {
+		digits, prefixed = digits[len(prefix):], true
+	}
+	if strings.HasPrefix(digits, "-") || strings.HasPrefix(digits, "+") {
+		return "", false
+	}
+	if !strings.Contains(digits, "_") {
+		return sign + digits, true
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] != '_' {
+			continue
+		}
+		follows := i > 0 && digits[i-1] != '_' || i == 0 && prefixed
+		if !follows || i == len(digits)-1 || digits[i+1] == '_' {
+			return "", false
+		}
+	}
+	return sign + strings.ReplaceAll(digits, "_", ""), true
+}
+
+// minus signs other than '-' read as one in front of a number: the minus sign, and the small
+// and fullwidth hyphen-minus that go with fullwidth digits
+var minusSigns = []rune{'\u2212', '\uFE63', '\uFF0D'}
+
+// asciiMinus replaces a leading minus sign of minusSigns by '-', minus is the sign the
+// number was written with, '-' when it has none of them
+func asciiMinus(number string) (text string, minus rune) {
+	r, size := utf8.DecodeRuneInString(number)
+	if slices.Contains(minusSigns, r) {
+		return "-" + number[size:], r
+	}
+	return number, '-'
+}
+
+// writes the minus sign of a negative result as the number it came from was written
+func withMinus(result string, minus rune) string {
+	if minus == '-' || !strings.HasPrefix(result, "-") {
+		return result
+	}
+	return string(minus) + result[1:]
+}
+
+// writes the ASCII digits of a conversion result in the script of the word it came from
+// when NativeDigits is set, letters, signs and 0x/0b prefixes stay as they are
+func (tp *TokenProcessor) nativeDigits(result string, zero rune) string {
+	if !tp.opts.NativeDigits || zero == '0' {
+		return result
+	}
+	sign, digits := "", result
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	prefix := ""
+	if strings.HasPrefix(digits, "0x") || strings.HasPrefix(digits, "0b") {
+		prefix, digits = digits[:2], digits[2:]
+	}
+	return sign + prefix + strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return zero + r - '0'
+		}
+		return r
+	}, digits)
+}
+
+// groups the digits of a decimal result in the DigitGrouping style: 1048576 -> 1,048,576
+func (tp *TokenProcessor) groupDigits(number string) string {
+	separator, ok := groupingSeparators[tp.opts.DigitGrouping]
+	if !ok {
+		return number
+	}
+	sign, digits := "", number
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	// The last group has three digits, the others three or, in the Indian style, two
+	size := 3
+	var groups []string
+	for len(digits) > size {
+		groups = append(groups, digits[len(digits)-size:])
+		digits = digits[:len(digits)-size]
+		if tp.opts.DigitGrouping == config.GROUPING_IN {
+			size = 2
+		}
+	}
+	groups = append(groups, digits)
+	slices.Reverse(groups)
+	return sign + strings.Join(groups, separator)
+}
+
+// digitGroupAt reports whether runes[i] is the separator of the DigitGrouping style between
+// two digit groups as groupDigits writes them, a digit before it and a full group after it,
+// so the tokenizer keeps it in the word instead of reading a punctuation mark: 1,048,576
+func (tp *TokenProcessor) digitGroupAt(runes []rune, i int) bool {
+	separator, ok := groupingSeparators[tp.opts.DigitGrouping]
+	if !ok || string(runes[i]) != separator || i == 0 || !unicode.IsDigit(runes[i-1]) {
+		return false
+	}
+	size := 0
+	for j := i + 1; j < len(runes) && unicode.IsDigit(runes[j]); j++ {
+		size++
+	}
+	return size == 3 || size == 2 && tp.opts.DigitGrouping == config.GROUPING_IN
+}
+
+// removes the separators of the DigitGrouping style from a decimal number, so grouped
+// results can be converted again: 1,048,576 (tohex)
+func (tp *TokenProcessor) ungroupDigits(number string) string {
+	separator, ok := groupingSeparators[tp.opts.DigitGrouping]
+	if !ok {
+		return number
+	}
+	return strings.ReplaceAll(number, separator, "")
+}
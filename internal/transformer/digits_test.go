@@ -0,0 +1,163 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestProcessTextUnicodeDigits(t *testing.T) {
+	native := config.DefaultOptions()
+	native.NativeDigits = true
+	native.RadixPrefix = true
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     config.Options
+		expected string
+	}{
+		{"arabic-indic hex", "١E (hex) files", config.DefaultOptions(), "30 files"},
+		{"fullwidth bin", "１０ (bin)", config.DefaultOptions(), "2"},
+		{"devanagari tohex", "२५५ (tohex)", config.DefaultOptions(), "ff"},
+		{"extended arabic-indic rom", "۱۴ (rom)", config.DefaultOptions(), "XIV"},
+		{"mathematical digits", "𝟏𝟎 (tobin)", config.DefaultOptions(), "1010"},
+		{"mixed scripts", "١2 (hex) ١۲ (bin)", config.DefaultOptions(), "١2 ١۲"},
+		{"native hex", "١E (hex)", native, "٣٠"},
+		{"native tobin keeps the prefix", "٥ (tobin)", native, "0b١٠١"},
+		{"native with ascii digits", "1E (hex)", native, "30"},
+		{"native rom has no digits", "۱۴ (rom)", native, "XIV"},
+	}
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, test.opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+}
+
+func TestDigitZero(t *testing.T) {
+	for _, r := range []rune("0٣۷७৫８𝟗𝟡") {
+		zero := digitZero(r)
+		if value := r - zero; value < 0 || value > 9 || digitZero(zero) != zero {
+			t.Errorf("digitZero(%q) = %q, not the zero of its script", r, zero)
+		}
+	}
+}
+
+func TestProcessTextDigitGrouping(t *testing.T) {
+	tests := []struct {
+		grouping string
+		input    string
+		expected string
+	}{
+		{config.GROUPING_NONE, "FFFFF (hex)", "1048575"},
+		{config.GROUPING_EN, "FFFFF (hex) 3E8 (hex) 3E7 (hex)", "1,048,575 1,000 999"},
+		{config.GROUPING_DE, "1111101000 (bin)", "1.000"},
+		{config.GROUPING_FR, "MMXXIV (unrom)", "2\u202F024"},
+		{config.GROUPING_CH, "-FFFFF (hex)", "-1'048'575"},
+		{config.GROUPING_IN, "989680 (hex) 3E8 (hex)", "1,00,00,000 1,000"},
+		{config.GROUPING_EN, "10000 (tohex) 5000 (rom)", "2710 5000"},
+		{config.GROUPING_EN, "FFFFF (hex) (tohex)", "fffff"},
+	}
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.DigitGrouping = test.grouping
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.grouping, test.input, result, test.expected)
+		}
+	}
+
+	opts := config.DefaultOptions()
+	opts.DigitGrouping, opts.NativeDigits = config.GROUPING_EN, true
+	if result := ProcessTextWithOptions("١٠٠٠ (tobin) ١E٨٤٨ (hex)", opts); result != "١١١١١٠١٠٠٠ ١٢٥,٠٠٠" {
+		t.Errorf("Expected native digits with ASCII separators, got %q", result)
+	}
+}
+
+func TestProcessTextDigitGroupingReadBack(t *testing.T) {
+	tests := []struct {
+		grouping string
+		input    string
+		expected string
+	}{
+		{config.GROUPING_EN, "1,048,576 (tohex)", "100000"},
+		{config.GROUPING_DE, "1.048.576 (tobin)", "100000000000000000000"},
+		{config.GROUPING_IN, "1,00,00,000 (tohex)", "989680"},
+		{config.GROUPING_EN, "so 1,2 and 3,4567 stay", "so 1, 2 and 3, 4567 stay"},
+		{config.GROUPING_CH, "FFFF (hex) and ' quoted ' ok", "65'535 and 'quoted' ok"},
+		{config.GROUPING_CH, "1'048'576 (tohex) ' a '", "100000 'a'"},
+	}
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.DigitGrouping = test.grouping
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.grouping, test.input, result, test.expected)
+		}
+	}
+
+	// Grouped output reads back unchanged, as --verify transforms it again
+	for _, grouping := range []string{config.GROUPING_EN, config.GROUPING_DE, config.GROUPING_CH, config.GROUPING_IN} {
+		opts := config.DefaultOptions()
+		opts.DigitGrouping = grouping
+		first := ProcessTextWithOptions("FFFFF (hex), then 'done'.", opts)
+		if second := ProcessTextWithOptions(first, opts); second != first {
+			t.Errorf("%s: %q changed to %q when transformed again", grouping, first, second)
+		}
+	}
+}
+
+func TestProcessTextNumericLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0xFF (hex) 0B1010 (bin)", "255 10"},
+		{"-0x10 (hex) +0b11 (bin)", "-16 3"},
+		{"FF_FF (hex) 0x_1F (hex) 1_000 (tohex) 1_0 (bin)", "65535 31 3e8 2"},
+		{"0b1 (hex) 0x1 (bin)", "177 0x1"},
+		{"FFFFFFFFFFFFFFFFFFFF (hex)", "1208925819614629174706175"},
+		{"-1_0000_0000_0000_0000 (hex)", "-18446744073709551616"},
+		{"9223372036854775808 (tohex) 18446744073709551616 (tobin)", "8000000000000000 1" + strings.Repeat("0", 64)},
+		{"99999999999999999999 (rom)", "99999999999999999999"},
+		{"1__0 (bin) _10 (bin) 10_ (bin) 0x (hex) 0x-1 (hex) --1 (tohex)", "1__0 _10 10_ 0x 0x-1 --1"},
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	_, warnings := ProcessTextWarnings("1__0 (bin) 99999999999999999999 (rom)", config.DefaultOptions())
+	if len(warnings) != 2 || warnings[0].Reason != `"1__0" is not a binary number` || warnings[1].Reason != `"99999999999999999999" is not a number from 1 to 3999` {
+		t.Errorf("Expected a warning for each literal left unchanged, got %v", warnings)
+	}
+
+	opts := config.DefaultOptions()
+	opts.UpperHex, opts.RadixPrefix = true, true
+	if result := ProcessTextWithOptions("-123456789012345678901234567890 (tohex)", opts); result != "-0x18EE90FF6C373E0EE4E3F0AD2" {
+		t.Errorf("Expected the radix options on a big result, got %q", result)
+	}
+}
+
+func TestProcessTextSigns(t *testing.T) {
+	native := config.DefaultOptions()
+	native.NativeDigits, native.RadixPrefix = true, true
+
+	tests := []struct {
+		input    string
+		opts     config.Options
+		expected string
+	}{
+		{"-FF (hex) +FF (hex) -0 (hex)", config.DefaultOptions(), "-255 255 0"},
+		{"-255 (tohex) -10 (tobin) -1010 (bin)", config.DefaultOptions(), "-ff -1010 -10"},
+		{"−FF (hex) −10 (tobin)", config.DefaultOptions(), "−255 −1010"},
+		{"－１０ (bin) ﹣0x1F (hex)", config.DefaultOptions(), "－2 ﹣31"},
+		{"−٥ (tobin)", native, "−0b١٠١"},
+		{"-14 (rom) −−FF (hex) -−FF (hex)", config.DefaultOptions(), "-14 −−FF -−FF"},
+	}
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, test.opts); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
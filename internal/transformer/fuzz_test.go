@@ -0,0 +1,85 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/testutils"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// inputs the FSM has edge cases for: unclosed and nested parentheses, commands split by
+// line breaks, counts, forward markers, escapes, quotes, raw regions and conversions
+var fuzzSeeds = []string{
+	"it was a apple (up) , ' really ' . 1E (hex) files",
+	"(up", "(up, 2", "((up))", "(up (low))", "word (up, 0) (low, -1) (cap, all)",
+	"(up,\n2) word (cap>, 3) x", "\\(up\\) (rev) (title, 2) (rom) (unrom)",
+	"(abcdefghijk) (abcdefghij) (up, 99999999999999999999)",
+	"' open quote\n\" nested ' \" ' end", "(raw)(up) kept(endraw) (raw) never closed",
+	"FF (hex) (tohex) (tobin) 101 (bin) -7 (tohex)", "١E (hex) ２५ (bin)",
+	"a hour a honest an car . . . ! ? ...", "\uFEFF\r\nline (up)\r\n",
+	"up_ (up) UP_", "\xff\xfe invalid (up)",
+}
+
+func FuzzProcessText(f *testing.F) {
+	testutils.RequireSuite(f, testutils.SUITE_FUZZ_SMOKE)
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	processor, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		output, warnings := ProcessTextWarnings(input, config.DefaultOptions())
+		if !utf8.ValidString(output) {
+			t.Errorf("ProcessText(%q) = %q, not valid UTF-8", input, output)
+		}
+		if strings.Contains(output, "UP_") && !strings.Contains(strings.ToUpper(input), "UP_") {
+			t.Errorf("ProcessText(%q) = %q leaks an internal marker", input, output)
+		}
+		if again := ProcessText(input); again != output {
+			t.Errorf("ProcessText(%q) is not deterministic: %q then %q", input, output, again)
+		}
+		if pooled := processor.Process(input); pooled != output {
+			t.Errorf("Processor.Process(%q) = %q, ProcessText gives %q", input, pooled, output)
+		}
+
+		previous := -1
+		for _, warning := range warnings {
+			if warning.Offset < previous || warning.Offset >= len(input) || warning.Line < 1 || warning.Column < 1 {
+				t.Fatalf("ProcessTextWarnings(%q): warning %+v is out of order or outside the text", input, warning)
+			}
+			if !strings.HasPrefix(input[warning.Offset:], "(") && !strings.HasPrefix(input[warning.Offset:], warning.Command) {
+				t.Errorf("ProcessTextWarnings(%q): warning %+v does not point at a command or quote", input, warning)
+			}
+			previous = warning.Offset
+		}
+	})
+}
+
+// The segments of a text must transform it like a single pass: the output of a segment
+// up to a cut is the transformation of the text up to the cut
+func FuzzProcessSegment(f *testing.F) {
+	testutils.RequireSuite(f, testutils.SUITE_FUZZ_SMOKE)
+	for _, seed := range fuzzSeeds {
+		f.Add(seed + "\n" + seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		if !utf8.ValidString(input) {
+			return
+		}
+		segment := ProcessSegment(input, config.DefaultOptions())
+		if segment.Output != ProcessText(input) {
+			t.Fatalf("ProcessSegment(%q).Output = %q, ProcessText gives %q", input, segment.Output, ProcessText(input))
+		}
+		for _, cut := range segment.Cuts {
+			if prefix := ProcessText(input[:cut.Input]); !cut.Tied && prefix != segment.Output[:cut.Output] {
+				t.Errorf("ProcessSegment(%q): output before the cut at %d is %q, the text before it gives %q",
+					input, cut.Input, segment.Output[:cut.Output], prefix)
+			}
+		}
+	})
+}
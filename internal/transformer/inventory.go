@@ -0,0 +1,78 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// Occurrence is one command written in a text, see Inventory
+type Occurrence struct {
+	Line    int    `json:"line"`              // 1-based line of the command
+	Column  int    `json:"column"`            // 1-based column of its opening parenthesis, in characters
+	Offset  int    `json:"offset"`            // byte offset of its opening parenthesis
+	Command string `json:"command"`           // the command as written: "(up>, 2)"
+	Name    string `json:"name"`              // registered name, an alias resolved: "up"
+	Count   string `json:"count,omitempty"`   // count as written: "2", "all"
+	Forward bool   `json:"forward,omitempty"` // written with the forward marker
+	Problem string `json:"problem,omitempty"` // why it is not a valid command, "" when it is
+}
+
+// Inventory lists every command of text in order, as the transformation finds them: the
+// valid ones and the parenthesized texts that name a command but are not valid or have a
+// count that is not positive, with their problem. Commands inside raw regions, escaped parentheses and, with opts.Markdown, code
+// are not commands and are not listed. Nothing is left out for not applying, (up) before
+// the first word is listed like any other.
+func Inventory(text string, opts config.Options) []Occurrence {
+	if text == "" {
+		return nil
+	}
+	processor := NewTokenProcessor()
+	processor.listing = true
+	tokenizeInto(processor, text, opts)
+	processor.locateOccurrences(text)
+	return processor.found
+}
+
+// noteCommand lists the command cmdValue, written at rune index at, under Inventory
+func (tp *TokenProcessor) noteCommand(at int, cmdValue, problem string) {
+	if !tp.listing {
+		return
+	}
+	cmd, count, _ := strings.Cut(cmdValue, ",")
+	name, forward := commandName(cmd)
+	if info, ok := lookupCommand(name, tp.opts.CommandAliases); ok {
+		name = info.Name
+	}
+	count = strings.TrimSpace(count)
+	if _, ok := parseCount(count); problem == "" && count != "" && !ok {
+		problem = "count must be positive"
+	}
+	tp.found = append(tp.found, Occurrence{
+		Offset:  at,
+		Command: "(" + cmdValue + ")",
+		Name:    name,
+		Count:   count,
+		Forward: forward,
+		Problem: problem,
+	})
+}
+
+// locateOccurrences turns the rune indexes recorded by noteCommand into lines, columns and
+// byte offsets of text, as locateWarnings does
+func (tp *TokenProcessor) locateOccurrences(text string) {
+	slices.SortStableFunc(tp.found, func(a, b Occurrence) int { return a.Offset - b.Offset })
+	line, lineStart, offset, i := 1, 0, 0, 0
+	for k := range tp.found {
+		o := &tp.found[k]
+		for ; i < o.Offset; i++ {
+			r, size := utf8.DecodeRuneInString(text[offset:])
+			offset += size
+			if r == '\n' {
+				line, lineStart = line+1, i+1
+			}
+		}
+		o.Line, o.Column, o.Offset = line, o.Offset-lineStart+1, offset
+	}
+}
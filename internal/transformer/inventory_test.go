@@ -0,0 +1,41 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"reflect"
+	"testing"
+)
+
+func TestInventory(t *testing.T) {
+	aliases := config.DefaultOptions()
+	aliases.CommandAliases = map[string]string{"caps": "cap"}
+	markdown := config.DefaultOptions()
+	markdown.Markdown = true
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     config.Options
+		expected []Occurrence
+	}{
+		{"none", "plain text (hello)", config.DefaultOptions(), nil},
+		{"commands", "go (up, 2) 1E (hex)\n  é (cap>, all) x", config.DefaultOptions(), []Occurrence{
+			{Line: 1, Column: 4, Offset: 3, Command: "(up, 2)", Name: "up", Count: "2"},
+			{Line: 1, Column: 15, Offset: 14, Command: "(hex)", Name: "hex"},
+			{Line: 2, Column: 5, Offset: 25, Command: "(cap>, all)", Name: "cap", Count: "all", Forward: true},
+		}},
+		{"not applied", "(up) a (low, 0) ff (hex, 2)", config.DefaultOptions(), []Occurrence{
+			{Line: 1, Column: 1, Offset: 0, Command: "(up)", Name: "up"},
+			{Line: 1, Column: 8, Offset: 7, Command: "(low, 0)", Name: "low", Count: "0", Problem: "count must be positive"},
+			{Line: 1, Column: 20, Offset: 19, Command: "(hex, 2)", Name: "hex", Count: "2", Problem: "hex takes no count"},
+		}},
+		{"alias", "it (CAPS)", aliases, []Occurrence{{Line: 1, Column: 4, Offset: 3, Command: "(CAPS)", Name: "cap"}}},
+		{"escaped and raw", `\(up\) (raw)(up)(endraw)`, config.DefaultOptions(), nil},
+		{"markdown code", "`x (up)` y (low)", markdown, []Occurrence{{Line: 1, Column: 12, Offset: 11, Command: "(low)", Name: "low"}}},
+	}
+	for _, test := range tests {
+		if found := Inventory(test.input, test.opts); !reflect.DeepEqual(found, test.expected) {
+			t.Errorf("%s: Inventory(%q) = %+v, expected %+v", test.name, test.input, found, test.expected)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package transformer
+
+import "strings"
+
+// URL schemes of markdown autolinks: <https://example.com>
+var autolinkSchemes = []string{"http://", "https://", "mailto:", "ftp://"}
+
+// markdownRawAt is the structural scanner of the markdown mode. It reports whether a fenced
+// code block, an inline code span, a link destination or an autolink starts at runes[i],
+// and returns the index after it. open is set for a code block that is never closed.
+// The tokenizer passes these through as raw regions.
+func markdownRawAt(runes []rune, i int) (end int, open bool, ok bool) {
+	switch runes[i] {
+	case '`', '~', ' ':
+		if i == 0 || runes[i-1] == '\n' {
+			if end, open, ok := fencedBlockAt(runes, i); ok {
+				return end, open, true
+			}
+		}
+		// Only a whole run of backticks opens a code span
+		if runes[i] == '`' && (i == 0 || runes[i-1] != '`') {
+			end, ok = codeSpanAt(runes, i)
+		}
+	case '(':
+		if i > 0 && runes[i-1] == ']' {
+			end, ok = linkDestinationAt(runes, i)
+		}
+	case '<':
+		end, ok = autolinkAt(runes, i)
+	}
+	return end, false, ok
+}
+
+// fencedBlockAt matches a fenced code block starting at the line start i: a fence of three or
+// more ` or ~ indented by up to three spaces, up to the end of the closing fence line or of the
+// text. The line break after the closing fence is not part of the block.
+func fencedBlockAt(runes []rune, i int) (end int, open bool, ok bool) {
+	fence, length, ok := fenceAt(runes, i)
+	if !ok {
+		return 0, false, false
+	}
+	lineEnd := func(from int) int {
+		for from < len(runes) && runes[from] != '\n' {
+			from++
+		}
+		return from
+	}
+
+	end, open = lineEnd(i), true
+	for open && end < len(runes) {
+		start := end + 1
+		end = lineEnd(start)
+		closing, closingLength, ok := fenceAt(runes, start)
+		open = !ok || closing != fence || closingLength < length ||
+			strings.TrimSpace(string(runes[start:end])) != strings.Repeat(string(fence), closingLength)
+	}
+	// A Windows line ending stays a line break of the text
+	if end > i && end < len(runes) && runes[end-1] == '\r' {
+		end--
+	}
+	return end, open, true
+}
+
+// fenceAt reports the fence rune and length of a fence line starting at i
+func fenceAt(runes []rune, i int) (rune, int, bool) {
+	for indent := 0; indent < 3 && i < len(runes) && runes[i] == ' '; indent++ {
+		i++
+	}
+	if i >= len(runes) || (runes[i] != '`' && runes[i] != '~') {
+		return 0, 0, false
+	}
+	fence, length := runes[i], runLength(runes, i)
+	return fence, length, length >= 3
+}
+
+// codeSpanAt matches an inline code span: a run of backticks closed by a run of the same
+// length on the same line
+func codeSpanAt(runes []rune, i int) (int, bool) {
+	length := runLength(runes, i)
+	for j := i + length; j < len(runes) && runes[j] != '\n'; {
+		if runes[j] != '`' {
+			j++
+			continue
+		}
+		closing := runLength(runes, j)
+		if closing == length {
+			return j + closing, true
+		}
+		j += closing
+	}
+	return 0, false
+}
+
+// linkDestinationAt matches the destination after a link text, "(url)" in [text](url),
+// with balanced parentheses on the same line
+func linkDestinationAt(runes []rune, i int) (int, bool) {
+	depth := 0
+	for j := i; j < len(runes) && runes[j] != '\n'; j++ {
+		switch runes[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return j + 1, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// autolinkAt matches <scheme:...> without spaces on the same line
+func autolinkAt(runes []rune, i int) (int, bool) {
+	rest := string(runes[i+1 : min(i+1+8, len(runes))])
+	known := false
+	for _, scheme := range autolinkSchemes {
+		known = known || strings.HasPrefix(rest, scheme)
+	}
+	if !known {
+		return 0, false
+	}
+	for j := i + 1; j < len(runes); j++ {
+		switch runes[j] {
+		case '>':
+			return j + 1, true
+		case ' ', '\t', '\n', '<':
+			return 0, false
+		}
+	}
+	return 0, false
+}
+
+// markdownMarkerEnd reports whether tokens, the rest of the text from the first token of a
+// line past its indentation, start with a heading marker (# to ######) or a list marker
+// (-, *, + or a number ending in . or )) followed by a space. It returns the number of
+// tokens of the marker, 0 when there is none.
+func markdownMarkerEnd(tokens []Token) (end int, heading bool) {
+	if len(tokens) == 0 || tokens[0].Type != WORD {
+		return 0, false
+	}
+	marker := tokens[0].Value
+	switch {
+	case len(marker) <= 6 && strings.Trim(marker, "#") == "":
+		end, heading = 1, true
+	case marker == "-" || marker == "*" || marker == "+":
+		end = 1
+	case isListNumber(strings.TrimSuffix(marker, ")")) && strings.HasSuffix(marker, ")"):
+		end = 1
+	case isListNumber(marker) && len(tokens) > 1 && tokens[1].Type == PUNCTUATION && tokens[1].Value == ".":
+		end = 2
+	}
+	if end == 0 || end >= len(tokens) || tokens[end].Type != SPACE {
+		return 0, false
+	}
+	return end, heading
+}
+
+// isListNumber reports whether s is the number of an ordered list item, one to nine digits
+func isListNumber(s string) bool {
+	if s == "" || len(s) > 9 {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// headingCase capitalizes a word of a markdown heading like (title) without lowering the
+// rest of it, so "NASA" stays "NASA". The small words stay as written unless first.
+func headingCase(word string, first bool) string {
+	segments := strings.Split(word, "-")
+	for i, segment := range segments {
+		if (!first || i > 0) && titleSmallWords[strings.ToLower(segment)] {
+			continue
+		}
+		segments[i] = capitalizeFirstLetter(segment)
+	}
+	return strings.Join(segments, "-")
+}
+
+// returns the length of the run of runes[i] starting at i
+func runLength(runes []rune, i int) int {
+	n := 0
+	for i+n < len(runes) && runes[i+n] == runes[i] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,72 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextMarkdown(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.Markdown = true
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"fenced block", "run it (up) :\n```go\nx := f(a , b) // a apple (up)\n```\nthen ' go '", "run IT:\n```go\nx := f(a , b) // a apple (up)\n```\nthen 'go'"},
+		{"tilde fence and indentation", "  ~~~~\n' a ' ,b\n  ~~~ \n  ~~~~\na apple", "  ~~~~\n' a ' ,b\n  ~~~ \n  ~~~~\nan apple"},
+		{"unclosed fence", "text (up)\n```\ncode (up) ,\n", "TEXT\n```\ncode (up) ,\n"},
+		{"crlf after the fence", "```\r\nx ,y\r\n```\r\nok (up)", "```\r\nx ,y\r\n```\r\nOK"},
+		{"inline code", "use `a ,b (up)` now (up)", "use `a ,b (up)` NOW"},
+		{"double backticks", "see ``x ` y`` here", "see ``x ` y`` here"},
+		{"unclosed backticks", "a `` apple (up)", "a `` APPLE"},
+		{"link destination", "read [the docs](http://x.org/a_(b)?q=1,2) (up, 2) .", "read [THE DOCS](http://x.org/a_(b)?q=1,2)."},
+		{"image", "![a apple](img/a.png) !", "![a apple](img/a.png)!"},
+		{"autolink", "mail <mailto:me@x.org> or <https://x.org/?a=1,b> , bye", "mail <mailto:me@x.org> or <https://x.org/?a=1,b>, bye"},
+		{"not an autolink", "1 <2 , 3> 4", "1 <2, 3> 4"},
+		{"commands outside links", "(up) here (cap)", "Here"},
+	}
+
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessTextWithOptions(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+
+	// Without the mode markdown is plain text
+	if result := ProcessText("use `a ,b`"); result != "use `a, b`" {
+		t.Errorf("Expected code to be transformed without --markdown, got %q", result)
+	}
+}
+
+func TestProcessTextMarkdownSentences(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.Markdown = true
+	opts.CapitalizeSentences = true
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"heading", "# the quick guide to NASA\nit starts here.", "# The Quick Guide to NASA\nIt starts here."},
+		{"heading level and hyphens", "### state-of-the-art tools for a start", "### State-of-the-Art Tools for a Start"},
+		{"heading sentences", "## intro. the end", "## Intro. The End"},
+		{"not a heading", "####### seven\n#hashtag here", "####### seven\n#Hashtag here"},
+		{"bullet items", "- apples , pears\n* one. two\n  + nested item", "- Apples, pears\n* One. Two\n+ Nested item"},
+		{"ordered items", "1. first item\n2) second item", "1. First item\n2) Second item"},
+		{"no marker without a space", "-x and more\n1.first", "-X and more\n1. First"},
+	}
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessTextWithOptions(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+
+	// Without markdown a heading is a sentence whose first word is the marker
+	opts.Markdown = false
+	if result := ProcessTextWithOptions("# the guide", opts); result != "# the guide" {
+		t.Errorf("Expected the heading to stay a sentence without --markdown, got %q", result)
+	}
+}
@@ -0,0 +1,200 @@
+package transformer
+
+import (
+	"context"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"sync"
+)
+
+// Pipeline stages that PerCallOptions can switch on or off for a single call
+type Stage uint
+
+const (
+	STAGE_ARTICLES  Stage = 1 << iota // a -> an before vowels and h
+	STAGE_QUOTES                      // quote pairing and spacing
+	STAGE_ORDINALS                    // "1 st" -> "1st"
+	STAGE_SENTENCES                   // capitalize the first letter of every sentence
+)
+
+// names of the stages in ParseStages lists
+var stageNames = map[string]Stage{
+	"articles":  STAGE_ARTICLES,
+	"quotes":    STAGE_QUOTES,
+	"ordinals":  STAGE_ORDINALS,
+	"sentences": STAGE_SENTENCES,
+}
+
+// ParseStages reads a comma separated list of stage names: "articles,quotes"
+func ParseStages(list string) (Stage, error) {
+	var stages Stage
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		stage, ok := stageNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown stage %q, expected articles, quotes, ordinals or sentences", name)
+		}
+		stages |= stage
+	}
+	return stages, nil
+}
+
+// Phases of ProcessContext reported to a PhaseHook
+const (
+	PHASE_TRANSFORM    = "transform"    // tokenizing and applying the commands
+	PHASE_POST_PROCESS = "post-process" // assembling the text, then articles, quotes, dialogue and normalization
+)
+
+// PhaseHook is called when ProcessContext enters a phase, the returned function when it leaves it
+type PhaseHook func(ctx context.Context, phase string) (done func())
+
+type phaseHookKey struct{}
+
+// WithPhaseHook returns ctx making ProcessContext report its phases to hook, e.g. for tracing
+func WithPhaseHook(ctx context.Context, hook PhaseHook) context.Context {
+	return context.WithValue(ctx, phaseHookKey{}, hook)
+}
+
+// enters phase when ctx carries a hook, the returned function leaves it
+func enterPhase(ctx context.Context, phase string) func() {
+	if hook, ok := ctx.Value(phaseHookKey{}).(PhaseHook); ok && hook != nil {
+		return hook(ctx, phase)
+	}
+	return func() {}
+}
+
+// PerCallOptions adjusts the Processor's base options for one call only.
+// The zero value runs the call with the base options unchanged.
+type PerCallOptions struct {
+	Language string // punctuation preset for this call, see config.LanguagePunctuation, and digit grouping when the base groups digits; "" keeps the base rules
+	Enable   Stage  // stages switched on for this call
+	Disable  Stage  // stages switched off for this call, wins over Enable
+}
+
+// Processor transforms texts with a shared base configuration and pooled buffers.
+// It is safe for concurrent use: per-call options only change a private copy of the base options.
+type Processor struct {
+	base config.Options
+	pool sync.Pool // *TokenProcessor
+}
+
+// NewProcessor validates the base options and returns a Processor using them
+func NewProcessor(base config.Options) (*Processor, error) {
+	if err := base.Validate(); err != nil {
+		return nil, err
+	}
+	if err := ValidateAliases(base.CommandAliases); err != nil {
+		return nil, err
+	}
+	p := &Processor{base: base}
+	p.pool.New = func() any {
+		return NewTokenProcessor()
+	}
+	return p, nil
+}
+
+// Options returns the base options of the processor
+func (p *Processor) Options() config.Options {
+	return p.base
+}
+
+// Process transforms text with the base options
+func (p *Processor) Process(text string) string {
+	result, _ := p.ProcessContext(context.Background(), text, PerCallOptions{})
+	return result
+}
+
+// ProcessContext transforms text with the base options adjusted by call.
+// A cancelled context is reported before processing starts and before the result is assembled.
+func (p *Processor) ProcessContext(ctx context.Context, text string, call PerCallOptions) (string, error) {
+	result, _, err := p.ProcessWarnings(ctx, text, call)
+	return result, err
+}
+
+// ProcessWarnings works like ProcessContext and also returns a warning for every
+// command that was dropped or left in the text, in text order
+func (p *Processor) ProcessWarnings(ctx context.Context, text string, call PerCallOptions) (string, []Warning, error) {
+	report, err := p.ProcessReport(ctx, text, call)
+	return report.Output, report.Warnings, err
+}
+
+// Report is what one call to ProcessReport did
+type Report struct {
+	Output   string
+	Warnings []Warning      // commands dropped or left in the text, in text order
+	Applied  map[string]int // words changed per command name: (up, 3) adds 3 to "up", nil when none
+	Edits    []Edit         // every change to the text, only filled by TraceText
+}
+
+// ProcessReport works like ProcessWarnings and also counts the words every command changed.
+// Under config.UNAPPLIED_ERROR a command that was not applied returns the full report and
+// ErrUnapplied.
+func (p *Processor) ProcessReport(ctx context.Context, text string, call PerCallOptions) (Report, error) {
+	opts, err := p.resolve(call)
+	if err != nil {
+		return Report{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+	if text == "" {
+		return Report{}, nil
+	}
+
+	processor := p.pool.Get().(*TokenProcessor)
+	defer func() {
+		processor.reset()
+		p.pool.Put(processor)
+	}()
+
+	done := enterPhase(ctx, PHASE_TRANSFORM)
+	tokenizeInto(processor, text, opts)
+	done()
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+	// The processor goes back to the pool, its warnings and counts stay with the caller
+	report := Report{Warnings: processor.warnings, Applied: processor.applied}
+
+	done = enterPhase(ctx, PHASE_POST_PROCESS)
+	defer done()
+	report.Output = render(processor, opts)
+	return report, UnappliedError(report.Warnings, opts)
+}
+
+// resolve applies the per-call overrides to a copy of the base options
+func (p *Processor) resolve(call PerCallOptions) (config.Options, error) {
+	opts := p.base
+	if call.Language != "" {
+		rules, err := config.LanguagePunctuation(call.Language)
+		if err != nil {
+			return opts, err
+		}
+		opts.Punctuation = rules
+		// Grouped results follow the language of the call too
+		if opts.DigitGrouping != "" && opts.DigitGrouping != config.GROUPING_NONE {
+			if opts.DigitGrouping, err = config.LanguageGrouping(call.Language); err != nil {
+				return opts, err
+			}
+		}
+	}
+
+	set := func(stage Stage, value *bool, enabledMeansSkip bool) {
+		if call.Enable&stage != 0 {
+			*value = !enabledMeansSkip
+		}
+		if call.Disable&stage != 0 {
+			*value = enabledMeansSkip
+		}
+	}
+	set(STAGE_ARTICLES, &opts.SkipArticles, true)
+	set(STAGE_QUOTES, &opts.SkipQuotes, true)
+	set(STAGE_ORDINALS, &opts.NormalizeOrdinals, false)
+	set(STAGE_SENTENCES, &opts.CapitalizeSentences, false)
+
+	return opts, opts.Validate()
+}
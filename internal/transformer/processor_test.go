@@ -0,0 +1,223 @@
+package transformer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"sync"
+	"testing"
+)
+
+func TestProcessorMatchesProcessText(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "it was a apple (up) , ' really ' . 1E (hex) files"
+	if result, expected := p.Process(text), ProcessText(text); result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessorPerCallOptions(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		call     PerCallOptions
+		input    string
+		expected string
+	}{
+		{"defaults", PerCallOptions{}, "a apple ' hi ' ?", "an apple 'hi'?"},
+		{"french", PerCallOptions{Language: "fr"}, "Quoi?oui", "Quoi ? oui"},
+		{"no articles", PerCallOptions{Disable: STAGE_ARTICLES}, "a apple", "a apple"},
+		{"no quotes", PerCallOptions{Disable: STAGE_QUOTES}, "' hi '", "' hi '"},
+		{"sentences", PerCallOptions{Enable: STAGE_SENTENCES}, "one. two", "One. Two"},
+		{"ordinals", PerCallOptions{Enable: STAGE_ORDINALS}, "the 1 st", "the 1st"},
+		{"disable wins", PerCallOptions{Enable: STAGE_SENTENCES, Disable: STAGE_SENTENCES}, "one. two", "one. two"},
+	}
+
+	for _, test := range tests {
+		result, err := p.ProcessContext(context.Background(), test.input, test.call)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("%s: ProcessContext(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+
+	grouped := config.DefaultOptions()
+	grouped.DigitGrouping = config.GROUPING_EN
+	if p, err := NewProcessor(grouped); err != nil {
+		t.Fatal(err)
+	} else if result, err := p.ProcessContext(context.Background(), "FFFFF (hex)?", PerCallOptions{Language: "fr"}); err != nil || result != "1\u202F048\u202F575 ?" {
+		t.Errorf("Expected French grouping and spacing, got %q, %v", result, err)
+	}
+
+	// Per-call options never leak into the base options
+	if p.Options().Punctuation != nil || p.Options().SkipArticles {
+		t.Errorf("base options were modified: %+v", p.Options())
+	}
+}
+
+func TestProcessorErrors(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.SmartQuotes = true
+	opts.ASCIIQuotes = true
+	if _, err := NewProcessor(opts); err == nil {
+		t.Error("Expected error for invalid base options")
+	}
+
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ProcessContext(context.Background(), "text", PerCallOptions{Language: "xx"}); err == nil {
+		t.Error("Expected error for unknown language")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.ProcessContext(ctx, "text", PerCallOptions{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestProcessorWarnings(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Pooled processors must not hand one call's warnings to the next
+	for _, text := range []string{"zz (hex)", "fine (up)"} {
+		_, expected := ProcessTextWarnings(text, p.Options())
+		result, warnings, err := p.ProcessWarnings(context.Background(), text, PerCallOptions{})
+		if err != nil || fmt.Sprint(warnings) != fmt.Sprint(expected) || result != ProcessText(text) {
+			t.Errorf("ProcessWarnings(%q) = %q, %v, %v, expected warnings %v", text, result, warnings, err, expected)
+		}
+	}
+}
+
+func TestProcessorReport(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"one two three (up, 2) 1010 (bin)": "map[bin:1 up:2]",
+		"(cap>) next word and zz (hex)":    "map[cap:1]",
+		"nothing to do":                    "map[]",
+	}
+	for text, expected := range tests {
+		report, err := p.ProcessReport(context.Background(), text, PerCallOptions{})
+		if err != nil || fmt.Sprint(report.Applied) != expected || report.Output != ProcessText(text) {
+			t.Errorf("ProcessReport(%q) = %+v, %v, expected applied %s", text, report, err, expected)
+		}
+	}
+}
+
+// run with -race: one shared Processor, options varying per goroutine
+func TestProcessorConcurrent(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			call, expected := PerCallOptions{}, fmt.Sprintf("word%d? an Apple", i)
+			if i%2 == 0 {
+				call, expected = PerCallOptions{Language: "fr", Disable: STAGE_ARTICLES}, fmt.Sprintf("word%d ? a Apple", i)
+			}
+			for j := 0; j < 50; j++ {
+				result, err := p.ProcessContext(context.Background(), fmt.Sprintf("word%d? a apple (cap)", i), call)
+				if err != nil || result != expected {
+					t.Errorf("goroutine %d: got %q, %v, expected %q", i, result, err, expected)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestParseStages(t *testing.T) {
+	stages, err := ParseStages("articles, sentences,")
+	if err != nil || stages != STAGE_ARTICLES|STAGE_SENTENCES {
+		t.Errorf("Unexpected stages %b, %v", stages, err)
+	}
+	if stages, err := ParseStages(""); err != nil || stages != 0 {
+		t.Errorf("Expected no stages for an empty list, got %b, %v", stages, err)
+	}
+	if _, err := ParseStages("quotes,spelling"); err == nil {
+		t.Error("Expected error for an unknown stage")
+	}
+}
+
+func TestProcessorPhaseHook(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	ctx := WithPhaseHook(context.Background(), func(ctx context.Context, phase string) func() {
+		events = append(events, "enter "+phase)
+		return func() { events = append(events, "leave "+phase) }
+	})
+	if _, err := p.ProcessContext(ctx, "a apple", PerCallOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := fmt.Sprint([]string{"enter transform", "leave transform", "enter post-process", "leave post-process"})
+	if fmt.Sprint(events) != expected {
+		t.Errorf("Expected phases %s, got %v", expected, events)
+	}
+}
+
+func TestProcessAll(t *testing.T) {
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputs := make([]Input, 50)
+	for i := range inputs {
+		inputs[i] = Input{Text: fmt.Sprintf("a apple (up) %d", i)}
+	}
+	inputs[7].Options.Language = "xx"
+	inputs[9] = Input{Text: "Quoi?oui", Options: PerCallOptions{Language: "fr"}}
+
+	results := p.ProcessAll(context.Background(), inputs, 4)
+	if len(results) != len(inputs) {
+		t.Fatalf("Expected %d results, got %d", len(inputs), len(results))
+	}
+	for i, result := range results {
+		switch i {
+		case 7:
+			if result.Err == nil {
+				t.Errorf("Expected an error for the unknown language, got %+v", result)
+			}
+		case 9:
+			if result.Err != nil || result.Output != "Quoi ? oui" {
+				t.Errorf("Per-input options not applied: %+v", result)
+			}
+		default:
+			if result.Err != nil || result.Output != fmt.Sprintf("an APPLE %d", i) {
+				t.Errorf("Input %d: got %+v", i, result)
+			}
+		}
+	}
+	if results := p.ProcessAll(context.Background(), nil, 0); len(results) != 0 {
+		t.Errorf("Expected no results for no inputs, got %v", results)
+	}
+}
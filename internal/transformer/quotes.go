@@ -0,0 +1,66 @@
+package transformer
+
+// openQuote is a quote waiting for the one that closes it
+type openQuote struct {
+	at   int  // rune index
+	r    rune // the quote as written
+	kind rune // the straight quote it pairs as, '"' or '\''
+}
+
+// pairQuote gives the quote q its role against stack, the quotes open before it innermost
+// last, and returns the stack after it. A quote closes the innermost open quote of its kind,
+// the quotes still open inside that pair are stray and returned too, until the stack grows.
+func pairQuote(stack []openQuote, q openQuote) (role int, rest, stray []openQuote) {
+	for j := len(stack) - 1; j >= 0; j-- {
+		if stack[j].kind == q.kind {
+			return QUOTE_CLOSE, stack[:j], stack[j+1:]
+		}
+	}
+	return QUOTE_OPEN, append(stack, q), nil
+}
+
+// quoteKind returns the straight quote r pairs as, 0 when it is no quote. With typographic
+// the curly quotes count as the straight ones straightenQuotes turns them into.
+func quoteKind(r rune, typographic bool) rune {
+	switch {
+	case r == '"' || r == '\'':
+		return r
+	case !typographic:
+	case r == '“' || r == '”' || r == '„':
+		return '"'
+	case r == '‘' || r == '’':
+		return '\''
+	}
+	return 0
+}
+
+// noteQuote pairs the quote at runes[i], if it is one, as fixQuotes pairs the quotes of the
+// output, and warns about a quote it leaves open inside a closed pair. Raw regions and
+// commands never get here.
+func (tp *TokenProcessor) noteQuote(runes []rune, i int) {
+	kind := quoteKind(runes[i], tp.opts.ASCIIQuotes)
+	if kind == 0 || tp.opts.SkipQuotes || isContraction(runes, i) || betweenDigits(runes, i) {
+		return
+	}
+	_, stack, stray := pairQuote(tp.quotes, openQuote{at: i, r: runes[i], kind: kind})
+	for _, quote := range stray {
+		tp.warn(quote.at, string(quote.r), "quote left open inside a closed pair")
+	}
+	tp.quotes = stack
+}
+
+// noteQuotes notes the quotes of runes[from:to], written in a word as they are
+func (tp *TokenProcessor) noteQuotes(runes []rune, from, to int) {
+	for i := from; i < to; i++ {
+		tp.noteQuote(runes, i)
+	}
+}
+
+// endQuotes warns about the quotes still open at a blank line or the end of the text,
+// the next paragraph pairs its quotes on its own
+func (tp *TokenProcessor) endQuotes() {
+	for _, quote := range tp.quotes {
+		tp.warn(quote.at, string(quote.r), "quote never closed in its paragraph")
+	}
+	tp.quotes = tp.quotes[:0]
+}
@@ -0,0 +1,59 @@
+package transformer
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextQuoteParagraphs(t *testing.T) {
+	ascii := config.DefaultOptions()
+	ascii.ASCIIQuotes = true
+	skip := config.DefaultOptions()
+	skip.SkipQuotes = true
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     config.Options
+		expected string
+		warnings []string
+	}{
+		{"balanced", "' a '\n\n\" b \"", config.DefaultOptions(), "'a'\n\n\"b\"", nil},
+		{"pairs across lines", "' a\nb '", config.DefaultOptions(), "'a\nb'", nil},
+		{"reset at blank line", "the boys ' toys\n\nshe said ' hi ' twice", config.DefaultOptions(), "the boys 'toys\n\nshe said 'hi' twice", []string{"1:10: ': quote never closed in its paragraph"}},
+		{"blank line with spaces", "\" a\n \t\n' b '", config.DefaultOptions(), "\"a\n\n'b'", []string{"1:1: \": quote never closed in its paragraph"}},
+		{"end of text", "a ' b ' c ' d", config.DefaultOptions(), "a 'b' c 'd", []string{"1:11: ': quote never closed in its paragraph"}},
+		{"stray", "\" a ' b \"", config.DefaultOptions(), "\"a ' b\"", []string{"1:5: ': quote left open inside a closed pair"}},
+		{"contractions", "don't ' it's ' ok", config.DefaultOptions(), "don't 'it's' ok", nil},
+		{"raw region", "(raw)' (endraw)' a '", config.DefaultOptions(), "' 'a'", nil},
+		{"parenthesized", "(a 'b) c '", config.DefaultOptions(), "(a 'b) c'", nil},
+		{"typographic", "“ a\n\n‘ b ’", ascii, "\"a\n\n'b'", []string{"1:1: “: quote never closed in its paragraph"}},
+		{"quotes skipped", "a ' b", skip, "a ' b", nil},
+	}
+	for _, test := range tests {
+		output, warnings := ProcessTextWarnings(test.input, test.opts)
+		if output != test.expected {
+			t.Errorf("%s: ProcessTextWarnings(%q) = %q, expected %q", test.name, test.input, output, test.expected)
+		}
+		var got []string
+		for _, warning := range warnings {
+			got = append(got, warning.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.warnings) {
+			t.Errorf("%s: warnings for %q are %q, expected %q", test.name, test.input, got, test.warnings)
+		}
+	}
+}
+
+func TestProcessSegmentQuoteParagraphs(t *testing.T) {
+	// The open quote keeps the first line from being cut, the blank line ends it
+	segment := ProcessSegment("' a\nb\n\nc\n", config.DefaultOptions())
+	var cuts []int
+	for _, cut := range segment.Cuts {
+		cuts = append(cuts, cut.Input)
+	}
+	if fmt.Sprint(cuts) != "[7 9]" || segment.Open {
+		t.Errorf("ProcessSegment cuts at %v, open %v, expected [7 9] and closed", cuts, segment.Open)
+	}
+}
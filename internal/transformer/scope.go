@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+)
+
+// scopeStart returns where the boundary of opts.CommandScope ending at the token at i
+// starts, -1 when there is none: the first line break of a blank line whose second one is
+// at i, or under SCOPE_SENTENCE i itself for punctuation ending a sentence. With first the
+// token is the first one a backward command looks at past spaces, and punctuation there
+// ends the command's own sentence: "It is done. (up)".
+func (tp *TokenProcessor) scopeStart(i int, first bool) int {
+	token := tp.tokens[i]
+	switch {
+	case tp.opts.CommandScope == "":
+	case token.Type == NEWLINE:
+		return tp.paragraphStart(i)
+	case token.Type == PUNCTUATION && tp.opts.CommandScope == config.SCOPE_SENTENCE:
+		if !(first && tp.onlySpacesAfter(i)) && strings.ContainsAny(token.Value, ".!?") {
+			return i
+		}
+	}
+	return -1
+}
+
+// paragraphStart returns the first line break of the blank line whose second one is the
+// NEWLINE token at i, -1 when the line ending at i is not blank
+func (tp *TokenProcessor) paragraphStart(i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if tp.tokens[j].Type == NEWLINE {
+			return j
+		}
+		if tp.tokens[j].Type != SPACE {
+			break
+		}
+	}
+	return -1
+}
+
+// blankStart reports whether the text starts with a blank line under a command scope: the
+// line break ending it is a boundary once the text before is joined to it
+func (tp *TokenProcessor) blankStart() bool {
+	if tp.opts.CommandScope == "" {
+		return false
+	}
+	for i := 0; i < tp.tokenIdx; i++ {
+		switch tp.tokens[i].Type {
+		case NEWLINE:
+			return true
+		case SPACE:
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// onlySpacesAfter reports whether the tokens after i are SPACE tokens, if any
+func (tp *TokenProcessor) onlySpacesAfter(i int) bool {
+	for j := i + 1; j < tp.tokenIdx; j++ {
+		if tp.tokens[j].Type != SPACE {
+			return false
+		}
+	}
+	return true
+}
+
+// endPending ends every forward command waiting for words, at a boundary of the scope or at
+// the end of the text. One without a word yet is not applied.
+func (tp *TokenProcessor) endPending() {
+	for i := range tp.pending {
+		if pending := tp.pending[i]; pending.pos >= 0 {
+			tp.unapplied(pending.at, pending.pos, pending.text, "no following word")
+		} else {
+			tp.shortCount(pending.at, pending.text, pending.count, pending.count-pending.remaining)
+		}
+	}
+	tp.pending = tp.pending[:0]
+}
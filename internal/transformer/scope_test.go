@@ -0,0 +1,48 @@
+package transformer
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextCommandScope(t *testing.T) {
+	sentence := config.DefaultOptions()
+	sentence.CommandScope = config.SCOPE_SENTENCE
+	sentence.ShortCountPolicy = config.SHORT_COUNT_WARN
+	paragraph := config.DefaultOptions()
+	paragraph.CommandScope = config.SCOPE_PARAGRAPH
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     config.Options
+		expected string
+		warnings []string
+	}{
+		{"unscoped", "a\n\nb (up, 2)", config.DefaultOptions(), "A\n\nB", nil},
+		{"paragraph", "end of the old one\n\nnew para (cap, 5)", paragraph, "end of the old one\n\nNew Para", nil},
+		{"blank line with spaces", "a\n  \nb (up, 2)", paragraph, "a\n\nB", nil},
+		{"lines of a paragraph", "one two.\nthree (up, 3)", paragraph, "ONE TWO.\nTHREE", nil},
+		{"sentence", "one two. three four (up, 3)", sentence, "one two. THREE FOUR", []string{"1:21: (up, 3): count 3 reaches only 2 words"}},
+		{"sentence of the command", "It is done. (up) It is done ! (up, 2)", sentence, "It is DONE. It IS DONE!", nil},
+		{"count all", "First one? second one here (low, all)", sentence, "First one? second one here", nil},
+		{"forward", "(up>, 3) one two. three", sentence, "ONE TWO. three", []string{"1:1: (up>, 3): count 3 reaches only 2 words"}},
+		{"forward paragraph", "(cap>, 5) one\n\ntwo", paragraph, "One\n\ntwo", nil},
+		{"nothing in scope", "end.\n\n(up) x", paragraph, "end.\n\nx", []string{"3:1: (up): no preceding word"}},
+		{"forward nothing in scope", "x (up>)\n\ny", paragraph, "x\n\ny", []string{"1:3: (up>): no following word"}},
+	}
+	for _, test := range tests {
+		output, warnings := ProcessTextWarnings(test.input, test.opts)
+		if output != test.expected {
+			t.Errorf("%s: ProcessTextWarnings(%q) = %q, expected %q", test.name, test.input, output, test.expected)
+		}
+		var got []string
+		for _, warning := range warnings {
+			got = append(got, warning.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.warnings) {
+			t.Errorf("%s: warnings for %q are %q, expected %q", test.name, test.input, got, test.warnings)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Segment is one part of a longer text transformed on its own, see ProcessSegment
+type Segment struct {
+	Output   string
+	Reaches  []Reach // backward commands that wanted more words than the segment has before them, in order
+	Open     bool    // a forward command, a quote or a dialogue quotation continues past the end
+	Cuts     []Cut   // line breaks nothing continues across, in order
+	Warnings []Warning
+	Applied  []CommandCount // words changed by every command, in text order
+}
+
+// CommandCount is how many words one command of a segment changed
+type CommandCount struct {
+	Line    int    // 1-based line of the command
+	Offset  int    // byte offset of its opening parenthesis
+	Command string // command name: "up"
+	Words   int
+}
+
+// Cut is a line break of a segment that nothing continues across: no forward command, quote
+// or quotation is open there. Both offsets point just after the line break. A tied cut has
+// a backward command after it reaching over it, the output before it holds what the command
+// changed there.
+type Cut struct {
+	Input  int  // offset in the segment text
+	Output int  // offset in the segment output
+	Words  int  // words before the line break
+	Scope  int  // of them, the words before the last boundary of the command scope, -1 when there is none
+	Tied   bool // a backward command reaches over it
+}
+
+// Reach is a backward command whose count goes past the start of its segment, into the words
+// of the text before it. ProcessPiece applies it there and tells the command what it did.
+type Reach struct {
+	Command  string   // command name: "low"
+	Words    int      // words it wants from the text before the segment
+	Changed  int      // of them, the words it changed
+	Problems []string // why it could not convert the others, in text order
+}
+
+// ProcessSegment transforms text, a part of a longer text cut after line breaks, and reports
+// how it depends on the text around it. Apart from commands, quotes and dialogue nothing
+// connects two lines, so the output up to a cut that is not tied is exactly the
+// transformation of the text up to the cut, and a part that follows no open part transforms
+// the same on its own as within the whole text, up to the words its Reaches change, see
+// ProcessPiece.
+func ProcessSegment(text string, opts config.Options) Segment {
+	if text == "" {
+		return Segment{}
+	}
+	return tokenize(text, opts).segment(text, opts)
+}
+
+// ProcessPiece transforms text, a segment up to one of its cuts, as it transforms within the
+// whole text. before holds a Reach for every command of Segment.Reaches, in order, with the
+// words it found in the text before and what it did there. after holds the backward commands
+// of the text after, in order, each changing its last Words words; they are returned with
+// Changed and Problems filled in for the command to hear about.
+func ProcessPiece(text string, opts config.Options, before, after []Reach) (Segment, []Reach) {
+	if text == "" {
+		return Segment{}, nil
+	}
+	processor := NewTokenProcessor()
+	processor.before = before
+	for _, reach := range after {
+		processor.after = append(processor.after, Reach{Command: reach.Command, Words: reach.Words})
+	}
+	tokenizeInto(processor, text, opts)
+	return processor.segment(text, opts), processor.after
+}
+
+// segment renders the tokenized text and finds its cuts
+func (tp *TokenProcessor) segment(text string, opts config.Options) Segment {
+	words, scopes := tp.lineWords()
+	output := render(tp, opts)
+	segment := Segment{Output: output, Reaches: tp.reaches, Open: tp.open[len(tp.open)-1], Warnings: tp.warnings, Applied: tp.counts}
+	input, out := 0, 0
+	for k, open := range tp.open[:len(tp.open)-1] {
+		input += strings.IndexByte(text[input:], '\n') + 1
+		out += strings.IndexByte(output[out:], '\n') + 1
+		if !open {
+			segment.Cuts = append(segment.Cuts, Cut{Input: input, Output: out, Words: words[k], Scope: scopes[k], Tied: tp.tied[k]})
+		}
+	}
+	return segment
+}
+
+// lineWords returns for every line break the words before it, and how many of them come
+// before the last boundary of the command scope, -1 when there is none. A backward command
+// after the line break reaches no further back than that boundary.
+func (tp *TokenProcessor) lineWords() (words, scopes []int) {
+	count, scope := 0, -1
+	if tp.blankStart() {
+		scope = 0
+	}
+	for i := 0; i < tp.tokenIdx; i++ {
+		if tp.tokens[i].Type == WORD {
+			count++
+		} else if tp.scopeStart(i, false) >= 0 {
+			scope = count
+		}
+		if tp.tokens[i].Type == NEWLINE {
+			words, scopes = append(words, count), append(scopes, scope)
+		}
+	}
+	return words, scopes
+}
+
+// reachInto applies the backward command of reach, written in the text after, to the last
+// reach.Words words, in forward order like processCommand
+func (tp *TokenProcessor) reachInto(reach *Reach) {
+	var wordIndices []int
+	for i := tp.tokenIdx - 1; i >= 0 && len(wordIndices) < reach.Words; i-- {
+		if tp.tokens[i].Type == WORD {
+			wordIndices = append(wordIndices, i)
+		}
+	}
+	for i := len(wordIndices) - 1; i >= 0; i-- {
+		if tp.applyCommand(wordIndices[i], reach.Command) {
+			reach.Changed++
+		} else {
+			reach.Problems = append(reach.Problems, conversionProblem(reach.Command, tp.tokens[wordIndices[i]].Value))
+		}
+	}
+}
+
+// SegmentEnd returns the length of the longest prefix of text that ends with a line break
+// and leaves at least minWords whitespace separated words after it, or -1 when there is none.
+// The words left over start the next segment, commands there can reach back to them.
+func SegmentEnd(text string, minWords int) int {
+	words := 0
+	inWord := false
+	for end := len(text); end > 0; {
+		r, size := utf8.DecodeLastRuneInString(text[:end])
+		end -= size
+		if r == '\n' && words >= minWords {
+			return end + 1
+		}
+		space := unicode.IsSpace(r)
+		if !space && !inWord {
+			words++
+		}
+		inWord = !space
+	}
+	return -1
+}
@@ -0,0 +1,151 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessSegment(t *testing.T) {
+	dialogue := config.DefaultOptions()
+	dialogue.Dialogue = config.DIALOGUE_AMERICAN
+	markdown := config.DefaultOptions()
+	markdown.Markdown = true
+	paragraphs := config.DefaultOptions()
+	paragraphs.CommandScope = config.SCOPE_PARAGRAPH
+	sentences := config.DefaultOptions()
+	sentences.CommandScope = config.SCOPE_SENTENCE
+
+	tests := []struct {
+		name        string
+		input       string
+		opts        config.Options
+		reachesBack bool // a command reaches into the text before
+		open        bool
+		cuts        []Cut
+	}{
+		{"independent lines", "one (up)\ntwo\n", config.DefaultOptions(), false, false, []Cut{{9, 4, 1, -1, false}, {13, 8, 2, -1, false}}},
+		{"reaches back", "one\ntwo (up, 3)", config.DefaultOptions(), true, false, []Cut{{4, 4, 1, -1, true}}},
+		{"reaches over a line", "one\ntwo\nthree (up, 2)\nfour", config.DefaultOptions(), false, false, []Cut{{4, 4, 1, -1, false}, {8, 8, 2, -1, true}, {22, 14, 3, -1, false}}},
+		{"forward command", "one (up>)\ntwo\nthree", config.DefaultOptions(), false, false, []Cut{{14, 8, 2, -1, false}}},
+		{"forward command over lines", "one (up>, 2)\ntwo\nthree", config.DefaultOptions(), false, false, nil},
+		{"open forward command", "one (up>)\n", config.DefaultOptions(), false, true, nil},
+		{"quote over lines", "' one\ntwo '\nthree", config.DefaultOptions(), false, false, []Cut{{12, 10, 4, -1, false}}},
+		{"open quote", "one\n' two", config.DefaultOptions(), false, true, []Cut{{4, 4, 1, -1, false}}},
+		{"raw region over lines", "(raw)one\ntwo(endraw)\nthree (raw)four\n", config.DefaultOptions(), false, true, []Cut{{21, 8, 0, -1, false}}},
+		{"fenced block", "x\n```\ny\n```\nz", markdown, false, false, []Cut{{2, 2, 1, -1, false}, {12, 12, 1, -1, false}}},
+		{"unclosed fenced block", "x\n```\ny\n", markdown, false, true, []Cut{{2, 2, 1, -1, false}}},
+		{"quoted words in a sentence", "it is \"fine\" there", dialogue, false, true, nil},
+		{"paragraph boundary", "one\n\ntwo\n", paragraphs, false, false, []Cut{{4, 4, 1, -1, false}, {5, 5, 1, 1, false}, {9, 9, 2, 1, false}}},
+		{"sentence boundary", "a. b\n", sentences, false, false, []Cut{{5, 5, 2, 1, false}}},
+		{"blank first line", "\nthree (up, 5)", config.DefaultOptions(), true, false, []Cut{{1, 1, 0, -1, true}}},
+		{"blank first line ends a paragraph", "\nthree (up, 5)", paragraphs, false, false, []Cut{{1, 1, 0, 0, true}}},
+	}
+
+	for _, test := range tests {
+		segment := ProcessSegment(test.input, test.opts)
+		if segment.Output != ProcessTextWithOptions(test.input, test.opts) {
+			t.Errorf("%s: output %q differs from a single pass", test.name, segment.Output)
+		}
+		if reachesBack := len(segment.Reaches) > 0; reachesBack != test.reachesBack || segment.Open != test.open {
+			t.Errorf("%s: reaches back %v, open %v, expected %v, %v", test.name, reachesBack, segment.Open, test.reachesBack, test.open)
+		}
+		if len(segment.Cuts) != len(test.cuts) {
+			t.Errorf("%s: cuts %v, expected %v", test.name, segment.Cuts, test.cuts)
+			continue
+		}
+		for i, cut := range segment.Cuts {
+			if cut != test.cuts[i] {
+				t.Errorf("%s: cuts %v, expected %v", test.name, segment.Cuts, test.cuts)
+				break
+			}
+			// The output up to a cut is the transformation of the text up to it, but for the
+			// words a command after a tied one changes
+			if prefix := ProcessTextWithOptions(test.input[:cut.Input], test.opts); !cut.Tied && prefix != segment.Output[:cut.Output] {
+				t.Errorf("%s: output up to cut %d is %q, the text up to it gives %q", test.name, i, segment.Output[:cut.Output], prefix)
+			}
+		}
+	}
+}
+
+// Pieces transformed with the commands reaching across them give the output, warnings and
+// counts of the whole text
+func TestProcessPiece(t *testing.T) {
+	shortCounts := config.DefaultOptions()
+	shortCounts.ShortCountPolicy = config.SHORT_COUNT_WARN
+	tests := []struct {
+		name     string
+		head     string // text the command of tail reaches into
+		tail     string
+		words    int // words of head it reaches
+		opts     config.Options
+		expected string
+	}{
+		{"case", "one two\n", "three (up, 4)\n", 2, shortCounts, "ONE TWO\nTHREE\n"},
+		{"count all", "One Two\n", "three (low, all)\n", 2, config.DefaultOptions(), "one two\nthree\n"},
+		{"no word of its own", "one two\n", "(up, 2) three\n", 2, config.DefaultOptions(), "ONE TWO\nthree\n"},
+		{"conversion", "zz ff\n", "(hex) x\n", 1, config.DefaultOptions(), "zz 255\nx\n"},
+		{"conversion problem", "ff zz\n", "(hex) x\n", 1, config.DefaultOptions(), "ff zz\nx\n"},
+	}
+	for _, test := range tests {
+		output, warnings := ProcessTextWarnings(test.head+test.tail, test.opts)
+		if output != test.expected {
+			t.Fatalf("%s: single pass gives %q, expected %q", test.name, output, test.expected)
+		}
+
+		reaches := ProcessSegment(test.tail, test.opts).Reaches
+		if len(reaches) != 1 {
+			t.Fatalf("%s: expected one command reaching back, got %v", test.name, reaches)
+		}
+		head, reached := ProcessPiece(test.head, test.opts, nil, []Reach{{Command: reaches[0].Command, Words: test.words}})
+		reached[0].Words = test.words
+		tail, _ := ProcessPiece(test.tail, test.opts, reached, nil)
+		if head.Output+tail.Output != output {
+			t.Errorf("%s: pieces give %q, the whole text %q", test.name, head.Output+tail.Output, output)
+		}
+		var got []Warning
+		for _, warning := range tail.Warnings {
+			warning.Line++
+			got = append(got, warning)
+		}
+		if len(got) != len(warnings) {
+			t.Errorf("%s: pieces warn %v, the whole text %v", test.name, got, warnings)
+			continue
+		}
+		for i := range got {
+			if got[i].Line != warnings[i].Line || got[i].Command != warnings[i].Command || got[i].Reason != warnings[i].Reason {
+				t.Errorf("%s: pieces warn %v, the whole text %v", test.name, got, warnings)
+				break
+			}
+		}
+		whole := ProcessSegment(test.head+test.tail, test.opts).Applied
+		if len(tail.Applied) != len(whole) || len(whole) > 0 && tail.Applied[0].Words != whole[0].Words {
+			t.Errorf("%s: pieces count %v, the whole text %v", test.name, tail.Applied, whole)
+		}
+	}
+}
+
+func TestSegmentEnd(t *testing.T) {
+	tests := []struct {
+		text     string
+		minWords int
+		expected int
+	}{
+		{"one\ntwo three\nfour", 1, 14},
+		{"one\ntwo three\nfour", 2, 4},
+		{"one\ntwo three\nfour", 4, -1},
+		{"one two", 0, -1},
+		{"one\n", 0, 4},
+	}
+	for _, test := range tests {
+		if end := SegmentEnd(test.text, test.minWords); end != test.expected {
+			t.Errorf("SegmentEnd(%q, %d) = %d, expected %d", test.text, test.minWords, end, test.expected)
+		}
+	}
+}
+
+// A command split by a line break is no command, so no token ends up holding the line break
+func TestCommandsNeverSpanLines(t *testing.T) {
+	if result := ProcessText("word (up,\n2)"); result != "word (up,\n2)" {
+		t.Errorf("Expected the split command to stay text, got %q", result)
+	}
+}
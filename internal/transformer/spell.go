@@ -0,0 +1,69 @@
+package transformer
+
+import "strings"
+
+var (
+	spelledOnes = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine",
+		"ten", "eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	spelledTens = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	// short scale names of every power of a thousand, from 10^3
+	spelledScales = []string{"thousand", "million", "billion", "trillion", "quadrillion", "quintillion",
+		"sextillion", "septillion", "octillion", "nonillion", "decillion"}
+)
+
+// spellNumber writes number, ASCII digits with an optional sign, in English words:
+// "-1042" -> "minus one thousand forty-two". ok is false for a number of more than 36 digits,
+// no scale name goes past decillion.
+func spellNumber(number string) (string, bool) {
+	unsigned := strings.TrimLeft(number, "+-")
+	digits := strings.TrimLeft(unsigned, "0")
+	if len(number)-len(unsigned) > 1 || unsigned == "" || strings.Trim(digits, "0123456789") != "" || len(digits) > 3*(len(spelledScales)+1) {
+		return "", false
+	}
+	if digits == "" {
+		return spelledOnes[0], true
+	}
+	var words []string
+	if strings.HasPrefix(number, "-") {
+		words = append(words, "minus")
+	}
+
+	// Groups of three digits from the highest scale down
+	first := len(digits) % 3
+	if first == 0 {
+		first = 3
+	}
+	for start, end := 0, first; start < len(digits); start, end = end, end+3 {
+		group := 0
+		for _, d := range digits[start:end] {
+			group = group*10 + int(d-'0')
+		}
+		if group == 0 {
+			continue
+		}
+		words = append(words, spellHundreds(group)...)
+		if scale := (len(digits)-end)/3 - 1; scale >= 0 {
+			words = append(words, spelledScales[scale])
+		}
+	}
+	return strings.Join(words, " "), true
+}
+
+// spells a number from 1 to 999: 342 -> three hundred forty-two
+func spellHundreds(n int) []string {
+	var words []string
+	if n >= 100 {
+		words = append(words, spelledOnes[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		words = append(words, spelledOnes[n])
+	case n%10 == 0:
+		words = append(words, spelledTens[n/10])
+	default:
+		words = append(words, spelledTens[n/10]+"-"+spelledOnes[n%10])
+	}
+	return words
+}
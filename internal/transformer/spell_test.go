@@ -0,0 +1,68 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestSpellNumber(t *testing.T) {
+	tests := []struct {
+		number   string
+		expected string
+	}{
+		{"0", "zero"},
+		{"-0", "zero"},
+		{"7", "seven"},
+		{"13", "thirteen"},
+		{"40", "forty"},
+		{"42", "forty-two"},
+		{"100", "one hundred"},
+		{"+342", "three hundred forty-two"},
+		{"1000", "one thousand"},
+		{"-1042", "minus one thousand forty-two"},
+		{"1000001", "one million one"},
+		{"007", "seven"},
+		{"9223372036854775807", "nine quintillion two hundred twenty-three quadrillion three hundred seventy-two trillion thirty-six billion eight hundred fifty-four million seven hundred seventy-five thousand eight hundred seven"},
+		{"1" + strings.Repeat("0", 33), "one decillion"},
+	}
+	for _, test := range tests {
+		if result, ok := spellNumber(test.number); !ok || result != test.expected {
+			t.Errorf("spellNumber(%q) = %q, %v, expected %q", test.number, result, ok, test.expected)
+		}
+	}
+	for _, number := range []string{"", "-", "12a", "--1", "1" + strings.Repeat("0", 36)} {
+		if result, ok := spellNumber(number); ok {
+			t.Errorf("spellNumber(%q) = %q, expected no spelling", number, result)
+		}
+	}
+}
+
+func TestProcessTextSpell(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"I have 42 (spell) cats", "I have forty-two cats"},
+		{"a 8 (spell) o'clock train", "an eight o'clock train"},
+		{"15 (spell) (up)", "FIFTEEN"},
+		{"-1_000_042 (spell) and −3 (spell)", "minus one million forty-two and minus three"},
+		{"٤٢ (spell)", "forty-two"},
+		{"x (spell) 0x10 (spell)", "x 0x10"},
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	opts := config.DefaultOptions()
+	opts.DigitGrouping = config.GROUPING_EN
+	if result := ProcessTextWithOptions("400 (hex) (spell)", opts); result != "one thousand twenty-four" {
+		t.Errorf("Expected a grouped result to be spelled, got %q", result)
+	}
+	_, warnings := ProcessTextWarnings("twelve (spell)", config.DefaultOptions())
+	if len(warnings) != 1 || warnings[0].Reason != `"twelve" is not a whole number of at most 36 digits` {
+		t.Errorf("Expected a warning for a word that is not a number, got %v", warnings)
+	}
+}
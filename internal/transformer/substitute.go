@@ -0,0 +1,80 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+)
+
+// substitute runs the enabled substitutions of stage on text, one line at a time so the
+// line breaks and the cuts between them stay where they are. Raw regions of the input are
+// skipped, the output holds their placeholders instead.
+func substitute(text string, opts config.Options, stage string) string {
+	var rules []config.Substitution
+	for _, rule := range opts.Substitutions {
+		ruleStage := rule.Stage
+		if ruleStage == "" {
+			ruleStage = config.SUBSTITUTE_AFTER
+		}
+		if ruleStage == stage && !rule.Disabled {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) == 0 {
+		return text
+	}
+	if stage == config.SUBSTITUTE_AFTER {
+		var result strings.Builder
+		substituteLines(&result, text, rules)
+		return result.String()
+	}
+	return outsideRaw(text, opts, func(result *strings.Builder, part string) {
+		substituteLines(result, part, rules)
+	})
+}
+
+// outsideRaw rewrites text with write, which writes its own version of every part outside
+// the raw regions to result. The raw regions are copied as they are.
+func outsideRaw(text string, opts config.Options, write func(result *strings.Builder, part string)) string {
+	var result strings.Builder
+	result.Grow(len(text))
+	for text != "" {
+		end, raw := len(text), -1
+		if opts.RawStart != "" {
+			if start := strings.Index(text, opts.RawStart); start >= 0 {
+				end, raw = start, len(text)
+				if close := strings.Index(text[start:], opts.RawEnd); close >= 0 {
+					raw = start + close + len(opts.RawEnd)
+				}
+			}
+		}
+		write(&result, text[:end])
+		if raw < 0 {
+			break
+		}
+		result.WriteString(text[end:raw])
+		text = text[raw:]
+	}
+	return result.String()
+}
+
+// writes text to result with rules run on every line, the line endings are kept out of reach
+func substituteLines(result *strings.Builder, text string, rules []config.Substitution) {
+	for text != "" {
+		line, rest, found := strings.Cut(text, "\n")
+		ending := ""
+		if found {
+			ending = "\n"
+		}
+		if strings.HasSuffix(line, "\r") {
+			line, ending = line[:len(line)-1], "\r"+ending
+		}
+		for _, rule := range rules {
+			if re, err := rule.Regexp(); err == nil {
+				line = re.ReplaceAllString(line, rule.Replacement)
+			}
+		}
+		result.WriteString(line)
+		result.WriteString(ending)
+		text = rest
+	}
+}
@@ -0,0 +1,32 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextSubstitutions(t *testing.T) {
+	golang := config.Substitution{Name: "go", Pattern: `(?i)\bgolang\b`, Replacement: "Go"}
+	colour := config.Substitution{Name: "colour", Pattern: `\bcolou?r\b`, Replacement: "hue", Stage: config.SUBSTITUTE_BEFORE}
+	tests := []struct {
+		name          string
+		input         string
+		substitutions []config.Substitution
+		expected      string
+	}{
+		{"after the commands", "i like golang (up) and GOLANG", []config.Substitution{golang}, "i like Go and Go"},
+		{"before the commands", "the colour (up) here", []config.Substitution{colour}, "the HUE here"},
+		{"in order", "ab", []config.Substitution{{Pattern: "a", Replacement: "b"}, {Pattern: "bb", Replacement: "c"}}, "c"},
+		{"submatches", "John Smith", []config.Substitution{{Pattern: `(\w+) (?P<last>\w+)`, Replacement: "${last}, $1"}}, "Smith, John"},
+		{"disabled", "golang", []config.Substitution{{Pattern: "golang", Replacement: "Go", Disabled: true}}, "golang"},
+		{"line by line", "one\r\ntwo\n", []config.Substitution{{Pattern: `(?s)e.*t|$`, Replacement: "!"}}, "one!\r\ntwo!\n"},
+		{"raw regions", "colour (raw)colour golang(endraw) golang", []config.Substitution{golang, colour}, "hue colour golang Go"},
+	}
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.Substitutions = test.substitutions
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Rules an Edit is attributed to, in pipeline order
+const (
+	RULE_AUTOCORRECT = "autocorrect" // a typo of the autocorrect dictionary was corrected
+	RULE_COMMAND     = "command"     // a command changed the words before or after it and was removed
+	RULE_SENTENCES   = "sentences"   // the first letter of a sentence was capitalized
+	RULE_ARTICLES    = "articles"    // a and an were matched to the next word
+	RULE_ORDINALS    = "ordinals"    // an ordinal suffix was joined to its number
+	RULE_QUOTES      = "quotes"      // quotes were paired, styled or their spacing fixed
+	RULE_PUNCTUATION = "punctuation" // the spacing around punctuation was fixed
+	RULE_WHITESPACE  = "whitespace"  // runs of spaces or the spaces at the end of a line were removed
+	RULE_ESCAPES     = "escapes"     // \( and \) became literal parentheses
+	RULE_OTHER       = "other"       // any other stage: dialogue, normalization, soft hyphens
+)
+
+// Every rule in pipeline order, the order of Edit.Rules
+var ruleOrder = []string{RULE_AUTOCORRECT, RULE_COMMAND, RULE_SENTENCES, RULE_ARTICLES, RULE_ORDINALS,
+	RULE_QUOTES, RULE_PUNCTUATION, RULE_WHITESPACE, RULE_ESCAPES, RULE_OTHER}
+
+// Tokens of either text aligned at once; a long line is aligned window by window
+const TRACE_WINDOW_TOKENS = 256
+
+// Edit is one change from the input to the output of TraceText
+type Edit struct {
+	Offset      int      `json:"offset"`             // byte offset of Original in the input
+	Line        int      `json:"line"`               // 1-based line of Offset
+	Column      int      `json:"column"`             // 1-based column of Offset, in characters
+	Original    string   `json:"original"`           // input text replaced, "" for an insertion
+	Replacement string   `json:"replacement"`        // output text in its place, "" for a deletion
+	Rules       []string `json:"rules"`              // RULE_* responsible, in pipeline order
+	Commands    []string `json:"commands,omitempty"` // the commands responsible as written: "(up, 2)"
+}
+
+// traceEvent is a change recorded by the pipeline: rune indexes of the input, later byte offsets
+type traceEvent struct {
+	start, end int
+	rule       string
+	command    string
+}
+
+// TraceText runs the same pipeline as ProcessTextWarnings and also returns every edit from
+// text to the output with the rules responsible. The edits come from a diff of the two texts,
+// so they show exactly what changed; command, article and sentence edits are attributed by the
+// pipeline and the others by what they change.
+func TraceText(text string, opts config.Options) Report {
+	if text == "" {
+		return Report{}
+	}
+	processor := NewTokenProcessor()
+	processor.tracing = true
+	tokenizeInto(processor, text, opts)
+	report := Report{Warnings: processor.warnings, Applied: processor.applied}
+	report.Output = render(processor, opts)
+	report.Edits = traceEdits(text, report.Output, processor.events)
+	return report
+}
+
+// record notes that rule changed the input runes [start, end)
+func (tp *TokenProcessor) record(start, end int, rule, command string) {
+	if tp.tracing {
+		tp.events = append(tp.events, traceEvent{start: start, end: end, rule: rule, command: command})
+	}
+}
+
+// recordToken notes that rule changed the token at idx, written as original in the input
+func (tp *TokenProcessor) recordToken(idx int, original, rule, command string) {
+	if tp.tracing && tp.tokens[idx].Value != original {
+		start := tp.starts[idx]
+		tp.record(start, start+utf8.RuneCountInString(original), rule, command)
+	}
+}
+
+// traceEdits diffs text and output line by line when both have as many lines, and
+// attributes every edit to the events overlapping it
+func traceEdits(text, output string, events []traceEvent) []Edit {
+	events = eventOffsets(text, events)
+	oldLines, newLines := strings.SplitAfter(text, "\n"), strings.SplitAfter(output, "\n")
+	if len(oldLines) != len(newLines) {
+		// A stage moved a line break: the texts are diffed as one line
+		oldLines, newLines = []string{text}, []string{output}
+	}
+
+	var edits []Edit
+	offset := 0
+	for i, oldLine := range oldLines {
+		if newLine := newLines[i]; oldLine != newLine {
+			for _, hunk := range diffLine(oldLine, newLine) {
+				edit := newEdit(text, offset, i+1, oldLine, hunk, events)
+				// Edits come in text order: events ending before this one cannot overlap the next
+				for len(events) > 0 && events[0].end <= edit.Offset {
+					events = events[1:]
+				}
+				edits = append(edits, edit)
+			}
+		}
+		offset += len(oldLine)
+	}
+	return edits
+}
+
+// eventOffsets turns the rune indexes of the events into byte offsets of text, sorted by start
+func eventOffsets(text string, events []traceEvent) []traceEvent {
+	positions := make([]int, 0, 2*len(events))
+	for _, event := range events {
+		positions = append(positions, event.start, event.end)
+	}
+	slices.Sort(positions)
+	positions = slices.Compact(positions)
+
+	offsets := make(map[int]int, len(positions))
+	runeIdx, offset := 0, 0
+	for _, position := range positions {
+		for runeIdx < position && offset < len(text) {
+			_, size := utf8.DecodeRuneInString(text[offset:])
+			offset += size
+			runeIdx++
+		}
+		offsets[position] = offset
+	}
+	converted := make([]traceEvent, len(events))
+	for i, event := range events {
+		event.start, event.end = offsets[event.start], offsets[event.end]
+		converted[i] = event
+	}
+	slices.SortStableFunc(converted, func(a, b traceEvent) int { return a.start - b.start })
+	return converted
+}
+
+// lineHunk is a change within a line: bytes [start, end) of the old line become text
+type lineHunk struct {
+	start, end   int
+	line, column int // of start, from 0
+	text         string
+	text0        int // byte offset of text in the new line
+}
+
+// diffLine returns the changes from oldLine to newLine at the granularity of words,
+// whitespace runs and single other characters
+func diffLine(oldLine, newLine string) []lineHunk {
+	a, b := diffTokens(oldLine), diffTokens(newLine)
+	// An unchanged start and end are common and keep the diff small
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	head := strings.Join(a[:prefix], "")
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	var hunks []lineHunk
+	var current *lineHunk
+	oldPos, newPos := len(head), len(head)
+	line := strings.Count(head, "\n")
+	column := utf8.RuneCountInString(head[strings.LastIndexByte(head, '\n')+1:])
+	ai, bi := 0, 0
+	for _, op := range diffOps(a, b) {
+		if op == diffEqual && current != nil {
+			current.text = newLine[current.text0:newPos]
+			hunks = append(hunks, *current)
+			current = nil
+		}
+		if op != diffEqual && current == nil {
+			current = &lineHunk{start: oldPos, end: oldPos, line: line, column: column, text0: newPos}
+		}
+		if op != diffInsert {
+			oldPos += len(a[ai])
+			if a[ai] == "\n" {
+				line, column = line+1, 0
+			} else {
+				column += utf8.RuneCountInString(a[ai])
+			}
+			ai++
+		}
+		if op != diffDelete {
+			newPos += len(b[bi])
+			bi++
+		}
+		if current != nil {
+			current.end = oldPos
+		}
+	}
+	if current != nil {
+		current.text = newLine[current.text0:newPos]
+		hunks = append(hunks, *current)
+	}
+	return hunks
+}
+
+// diffTokens splits a line into runs of word characters, runs of spaces and tabs, and
+// single other characters, the line break included
+func diffTokens(line string) []string {
+	var tokens []string
+	for len(line) > 0 {
+		r, size := utf8.DecodeRuneInString(line)
+		end := size
+		if class := diffClass(r); class != 0 {
+			for end < len(line) {
+				next, nextSize := utf8.DecodeRuneInString(line[end:])
+				if diffClass(next) != class {
+					break
+				}
+				end += nextSize
+			}
+		}
+		tokens = append(tokens, line[:end])
+		line = line[end:]
+	}
+	return tokens
+}
+
+// diffClass groups the runes a diff token may hold several of: 1 word, 2 blank, 0 neither
+func diffClass(r rune) int {
+	switch {
+	case isWordRune(r) || unicode.IsMark(r) || r == '_':
+		return 1
+	case r == ' ' || r == '\t':
+		return 2
+	}
+	return 0
+}
+
+// Operations of a token diff
+const (
+	diffEqual   = iota
+	diffDelete  // a token of the old line only
+	diffInsert  // a token of the new line only
+	diffReplace // a token whose case changed, a change that keeps the token in place
+)
+
+// diffOps returns the operations turning a into b. Edits are local, so a long line is aligned
+// TRACE_WINDOW_TOKENS at a time, keeping the operations of the first half of every window.
+func diffOps(a, b []string) []int {
+	var ops []int
+	for len(a) > TRACE_WINDOW_TOKENS || len(b) > TRACE_WINDOW_TOKENS {
+		window := diffAlign(a[:min(len(a), TRACE_WINDOW_TOKENS)], b[:min(len(b), TRACE_WINDOW_TOKENS)])
+		// Keep up to the last token left in place in the first half, or the first half as is
+		kept, ai, bi := 0, 0, 0
+		for i, op := range window {
+			if ai >= TRACE_WINDOW_TOKENS/2 || bi >= TRACE_WINDOW_TOKENS/2 {
+				if kept == 0 {
+					kept = i
+				}
+				break
+			}
+			if op != diffInsert {
+				ai++
+			}
+			if op != diffDelete {
+				bi++
+			}
+			if op == diffEqual {
+				kept = i + 1
+			}
+		}
+		for _, op := range window[:kept] {
+			if op != diffInsert {
+				a = a[1:]
+			}
+			if op != diffDelete {
+				b = b[1:]
+			}
+		}
+		ops = append(ops, window[:kept]...)
+	}
+	return append(ops, diffAlign(a, b)...)
+}
+
+// diffAlign returns the operations turning a into b that keep the most text in place, words
+// counting double so a changed word is not aligned by the spaces around it
+func diffAlign(a, b []string) []int {
+	n, m := len(a), len(b)
+	weights := make([]int32, n)
+	for i, token := range a {
+		weights[i] = int32(len(token))
+		if r, _ := utf8.DecodeRuneInString(token); diffClass(r) == 1 {
+			weights[i] *= 2
+		}
+	}
+	// Tokens as numbers, and their lowercase forms, keep the table loop cheap
+	ids, folded := map[string]int32{}, map[string]int32{}
+	intern := func(tokens []string) ([]int32, []int32) {
+		exact, lower := make([]int32, len(tokens)), make([]int32, len(tokens))
+		for i, token := range tokens {
+			exact[i] = internID(ids, token)
+			lower[i] = internID(folded, strings.ToLower(token))
+		}
+		return exact, lower
+	}
+	aIDs, aFolded := intern(a)
+	bIDs, bFolded := intern(b)
+
+	// score[i*(m+1)+j] is the best score for a[i:] and b[j:]
+	width := m + 1
+	score := make([]int32, (n+1)*width)
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			best := max(score[(i+1)*width+j], score[i*width+j+1])
+			if aIDs[i] == bIDs[j] {
+				best = max(best, score[(i+1)*width+j+1]+weights[i])
+			} else if aFolded[i] == bFolded[j] {
+				best = max(best, score[(i+1)*width+j+1]+weights[i]/2)
+			}
+			score[i*width+j] = best
+		}
+	}
+
+	ops := make([]int, 0, max(n, m))
+	i, j := 0, 0
+	for i < n || j < m {
+		here := i*width + j
+		switch {
+		case i < n && j < m && aIDs[i] == bIDs[j] && score[here] == score[here+width+1]+weights[i]:
+			ops = append(ops, diffEqual)
+			i, j = i+1, j+1
+		case i < n && j < m && aFolded[i] == bFolded[j] && score[here] == score[here+width+1]+weights[i]/2:
+			ops = append(ops, diffReplace)
+			i, j = i+1, j+1
+		case i < n && score[here] == score[here+width]:
+			ops = append(ops, diffDelete)
+			i++
+		default:
+			ops = append(ops, diffInsert)
+			j++
+		}
+	}
+	return ops
+}
+
+// internID returns the number of token in ids, adding it when new
+func internID(ids map[string]int32, token string) int32 {
+	id, ok := ids[token]
+	if !ok {
+		id = int32(len(ids))
+		ids[token] = id
+	}
+	return id
+}
+
+// newEdit places a hunk of the line starting at byte offset lineStart of text and
+// attributes it
+func newEdit(text string, lineStart, line int, oldLine string, hunk lineHunk, events []traceEvent) Edit {
+	e := Edit{
+		Offset:      lineStart + hunk.start,
+		Line:        line + hunk.line,
+		Column:      hunk.column + 1,
+		Original:    oldLine[hunk.start:hunk.end],
+		Replacement: hunk.text,
+	}
+	start, end := e.Offset, e.Offset+len(e.Original)
+
+	found := map[string]bool{}
+	covered := [2]int{end, start} // the span of the events, empty when there are none
+	for _, event := range events {
+		if event.start >= max(end, start+1) {
+			break
+		}
+		// An insertion belongs to an event around it, a replacement to any event it overlaps
+		overlaps := event.start < end && event.end > start
+		if start == end {
+			overlaps = event.start < start && event.end > start
+		}
+		if !overlaps {
+			continue
+		}
+		found[event.rule] = true
+		covered = [2]int{min(covered[0], event.start), max(covered[1], event.end)}
+		if event.command != "" && !slices.Contains(e.Commands, event.command) {
+			e.Commands = append(e.Commands, event.command)
+		}
+	}
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if len(found) == 0 {
+		e.Rules = []string{classifyEdit(e.Original, e.Replacement, before, after)}
+		return e
+	}
+	// A hunk merges the text the events changed with the spaces next to it, removed with a
+	// command or by the punctuation or quote beyond them: "apple (up) ," -> "APPLE,"
+	spacedBy := func(blank string, r rune) bool {
+		return blank != "" && strings.Trim(blank, " \t") == "" && (isPunctuation(r) || strings.ContainsRune(QUOTE_RUNES, r))
+	}
+	if head := text[start:max(start, covered[0])]; spacedBy(head, before) && !strings.HasPrefix(e.Replacement, head) {
+		found[classifyEdit(head, "", before, 0)] = true
+	}
+	if tail := text[min(end, covered[1]):end]; spacedBy(tail, after) && !strings.HasSuffix(e.Replacement, tail) {
+		found[classifyEdit(tail, "", 0, after)] = true
+	}
+	for _, rule := range ruleOrder {
+		if found[rule] {
+			e.Rules = append(e.Rules, rule)
+		}
+	}
+	return e
+}
+
+// classifyEdit names the rule of an edit no pipeline event explains, from the text it
+// changes and the characters around it
+func classifyEdit(original, replacement string, before, after rune) string {
+	isQuote := func(r rune) bool { return strings.ContainsRune(QUOTE_RUNES, r) }
+	blank := func(s string) bool { return strings.Trim(s, " \t") == "" }
+	switch {
+	case strings.ContainsFunc(original+replacement, isQuote) || (blank(original) && blank(replacement) && (isQuote(before) || isQuote(after))):
+		return RULE_QUOTES
+	case strings.HasSuffix(original, `\`) && (after == '(' || after == ')'), strings.Contains(original, `\(`), strings.Contains(original, `\)`):
+		return RULE_ESCAPES
+	case blank(original) && blank(replacement) && unicode.IsDigit(before) && ordinalStart(after):
+		return RULE_ORDINALS
+	case strings.ContainsFunc(original, isSuperscriptLetter),
+		strings.ContainsFunc(original, unicode.IsDigit) && strings.ReplaceAll(original, " ", "") == replacement:
+		return RULE_ORDINALS
+	case blank(original) && blank(replacement) && (isPunctuation(before) || isPunctuation(after) || isPunctuationText(original+replacement)):
+		return RULE_PUNCTUATION
+	case blank(original) && blank(replacement):
+		return RULE_WHITESPACE
+	case blank(strings.TrimFunc(original, isPunctuation)) && blank(strings.TrimFunc(replacement, isPunctuation)):
+		return RULE_PUNCTUATION
+	}
+	return RULE_OTHER
+}
+
+// Quote characters the quote stage pairs, styles or respaces
+const QUOTE_RUNES = "'\"‘’“”«»‹›„‚"
+
+// isPunctuation reports whether r is punctuation whose spacing the pipeline fixes
+func isPunctuation(r rune) bool {
+	return unicode.IsPunct(r) && !strings.ContainsRune(QUOTE_RUNES, r)
+}
+
+// isPunctuationText reports whether s holds punctuation only
+func isPunctuationText(s string) bool {
+	return s != "" && strings.TrimFunc(s, isPunctuation) == ""
+}
+
+// isSuperscriptLetter reports whether r is a superscript letter of an ordinal suffix
+func isSuperscriptLetter(r rune) bool {
+	_, ok := superscriptLetters[r]
+	return ok
+}
+
+// ordinalStart reports whether r can start an ordinal suffix: st, nd, rd, th
+func ordinalStart(r rune) bool {
+	return strings.ContainsRune("sntrSNTR", r) || isSuperscriptLetter(r)
+}
@@ -0,0 +1,95 @@
+package transformer
+
+import (
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+// describe writes an edit as line:column "original" -> "replacement" rules commands
+func describe(e Edit) string {
+	s := fmt.Sprintf("%d:%d %q -> %q %s", e.Line, e.Column, e.Original, e.Replacement, strings.Join(e.Rules, ","))
+	if len(e.Commands) > 0 {
+		s += " " + strings.Join(e.Commands, ",")
+	}
+	return s
+}
+
+// applyEdits replays edits on text, to check they turn the input into the output
+func applyEdits(text string, edits []Edit) string {
+	var b strings.Builder
+	at := 0
+	for _, e := range edits {
+		b.WriteString(text[at:e.Offset])
+		b.WriteString(e.Replacement)
+		at = e.Offset + len(e.Original)
+	}
+	b.WriteString(text[at:])
+	return b.String()
+}
+
+func TestTraceText(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.CapitalizeSentences, opts.NormalizeOrdinals = true, true
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"unchanged", "Nothing to do here.\n", nil},
+		{"command", "It was apple (up) .", []string{`1:8 "apple (up) " -> "APPLE" command,punctuation (up)`}},
+		{"command before punctuation", "apple (up) , b", []string{`1:1 "apple (up) " -> "APPLE" command,punctuation (up)`}},
+		{"command before a quote", "He said ' hi (up) '", []string{`1:10 " hi (up) " -> "HI" command,quotes (up)`}},
+		{"article", "It is a egg.", []string{`1:7 "a" -> "an" articles`}},
+		{"conversion", "1E (hex) files", []string{`1:1 "1E (hex)" -> "30" command (hex)`}},
+		{"counted", "So it goes (up, 2) on", []string{`1:4 "it" -> "IT" command (up, 2)`, `1:7 "goes (up, 2)" -> "GOES" command (up, 2)`}},
+		{"forward", "Go (up>, 2) now and then", []string{`1:4 "(up>, 2) now" -> "NOW" command (up>, 2)`, `1:17 "and" -> "AND" command (up>, 2)`}},
+		{"sentence", "Done. next one", []string{`1:7 "next" -> "Next" sentences`}},
+		{"punctuation", "Well ,ok !", []string{`1:5 " " -> "" punctuation`, `1:7 "" -> " " punctuation`, `1:9 " " -> "" punctuation`}},
+		{"quotes", "He said ' hi '", []string{`1:10 " " -> "" quotes`, `1:13 " " -> "" quotes`}},
+		{"whitespace", "One  two", []string{`1:4 "  " -> " " whitespace`}},
+		{"ordinal", "The 1 st one", []string{`1:5 "1 st" -> "1st" ordinals`}},
+		{"escapes", `A \(b\) c`, []string{`1:3 "\\" -> "" escapes`, `1:6 "\\" -> "" escapes`}},
+		{"later line", "First.\n\nsecond (up)", []string{`3:1 "second (up)" -> "SECOND" command (up)`}},
+		{"columns in characters", "Ünïcödé wörds (low)", []string{`1:14 " (low)" -> "" command (low)`}},
+	}
+
+	for _, test := range tests {
+		report := TraceText(test.input, opts)
+		var got []string
+		for _, e := range report.Edits {
+			got = append(got, describe(e))
+		}
+		if strings.Join(got, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("%s: edits of %q are\n%s\nexpected\n%s", test.name, test.input, strings.Join(got, "\n"), strings.Join(test.expected, "\n"))
+		}
+		if output, _ := ProcessTextWarnings(test.input, opts); report.Output != output {
+			t.Errorf("%s: TraceText output %q differs from ProcessTextWarnings %q", test.name, report.Output, output)
+		}
+	}
+}
+
+func TestTraceTextReplays(t *testing.T) {
+	inputs := []string{
+		"it (up) was a apple ,really ' quoted ' 1E (hex) \\(raw\\)\n\n\tIndented   line (cap, 2) .\n",
+		"\"He said ... \" she said (up, all) ?!\n",
+		"one\n\n\n\ntwo (up)\n   \n",
+		strings.Repeat("word (up) a apple ,next (cap) one ", 400),
+	}
+	for _, input := range inputs {
+		for _, preserve := range []bool{false, true} {
+			opts := config.DefaultOptions()
+			opts.PreserveWhitespace = preserve
+			report := TraceText(input, opts)
+			if replayed := applyEdits(input, report.Edits); replayed != report.Output {
+				t.Errorf("Edits of %.40q replay to %.80q, expected %.80q", input, replayed, report.Output)
+			}
+			for _, e := range report.Edits {
+				if input[e.Offset:e.Offset+len(e.Original)] != e.Original || len(e.Rules) == 0 {
+					t.Errorf("Edit %s does not match the input at offset %d", describe(e), e.Offset)
+				}
+			}
+		}
+	}
+}
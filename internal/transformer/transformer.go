@@ -1,9 +1,18 @@
 package transformer
 
 import (
-	"go-reloaded/internal/config"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"math"
+	"math/big"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
 )
 
 // Token types
@@ -13,11 +22,59 @@ const (
 	PUNCTUATION
 	SPACE
 	NEWLINE
+	RAW // text of a raw region, kept exactly as written
 )
 
 type Token struct {
 	Type  int
 	Value string
+	Flags int // TOKEN_* metadata left by the commands applied to the token
+}
+
+// Token flags
+const (
+	TOKEN_UPPERCASED = 1 << iota // (up) was the last case command applied: the article "A" becomes "AN", not "An"
+)
+
+// Suffix turning a command into a forward command: (up>) or (cap>, 2)
+const FORWARD_MARKER = ">"
+
+// Parenthesized text that is no command stays one word up to this many runes from the
+// opening parenthesis to the closing one, longer text is tokenized like any other
+const WORD_PARENS_RUNES = 10
+
+// Room for the count of a multi-word command and the spaces written around the parts of a
+// command: "( up> , 1000 )"
+const (
+	COUNT_RUNES    = 6
+	COMMAND_SPACES = 6
+)
+
+// Count keyword applying a multi-word command to every preceding word: (low, all)
+const COUNT_ALL = "all"
+
+// Escape character for literal parentheses: \(up\) is emitted as (up)
+const ESCAPE = '\\'
+
+// U+00AD, only rendered when a line breaks inside the word
+const SOFT_HYPHEN = '\u00AD'
+
+// Private use rune standing for a raw region while the post-passes run over the text
+const RAW_PLACEHOLDER = '\uE000'
+
+// names of the token types, used when tracing
+var tokenTypeNames = map[int]string{
+	WORD:        "WORD",
+	COMMAND:     "COMMAND",
+	PUNCTUATION: "PUNCTUATION",
+	SPACE:       "SPACE",
+	NEWLINE:     "NEWLINE",
+	RAW:         "RAW",
+}
+
+// String formats a token for traces: WORD("hello")
+func (t Token) String() string {
+	return fmt.Sprintf("%s(%q)", tokenTypeNames[t.Type], t.Value)
 }
 
 // Low-level FSM states
@@ -30,39 +87,252 @@ const (
 type TokenProcessor struct {
 	tokens   []Token
 	tokenIdx int
-	output   strings.Builder
+	output   []byte // assembled text, appended to and truncated in place by flushTokens
+	opts     config.Options
+	pending  []pendingCommand // forward commands waiting for upcoming words
+	settling []pendingCommand // forward commands whose first word just arrived, see resolvePending
+
+	punctuation map[rune]int   // punctuation runes and their config.ATTACH_* spacing rule
+	skipSpace   bool           // drop the next SPACE token, it separated a removed command
+	lineEndings []string       // original ending of every line written by flushTokens
+	raw         []string       // text of every RAW token written by flushTokens, in order
+	rawOpen     bool           // the last raw region has no end marker
+	quotes      []openQuote    // quotes of the paragraph not closed yet, see noteQuote
+	reaches     []Reach        // backward commands whose count goes past the start of the text
+	before      []Reach        // what those commands did in the text before, see ProcessPiece
+	after       []Reach        // backward commands of later text reaching into this one, see ProcessPiece
+	lineBreaks  []int          // token index of every NEWLINE token
+	open        []bool         // per line break, and last for the end: a forward command, quote or quotation continues past it
+	tied        []bool         // per line break: a backward command reaches over it
+	warnings    []Warning      // commands dropped or left as text, see warn
+	applied     map[string]int // words changed per command name, see countApplied
+	counts      []CommandCount // the same per command as written, located by locateCounts
+	listing     bool           // note every command found, see Inventory
+	found       []Occurrence   // commands noted while listing, located by locateOccurrences
+
+	// Tracing, see TraceText: the source rune index of every token and the changes made
+	tracing bool
+	starts  []int        // rune index of the first rune of the token at the same index
+	at, cur int          // start of the next token, index of the rune the FSM is at
+	events  []traceEvent // tokens and commands changed by command, article and sentence rules
+}
+
+// A forward command such as (up>, 3) that still has words left to transform
+type pendingCommand struct {
+	cmd       string
+	remaining int
+	count     int    // words it was written for
+	at        int    // rune index of the command, for warnings
+	text      string // the command as written
+	pos       int    // token index it was written at until its first word decides its fate, then -1
+	applied   bool   // the first word was changed
 }
 
 // ProcessText - Single pass dual FSM implementation
-// main entry point
+// main entry point, uses the default options
 func ProcessText(text string) string {
+	return ProcessTextWithOptions(text, config.DefaultOptions())
+}
+
+// ProcessTextWithOptions runs the same pipeline as ProcessText with custom options
+func ProcessTextWithOptions(text string, opts config.Options) string {
 	if text == "" {
 		return ""
 	}
 
-	runes := []rune(text)
+	return render(tokenize(text, opts), opts)
+}
+
+// ProcessTextWarnings runs the same pipeline as ProcessTextWithOptions and also returns
+// a warning for every command that was dropped or left in the text, in text order
+func ProcessTextWarnings(text string, opts config.Options) (string, []Warning) {
+	if text == "" {
+		return "", nil
+	}
+	processor := tokenize(text, opts)
+	return render(processor, opts), processor.warnings
+}
+
+// render assembles the processed tokens and runs the string post-passes
+func render(processor *TokenProcessor, opts config.Options) string {
+	if !opts.SkipArticles {
+		processor.fixArticles(opts.ArticleRules())
+	}
+
+	// Flush all tokens to output
+	processor.flushTokens()
+
+	// Post-process ordinals and quotes
+	result := string(processor.output)
+	if opts.NormalizeOrdinals {
+		result = fixOrdinals(result, opts.PlainOrdinals)
+	}
+	if opts.ASCIIQuotes {
+		result = straightenQuotes(result)
+	}
+	if !opts.SkipQuotes {
+		result = fixQuotes(result, opts.SmartQuotes, processor.open)
+	}
+	if opts.Dialogue != "" {
+		result = formatDialogue(result, opts.Dialogue, processor.open)
+	}
+	result = substitute(result, opts, config.SUBSTITUTE_AFTER)
+	result = restoreRaw(result, processor.raw)
+	// Case mapping can leave characters in another form, so the output is normalized again
+	result = normalize(result, opts.Normalize)
+	return restoreLineEndings(result, processor.lineEndings, opts.LineEnding)
+}
+
+// applies one of the config.NORMALIZE_* forms
+func normalize(text string, form string) string {
+	switch form {
+	case config.NORMALIZE_NFC:
+		return norm.NFC.String(text)
+	case config.NORMALIZE_NFD:
+		return norm.NFD.String(text)
+	}
+	return text
+}
+
+// puts the text of the raw regions back in place of their placeholders
+func restoreRaw(text string, raw []string) string {
+	if len(raw) == 0 {
+		return text
+	}
+	var result strings.Builder
+	result.Grow(len(text))
+	for _, region := range raw {
+		idx := strings.IndexRune(text, RAW_PLACEHOLDER)
+		if idx < 0 {
+			break
+		}
+		result.WriteString(text[:idx])
+		result.WriteString(region)
+		text = text[idx+utf8.RuneLen(RAW_PLACEHOLDER):]
+	}
+	result.WriteString(text)
+	return result.String()
+}
+
+// The post-passes work on "\n" only; this puts back the requested line endings:
+// every line as it was written (auto) or one ending for the whole text (lf, crlf)
+func restoreLineEndings(text string, original []string, mode string) string {
+	switch mode {
+	case config.LINE_ENDING_LF:
+		return text
+	case config.LINE_ENDING_CRLF:
+		return strings.ReplaceAll(text, "\n", "\r\n")
+	}
+
+	if !slices.Contains(original, "\r\n") {
+		return text
+	}
+	var result strings.Builder
+	result.Grow(len(text) + len(original))
+	line := 0
+	for {
+		idx := strings.IndexByte(text, '\n')
+		if idx < 0 || line >= len(original) {
+			result.WriteString(text)
+			return result.String()
+		}
+		result.WriteString(text[:idx])
+		result.WriteString(original[line])
+		text = text[idx+1:]
+		line++
+	}
+}
+
+// TokenizeWithOptions returns the token stream after every command has been
+// applied, just before output assembly and post-processing (used for tracing)
+func TokenizeWithOptions(text string, opts config.Options) []Token {
+	processor := tokenize(text, opts)
+	return append([]Token(nil), processor.tokens[:processor.tokenIdx]...)
+}
+
+// tokenize runs the low-level FSM over the text and lets the TokenProcessor apply commands
+func tokenize(text string, opts config.Options) *TokenProcessor {
 	processor := NewTokenProcessor()
+	tokenizeInto(processor, text, opts)
+	return processor
+}
+
+// tokenizeInto runs the low-level FSM with an empty, possibly reused, TokenProcessor
+func tokenizeInto(processor *TokenProcessor, text string, opts config.Options) {
+	if opts.Normalize == config.NORMALIZE_NFC || opts.Normalize == config.NORMALIZE_NFD {
+		// Commands see composed characters in both modes, render emits the requested form
+		text = norm.NFC.String(text)
+	}
+	text = substitute(text, opts, config.SUBSTITUTE_BEFORE)
+	text, undefined := expandVariables(text, opts)
+	runes := []rune(text)
+	processor.opts = opts
+	processor.punctuation = opts.PunctuationRules()
+	for _, variable := range undefined {
+		processor.warn(variable.at, variable.text, "undefined variable")
+	}
+	rawStart, rawEnd := []rune(opts.RawStart), []rune(opts.RawEnd)
+	lookahead := commandRunes(opts.CommandAliases)
 
 	state := STATE_TEXT
 	var wordBuilder strings.Builder // Accumulates characters for current word
 	var cmdBuilder strings.Builder  // Accumulates characters for current command
+	cmdStart := 0                   // rune index of the opening parenthesis of the current command
 
 	for i := 0; i < len(runes); i++ {
 		r := runes[i]
+		if processor.tracing {
+			processor.cur = i
+			if wordBuilder.Len() == 0 {
+				processor.at = i
+			}
+		}
 
 		switch state {
 		case STATE_TEXT:
+			if r == RAW_PLACEHOLDER || (len(rawStart) > 0 && hasRunesAt(runes, i, rawStart)) {
+				if wordBuilder.Len() > 0 {
+					processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
+					wordBuilder.Reset()
+				}
+				if r == RAW_PLACEHOLDER {
+					// A placeholder in the input is raw text itself, so every placeholder stands for one region
+					processor.addToken(Token{Type: RAW, Value: string(r)})
+				} else {
+					i = processor.addRaw(runes, i+len(rawStart), rawEnd) - 1
+				}
+				continue
+			}
+			if opts.Markdown {
+				// The ! of an image belongs to its link text: ![alt](src)
+				if r == '!' && i+1 < len(runes) && runes[i+1] == '[' {
+					wordBuilder.WriteRune(r)
+					continue
+				}
+				// Code and link destinations pass through like raw regions
+				if end, open, ok := markdownRawAt(runes, i); ok {
+					if wordBuilder.Len() > 0 {
+						processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
+						wordBuilder.Reset()
+					}
+					processor.addRawText(runes[i:end])
+					processor.rawOpen = open
+					i = end - 1
+					continue
+				}
+			}
 			switch r {
 			case '(':
-				// Look ahead to see if this is a valid command (max 10 chars)
+				// Look ahead to see if this is a valid command
 				if i+1 < len(runes) {
-					// Find the closing parenthesis within 10 characters
+					// Find the closing parenthesis within lookahead characters
 					closeParen := -1
-					maxLookAhead := i + 11 // i+1 + 10 chars max, assuming that no command has more than 10 runes
+					maxLookAhead := i + 1 + lookahead
 					if maxLookAhead > len(runes) {
 						maxLookAhead = len(runes)
 					}
-					for j := i + 1; j < maxLookAhead; j++ {
+					for j := i + 1; j < maxLookAhead && runes[j] != '\n'; j++ {
+						// Commands never span lines, so no token holds a line break
 						if runes[j] == ')' {
 							closeParen = j
 							break
@@ -73,18 +343,35 @@ func ProcessText(text string) string {
 						// Extract potential command
 						potentialCmd := string(runes[i+1 : closeParen])
 						if processor.isValidCommand(potentialCmd) {
+							processor.noteCommand(i, potentialCmd, "")
 							// Valid command - flush current word and switch to command state
 							if wordBuilder.Len() > 0 {
-								processor.addToken(Token{WORD, wordBuilder.String()})
+								processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
 								wordBuilder.Reset()
 							}
 							state = STATE_COMMAND
+							cmdStart = i
 							break
-						} else {
-							// Invalid command - treat entire thing as word
-							wordBuilder.WriteString(string(runes[i : closeParen+1]))
+						} else if problem := commandProblem(potentialCmd, opts.CommandAliases); problem != "" {
+							// Invalid command - treat entire thing as word, noting it as it names a command
+							written := string(runes[i : closeParen+1])
+							processor.warn(i, written, problem)
+							processor.noteCommand(i, potentialCmd, problem)
+							if opts.UnappliedCommandPolicy == config.UNAPPLIED_DROP {
+								processor.dropMalformed(i, written, wordBuilder.Len() > 0)
+								i = closeParen
+								break
+							}
+							processor.noteQuotes(runes, i+1, closeParen)
+							wordBuilder.WriteString(written)
 							i = closeParen // Skip to after closing paren
 							break
+						} else if closeParen-i <= WORD_PARENS_RUNES {
+							// Short parenthesized text stays one word: "(a b)"
+							processor.noteQuotes(runes, i+1, closeParen)
+							wordBuilder.WriteString(string(runes[i : closeParen+1]))
+							i = closeParen
+							break
 						}
 					}
 				}
@@ -94,32 +381,89 @@ func ProcessText(text string) string {
 			case ' ', '\t':
 				// Flush word and add space
 				if wordBuilder.Len() > 0 {
-					processor.addToken(Token{WORD, wordBuilder.String()})
+					processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
 					wordBuilder.Reset()
 				}
-				processor.addToken(Token{SPACE, " "})
+				if !opts.PreserveWhitespace {
+					processor.addToken(Token{Type: SPACE, Value: " "})
+					break
+				}
+				// Keep the whole run of spaces and tabs in one token
+				spaceEnd := i + 1
+				for spaceEnd < len(runes) && (runes[spaceEnd] == ' ' || runes[spaceEnd] == '\t') {
+					spaceEnd++
+				}
+				processor.addToken(Token{Type: SPACE, Value: string(runes[i:spaceEnd])})
+				i = spaceEnd - 1
 			case '\n':
 				// Flush word and add newline
 				if wordBuilder.Len() > 0 {
-					processor.addToken(Token{WORD, wordBuilder.String()})
+					processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
 					wordBuilder.Reset()
 				}
-				processor.addToken(Token{NEWLINE, "\n"})
-			case ',', '.', '!', '?', ';', ':':
-				// Flush word and add punctuation
+				processor.addToken(Token{Type: NEWLINE, Value: "\n"})
+			case '\r':
+				if i+1 >= len(runes) || runes[i+1] != '\n' {
+					// Lone carriage return - part of the word as before
+					wordBuilder.WriteRune(r)
+					break
+				}
+				// Windows line ending, remembered so the output can reproduce it
 				if wordBuilder.Len() > 0 {
-					processor.addToken(Token{WORD, wordBuilder.String()})
+					processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
 					wordBuilder.Reset()
 				}
-				processor.addToken(Token{PUNCTUATION, string(r)})
+				processor.addToken(Token{Type: NEWLINE, Value: "\r\n"})
+				i++
+			case ESCAPE:
+				// \( and \) produce literal parentheses that never start a command
+				if i+1 < len(runes) && (runes[i+1] == '(' || runes[i+1] == ')') {
+					wordBuilder.WriteRune(runes[i+1])
+					i++
+				} else {
+					wordBuilder.WriteRune(r)
+				}
+			case SOFT_HYPHEN:
+				// Invisible discretionary hyphen from typeset sources
+				if !opts.StripSoftHyphens {
+					wordBuilder.WriteRune(r)
+				}
 			default:
-				wordBuilder.WriteRune(r)
+				attach, isPunct := processor.punctuation[r]
+				if !isPunct {
+					processor.noteQuote(runes, i)
+					wordBuilder.WriteRune(r)
+					break
+				}
+				if processor.digitGroupAt(runes, i) {
+					wordBuilder.WriteRune(r)
+					break
+				}
+				if end := wordDashEnd(runes, i); end > i {
+					wordBuilder.WriteString(string(runes[i:end]))
+					i = end - 1
+					break
+				}
+
+				// Flush word and add punctuation
+				if wordBuilder.Len() > 0 {
+					processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
+					wordBuilder.Reset()
+				}
+				// Groups like "..." or "!?" with the same spacing rule form a single unit
+				groupEnd := i + 1
+				for groupEnd < len(runes) && processor.attachOf(runes[groupEnd]) == attach &&
+					(runes[groupEnd] == runes[groupEnd-1] || wordDashEnd(runes, groupEnd) == groupEnd) {
+					groupEnd++
+				}
+				processor.addToken(Token{Type: PUNCTUATION, Value: string(runes[i:groupEnd])})
+				i = groupEnd - 1
 			}
 
 		case STATE_COMMAND:
 			if r == ')' {
 				// Process valid command
-				processor.processCommand(cmdBuilder.String())
+				processor.processCommand(cmdBuilder.String(), cmdStart)
 				cmdBuilder.Reset()
 				state = STATE_TEXT
 			} else {
@@ -129,300 +473,804 @@ func ProcessText(text string) string {
 	}
 
 	// Flush remaining word
+	processor.cur = len(runes)
 	if wordBuilder.Len() > 0 {
-		processor.addToken(Token{WORD, wordBuilder.String()})
+		processor.addToken(Token{Type: WORD, Value: wordBuilder.String()})
+	}
+	// Backward commands of later text change the last words like the commands of this one
+	for i := range processor.after {
+		processor.reachInto(&processor.after[i])
 	}
 
-	// Flush all tokens to output
-	processor.flushTokens()
+	// Optional sentence stage runs on the final tokens, before articles are fixed
+	if opts.CapitalizeSentences {
+		processor.capitalizeSentences()
+	}
+	processor.open = append(processor.open, len(processor.pending) > 0 || processor.rawOpen)
+	processor.endPending()
+	processor.endQuotes()
+	processor.locateWarnings(text)
+	processor.locateCounts(text)
+}
+
+// reports whether runes[i:] starts with prefix
+func hasRunesAt(runes []rune, i int, prefix []rune) bool {
+	return len(runes)-i >= len(prefix) && slices.Equal(runes[i:i+len(prefix)], prefix)
+}
 
-	// Post-process articles and quotes
-	result := processor.output.String()
-	result = fixArticles(result)
-	return fixQuotes(result)
+// addRaw adds the raw region starting at runes[start] up to the end marker, or to the end of
+// the text when there is none, and returns the index after it
+func (tp *TokenProcessor) addRaw(runes []rune, start int, end []rune) int {
+	stop, next := len(runes), len(runes)
+	for j := start; j < len(runes); j++ {
+		if hasRunesAt(runes, j, end) {
+			stop, next = j, j+len(end)
+			break
+		}
+	}
+	tp.rawOpen = stop == len(runes)
+	tp.addRawText(runes[start:stop])
+	return next
+}
+
+// addRawText adds every line of a raw region as a RAW token, its line breaks stay
+// NEWLINE tokens that the region continues across
+func (tp *TokenProcessor) addRawText(runes []rune) {
+	lineStart := 0
+	for j := 0; j <= len(runes); j++ {
+		if j < len(runes) && runes[j] != '\n' {
+			continue
+		}
+		lineEnd := j
+		if j < len(runes) && lineEnd > lineStart && runes[lineEnd-1] == '\r' {
+			lineEnd--
+		}
+		if lineEnd > lineStart {
+			tp.addToken(Token{Type: RAW, Value: string(runes[lineStart:lineEnd])})
+		}
+		if j < len(runes) {
+			tp.addToken(Token{Type: NEWLINE, Value: string(runes[lineEnd : j+1])})
+			tp.open[len(tp.open)-1] = true
+		}
+		lineStart = j + 1
+	}
 }
 
 // --------------- CORE PROCESSING FUNCTIONS  ---------------
 func (tp *TokenProcessor) addToken(token Token) {
+	start := tp.at
+	tp.at = tp.cur
+	if tp.skipSpace {
+		tp.skipSpace = false
+		if token.Type == SPACE {
+			return
+		}
+	}
 	if tp.tokenIdx < len(tp.tokens) {
 		tp.tokens[tp.tokenIdx] = token
-		tp.tokenIdx++
 	} else {
-		// Buffer is full, flush first half to output
-		halfSize := len(tp.tokens) / 2
-		for i := 0; i < halfSize; i++ {
-			token := tp.tokens[i]
-			switch token.Type {
-			case WORD:
-				if tp.output.Len() > 0 && !strings.HasSuffix(tp.output.String(), " ") && !strings.HasSuffix(tp.output.String(), "\n") {
-					tp.output.WriteByte(' ')
-				}
-				tp.output.WriteString(token.Value)
-			case PUNCTUATION:
-				// Handle spacing for punctuation
-				if token.Value == "(" {
-					// Opening parenthesis preserves space before it
-					tp.output.WriteString(token.Value)
-				} else {
-					// Other punctuation - remove trailing space
-					result := tp.output.String()
-					if strings.HasSuffix(result, " ") {
-						tp.output.Reset()
-						tp.output.WriteString(result[:len(result)-1])
-					}
-					tp.output.WriteString(token.Value)
-				}
-			case SPACE:
-				if tp.output.Len() > 0 && !strings.HasSuffix(tp.output.String(), " ") && !strings.HasSuffix(tp.output.String(), "\n") {
-					tp.output.WriteByte(' ')
-				}
-			case NEWLINE:
-				tp.output.WriteByte('\n')
-			}
-		}
+		// Belt is full - grow it instead of flushing, so commands like (low, all)
+		// can still reach every earlier word. Size stays bounded by the chunk size.
+		tp.tokens = append(tp.tokens, token)
+	}
+	if tp.tracing {
+		tp.starts = append(tp.starts[:tp.tokenIdx], start)
+	}
+	tp.tokenIdx++
+	if token.Type == WORD && len(tp.opts.Autocorrect) > 0 {
+		tp.autocorrect(tp.tokenIdx - 1)
+	}
 
-		// Shift remaining tokens to beginning
-		for i := 0; i < halfSize; i++ {
-			tp.tokens[i] = tp.tokens[halfSize+i]
+	if len(tp.pending) > 0 && tp.scopeStart(tp.tokenIdx-1, false) >= 0 {
+		tp.endPending()
+	}
+	if token.Type == NEWLINE {
+		if len(tp.quotes) > 0 && tp.paragraphStart(tp.tokenIdx-1) >= 0 {
+			tp.endQuotes()
 		}
-		tp.tokenIdx = halfSize
-
-		// Add new token
-		tp.tokens[tp.tokenIdx] = token
-		tp.tokenIdx++
+		tp.lineBreaks = append(tp.lineBreaks, tp.tokenIdx-1)
+		tp.open = append(tp.open, len(tp.pending) > 0)
+		tp.tied = append(tp.tied, false)
+	}
+	if token.Type == WORD && len(tp.pending) > 0 {
+		tp.resolvePending(tp.tokenIdx - 1)
 	}
 }
 
-func (tp *TokenProcessor) processCommand(cmdValue string) {
+// applies the command written at rune index at, or records why it cannot be applied
+func (tp *TokenProcessor) processCommand(cmdValue string, at int) {
 	// Check if command is valid before processing
 	if !tp.isValidCommand(cmdValue) {
 		// Invalid command - ignore it completely
 		return
 	}
 
-	if tp.tokenIdx == 0 {
-		return // No words to transform
+	text := "(" + cmdValue + ")"
+	cmd, countStr, _ := splitCommand(cmdValue)
+	name, forward := commandName(cmd)
+	info, _ := lookupCommand(name, tp.opts.CommandAliases)
+	cmd = info.Name
+
+	count := 1
+	if countStr != "" {
+		var ok bool
+		if count, ok = parseCount(countStr); !ok {
+			tp.unapplied(at, tp.tokenIdx, text, "count must be positive")
+			return
+		}
 	}
 
-	// Find last word token
-	lastWordIdx := -1
-	for i := tp.tokenIdx - 1; i >= 0; i-- {
-		if tp.tokens[i].Type == WORD {
-			lastWordIdx = i
+	// Forward command - resolved by addToken as the next words arrive, the first one decides
+	// whether the command leaves the text
+	if forward {
+		tp.pending = append(tp.pending, pendingCommand{cmd: cmd, remaining: count, count: count, at: at, text: text, pos: tp.tokenIdx})
+		return
+	}
+
+	// Find word indices to transform (in reverse order), up to the boundary of the scope
+	var wordIndices []int
+	bound := -1
+	for i := tp.tokenIdx - 1; i >= 0 && len(wordIndices) < count; i-- {
+		if bound = tp.scopeStart(i, len(wordIndices) == 0); bound >= 0 {
 			break
 		}
+		if tp.tokens[i].Type == WORD {
+			wordIndices = append(wordIndices, i)
+		}
 	}
-
-	if lastWordIdx == -1 {
+	// The command ties the lines it reaches over together, from the boundary on a blank
+	// line included
+	earliest := bound - 1
+	if len(wordIndices) == count {
+		earliest = wordIndices[len(wordIndices)-1]
+	}
+	for k := len(tp.lineBreaks) - 1; k >= 0 && tp.lineBreaks[k] > earliest; k-- {
+		tp.tied[k] = true
+	}
+	// Past the start of the text it goes on in the text before, see ProcessPiece
+	var before Reach
+	if len(wordIndices) < count && bound < 0 && !tp.blankStart() {
+		if n := len(tp.reaches); n < len(tp.before) {
+			before = tp.before[n]
+		}
+		tp.reaches = append(tp.reaches, Reach{Command: cmd, Words: count - len(wordIndices)})
+	}
+	found := len(wordIndices) + before.Words
+	if found == 0 {
+		tp.unapplied(at, tp.tokenIdx, text, "no preceding word")
 		return
 	}
+	if found < count {
+		tp.shortCount(at, text, count, found)
+	}
 
-	// Parse command
-	if strings.Contains(cmdValue, ",") {
-		parts := strings.Split(cmdValue, ",")
-		if len(parts) == 2 {
-			cmd := strings.TrimSpace(parts[0])
-			countStr := strings.TrimSpace(parts[1])
-			if count, err := strconv.Atoi(countStr); err == nil && count > 0 {
-				// Find word indices to transform (in reverse order)
-				var wordIndices []int
-				for i := tp.tokenIdx - 1; i >= 0 && len(wordIndices) < count; i-- {
-					if tp.tokens[i].Type == WORD {
-						wordIndices = append(wordIndices, i)
-					}
-				}
-				// Transform words in forward order
-				for i := len(wordIndices) - 1; i >= 0; i-- {
-					idx := wordIndices[i]
-					// Mark articles transformed by (up) command
-					if cmd == "up" && (tp.tokens[idx].Value == "a" || tp.tokens[idx].Value == "an") {
-						tp.tokens[idx].Value = "UP_" + tp.transformWord(tp.tokens[idx].Value, cmd)
-					} else {
-						tp.tokens[idx].Value = tp.transformWord(tp.tokens[idx].Value, cmd)
-					}
+	// The words before the text come first
+	for _, problem := range before.Problems {
+		tp.warn(at, text, problem)
+	}
+	if before.Changed > 0 {
+		tp.countApplied(at, cmd, before.Changed)
+	}
+
+	// Transform words in forward order
+	applied := before.Changed > 0
+	for i := len(wordIndices) - 1; i >= 0; i-- {
+		word := tp.tokens[wordIndices[i]].Value
+		if tp.applyCommand(wordIndices[i], cmd) {
+			tp.recordToken(wordIndices[i], word, RULE_COMMAND, text)
+			tp.countApplied(at, cmd, 1)
+			applied = true
+		} else {
+			tp.warn(at, text, conversionProblem(cmd, tp.tokens[wordIndices[i]].Value))
+		}
+	}
+	tp.settle(at, tp.tokenIdx, text, applied)
+}
+
+// applies a single command to the word token at idx, false when a conversion cannot read the word
+func (tp *TokenProcessor) applyCommand(idx int, cmd string) bool {
+	word := tp.tokens[idx].Value
+	switch cmd {
+	case "hex", "bin", "tohex", "tobin", "rom", "spell":
+		number, zero, ok := numberText(word)
+		if !ok {
+			return false
+		}
+		number, minus := asciiMinus(number)
+		result, ok := tp.convertNumber(number, cmd)
+		if !ok {
+			return false
+		}
+		tp.tokens[idx].Value = withMinus(tp.nativeDigits(result, zero), minus)
+	case "unrom":
+		val, ok := fromRoman(withoutSoftHyphens(word))
+		if !ok {
+			return false
+		}
+		tp.tokens[idx].Value = tp.groupDigits(strconv.FormatInt(val, 10))
+	default:
+		tp.tokens[idx].Value = tp.transformWord(word, cmd)
+		// Remember the case command, fixArticles turns an uppercased "A" into "AN" rather than "An"
+		switch cmd {
+		case "up":
+			tp.tokens[idx].Flags |= TOKEN_UPPERCASED
+		case "low", "cap", "title":
+			tp.tokens[idx].Flags &^= TOKEN_UPPERCASED
+		}
+	}
+	return true
+}
+
+// converts number, a word with ASCII digits, for cmd: hex, bin, tohex, tobin, rom or spell
+func (tp *TokenProcessor) convertNumber(number, cmd string) (string, bool) {
+	base := 10
+	switch cmd {
+	case "hex":
+		base = 16
+	case "bin":
+		base = 2
+	}
+	if base == 10 {
+		number = tp.ungroupDigits(number)
+	}
+	number, ok := literalDigits(number, base)
+	if !ok {
+		return "", false
+	}
+	if cmd == "spell" {
+		return spellNumber(number)
+	}
+	val, err := strconv.ParseInt(number, base, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return tp.convertBig(number, base, cmd)
+	} else if err != nil {
+		return "", false
+	}
+	switch cmd {
+	case "tohex":
+		return tp.formatRadix(strconv.FormatInt(val, 16), 16), true
+	case "tobin":
+		return tp.formatRadix(strconv.FormatInt(val, 2), 2), true
+	case "rom":
+		return toRoman(val)
+	}
+	return tp.groupDigits(strconv.FormatInt(val, 10)), true
+}
+
+// converts a number in base past the int64 range for cmd, no roman numeral is that large
+func (tp *TokenProcessor) convertBig(number string, base int, cmd string) (string, bool) {
+	val, ok := new(big.Int).SetString(number, base)
+	if !ok || cmd == "rom" {
+		return "", false
+	}
+	switch cmd {
+	case "tohex":
+		return tp.formatRadix(val.Text(16), 16), true
+	case "tobin":
+		return tp.formatRadix(val.Text(2), 2), true
+	}
+	return tp.groupDigits(val.String()), true
+}
+
+// uppercases the first letter of every sentence: the first word of the text,
+// of each line and after '.', '!' or '?'. Articles are capitalized like any
+// word so fixArticles later keeps "A"/"An" in sync with the next word.
+// In markdown mode a list marker is skipped, so the item starts a sentence, and
+// the words of a heading are capitalized by headingCase.
+func (tp *TokenProcessor) capitalizeSentences() {
+	sentenceStart, lineStart, heading := true, true, false
+	for i := 0; i < tp.tokenIdx; i++ {
+		token := &tp.tokens[i]
+		if lineStart && token.Type != SPACE && token.Type != NEWLINE {
+			lineStart = false
+			if tp.opts.Markdown {
+				if end, isHeading := markdownMarkerEnd(tp.tokens[i:tp.tokenIdx]); end > 0 {
+					heading = isHeading
+					i += end - 1
+					continue
 				}
 			}
 		}
-	} else {
-		// Single word command
-		switch cmdValue {
-		case "hex":
-			if val, err := strconv.ParseInt(tp.tokens[lastWordIdx].Value, 16, 64); err == nil {
-				tp.tokens[lastWordIdx].Value = strconv.FormatInt(val, 10)
-			}
-		case "bin":
-			if val, err := strconv.ParseInt(tp.tokens[lastWordIdx].Value, 2, 64); err == nil {
-				tp.tokens[lastWordIdx].Value = strconv.FormatInt(val, 10)
+		switch token.Type {
+		case NEWLINE:
+			sentenceStart, lineStart, heading = true, true, false
+		case PUNCTUATION:
+			if token.Value == "." || token.Value == "!" || token.Value == "?" {
+				sentenceStart = true
 			}
-		default:
-			// Mark articles transformed by (up) command
-			if cmdValue == "up" && (tp.tokens[lastWordIdx].Value == "a" || tp.tokens[lastWordIdx].Value == "an") {
-				tp.tokens[lastWordIdx].Value = "UP_" + tp.transformWord(tp.tokens[lastWordIdx].Value, cmdValue)
-			} else {
-				tp.tokens[lastWordIdx].Value = tp.transformWord(tp.tokens[lastWordIdx].Value, cmdValue)
+		case WORD:
+			if heading {
+				word := token.Value
+				token.Value = headingCase(token.Value, sentenceStart)
+				tp.recordToken(i, word, RULE_SENTENCES, "")
+				sentenceStart = false
+			} else if sentenceStart {
+				word := token.Value
+				token.Value = capitalizeFirstLetter(token.Value)
+				tp.recordToken(i, word, RULE_SENTENCES, "")
+				sentenceStart = false
 			}
 		}
 	}
 }
 
+// applies waiting forward commands to the word token that just arrived at idx
+func (tp *TokenProcessor) resolvePending(idx int) {
+	remaining := tp.pending[:0]
+	for _, pending := range tp.pending {
+		word := tp.tokens[idx].Value
+		if tp.applyCommand(idx, pending.cmd) {
+			tp.recordToken(idx, word, RULE_COMMAND, pending.text)
+			tp.countApplied(pending.at, pending.cmd, 1)
+			pending.applied = true
+		} else {
+			tp.warn(pending.at, pending.text, conversionProblem(pending.cmd, tp.tokens[idx].Value))
+		}
+		if pending.pos >= 0 {
+			tp.settling = append(tp.settling, pending)
+			pending.pos = -1
+		}
+		pending.remaining--
+		if pending.remaining > 0 {
+			remaining = append(remaining, pending)
+		}
+	}
+	tp.pending = remaining
+
+	// Removing or keeping a command moves the tokens after it, so it is done once the
+	// pending list is consistent again
+	for i := 0; i < len(tp.settling); i++ {
+		settled := tp.settling[i]
+		tp.settle(settled.at, settled.pos, settled.text, settled.applied)
+	}
+	tp.settling = tp.settling[:0]
+}
+
 // --------------- POST-PROCESSING PIPELINE ---------------
-func fixQuotes(text string) string {
+// pairs quotes within each paragraph and fixes the spacing inside them, with smart set the
+// pairs are emitted as typographic quotes (“ ” ‘ ’) and in-word apostrophes as ’.
+// open gets marked at every line break, and last at the end, where a quote is still open.
+func fixQuotes(text string, smart bool, open []bool) string {
 	runes := []rune(text)
-	var result strings.Builder
-
-	singleQuoteCount := 0
-	doubleQuoteCount := 0
+	roles := quoteRoles(runes, open)
+	result := make([]byte, 0, len(text))
 
 	for i := 0; i < len(runes); i++ {
 		r := runes[i]
 
-		if r == '\'' {
-			singleQuoteCount++
-			if singleQuoteCount%2 == 1 {
-				// Odd quote - stick to right letter
-				result.WriteRune(r)
-				// Skip space after quote if present
-				if i+1 < len(runes) && runes[i+1] == ' ' {
-					i++ // Skip the space
-				}
-			} else {
-				// Even quote - stick to left letter
-				// Remove space before quote if present
-				resultStr := result.String()
-				if strings.HasSuffix(resultStr, " ") {
-					result.Reset()
-					result.WriteString(resultStr[:len(resultStr)-1])
-				}
-				result.WriteRune(r)
+		switch roles[i] {
+		case QUOTE_OPEN:
+			// Opening quote - stick to right letter
+			result = utf8.AppendRune(result, styleQuote(r, true, smart))
+			// Skip spaces after quote if present
+			for i+1 < len(runes) && (runes[i+1] == ' ' || runes[i+1] == '\t') {
+				i++ // Skip the space
 			}
-		} else if r == '"' {
-			doubleQuoteCount++
-			if doubleQuoteCount%2 == 1 {
-				// Odd quote - stick to right letter
-				result.WriteRune(r)
-				// Skip space after quote if present
-				if i+1 < len(runes) && runes[i+1] == ' ' {
-					i++ // Skip the space
-				}
+		case QUOTE_CLOSE:
+			// Closing quote - stick to left letter
+			// Remove spaces before quote if present
+			for len(result) > 0 && (result[len(result)-1] == ' ' || result[len(result)-1] == '\t') {
+				result = result[:len(result)-1]
+			}
+			result = utf8.AppendRune(result, styleQuote(r, false, smart))
+		case QUOTE_LITERAL:
+			// Apostrophe inside a word (don't, it's, John's) or a quote left unclosed inside a pair
+			if r == '\'' {
+				result = utf8.AppendRune(result, styleQuote(r, false, smart))
 			} else {
-				// Even quote - stick to left letter
-				// Remove space before quote if present
-				resultStr := result.String()
-				if strings.HasSuffix(resultStr, " ") {
-					result.Reset()
-					result.WriteString(resultStr[:len(resultStr)-1])
-				}
-				result.WriteRune(r)
+				result = utf8.AppendRune(result, r)
 			}
-		} else {
-			result.WriteRune(r)
+		default:
+			result = utf8.AppendRune(result, r)
 		}
 	}
 
-	return result.String()
+	return string(result)
 }
 
-func fixArticles(text string) string {
-	// Process line by line to preserve line breaks
-	lines := strings.Split(text, "\n")
-	for lineIdx, line := range lines {
-		if line == "" {
+// Roles of the quote runes found by quoteRoles
+const (
+	QUOTE_NONE    = iota // not a quote
+	QUOTE_OPEN           // opens a pair, attaches to the next word
+	QUOTE_CLOSE          // closes a pair, attaches to the previous word
+	QUOTE_LITERAL        // apostrophe or stray quote, left where it is
+)
+
+// assigns a role to every quote using a stack, so quotes nest: "He said 'stop' twice".
+// A quote closes the innermost open quote of the same kind; quotes still open inside
+// that pair are stray and become literal. Pairing starts over after every blank line, so
+// an unbalanced quote never reaches into the next paragraph; quotes never closed by the
+// end of their paragraph keep opening behavior.
+func quoteRoles(runes []rune, open []bool) []int {
+	roles := make([]int, len(runes))
+	var stack []openQuote // the open quotes, innermost last
+	line := 0
+	blank := false // only spaces and tabs since the last line break
+
+	for i, r := range runes {
+		switch {
+		case r == '\n':
+			if blank {
+				stack = stack[:0]
+			}
+			markOpen(open, line, len(stack) > 0)
+			line++
+			blank = true
+			continue
+		case r == ' ' || r == '\t':
+			continue
+		}
+		blank = false
+		if r != '\'' && r != '"' {
+			continue
+		}
+		if r == '\'' && (isContraction(runes, i) || betweenDigits(runes, i)) {
+			roles[i] = QUOTE_LITERAL
 			continue
 		}
 
-		words := strings.Fields(line)
-		for i := 0; i < len(words)-1; i++ {
-			switch words[i] {
-			case "a", "A", "an", "An", "AN", "UP_A", "UP_AN":
-				nextWord := words[i+1]
-				if len(nextWord) > 0 {
-					// Remove punctuation for vowel check
-					cleanWord := nextWord
-					for strings.HasSuffix(cleanWord, ".") || strings.HasSuffix(cleanWord, ",") || strings.HasSuffix(cleanWord, "!") || strings.HasSuffix(cleanWord, "?") || strings.HasSuffix(cleanWord, ";") || strings.HasSuffix(cleanWord, ":") {
-						cleanWord = cleanWord[:len(cleanWord)-1]
-					}
+		var stray []openQuote
+		roles[i], stack, stray = pairQuote(stack, openQuote{at: i, r: r, kind: r})
+		for _, quote := range stray {
+			roles[quote.at] = QUOTE_LITERAL
+		}
+	}
+	markOpen(open, len(open)-1, len(stack) > 0)
+	return roles
+}
 
-					if len(cleanWord) > 0 {
-						first := strings.ToLower(cleanWord)[0]
-						if first == 'a' || first == 'e' || first == 'i' || first == 'o' || first == 'u' || first == 'h' {
-							// Should be "an"
-							switch words[i] {
-							case "a":
-								words[i] = "an"
-							case "A":
-								words[i] = "An" // From (cap) command
-
-							case "UP_A":
-								words[i] = "AN" // From (up) command
-							case "AN":
-								words[i] = "AN" // Already fully uppercase
-							case "UP_AN":
-								words[i] = "AN"
-							case "UP_An":
-								words[i] = "An"
-							}
-						} else {
-							// Should be "a"
-							switch words[i] {
-							case "an":
-								words[i] = "a"
-							case "An":
-								words[i] = "A"
-
-							case "UP_A":
-								words[i] = "A" // From (up) command
-							case "AN":
-								words[i] = "A" // Preserve uppercase from (up) command
-							case "UP_AN":
-								words[i] = "AN"
-							case "UP_An":
-								words[i] = "An"
-							}
-						}
-					}
+// marks open[i] when something is still open there, open may be shorter or nil
+func markOpen(open []bool, i int, isOpen bool) {
+	if isOpen && i >= 0 && i < len(open) {
+		open[i] = true
+	}
+}
+
+// returns the typographic form of a straight quote when smart quotes are enabled
+func styleQuote(r rune, opening, smart bool) rune {
+	if !smart {
+		return r
+	}
+	switch {
+	case r == '"' && opening:
+		return '“'
+	case r == '"':
+		return '”'
+	case opening:
+		return '‘'
+	}
+	return '’' // closing single quote and apostrophe
+}
+
+// replaces typographic quotes with straight ones so they are paired like ASCII quotes
+func straightenQuotes(text string) string {
+	return strings.NewReplacer("“", "\"", "”", "\"", "„", "\"", "‘", "'", "’", "'").Replace(text)
+}
+
+// reports whether the apostrophe at index i sits between two letters
+func isContraction(runes []rune, i int) bool {
+	return i > 0 && i+1 < len(runes) && unicode.IsLetter(runes[i-1]) && unicode.IsLetter(runes[i+1])
+}
+
+// reports whether the apostrophe at index i sits between two digits, a group separator: 65'535
+func betweenDigits(runes []rune, i int) bool {
+	return i > 0 && i+1 < len(runes) && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1])
+}
+
+// corrects a/an from the WORD token that follows each article, skipping only spaces.
+// exceptions (lowercase word -> "a"/"an") take precedence over the vowel/h rule.
+// An article followed by punctuation or a line break is left alone.
+func (tp *TokenProcessor) fixArticles(exceptions map[string]string) {
+	for i := 0; i < tp.tokenIdx; i++ {
+		article := &tp.tokens[i]
+		if article.Type != WORD {
+			continue
+		}
+		switch article.Value {
+		case "a", "A", "an", "An", "AN":
+		default:
+			continue
+		}
+
+		next := i + 1
+		for next < tp.tokenIdx && tp.tokens[next].Type == SPACE {
+			next++
+		}
+		if next == tp.tokenIdx || tp.tokens[next].Type != WORD {
+			continue
+		}
+		word := strings.ToLower(withoutSoftHyphens(tp.tokens[next].Value))
+		if word == "" {
+			continue
+		}
+
+		first := word[0]
+		useAn := first == 'a' || first == 'e' || first == 'i' || first == 'o' || first == 'u' || first == 'h'
+		if rule, ok := exceptions[word]; ok {
+			useAn = rule == config.ARTICLE_AN
+		}
+		written := article.Value
+
+		switch {
+		case article.Value == "AN" || (article.Value == "A" && article.Flags&TOKEN_UPPERCASED != 0):
+			// Fully uppercase, e.g. from the (up) command
+			article.Value = pick(useAn, "AN", "A")
+		case article.Value == "A" || article.Value == "An":
+			// Capitalized, e.g. from (cap) or a sentence start
+			article.Value = pick(useAn, "An", "A")
+		default:
+			article.Value = pick(useAn, "an", "a")
+		}
+		tp.recordToken(i, written, RULE_ARTICLES, "")
+	}
+}
+
+// returns ifTrue or ifFalse
+func pick(condition bool, ifTrue, ifFalse string) string {
+	if condition {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// joins detached ordinal suffixes ("1 st" -> "1st") when the suffix matches the number,
+// and optionally turns superscript suffixes ("1ˢᵗ") into plain letters
+func fixOrdinals(text string, plainSuperscripts bool) string {
+	runes := []rune(text)
+	var result strings.Builder
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !unicode.IsDigit(r) || (i > 0 && isWordRune(runes[i-1])) {
+			result.WriteRune(r)
+			continue
+		}
+
+		// Collect the full number
+		end := i
+		for end < len(runes) && unicode.IsDigit(runes[end]) {
+			end++
+		}
+		number := string(runes[i:end])
+
+		// Optional spaces, then a two letter suffix ending at a word boundary
+		suffixStart := end
+		for suffixStart < len(runes) && runes[suffixStart] == ' ' {
+			suffixStart++
+		}
+		suffixEnd := suffixStart + 2
+		if suffixEnd <= len(runes) && (suffixEnd == len(runes) || !isWordRune(runes[suffixEnd])) {
+			suffix, superscript := readOrdinalSuffix(runes[suffixStart:suffixEnd])
+			detached := suffixStart > end
+			if strings.ToLower(suffix) == ordinalSuffix(number) && (detached || (superscript && plainSuperscripts)) {
+				result.WriteString(number)
+				if superscript && !plainSuperscripts {
+					result.WriteString(string(runes[suffixStart:suffixEnd]))
+				} else {
+					result.WriteString(suffix)
 				}
+				i = suffixEnd - 1
+				continue
 			}
 		}
-		lines[lineIdx] = strings.Join(words, " ")
+
+		result.WriteString(number)
+		i = end - 1
+	}
+
+	return result.String()
+}
+
+// superscript letters used in typeset ordinals and their plain forms
+var superscriptLetters = map[rune]rune{
+	'ˢ': 's', 'ᵗ': 't', 'ⁿ': 'n', 'ᵈ': 'd', 'ʳ': 'r', 'ʰ': 'h',
+}
+
+// returns the suffix in plain letters and whether it was written in superscript
+func readOrdinalSuffix(runes []rune) (string, bool) {
+	plain := make([]rune, len(runes))
+	superscript := false
+	for i, r := range runes {
+		if mapped, ok := superscriptLetters[r]; ok {
+			plain[i] = mapped
+			superscript = true
+		} else {
+			plain[i] = r
+		}
+	}
+	return string(plain), superscript
+}
+
+// returns the English ordinal suffix for a number: 1st, 2nd, 3rd, 4th, 11th, 21st
+func ordinalSuffix(number string) string {
+	last := number[len(number)-1]
+	if len(number) >= 2 && number[len(number)-2] == '1' {
+		return "th"
+	}
+	switch last {
+	case '1':
+		return "st"
+	case '2':
+		return "nd"
+	case '3':
+		return "rd"
+	}
+	return "th"
+}
+
+// reports whether the rune can be part of a word (letters, digits, superscript letters)
+func isWordRune(r rune) bool {
+	if _, ok := superscriptLetters[r]; ok {
+		return true
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// words kept lowercase inside hyphenated compounds when small-word rules are enabled
+var titleSmallWords = map[string]bool{
+	"a": true, "an": true, "and": true, "as": true, "at": true, "but": true, "by": true,
+	"for": true, "in": true, "nor": true, "of": true, "on": true, "or": true, "the": true, "to": true,
+}
+
+// title-cases a word segment by segment: "state-of-the-art" -> "State-Of-The-Art"
+// ("State-of-the-Art" with small-word rules) and "o'brien" -> "O'Brien"
+func titleCase(word string, smallWords bool) string {
+	segments := strings.Split(strings.ToLower(word), "-")
+	for i, segment := range segments {
+		if smallWords && i > 0 && titleSmallWords[segment] {
+			continue
+		}
+		segments[i] = capitalizeSegment(segment)
+	}
+	return strings.Join(segments, "-")
+}
+
+// uppercases the first letter of a lowercase segment, plus the letter after a
+// one-letter elision such as o', d' or l' (contractions like don't are untouched)
+func capitalizeSegment(segment string) string {
+	runes := []rune(segment)
+	first := -1
+	for i, r := range runes {
+		if unicode.IsLetter(r) {
+			first = i
+			runes[i] = unicode.ToTitle(r)
+			break
+		}
+	}
+	if first == -1 {
+		return segment
+	}
+
+	apostrophe := first + 1
+	if apostrophe < len(runes) && (runes[apostrophe] == '\'' || runes[apostrophe] == '’') && len(runes)-apostrophe > 2 {
+		runes[apostrophe+1] = unicode.ToTitle(runes[apostrophe+1])
+	}
+	return string(runes)
+}
+
+// uppercases the first letter of a word, skipping leading quotes and brackets,
+// but leaves words starting with a digit untouched
+func capitalizeFirstLetter(word string) string {
+	for i, r := range word {
+		if unicode.IsLetter(r) {
+			return word[:i] + string(unicode.ToTitle(r)) + word[i+utf8.RuneLen(r):]
+		}
+		if unicode.IsDigit(r) {
+			return word
+		}
 	}
-	return strings.Join(lines, "\n")
+	return word
 }
 
 // --------------- helper functions ---------------
 
-// creates a new TokenProcessor with preallocated token buffer
+// creates a new TokenProcessor with a preallocated token buffer. The buffer only sets the
+// starting capacity: it grows with the text, and nothing is written out before render, so a
+// command reaches every word before it whatever its count.
 func NewTokenProcessor() *TokenProcessor {
-	tokenBufferSize := config.OVERLAP_WORDS * 4 // 4x OVERLAP_WORDS for consistency
+	tokenBufferSize := config.OVERLAP_WORDS * 4
 	return &TokenProcessor{
 		tokens: make([]Token, tokenBufferSize),
 	}
 }
 
+// reset empties the processor so its buffers can serve another text
+func (tp *TokenProcessor) reset() {
+	tp.tokenIdx = 0
+	tp.output = tp.output[:0]
+	tp.pending = tp.pending[:0]
+	tp.lineEndings = tp.lineEndings[:0]
+	tp.raw = tp.raw[:0]
+	tp.rawOpen = false
+	tp.quotes = tp.quotes[:0]
+	tp.reaches = nil
+	tp.before = nil
+	tp.after = nil
+	tp.lineBreaks = tp.lineBreaks[:0]
+	tp.open = tp.open[:0]
+	tp.tied = tp.tied[:0]
+	tp.warnings = nil
+	tp.applied = nil
+	tp.counts = tp.counts[:0]
+	tp.settling = tp.settling[:0]
+	tp.tracing = false
+	tp.listing = false
+	tp.found = nil
+	tp.starts = tp.starts[:0]
+	tp.events = nil
+}
+
 // validates command syntax before processing to prevent invalid transformations
 func (tp *TokenProcessor) isValidCommand(cmdValue string) bool {
+	cmd, countStr, ok := splitCommand(cmdValue)
+	if !ok {
+		return false
+	}
+	name, _ := commandName(cmd)
+
+	info, ok := lookupCommand(name, tp.opts.CommandAliases)
+	if !ok {
+		return false
+	}
+
 	// Check for valid single commands
-	switch cmdValue {
-	case "hex", "bin", "up", "low", "cap":
+	if countStr == "" {
 		return true
 	}
 
 	// Check for valid multi-word commands.
-	if strings.Contains(cmdValue, ",") {
-		parts := strings.Split(cmdValue, ",")
-		if len(parts) == 2 {
-			cmd := strings.TrimSpace(parts[0])
-			countStr := strings.TrimSpace(parts[1])
-			if cmd == "up" || cmd == "low" || cmd == "cap" {
-				if _, err := strconv.Atoi(countStr); err == nil {
-					return true
-				}
-			}
+	if info.MultiWord {
+		if strings.EqualFold(countStr, COUNT_ALL) {
+			return true
+		}
+		if _, err := strconv.Atoi(countStr); err == nil {
+			return true
 		}
 	}
 
 	return false
 }
 
+// splits a command into its name and optional count, without the spaces around them:
+// " up , 3 " -> ("up", "3"), "hex" -> ("hex", ""), "up," is not ok
+func splitCommand(cmdValue string) (cmd, countStr string, ok bool) {
+	if !strings.Contains(cmdValue, ",") {
+		return strings.TrimSpace(cmdValue), "", true
+	}
+
+	parts := strings.Split(cmdValue, ",")
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	cmd, countStr = strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	if countStr == "" {
+		// "up," is no command, the comma promises a count
+		return "", "", false
+	}
+	return cmd, countStr, true
+}
+
+// splits the forward marker off a command name: "up >" -> ("up", true)
+func commandName(cmd string) (name string, forward bool) {
+	name = strings.TrimSpace(cmd)
+	if !strings.HasSuffix(name, FORWARD_MARKER) {
+		return name, false
+	}
+	return strings.TrimSpace(strings.TrimSuffix(name, FORWARD_MARKER)), true
+}
+
+// parses the count of a multi-word command, "all" reaches every preceding word
+func parseCount(countStr string) (int, bool) {
+	if strings.EqualFold(countStr, COUNT_ALL) {
+		return math.MaxInt, true
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return 0, false
+	}
+	return count, true
+}
+
 // applies case transformations to individual words.
 func (tp *TokenProcessor) transformWord(word, cmd string) string {
 	switch cmd {
@@ -431,46 +1279,265 @@ func (tp *TokenProcessor) transformWord(word, cmd string) string {
 	case "low":
 		return strings.ToLower(word)
 	case "cap":
+		// Leading soft hyphens are invisible, capitalize the first visible letter
+		prefix := word[:len(word)-len(strings.TrimLeft(word, string(SOFT_HYPHEN)))]
+		word = word[len(prefix):]
 		if len(word) == 0 {
-			return word
+			return prefix
 		}
+		// Title case, not upper case, for the first rune: "ǆungla" -> "ǅungla"
 		lower := strings.ToLower(word)
-		return strings.ToUpper(string(lower[0])) + lower[1:]
+		first, size := utf8.DecodeRuneInString(lower)
+		return prefix + string(unicode.ToTitle(first)) + lower[size:]
+	case "title":
+		return titleCase(word, tp.opts.TitleSmallWords)
+	case "rev":
+		runes := []rune(word)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes)
 	}
 	return word
 }
 
+// formats number, written in base 16 or 2 with an optional minus sign, honoring the
+// UpperHex and RadixPrefix options
+func (tp *TokenProcessor) formatRadix(number string, base int) string {
+	sign, digits := "", number
+	if strings.HasPrefix(digits, "-") {
+		sign, digits = "-", digits[1:]
+	}
+	if base == 16 && tp.opts.UpperHex {
+		digits = strings.ToUpper(digits)
+	}
+
+	if tp.opts.RadixPrefix {
+		switch base {
+		case 16:
+			digits = "0x" + digits
+		case 2:
+			digits = "0b" + digits
+		}
+	}
+	return sign + digits
+}
+
+// removes soft hyphens so numeric parsing and vowel checks see the visible word
+func withoutSoftHyphens(word string) string {
+	return strings.ReplaceAll(word, string(SOFT_HYPHEN), "")
+}
+
+// roman numeral symbols in descending order, including subtractive pairs
+var romanNumerals = []struct {
+	value  int64
+	symbol string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"},
+	{100, "C"}, {90, "XC"}, {50, "L"}, {40, "XL"},
+	{10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// converts 1..3999 to a roman numeral, other values cannot be represented
+func toRoman(val int64) (string, bool) {
+	if val <= 0 || val > 3999 {
+		return "", false
+	}
+
+	var result strings.Builder
+	for _, numeral := range romanNumerals {
+		for val >= numeral.value {
+			result.WriteString(numeral.symbol)
+			val -= numeral.value
+		}
+	}
+	return result.String(), true
+}
+
+// parses a canonical roman numeral (case-insensitive), rejecting forms like IIII or VX
+func fromRoman(word string) (int64, bool) {
+	upper := strings.ToUpper(word)
+	if upper == "" {
+		return 0, false
+	}
+
+	var val int64
+	rest := upper
+	for _, numeral := range romanNumerals {
+		for strings.HasPrefix(rest, numeral.symbol) {
+			val += numeral.value
+			rest = rest[len(numeral.symbol):]
+		}
+	}
+	if rest != "" {
+		return 0, false
+	}
+
+	// Only accept the canonical spelling of the parsed value
+	if canonical, ok := toRoman(val); !ok || canonical != upper {
+		return 0, false
+	}
+	return val, true
+}
+
 // writes remaining tokens to output buffer with proper spacing and resets token buffer
 func (tp *TokenProcessor) flushTokens() {
+	if tp.opts.PreserveWhitespace {
+		tp.flushPreserving()
+		return
+	}
+
+	glueNext := false // previous punctuation attaches to the following word
 	for i := 0; i < tp.tokenIdx; i++ {
 		token := tp.tokens[i]
 		switch token.Type {
 		case WORD:
-			if tp.output.Len() > 0 && !strings.HasSuffix(tp.output.String(), " ") && !strings.HasSuffix(tp.output.String(), "\n") {
-				tp.output.WriteByte(' ')
+			if !glueNext {
+				tp.writeSpace()
 			}
-			tp.output.WriteString(token.Value)
+			tp.output = append(tp.output, token.Value...)
+			glueNext = false
 		case PUNCTUATION:
-			// Handle spacing for punctuation
-			if token.Value == "(" {
-				// Opening parenthesis preserves space before it
-				tp.output.WriteString(token.Value)
-			} else {
-				// Other punctuation - remove trailing space
-				result := tp.output.String()
-				if strings.HasSuffix(result, " ") {
-					tp.output.Reset()
-					tp.output.WriteString(result[:len(result)-1])
-				}
-				tp.output.WriteString(token.Value)
+			first, _ := utf8.DecodeRuneInString(token.Value)
+			switch tp.attachOf(first) {
+			case config.ATTACH_RIGHT:
+				tp.writeSpace()
+				glueNext = true
+			case config.ATTACH_BOTH:
+				tp.trimSpace()
+				glueNext = true
+			case config.ATTACH_SPACED:
+				tp.writeSpace()
+				glueNext = false
+			default:
+				// Classic punctuation - remove trailing space
+				tp.trimSpace()
+				glueNext = false
 			}
+			tp.output = append(tp.output, token.Value...)
 		case SPACE:
-			if tp.output.Len() > 0 && !strings.HasSuffix(tp.output.String(), " ") && !strings.HasSuffix(tp.output.String(), "\n") {
-				tp.output.WriteByte(' ')
+			// A raw region glues to the next word only when no space follows it
+			if !glueNext || tp.tokens[i-1].Type == RAW {
+				tp.writeSpace()
 			}
+		case RAW:
+			tp.writeRaw(token.Value)
+			glueNext = true
 		case NEWLINE:
-			tp.output.WriteByte('\n')
+			tp.trimSpace()
+			tp.output = append(tp.output, '\n')
+			tp.lineEndings = append(tp.lineEndings, token.Value)
+			glueNext = false
 		}
 	}
+	// No trailing spaces at the end of lines or of the text
+	tp.trimSpace()
 	tp.tokenIdx = 0
 }
+
+// flushPreserving is the non-normalizing assembler: SPACE tokens are written exactly as
+// they appeared in the input, only punctuation still moves to its configured side
+func (tp *TokenProcessor) flushPreserving() {
+	glueNext := false  // previous punctuation attaches to the following word
+	spaceNext := false // previous punctuation must be separated from the following word
+	for i := 0; i < tp.tokenIdx; i++ {
+		token := tp.tokens[i]
+		switch token.Type {
+		case WORD:
+			if spaceNext {
+				tp.writeSpace()
+			}
+			tp.output = append(tp.output, token.Value...)
+			glueNext, spaceNext = false, false
+		case PUNCTUATION:
+			first, _ := utf8.DecodeRuneInString(token.Value)
+			switch tp.attachOf(first) {
+			case config.ATTACH_RIGHT:
+				tp.writeSpace()
+				glueNext, spaceNext = true, false
+			case config.ATTACH_BOTH:
+				tp.trimSpaces()
+				glueNext, spaceNext = true, false
+			case config.ATTACH_SPACED:
+				tp.writeSpace()
+				glueNext, spaceNext = false, true
+			default:
+				tp.trimSpaces()
+				glueNext, spaceNext = false, true
+			}
+			tp.output = append(tp.output, token.Value...)
+		case SPACE:
+			if !glueNext {
+				tp.output = append(tp.output, token.Value...)
+				spaceNext = false
+			}
+		case RAW:
+			tp.writeRaw(token.Value)
+			glueNext, spaceNext = false, false
+		case NEWLINE:
+			tp.output = append(tp.output, '\n')
+			tp.lineEndings = append(tp.lineEndings, token.Value)
+			glueNext, spaceNext = false, false
+		}
+	}
+	tp.tokenIdx = 0
+}
+
+// writes a separating space unless the output is empty or already ends with whitespace
+func (tp *TokenProcessor) writeSpace() {
+	if n := len(tp.output); n > 0 && tp.output[n-1] != ' ' && tp.output[n-1] != '\t' && tp.output[n-1] != '\n' {
+		tp.output = append(tp.output, ' ')
+	}
+}
+
+// writes the placeholder of a raw region, the post-passes leave it alone and restoreRaw puts the text back
+func (tp *TokenProcessor) writeRaw(text string) {
+	tp.output = utf8.AppendRune(tp.output, RAW_PLACEHOLDER)
+	tp.raw = append(tp.raw, text)
+}
+
+// removes one trailing space from the output
+func (tp *TokenProcessor) trimSpace() {
+	if n := len(tp.output); n > 0 && tp.output[n-1] == ' ' {
+		tp.output = tp.output[:n-1]
+	}
+}
+
+// removes every trailing space and tab from the output
+func (tp *TokenProcessor) trimSpaces() {
+	n := len(tp.output)
+	for n > 0 && (tp.output[n-1] == ' ' || tp.output[n-1] == '\t') {
+		n--
+	}
+	tp.output = tp.output[:n]
+}
+
+// returns the end of the dashes starting at runes[i] when they belong to the word around
+// them rather than being punctuation, i otherwise: a single hyphen ("well-known", "-5"),
+// hyphens starting a word ("--verbose") and an en dash between digits ("10–20")
+func wordDashEnd(runes []rune, i int) int {
+	switch runes[i] {
+	case config.DOUBLE_HYPHEN:
+		end := i + 1
+		for end < len(runes) && runes[end] == config.DOUBLE_HYPHEN {
+			end++
+		}
+		startsWord := (i == 0 || unicode.IsSpace(runes[i-1])) && end < len(runes) && !unicode.IsSpace(runes[end])
+		if end == i+1 || startsWord {
+			return end
+		}
+	case '–':
+		if i > 0 && i+1 < len(runes) && unicode.IsDigit(runes[i-1]) && unicode.IsDigit(runes[i+1]) {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// returns the spacing rule of a punctuation rune, -1 for runes that are not punctuation
+func (tp *TokenProcessor) attachOf(r rune) int {
+	if attach, ok := tp.punctuation[r]; ok {
+		return attach
+	}
+	return -1
+}
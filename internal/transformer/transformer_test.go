@@ -1,6 +1,8 @@
 package transformer
 
 import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
 	"testing"
 )
 
@@ -64,6 +66,29 @@ func TestProcessTextCaseCap(t *testing.T) {
 	}
 }
 
+func TestProcessTextCaseCapUnicode(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"éclair (cap)", "Éclair"},
+		{"ÜBER (cap)", "Über"},
+		{"ñandú (cap)", "Ñandú"},
+		{"αθήνα (cap)", "Αθήνα"},
+		{"ΩΜΕΓΑ (cap)", "Ωμεγα"},
+		{"москва (cap)", "Москва"},
+		{"ЖУРНАЛ (cap)", "Журнал"},
+		{"ǆungla (cap)", "ǅungla"}, // digraph takes its title case form, not DŽ
+		{"ünd éclair (cap, 2)", "Ünd Éclair"},
+	}
+
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
 func TestProcessTextMultiWord(t *testing.T) {
 	text := "these three words (up, 3) test"
 	result := ProcessText(text)
@@ -84,6 +109,66 @@ func TestProcessTextArticles(t *testing.T) {
 	}
 }
 
+func TestProcessTextArticlesUppercased(t *testing.T) {
+	skip := config.DefaultOptions()
+	skip.SkipArticles = true
+	preserve := config.DefaultOptions()
+	preserve.PreserveWhitespace = true
+
+	tests := []struct {
+		input    string
+		opts     config.Options
+		expected string
+	}{
+		{"a (up) apple", config.DefaultOptions(), "AN apple"},
+		{"an (up) car", config.DefaultOptions(), "A car"},
+		{"A apple", config.DefaultOptions(), "An apple"},
+		{"a (cap) apple", config.DefaultOptions(), "An apple"},
+		{"a (up) (low) apple", config.DefaultOptions(), "an apple"},
+		{"a (up) (cap) apple", config.DefaultOptions(), "An apple"},
+		{"A apple and a (up) egg", config.DefaultOptions(), "An apple and AN egg"},
+		{"x\n a  (up)   owl", preserve, "x\n AN   owl"},
+		{"a (up) apple", skip, "A apple"},
+	}
+
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, test.opts); result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextArticlesTokenLevel(t *testing.T) {
+	opts := config.DefaultOptions()
+	rules, err := config.ParsePunctuation("¿=right —=both", config.DefaultPunctuation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Punctuation = rules
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// The article is fixed from the next WORD token, whatever punctuation is glued to it
+		{"word—a apple", "word—an apple"},
+		{"a apple , then a egg .", "an apple, then an egg."},
+		// Punctuation or a line break after the article: nothing to look at
+		{"an ¿apple?", "an ¿apple?"},
+		{"a , apple", "a, apple"},
+		{"a\napple", "a\napple"},
+		// Whitespace inside words is not rewritten as a separator
+		{"a\u00a0apple", "a\u00a0apple"},
+		{"x  a   apple  ", "x an apple"},
+	}
+
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
 func TestProcessTextPunctuation(t *testing.T) {
 	text := "Hello , world ! How are you ?"
 	result := ProcessText(text)
@@ -123,3 +208,725 @@ func TestProcessTextEmpty(t *testing.T) {
 		t.Errorf("Expected %q, got %q", expected, result)
 	}
 }
+
+func TestProcessTextToHex(t *testing.T) {
+	text := "255 (tohex) equals FF"
+	result := ProcessText(text)
+	expected := "ff equals FF"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextToBinary(t *testing.T) {
+	text := "10 (tobin) equals 1010"
+	result := ProcessText(text)
+	expected := "1010 equals 1010"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextRadixOptions(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.UpperHex = true
+	opts.RadixPrefix = true
+
+	text := "255 (tohex) and -10 (tobin) and 16 (hex) (tohex)"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "0xFF and -0b1010 and 0x16"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextRoman(t *testing.T) {
+	text := "Chapter 14 (rom) follows chapter XIII (unrom) and xiv (unrom)"
+	result := ProcessText(text)
+	expected := "Chapter XIV follows chapter 13 and 14"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextRomanInvalid(t *testing.T) {
+	text := "0 (rom) 4000 (rom) IIII (unrom) hello (unrom)"
+	result := ProcessText(text)
+	expected := "0 4000 IIII hello"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextReverse(t *testing.T) {
+	text := "stressed (rev) desserts and café über (rev, 2) done"
+	result := ProcessText(text)
+	expected := "desserts desserts and éfac rebü done"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextSoftHyphenPreserved(t *testing.T) {
+	text := "a ­elephant (cap) and F­F (hex)"
+	result := ProcessText(text)
+	expected := "an ­Elephant and 255"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextSoftHyphenStripped(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.StripSoftHyphens = true
+
+	text := "type­set (up) words"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "TYPESET words"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextOrdinals(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.NormalizeOrdinals = true
+
+	text := "the 1 st, 2 nd and 23 rd place but not 1 th or 11 st, 12 th is fine"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "the 1st, 2nd and 23rd place but not 1 th or 11 st, 12th is fine"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextOrdinalsDisabledByDefault(t *testing.T) {
+	text := "the 1 st place"
+	result := ProcessText(text)
+	expected := "the 1 st place"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextPlainOrdinals(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.NormalizeOrdinals = true
+	opts.PlainOrdinals = true
+
+	text := "the 1ˢᵗ and 4 ᵗʰ entries"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "the 1st and 4th entries"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCountAll(t *testing.T) {
+	text := "THE Quick BROWN fox (low, all) jumps"
+	result := ProcessText(text)
+	expected := "the quick brown fox jumps"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCountAllLongText(t *testing.T) {
+	// Far more tokens than the initial belt size
+	words := strings.Repeat("word ", 200)
+	result := ProcessText(words + "(up, all)")
+	expected := strings.TrimSpace(strings.Repeat("WORD ", 200))
+
+	if result != expected {
+		t.Errorf("Expected %d uppercase words, got %q", 200, result)
+	}
+}
+
+func TestProcessTextLongRunsBeforeCommand(t *testing.T) {
+	lower := func(n int) string { return strings.Repeat("word ", n) }
+	upper := func(n int) string { return strings.Repeat("WORD ", n) }
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"past the initial buffer", upper(100) + "(low, 30) end", upper(70) + lower(30) + "end"},
+		{"hundreds of words", lower(500) + "(up, 300) end", lower(200) + upper(300) + "end"},
+		{"across lines", strings.Repeat("word, word\n", 100) + "end (up, 151)", strings.Repeat("word, word\n", 25) + strings.Repeat("WORD, WORD\n", 75) + "END"},
+		{"forward", "(up>, 300) " + lower(400), strings.TrimSpace(upper(300) + lower(100))},
+		{"count equal to the words", lower(1000) + "(up, 1000)", strings.TrimSpace(upper(1000))},
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("%s: got %q, expected %q", test.name, result, test.expected)
+		}
+	}
+
+	// A processor reused after a long text starts from an empty buffer
+	p, err := NewProcessor(config.DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Process(lower(2000) + "(up, all)")
+	if result := p.Process("a b (up, 5)"); result != "A B" {
+		t.Errorf("Expected only the words of the second text, got %q", result)
+	}
+}
+
+func TestProcessTextForwardCommand(t *testing.T) {
+	text := "(up>, 2) these words but (cap>) not these"
+	result := ProcessText(text)
+	expected := "THESE WORDS but Not these"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextForwardCommandChained(t *testing.T) {
+	text := "value (bin>) (hex>) 1010 and (low>, 3) THE END, REALLY"
+	result := ProcessText(text)
+	expected := "value 16 and the end, really"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCommandCase(t *testing.T) {
+	text := "say it (UP) twice (Cap, 2) and (LOW>, ALL) LOUD WORDS 1e (Hex)"
+	result := ProcessText(text)
+	expected := "say It Twice and loud words 30"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCommandSpacing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"so it goes ( up , 2 ) on", "so IT GOES on"},
+		{"go ( cap > ) now", "go Now"},
+		{"LOUD (low\t) and 1E ( hex )", "loud and 30"},
+		{"every word here (title, all)", "Every Word Here"},
+		{"go (up>, 1000) now", "go NOW"},
+		{"many words (cap, 123456) here", "Many Words here"},
+		// Text longer than a command is still tokenized, short text stays one word
+		{"see (an apple , or a egg) and (a egg)", "see (an apple, or an egg) and (a egg)"},
+		{"a ( hex , 2 ) b", "a ( hex , 2 ) b"},
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextCommandEmptyCount(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x (up,) y", "x (up,) y"},
+		{"1E (hex,) y", "1E (hex,) y"},
+		{"x ( low , ) y", "x ( low , ) y"},
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextCommandAliases(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.CommandAliases = map[string]string{"uppercase": "up", "reverse": "rev", "nothing": "none"}
+
+	text := "one two (Uppercase, 2) three (reverse) (uppercase>) go (nothing) (uppercase, x)"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "ONE TWO eerht GO (nothing) (uppercase, x)"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+	if _, warnings := ProcessTextWarnings(text, opts); len(warnings) != 1 || warnings[0].Command != "(uppercase, x)" {
+		t.Errorf("Expected a warning for the malformed alias, got %v", warnings)
+	}
+}
+
+func TestValidateAliases(t *testing.T) {
+	tests := []struct {
+		aliases map[string]string
+		valid   bool
+	}{
+		{nil, true},
+		{map[string]string{"uppercase": "up", "shout": "UP"}, true},
+		{map[string]string{"up": "low"}, false},
+		{map[string]string{"hexa": "hexadecimal"}, false},
+	}
+	for _, test := range tests {
+		if err := ValidateAliases(test.aliases); (err == nil) != test.valid {
+			t.Errorf("ValidateAliases(%v) = %v, expected valid %v", test.aliases, err, test.valid)
+		}
+	}
+	if _, err := NewProcessor(config.Options{CommandAliases: map[string]string{"shout": "yell"}}); err == nil {
+		t.Error("Expected NewProcessor to reject an alias of an unknown command")
+	}
+}
+
+func TestProcessTextTitle(t *testing.T) {
+	text := "a STATE-OF-THE-ART (title) tool by o'brien (title) that don't (title) fail"
+	result := ProcessText(text)
+	expected := "a State-Of-The-Art tool by O'Brien that Don't fail"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextTitleMultiWord(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.TitleSmallWords = true
+
+	text := "the élan of state-of-the-art design (title, 2)"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "the élan of State-of-the-Art Design"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	text = "the élan of (title, 3)"
+	result = ProcessTextWithOptions(text, opts)
+	expected = "The Élan Of"
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCapitalizeSentences(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.CapitalizeSentences = true
+
+	text := "hello there . is it you ? 'yes' it is !\nnew line, 42 apples. ünder the tree"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "Hello there. Is it you? 'Yes' it is!\nNew line, 42 apples. Ünder the tree"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextCapitalizeSentencesArticles(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.CapitalizeSentences = true
+
+	text := "it works. a apple a day. an car too."
+	result := ProcessTextWithOptions(text, opts)
+	expected := "It works. An apple a day. A car too."
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextEscapedCommand(t *testing.T) {
+	text := `write \(up\) to uppercase, or \(cap, 2) for two words (up)`
+	result := ProcessText(text)
+	expected := `write (up) to uppercase, or (cap, 2) for two WORDS`
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextBackslashKept(t *testing.T) {
+	text := `see path\to\file here`
+	result := ProcessText(text)
+	expected := `see path\to\file here`
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextPunctuationGroups(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"I was thinking ... You were right", "I was thinking... You were right"},
+		{"Really ?!And you ?", "Really?! And you?"},
+		{"wait ...then go", "wait... then go"},
+		{"what !? no !!", "what!? no!!"},
+		{"He said ' wait ... ' ok", "He said 'wait...' ok"},
+	}
+
+	for _, test := range tests {
+		result := ProcessText(test.input)
+		if result != test.expected {
+			t.Errorf("For %q expected %q, got %q", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestProcessTextContractionsInQuotes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"I don't know ' what it's about ' really", "I don't know 'what it's about' really"},
+		{"' John's car ' and ' Mary's bike '", "'John's car' and 'Mary's bike'"},
+		{"it's ' fine ' isn't it", "it's 'fine' isn't it"},
+		{"He said \" don't ' stop ' now \"", "He said \"don't 'stop' now\""},
+	}
+
+	for _, test := range tests {
+		result := ProcessText(test.input)
+		if result != test.expected {
+			t.Errorf("For %q expected %q, got %q", test.input, test.expected, result)
+		}
+	}
+}
+
+func TestTokenizeWithOptions(t *testing.T) {
+	tokens := TokenizeWithOptions("ff (up) ,ok", config.DefaultOptions())
+	expected := []Token{{Type: WORD, Value: "FF", Flags: TOKEN_UPPERCASED}, {Type: SPACE, Value: " "}, {Type: SPACE, Value: " "}, {Type: PUNCTUATION, Value: ","}, {Type: WORD, Value: "ok"}}
+
+	if len(tokens) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, tokens)
+	}
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Errorf("Token %d: expected %v, got %v", i, expected[i], tokens[i])
+		}
+	}
+	if tokens[0].String() != `WORD("FF")` {
+		t.Errorf("Unexpected token string %s", tokens[0].String())
+	}
+}
+
+func TestProcessTextSmartQuotes(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.SmartQuotes = true
+
+	text := `He said " don't ' stop ' now " .`
+	result := ProcessTextWithOptions(text, opts)
+	expected := "He said “don’t ‘stop’ now”."
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextASCIIQuotes(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.ASCIIQuotes = true
+
+	text := "He said “ it’s ‘ fine ’ ” ."
+	result := ProcessTextWithOptions(text, opts)
+	expected := `He said "it's 'fine'".`
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextFrenchPunctuation(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.Punctuation = config.FrenchPunctuation()
+
+	text := "Quoi?Vraiment!Oui , c'est ça;enfin:voilà..."
+	result := ProcessTextWithOptions(text, opts)
+	expected := "Quoi ? Vraiment ! Oui, c'est ça ; enfin : voilà..."
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextDashes(t *testing.T) {
+	spaced, err := config.ParsePunctuation("—=spaced --=spaced –=both", config.DefaultPunctuation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	custom := config.DefaultOptions()
+	custom.Punctuation = spaced
+	off, err := config.ParsePunctuation("—=off --=off –=off", config.DefaultPunctuation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	classic := config.DefaultOptions()
+	classic.Punctuation = off
+
+	tests := []struct {
+		input    string
+		opts     config.Options
+		expected string
+	}{
+		{"He paused — then spoke .", config.DefaultOptions(), "He paused—then spoke."},
+		{"wait -- what ? yes---no", config.DefaultOptions(), "wait--what? yes---no"},
+		{"1990 – 2000 , then 2001 –2002", config.DefaultOptions(), "1990 – 2000, then 2001 – 2002"},
+		{"— Hello , she said .\n—", config.DefaultOptions(), "—Hello, she said.\n—"},
+		{"it was — (up) a — b (cap, 2)", config.DefaultOptions(), "it WAS—A—B"},
+		// Single hyphens, hyphens starting a word and ranges belong to their words
+		{"well-known -5 and --verbose", config.DefaultOptions(), "well-known -5 and --verbose"},
+		{"pages 10–20 or x–y ,—-5", config.DefaultOptions(), "pages 10–20 or x – y,—-5"},
+		{"He paused—then -- no 10–20", custom, "He paused — then -- no 10–20"},
+		{"a–b x--y", custom, "a–b x -- y"},
+		{"word — word -- word", classic, "word — word -- word"},
+	}
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, test.opts); result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextCustomPunctuation(t *testing.T) {
+	rules, err := config.ParsePunctuation("—=both ¿=right ¡=right", config.DefaultPunctuation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := config.DefaultOptions()
+	opts.Punctuation = rules
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"wait — what", "wait—what"},
+		{"Dijo ¿ qué ? y ¡ hola !", "Dijo ¿qué? y ¡hola!"},
+		{"one ¿¡ two", "one ¿¡two"},
+	}
+
+	for _, test := range tests {
+		result := ProcessTextWithOptions(test.input, opts)
+		if result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+}
+
+func TestProcessTextArticleExceptions(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.ArticleExceptions = map[string]string{"university": config.ARTICLE_A, "fbi": config.ARTICLE_AN}
+
+	text := "an university, a FBI agent and a apple"
+	result := ProcessTextWithOptions(text, opts)
+	expected := "a university, an FBI agent and an apple"
+
+	if result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestProcessTextBuiltInArticleExceptions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"an university", "a university"},
+		{"an one-way street and an euro", "a one-way street and a euro"},
+		{"a hour, a honest man and a heir", "an hour, an honest man and an heir"},
+		{"a unicorn and a apple", "a unicorn and an apple"},
+		{"a FBI agent with a MBA", "an FBI agent with an MBA"},
+		{"a uncle", "an uncle"}, // words without an exception keep the vowel rule
+	}
+	for _, test := range tests {
+		if result := ProcessText(test.input); result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// Removing a word from the built-in list restores the vowel rule for it
+	opts := config.DefaultOptions()
+	opts.ArticleExceptions, _ = config.AddArticleExceptions(opts.ArticleRules(), "", "university")
+	if result := ProcessTextWithOptions("a university and an one", opts); result != "an university and a one" {
+		t.Errorf("Unexpected result with university removed: %q", result)
+	}
+}
+
+func TestProcessTextPreserveWhitespace(t *testing.T) {
+	opts := config.DefaultOptions()
+	opts.PreserveWhitespace = true
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"    indented  text\twith (up) tabs", "    indented  text\tWITH tabs"},
+		{"a  apple ,  really", "an  apple,  really"},
+		{"say '  hi  '  now", "say 'hi'  now"},
+		{"x ,y\n\t\tnext   line  ", "x, y\n\t\tnext   line  "},
+		{"one (up, 2)   two", "ONE   two"},
+	}
+
+	for _, test := range tests {
+		result := ProcessTextWithOptions(test.input, opts)
+		if result != test.expected {
+			t.Errorf("ProcessTextWithOptions(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	// The default assembler still collapses whitespace
+	if result := ProcessText("    indented  text\twith (up) tabs"); result != "indented text WITH tabs" {
+		t.Errorf("Expected collapsed whitespace, got %q", result)
+	}
+}
+
+func TestProcessTextLineEndings(t *testing.T) {
+	text := "a apple (up)\r\nsecond , line\nthird\r\n"
+
+	tests := []struct {
+		mode     string
+		expected string
+	}{
+		{config.LINE_ENDING_AUTO, "an APPLE\r\nsecond, line\nthird\r\n"},
+		{config.LINE_ENDING_LF, "an APPLE\nsecond, line\nthird\n"},
+		{config.LINE_ENDING_CRLF, "an APPLE\r\nsecond, line\r\nthird\r\n"},
+	}
+
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.LineEnding = test.mode
+		result := ProcessTextWithOptions(text, opts)
+		if result != test.expected {
+			t.Errorf("%s: expected %q, got %q", test.mode, test.expected, result)
+		}
+	}
+}
+
+func TestProcessTextNestedQuotes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`" He said ' stop ' twice . "`, `"He said 'stop' twice."`},
+		{`' she wrote " no " today '`, `'she wrote "no" today'`},
+		{`" a ' b "`, `"a ' b"`},
+		{`" outer ' inner " back ' end "`, `"outer ' inner" back 'end "`},
+	}
+
+	for _, test := range tests {
+		result := ProcessText(test.input)
+		if result != test.expected {
+			t.Errorf("ProcessText(%q) = %q, expected %q", test.input, result, test.expected)
+		}
+	}
+
+	opts := config.DefaultOptions()
+	opts.SmartQuotes = true
+	text := `" He said ' stop ' twice "`
+	if result, expected := ProcessTextWithOptions(text, opts), "“He said ‘stop’ twice”"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+// punctuation-heavy text: almost every token removes the space written before it
+var punctuationHeavyText = strings.Repeat("Well , no ! Yes ? Maybe ; then : stop . And ... so , on ! ", 200)
+
+func BenchmarkProcessTextPunctuationHeavy(b *testing.B) {
+	b.SetBytes(int64(len(punctuationHeavyText)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ProcessText(punctuationHeavyText)
+	}
+}
+
+func BenchmarkFlushTokensPunctuationHeavy(b *testing.B) {
+	processor := tokenize(punctuationHeavyText, config.DefaultOptions())
+	tokens := processor.tokenIdx
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		processor.reset()
+		processor.tokenIdx = tokens
+		processor.flushTokens()
+	}
+}
+
+func TestProcessTextNormalize(t *testing.T) {
+	decomposed := "café école (up, 2)"
+	composed := "café école (up, 2)"
+
+	tests := []struct {
+		form     string
+		expected string
+	}{
+		{config.NORMALIZE_NFC, "CAFÉ ÉCOLE"},
+		{config.NORMALIZE_NFD, "CAFÉ ÉCOLE"},
+	}
+
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.Normalize = test.form
+		for _, input := range []string{decomposed, composed} {
+			result := ProcessTextWithOptions(input, opts)
+			if result != test.expected {
+				t.Errorf("%s: ProcessTextWithOptions(%q) = %q, expected %q", test.form, input, result, test.expected)
+			}
+		}
+	}
+
+	// Without normalization each form is kept as written
+	if result := ProcessText(decomposed); result != "CAFÉ ÉCOLE" {
+		t.Errorf("Expected decomposed output, got %q", result)
+	}
+}
+
+func TestProcessTextRawRegions(t *testing.T) {
+	preserve := config.DefaultOptions()
+	preserve.PreserveWhitespace = true
+	custom := config.DefaultOptions()
+	custom.RawStart, custom.RawEnd = "<<", ">>"
+	disabled := config.DefaultOptions()
+	disabled.RawStart, disabled.RawEnd = "", ""
+	dialogue := config.DefaultOptions()
+	dialogue.Dialogue = config.DIALOGUE_AMERICAN
+	dialogue.SmartQuotes = true
+	ordinals := config.DefaultOptions()
+	ordinals.NormalizeOrdinals = true
+
+	tests := []struct {
+		name     string
+		input    string
+		opts     config.Options
+		expected string
+	}{
+		{"commands", "run (raw)x = f(1) (up)(endraw) now (up)", config.DefaultOptions(), "run x = f(1) (up) NOW"},
+		{"spacing and quotes", "see (raw)a  ,b ' c '(endraw) here , ' ok '", config.DefaultOptions(), "see a  ,b ' c ' here, 'ok'"},
+		{"articles", "a (raw)apple(endraw) a apple", config.DefaultOptions(), "a apple an apple"},
+		{"not counted by commands", "one two (raw)three(endraw) (up, 2)", config.DefaultOptions(), "ONE TWO three"},
+		{"glued to words", "x(raw)(low)(endraw)y", config.DefaultOptions(), "x(low)y"},
+		{"over lines", "a (raw)b  \n  (cap)\r\nc(endraw) d (up)", config.DefaultOptions(), "a b  \n  (cap)\r\nc D"},
+		{"unclosed", "go (raw)' x ' (up)", config.DefaultOptions(), "go ' x ' (up)"},
+		{"preserve whitespace", "a  (raw) b (endraw)  c (up)", preserve, "a   b   C"},
+		{"custom markers", "<<(up)>> (raw)(endraw) x (up)", custom, "(up) (raw)(endraw) X"},
+		{"disabled", "x (raw)(up)(endraw)", disabled, "x (RAW) (endraw)"},
+		{"dialogue", "\"stop\" she said (raw)\"hi\" she said(endraw)", dialogue, "“stop,” she said \"hi\" she said"},
+		{"ordinals", "(raw)1 st(endraw) 2 nd", ordinals, "1 st 2nd"},
+		{"placeholder in the input", "a \uE000 (raw)b(endraw) \uE000c", config.DefaultOptions(), "a \uE000 b \uE000c"},
+	}
+
+	for _, test := range tests {
+		if result := ProcessTextWithOptions(test.input, test.opts); result != test.expected {
+			t.Errorf("%s: ProcessTextWithOptions(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// ${name} and {{name}} placeholders, spaces inside the braces allowed, with the backslash
+// escaping one
+var placeholderPattern = regexp.MustCompile(`\\?(?:\$\{\s*([\p{L}_][\p{L}\p{Nd}_.-]*)\s*\}|\{\{\s*([\p{L}_][\p{L}\p{Nd}_.-]*)\s*\}\})`)
+
+// placeholder of no variable, left in the text
+type undefinedVariable struct {
+	at   int // rune index in the expanded text
+	text string
+}
+
+// expandVariables replaces the placeholders of text outside raw regions with the values of
+// opts.Variables. An escaped placeholder loses its backslash and stays, like one of no variable.
+func expandVariables(text string, opts config.Options) (string, []undefinedVariable) {
+	if !opts.ExpandVariables || (!strings.Contains(text, "${") && !strings.Contains(text, "{{")) {
+		return text, nil
+	}
+	var undefined []undefinedVariable
+	runes, counted := 0, 0 // runes of the expanded text up to byte counted
+	expanded := outsideRaw(text, opts, func(result *strings.Builder, part string) {
+		at := 0
+		for _, match := range placeholderPattern.FindAllStringSubmatchIndex(part, -1) {
+			result.WriteString(part[at:match[0]])
+			at = match[1]
+			placeholder := part[match[0]:match[1]]
+			if strings.HasPrefix(placeholder, `\`) {
+				result.WriteString(placeholder[1:])
+				continue
+			}
+			name := submatch(part, match, 1) + submatch(part, match, 2)
+			if value, ok := opts.Variables[name]; ok {
+				result.WriteString(value)
+				continue
+			}
+			runes += utf8.RuneCountInString(result.String()[counted:])
+			counted = result.Len()
+			undefined = append(undefined, undefinedVariable{at: runes, text: placeholder})
+			result.WriteString(placeholder)
+		}
+		result.WriteString(part[at:])
+	})
+	return expanded, undefined
+}
+
+// returns submatch n of match in text, "" when it did not take part
+func submatch(text string, match []int, n int) string {
+	if match[2*n] < 0 {
+		return ""
+	}
+	return text[match[2*n]:match[2*n+1]]
+}
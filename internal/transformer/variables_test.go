@@ -0,0 +1,38 @@
+package transformer
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"testing"
+)
+
+func TestProcessTextVariables(t *testing.T) {
+	variables := map[string]string{"name": "ada", "team.lead": "Grace Hopper", "ΧΩΡΑ": "Ελλάδα"}
+	tests := []struct {
+		name     string
+		input    string
+		expand   bool
+		expected string
+	}{
+		{"off by default", "Dear ${name}", false, "Dear ${name}"},
+		{"both syntaxes", "Dear ${name} and {{ team.lead }}", true, "Dear ada and Grace Hopper"},
+		{"before the commands", "Dear ${name} (cap), ask {{team.lead}} (up, 2)", true, "Dear Ada, ask GRACE HOPPER"},
+		{"unicode names", "from ${ΧΩΡΑ}", true, "from Ελλάδα"},
+		{"undefined", "Dear ${nobody} {{ }}", true, "Dear ${nobody} {{ }}"},
+		{"escaped", `cost \${name} and \{{name}}`, true, "cost ${name} and {{name}}"},
+		{"raw regions", "(raw)${name}(endraw) ${name}", true, "${name} ada"},
+	}
+	for _, test := range tests {
+		opts := config.DefaultOptions()
+		opts.ExpandVariables, opts.Variables = test.expand, variables
+		if result := ProcessTextWithOptions(test.input, opts); result != test.expected {
+			t.Errorf("%s: ProcessText(%q) = %q, expected %q", test.name, test.input, result, test.expected)
+		}
+	}
+
+	opts := config.DefaultOptions()
+	opts.ExpandVariables, opts.Variables = true, variables
+	_, warnings := ProcessTextWarnings("Dear ${name}, ${nobody} says {{ who }}", opts)
+	if len(warnings) != 2 || warnings[0].String() != "1:11: ${nobody}: undefined variable" || warnings[1].Command != "{{ who }}" {
+		t.Errorf("Expected a warning for each undefined variable, got %v", warnings)
+	}
+}
@@ -0,0 +1,232 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"math"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrUnapplied reports a command that was not applied under config.UNAPPLIED_ERROR
+var ErrUnapplied = errors.New("command not applied")
+
+// Warning reports a command that was dropped or left in the text as written, or one
+// applied to fewer words than its count asked for
+type Warning struct {
+	Line    int    // 1-based line of the command
+	Column  int    // 1-based column of its opening parenthesis, in characters
+	Offset  int    // byte offset of its opening parenthesis
+	Command string // the command as written: "(up, 0)"
+	Reason  string // why it was not applied: "count must be positive"
+	Applied bool   // the command was applied all the same, its count was clamped
+}
+
+// String formats a warning like a compiler message: 3:7: (up, 0): count must be positive
+func (w Warning) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", w.Line, w.Column, w.Command, w.Reason)
+}
+
+// warn records a warning for the command written at rune index at, its position is
+// resolved once the whole text is tokenized
+func (tp *TokenProcessor) warn(at int, command, reason string) {
+	tp.warnings = append(tp.warnings, Warning{Offset: at, Command: command, Reason: reason})
+}
+
+// UnappliedError returns the ErrUnapplied for the first of warnings when opts asks for
+// config.UNAPPLIED_ERROR, nil otherwise
+func UnappliedError(warnings []Warning, opts config.Options) error {
+	if opts.UnappliedCommandPolicy != config.UNAPPLIED_ERROR || len(warnings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrUnapplied, warnings[0])
+}
+
+// unapplied warns about the command text written at rune index at, before the token at pos,
+// and drops or keeps it as the policy says
+func (tp *TokenProcessor) unapplied(at, pos int, text, reason string) {
+	tp.warn(at, text, reason)
+	tp.settle(at, pos, text, false)
+}
+
+// settle takes a command that changed a word out of the text. One that changed none is
+// dropped too, or stays as written under UNAPPLIED_KEEP and UNAPPLIED_ERROR: a RAW token
+// no later command or stage changes.
+func (tp *TokenProcessor) settle(at, pos int, text string, applied bool) {
+	if policy := tp.opts.UnappliedCommandPolicy; !applied && (policy == config.UNAPPLIED_KEEP || policy == config.UNAPPLIED_ERROR) {
+		tp.insertToken(pos, at, Token{Type: RAW, Value: text})
+		return
+	}
+	tp.record(at, at+utf8.RuneCountInString(text), RULE_COMMAND, text)
+	if !tp.opts.PreserveWhitespace {
+		return
+	}
+	// The command disappears from the output, and so does one of the spaces around it
+	switch {
+	case pos > 0 && tp.tokens[pos-1].Type == SPACE:
+		tp.deleteToken(pos - 1)
+	case pos == tp.tokenIdx:
+		tp.skipSpace = true
+	case tp.tokens[pos].Type == SPACE:
+		tp.deleteToken(pos)
+	}
+}
+
+// shortCount warns under SHORT_COUNT_WARN about the command text written at rune index at,
+// whose count reached only words of the text. A count of all reaches every word there is.
+func (tp *TokenProcessor) shortCount(at int, text string, count, words int) {
+	if tp.opts.ShortCountPolicy != config.SHORT_COUNT_WARN || count == math.MaxInt {
+		return
+	}
+	noun := "words"
+	if words == 1 {
+		noun = "word"
+	}
+	tp.warn(at, text, fmt.Sprintf("count %d reaches only %d %s", count, words, noun))
+	tp.warnings[len(tp.warnings)-1].Applied = true
+}
+
+// dropMalformed takes a malformed command out of the text under UNAPPLIED_DROP, inside
+// when it is glued to the word before it
+func (tp *TokenProcessor) dropMalformed(at int, text string, inWord bool) {
+	if inWord {
+		tp.record(at, at+utf8.RuneCountInString(text), RULE_COMMAND, text)
+		return
+	}
+	tp.settle(at, tp.tokenIdx, text, true)
+}
+
+// insertToken puts token, written at rune index at, before the token at pos
+func (tp *TokenProcessor) insertToken(pos, at int, token Token) {
+	if tp.tokenIdx == len(tp.tokens) {
+		tp.tokens = append(tp.tokens, Token{})
+	}
+	copy(tp.tokens[pos+1:tp.tokenIdx+1], tp.tokens[pos:tp.tokenIdx])
+	tp.tokens[pos] = token
+	if tp.tracing {
+		tp.starts = slices.Insert(tp.starts[:tp.tokenIdx], pos, at)
+	}
+	tp.tokenIdx++
+	for k := len(tp.lineBreaks) - 1; k >= 0 && tp.lineBreaks[k] >= pos; k-- {
+		tp.lineBreaks[k]++
+	}
+	// Commands written later at the same place stay after the token
+	for _, pending := range [][]pendingCommand{tp.pending, tp.settling} {
+		for i := range pending {
+			if pending[i].pos > pos || (pending[i].pos == pos && pending[i].at > at) {
+				pending[i].pos++
+			}
+		}
+	}
+}
+
+// deleteToken removes the token at pos
+func (tp *TokenProcessor) deleteToken(pos int) {
+	copy(tp.tokens[pos:tp.tokenIdx-1], tp.tokens[pos+1:tp.tokenIdx])
+	if tp.tracing {
+		tp.starts = slices.Delete(tp.starts[:tp.tokenIdx], pos, pos+1)
+	}
+	tp.tokenIdx--
+	for k := len(tp.lineBreaks) - 1; k >= 0 && tp.lineBreaks[k] > pos; k-- {
+		tp.lineBreaks[k]--
+	}
+	for _, pending := range [][]pendingCommand{tp.pending, tp.settling} {
+		for i := range pending {
+			if pending[i].pos > pos {
+				pending[i].pos--
+			}
+		}
+	}
+}
+
+// countApplied adds words to the words changed by cmd, written at rune index at
+func (tp *TokenProcessor) countApplied(at int, cmd string, words int) {
+	if tp.applied == nil {
+		tp.applied = map[string]int{}
+	}
+	tp.applied[cmd] += words
+	// The words of one command are changed one after the other
+	if n := len(tp.counts); n > 0 && tp.counts[n-1].Offset == at && tp.counts[n-1].Command == cmd {
+		tp.counts[n-1].Words += words
+		return
+	}
+	tp.counts = append(tp.counts, CommandCount{Offset: at, Command: cmd, Words: words})
+}
+
+// locateWarnings turns the rune indexes recorded by warn into lines, columns and byte offsets
+// of text. Bytes that are not valid UTF-8 are one rune each, as in []rune(text).
+func (tp *TokenProcessor) locateWarnings(text string) {
+	slices.SortStableFunc(tp.warnings, func(a, b Warning) int { return a.Offset - b.Offset })
+	line, lineStart, offset, i := 1, 0, 0, 0
+	for k := range tp.warnings {
+		w := &tp.warnings[k]
+		for ; i < w.Offset; i++ {
+			r, size := utf8.DecodeRuneInString(text[offset:])
+			offset += size
+			if r == '\n' {
+				line, lineStart = line+1, i+1
+			}
+		}
+		w.Line, w.Column, w.Offset = line, w.Offset-lineStart+1, offset
+	}
+}
+
+// locateCounts turns the rune indexes recorded by countApplied into lines of text
+func (tp *TokenProcessor) locateCounts(text string) {
+	slices.SortStableFunc(tp.counts, func(a, b CommandCount) int { return a.Offset - b.Offset })
+	line, offset, i := 1, 0, 0
+	for k := range tp.counts {
+		c := &tp.counts[k]
+		for ; i < c.Offset; i++ {
+			r, size := utf8.DecodeRuneInString(text[offset:])
+			offset += size
+			if r == '\n' {
+				line++
+			}
+		}
+		c.Line, c.Offset = line, offset
+	}
+}
+
+// describes what is wrong with a parenthesized text that names a command but is not
+// a valid one, "" when it does not name a command and is ordinary text
+func commandProblem(cmdValue string, aliases map[string]string) string {
+	name, countStr, found := strings.Cut(cmdValue, ",")
+	if !found {
+		return ""
+	}
+	name, _ = commandName(name)
+	info, ok := lookupCommand(name, aliases)
+	if !ok {
+		return ""
+	}
+	countStr = strings.TrimSpace(countStr)
+	switch {
+	case strings.Contains(countStr, ","):
+		return "more than one count"
+	case !info.MultiWord:
+		return info.Name + " takes no count"
+	}
+	return fmt.Sprintf("count %q is not a number or %s", countStr, COUNT_ALL)
+}
+
+// describes why the conversion cmd left word unchanged
+func conversionProblem(cmd, word string) string {
+	switch cmd {
+	case "hex":
+		return fmt.Sprintf("%q is not a hexadecimal number", word)
+	case "bin":
+		return fmt.Sprintf("%q is not a binary number", word)
+	case "tohex", "tobin":
+		return fmt.Sprintf("%q is not a decimal number", word)
+	case "rom":
+		return fmt.Sprintf("%q is not a number from 1 to 3999", word)
+	case "unrom":
+		return fmt.Sprintf("%q is not a roman numeral", word)
+	case "spell":
+		return fmt.Sprintf("%q is not a whole number of at most 36 digits", word)
+	}
+	return ""
+}
@@ -0,0 +1,150 @@
+package transformer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"strings"
+	"testing"
+)
+
+func TestProcessTextWarnings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string // output
+		warnings []string
+	}{
+		{"clean", "it (up) works (cap)", "IT Works", nil},
+		{"no preceding word", "(up) start", "start", []string{"1:1: (up): no preceding word"}},
+		{"count not positive", "one two (up, 0) three", "one two three", []string{"1:9: (up, 0): count must be positive"}},
+		{"negative count", "one (low, -2)", "one", []string{"1:5: (low, -2): count must be positive"}},
+		{"bad count kept", "one (up, two) three", "one (up, two) three", []string{`1:5: (up, two): count "two" is not a number or all`}},
+		{"count on a conversion", "ff (hex, 2)", "ff (hex, 2)", []string{"1:4: (hex, 2): hex takes no count"}},
+		{"two counts", "a (up,1,2)", "a (up,1,2)", []string{"1:3: (up,1,2): more than one count"}},
+		{"not a number", "zz (hex) 12 (bin)", "zz 12", []string{`1:4: (hex): "zz" is not a hexadecimal number`, `1:13: (bin): "12" is not a binary number`}},
+		{"roman range", "0 (rom) IIII (unrom)", "0 IIII", []string{`1:3: (rom): "0" is not a number from 1 to 3999`, `1:14: (unrom): "IIII" is not a roman numeral`}},
+		{"forward conversion", "(hex>) zz", "zz", []string{`1:1: (hex>): "zz" is not a hexadecimal number`}},
+		{"no following word", "done (up>)", "done", []string{"1:6: (up>): no following word"}},
+		{"ordinary parentheses", "(hello) (up, x", "(hello) (up, x", nil},
+		{"escaped", `\(up\) and`, "(up) and", nil},
+		{"raw region", "(raw)(up)(endraw) x", "(up) x", nil},
+		{"later lines", "first\nsecond\n  é (up, 0)\n(low, 0)", "first\nsecond\né\n", []string{"3:5: (up, 0): count must be positive", "4:1: (low, 0): count must be positive"}},
+		{"text order", "done (up>) (up, 0)", "done", []string{"1:6: (up>): no following word", "1:12: (up, 0): count must be positive"}},
+	}
+
+	for _, test := range tests {
+		output, warnings := ProcessTextWarnings(test.input, config.DefaultOptions())
+		if output != test.expected {
+			t.Errorf("%s: ProcessTextWarnings(%q) = %q, expected %q", test.name, test.input, output, test.expected)
+		}
+		var got []string
+		for _, warning := range warnings {
+			got = append(got, warning.String())
+		}
+		if fmt.Sprint(got) != fmt.Sprint(test.warnings) {
+			t.Errorf("%s: warnings for %q are %q, expected %q", test.name, test.input, got, test.warnings)
+		}
+	}
+}
+
+func TestWarningOffset(t *testing.T) {
+	text := "é\n(up, 0) x"
+	_, warnings := ProcessTextWarnings(text, config.DefaultOptions())
+	if len(warnings) != 1 || text[warnings[0].Offset:warnings[0].Offset+7] != "(up, 0)" {
+		t.Errorf("Expected one warning at the byte offset of (up, 0), got %+v", warnings)
+	}
+}
+
+func TestUnappliedCommandPolicy(t *testing.T) {
+	tests := []struct {
+		input        string
+		classic      string
+		drop, keep   string
+		preserveKeep string // UNAPPLIED_KEEP with PreserveWhitespace
+		preserveDrop string // UNAPPLIED_DROP with PreserveWhitespace
+	}{
+		{"(up) start", "start", "start", "(up) start", "(up) start", "start"},
+		{"one two (up, 0) three", "one two three", "one two three", "one two (up, 0) three", "one two (up, 0) three", "one two three"},
+		{"one (up, two) three", "one (up, two) three", "one three", "one (up, two) three", "one (up, two) three", "one three"},
+		{"zz (hex) and 1F (hex)", "zz and 31", "zz and 31", "zz (hex) and 31", "zz (hex) and 31", "zz and 31"},
+		{"go (hex>) zz now", "go zz now", "go zz now", "go (hex>) zz now", "go (hex>) zz now", "go zz now"},
+		{"done (up>) .", "done.", "done.", "done (up>).", "done (up>).", "done."},
+		{"it (hex>)(bin>) zz", "it zz", "it zz", "it (hex>)(bin>) zz", "it (hex>)(bin>) zz", "itzz"},
+		{"keep (up, 0)  (up) spaced", "KEEP spaced", "KEEP spaced", "KEEP (up, 0) spaced", "KEEP (up, 0) spaced", "KEEP spaced"},
+	}
+	run := func(input, policy string, preserve bool) string {
+		opts := config.DefaultOptions()
+		opts.UnappliedCommandPolicy, opts.PreserveWhitespace = policy, preserve
+		output, _ := ProcessTextWarnings(input, opts)
+		return output
+	}
+	for _, test := range tests {
+		for _, c := range []struct {
+			policy   string
+			preserve bool
+			expected string
+		}{
+			{"", false, test.classic},
+			{config.UNAPPLIED_DROP, false, test.drop},
+			{config.UNAPPLIED_KEEP, false, test.keep},
+			{config.UNAPPLIED_ERROR, false, test.keep},
+			{config.UNAPPLIED_KEEP, true, test.preserveKeep},
+			{config.UNAPPLIED_DROP, true, test.preserveDrop},
+		} {
+			if output := run(test.input, c.policy, c.preserve); output != c.expected {
+				t.Errorf("%q with policy %q, preserving whitespace %v: got %q, expected %q", test.input, c.policy, c.preserve, output, c.expected)
+			}
+		}
+	}
+}
+
+func TestUnappliedError(t *testing.T) {
+	opts := config.DefaultOptions()
+	_, warnings := ProcessTextWarnings("fine (up) zz (hex)", opts)
+	if err := UnappliedError(warnings, opts); err != nil {
+		t.Errorf("Expected no error without UNAPPLIED_ERROR, got %v", err)
+	}
+	opts.UnappliedCommandPolicy = config.UNAPPLIED_ERROR
+	err := UnappliedError(warnings, opts)
+	if !errors.Is(err, ErrUnapplied) || !strings.Contains(err.Error(), `1:14: (hex): "zz" is not a hexadecimal number`) {
+		t.Errorf("Expected ErrUnapplied naming the command, got %v", err)
+	}
+	if err := UnappliedError(nil, opts); err != nil {
+		t.Errorf("Expected no error when every command applied, got %v", err)
+	}
+
+	processor, err := NewProcessor(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := processor.ProcessReport(context.Background(), "zz (hex)", PerCallOptions{})
+	if !errors.Is(err, ErrUnapplied) || report.Output != "zz (hex)" || len(report.Warnings) != 1 {
+		t.Errorf("Expected the report and ErrUnapplied, got %+v, %v", report, err)
+	}
+}
+
+func TestShortCountPolicy(t *testing.T) {
+	input := "one two (up, 5) three (cap, all) four (low>, 3) x\n(up, 4) go (rev>, 2) end"
+	expected := "One TWO THREE FOUR X\ngo dne"
+	for _, policy := range []string{"", config.SHORT_COUNT_CLAMP, config.SHORT_COUNT_WARN} {
+		opts := config.DefaultOptions()
+		opts.ShortCountPolicy = policy
+		output, warnings := ProcessTextWarnings(input, opts)
+		if output != expected {
+			t.Errorf("%q: ProcessText(%q) = %q, expected %q", policy, input, output, expected)
+		}
+		var got []string
+		for _, warning := range warnings {
+			got = append(got, warning.String())
+		}
+		want := ""
+		if policy == config.SHORT_COUNT_WARN {
+			want = "1:9: (up, 5): count 5 reaches only 2 words\n2:12: (rev>, 2): count 2 reaches only 1 word"
+		}
+		if strings.Join(got, "\n") != want {
+			t.Errorf("%q: warnings are %q, expected %q", policy, strings.Join(got, "\n"), want)
+		}
+	}
+}
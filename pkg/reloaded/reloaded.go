@@ -0,0 +1,239 @@
+// Package reloaded is the public API of go-reloaded: the text transformer and the file pipeline.
+// It follows semantic versioning, see Version. Everything under internal/ may change at any
+// time; programs outside this module should only depend on this package.
+package reloaded
+
+import (
+	"github.com/GiannisPettas/go-reloaded/internal/config"
+	"github.com/GiannisPettas/go-reloaded/internal/controller"
+	"github.com/GiannisPettas/go-reloaded/internal/transformer"
+)
+
+// Version of the public API. A breaking change to this package moves the module to a /v2 path.
+const Version = "1.0.0"
+
+// Options configures a transformation, DefaultOptions returns the settings of the CLI
+type Options = config.Options
+
+// Processor transforms many texts with shared base options, it is safe for concurrent use
+type Processor = transformer.Processor
+
+// PerCallOptions adjusts a Processor's base options for one call
+type PerCallOptions = transformer.PerCallOptions
+
+// Stage is a pipeline stage PerCallOptions can switch on or off
+type Stage = transformer.Stage
+
+// Warning reports a command that was dropped or left in the text as written
+type Warning = transformer.Warning
+
+// Input is one document of a Processor.ProcessAll batch
+type Input = transformer.Input
+
+// Result is what Processor.ProcessAll did with one Input
+type Result = transformer.Result
+
+// Report is the output, warnings and per-command counts of one transformed text
+type Report = transformer.Report
+
+// FileResult is what ProcessFileResult did with one file
+type FileResult = controller.Result
+
+// Edit is one change of a traced file, see Options.Trace
+type Edit = transformer.Edit
+
+// Substitution is a find/replace rule of Options.Substitutions
+type Substitution = config.Substitution
+
+// Occurrence is one command written in a text, see Inventory
+type Occurrence = transformer.Occurrence
+
+// CommandInfo describes one of the commands understood in a text, see Commands
+type CommandInfo = transformer.CommandInfo
+
+// Pipeline stages
+const (
+	STAGE_ARTICLES  = transformer.STAGE_ARTICLES
+	STAGE_QUOTES    = transformer.STAGE_QUOTES
+	STAGE_ORDINALS  = transformer.STAGE_ORDINALS
+	STAGE_SENTENCES = transformer.STAGE_SENTENCES
+)
+
+// Option values, see the matching Options fields
+const (
+	RAW_START = config.RAW_START
+	RAW_END   = config.RAW_END
+
+	FETCH_TIMEOUT = config.FETCH_TIMEOUT
+
+	NORMALIZE_NONE = config.NORMALIZE_NONE
+	NORMALIZE_NFC  = config.NORMALIZE_NFC
+	NORMALIZE_NFD  = config.NORMALIZE_NFD
+
+	LINE_ENDING_AUTO = config.LINE_ENDING_AUTO
+	LINE_ENDING_LF   = config.LINE_ENDING_LF
+	LINE_ENDING_CRLF = config.LINE_ENDING_CRLF
+
+	ENCODING_UTF8    = config.ENCODING_UTF8
+	ENCODING_LATIN1  = config.ENCODING_LATIN1
+	ENCODING_UTF16LE = config.ENCODING_UTF16LE
+	ENCODING_UTF16BE = config.ENCODING_UTF16BE
+	ENCODING_AUTO    = config.ENCODING_AUTO
+
+	COMPRESS_AUTO = config.COMPRESS_AUTO
+	COMPRESS_NONE = config.COMPRESS_NONE
+	COMPRESS_GZIP = config.COMPRESS_GZIP
+	COMPRESS_ZSTD = config.COMPRESS_ZSTD
+
+	BOM_KEEP  = config.BOM_KEEP
+	BOM_STRIP = config.BOM_STRIP
+	BOM_ADD   = config.BOM_ADD
+
+	UNAPPLIED_DROP  = config.UNAPPLIED_DROP
+	UNAPPLIED_KEEP  = config.UNAPPLIED_KEEP
+	UNAPPLIED_ERROR = config.UNAPPLIED_ERROR
+
+	SHORT_COUNT_CLAMP = config.SHORT_COUNT_CLAMP
+	SHORT_COUNT_WARN  = config.SHORT_COUNT_WARN
+
+	SCOPE_SENTENCE  = config.SCOPE_SENTENCE
+	SCOPE_PARAGRAPH = config.SCOPE_PARAGRAPH
+
+	SUBSTITUTE_BEFORE = config.SUBSTITUTE_BEFORE
+	SUBSTITUTE_AFTER  = config.SUBSTITUTE_AFTER
+
+	DIALOGUE_AMERICAN = config.DIALOGUE_AMERICAN
+	DIALOGUE_LOGICAL  = config.DIALOGUE_LOGICAL
+
+	GROUPING_NONE = config.GROUPING_NONE
+	GROUPING_EN   = config.GROUPING_EN
+	GROUPING_DE   = config.GROUPING_DE
+	GROUPING_FR   = config.GROUPING_FR
+	GROUPING_CH   = config.GROUPING_CH
+	GROUPING_IN   = config.GROUPING_IN
+
+	ARTICLE_A  = config.ARTICLE_A
+	ARTICLE_AN = config.ARTICLE_AN
+)
+
+// Punctuation spacing rules, the values of Options.Punctuation
+const (
+	ATTACH_LEFT   = config.ATTACH_LEFT
+	ATTACH_RIGHT  = config.ATTACH_RIGHT
+	ATTACH_BOTH   = config.ATTACH_BOTH
+	ATTACH_SPACED = config.ATTACH_SPACED
+)
+
+// Categories of ProcessFile errors, test them with errors.Is
+var (
+	ErrInput  = controller.ErrInput  // the input file is missing, unreadable or cannot be decoded
+	ErrOutput = controller.ErrOutput // the output cannot be encoded or written
+	ErrStrict = controller.ErrStrict // Options.Strict is set and a warning was reported
+	ErrVerify = controller.ErrVerify // Options.Verify is set and transforming the output again changes it
+	ErrMemory = controller.ErrMemory // Options.MaxMemory is set and the text to transform at once does not fit
+
+	ErrUnapplied = controller.ErrUnapplied // Options.UnappliedCommandPolicy is UNAPPLIED_ERROR and a command was not applied
+)
+
+// DefaultOptions returns the options the CLI runs with when no flag is given
+func DefaultOptions() Options {
+	return config.DefaultOptions()
+}
+
+// ProcessText validates opts and transforms text with them
+func ProcessText(text string, opts Options) (string, error) {
+	if err := opts.Validate(); err != nil {
+		return "", err
+	}
+	return transformer.ProcessTextWithOptions(text, opts), nil
+}
+
+// ProcessTextWarnings works like ProcessText and also returns a warning for every command
+// that was dropped or left in the text, in text order
+func ProcessTextWarnings(text string, opts Options) (string, []Warning, error) {
+	if err := opts.Validate(); err != nil {
+		return "", nil, err
+	}
+	result, warnings := transformer.ProcessTextWarnings(text, opts)
+	return result, warnings, nil
+}
+
+// Inventory validates opts and lists every command of text in order without transforming it,
+// with the ones that are not valid and their problem
+func Inventory(text string, opts Options) ([]Occurrence, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	return transformer.Inventory(text, opts), nil
+}
+
+// InventoryFile lists every command of the file at inputPath like Inventory, nothing is written
+func InventoryFile(inputPath string, opts Options) ([]Occurrence, error) {
+	return controller.InventoryFile(inputPath, opts)
+}
+
+// NewProcessor validates the base options and returns a Processor using them
+func NewProcessor(base Options) (*Processor, error) {
+	return transformer.NewProcessor(base)
+}
+
+// ProcessFile transforms the file at inputPath into outputPath, in chunks for large files
+func ProcessFile(inputPath, outputPath string, opts Options) error {
+	return controller.ProcessFileWithOptions(inputPath, outputPath, opts)
+}
+
+// ProcessFileResult works like ProcessFile and also returns the bytes, chunks, command counts,
+// warnings and duration of the run
+func ProcessFileResult(inputPath, outputPath string, opts Options) (FileResult, error) {
+	return controller.ProcessFileResult(inputPath, outputPath, opts)
+}
+
+// ParseStages reads a comma separated list of stage names: "articles,quotes"
+func ParseStages(list string) (Stage, error) {
+	return transformer.ParseStages(list)
+}
+
+// ParsePunctuation applies a space separated list of rune=mode rules on top of base: "?=spaced ¿=right"
+func ParsePunctuation(spec string, base map[rune]int) (map[rune]int, error) {
+	return config.ParsePunctuation(spec, base)
+}
+
+// LanguagePunctuation returns the punctuation preset of a language: "en" (classic) or "fr"
+func LanguagePunctuation(lang string) (map[rune]int, error) {
+	return config.LanguagePunctuation(lang)
+}
+
+// LanguageGrouping returns the digit grouping style of a language: "en" or "fr"
+func LanguageGrouping(lang string) (string, error) {
+	return config.LanguageGrouping(lang)
+}
+
+// AddArticleExceptions returns base extended by a comma separated list of words taking article
+func AddArticleExceptions(base map[string]string, article string, words string) (map[string]string, error) {
+	return config.AddArticleExceptions(base, article, words)
+}
+
+// ParseCommandAliases returns base extended by a space separated list of alias=command pairs,
+// checking that every alias stands for a command
+func ParseCommandAliases(spec string, base map[string]string) (map[string]string, error) {
+	aliases, err := config.ParseCommandAliases(spec, base)
+	if err != nil {
+		return nil, err
+	}
+	return aliases, transformer.ValidateAliases(aliases)
+}
+
+// LoadSubstitutions reads the find/replace rules of a substitutions file, see Options.Substitutions
+func LoadSubstitutions(path string) ([]Substitution, error) {
+	return config.LoadSubstitutions(path)
+}
+
+// LoadCorrections reads the typos and corrections of an autocorrect dictionary, see Options.Autocorrect
+func LoadCorrections(path string) (map[string]string, error) {
+	return config.LoadCorrections(path)
+}
+
+// Commands lists the commands understood in a text
+func Commands() []CommandInfo {
+	return transformer.Commands()
+}
@@ -0,0 +1,92 @@
+package reloaded
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessText(t *testing.T) {
+	result, err := ProcessText("it was a apple (up) , really", DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "it was an APPLE, really"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+
+	opts := DefaultOptions()
+	opts.SmartQuotes = true
+	opts.ASCIIQuotes = true
+	if _, err := ProcessText("text", opts); err == nil {
+		t.Error("Expected error for invalid options")
+	}
+}
+
+func TestProcessTextWarnings(t *testing.T) {
+	result, warnings, err := ProcessTextWarnings("one\n(up) two", DefaultOptions())
+	if err != nil || result != "ONE\ntwo" || len(warnings) != 0 {
+		t.Errorf("Unexpected result %q, %v, %v", result, warnings, err)
+	}
+	_, warnings, err = ProcessTextWarnings("one\nzz (hex)", DefaultOptions())
+	if err != nil || len(warnings) != 1 || warnings[0].Line != 2 || warnings[0].Column != 4 {
+		t.Errorf("Expected a warning at 2:4, got %v, %v", warnings, err)
+	}
+}
+
+func TestInventory(t *testing.T) {
+	found, err := Inventory("one\nzz (hex) two (up, 2)", DefaultOptions())
+	if err != nil || len(found) != 2 || found[0].Line != 2 || found[0].Column != 4 || found[1].Name != "up" || found[1].Count != "2" {
+		t.Errorf("Unexpected inventory %+v, %v", found, err)
+	}
+}
+
+func TestProcessor(t *testing.T) {
+	p, err := NewProcessor(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stages, err := ParseStages("sentences")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := p.ProcessContext(t.Context(), "one. two", PerCallOptions{Enable: stages})
+	if err != nil || result != "One. Two" {
+		t.Errorf("Expected %q, got %q, %v", "One. Two", result, err)
+	}
+}
+
+func TestProcessAll(t *testing.T) {
+	p, err := NewProcessor(DefaultOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := p.ProcessAll(t.Context(), []Input{{Text: "one (up)"}, {Text: "two", Options: PerCallOptions{Language: "xx"}}}, 2)
+	if len(results) != 2 || results[0].Output != "ONE" || results[0].Err != nil || results[1].Err == nil {
+		t.Errorf("Unexpected results %+v", results)
+	}
+}
+
+func TestProcessFile(t *testing.T) {
+	dir := t.TempDir()
+	input, output := filepath.Join(dir, "in.txt"), filepath.Join(dir, "out.txt")
+	if err := os.WriteFile(input, []byte("1E (hex) files"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ProcessFile(input, output, DefaultOptions()); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "30 files" {
+		t.Errorf("Expected %q, got %q", "30 files", data)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	if len(Commands()) == 0 {
+		t.Error("Expected the command list")
+	}
+}
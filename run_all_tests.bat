@@ -1,7 +1,19 @@
 @echo off
-echo Clearing all Go caches...
-go clean -cache -testcache -modcache -fuzzcache
+rem Runs the test matrix, one go test run per suite so a failure names its suite.
+rem Pass suite names to run only those: run_all_tests.bat unit golden
+setlocal enabledelayedexpansion
+set suites=%*
+if "%suites%"=="" set suites=unit integration golden fuzzsmoke
 
-echo Running all tests without cache...
-cd internal\testutils
-go test -count=1 -v -run TestAllProject
\ No newline at end of file
+set failed=
+for %%s in (%suites%) do (
+    echo Running the %%s suite...
+    go test -count=1 -tags %%s ./...
+    if errorlevel 1 set failed=!failed! %%s
+)
+
+if not "%failed%"=="" (
+    echo Failed suites:%failed%
+    exit /b 1
+)
+echo All suites passed: %suites%
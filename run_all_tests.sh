@@ -1,7 +1,19 @@
 #!/bin/bash
-echo "Clearing all Go caches..."
-go clean -cache -testcache -modcache -fuzzcache
+# Runs the test matrix, one go test run per suite so a failure names its suite.
+# Pass suite names to run only those: ./run_all_tests.sh unit golden
+suites=("$@")
+if [ ${#suites[@]} -eq 0 ]; then
+    suites=(unit integration golden fuzzsmoke)
+fi
 
-echo "Running all tests without cache..."
-cd internal/testutils
-go test -count=1 -v -run TestAllProject
\ No newline at end of file
+failed=()
+for suite in "${suites[@]}"; do
+    echo "Running the $suite suite..."
+    go test -count=1 -tags "$suite" ./... || failed+=("$suite")
+done
+
+if [ ${#failed[@]} -ne 0 ]; then
+    echo "Failed suites: ${failed[*]}"
+    exit 1
+fi
+echo "All suites passed: ${suites[*]}"
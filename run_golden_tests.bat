@@ -1,7 +1,3 @@
 @echo off
-echo Clearing all Go caches...
-go clean -cache -testcache -modcache -fuzzcache
-
 echo Running Golden Tests without cache...
-cd internal\testutils
-go test -count=1 -v -run TestGoldenCases
\ No newline at end of file
+go test -count=1 -v -tags golden -run "TestGoldenCases|TestGoldenFileCases|TestModeSnapshots" ./internal/testutils